@@ -0,0 +1,248 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package capsule builds and parses UEFI update capsules.
+package capsule
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/google/uuid"
+	"github.com/lukegb/goefivar/efivar"
+)
+
+var (
+	// ErrCorrupted is returned when a capsule is truncated or inconsistent.
+	ErrCorrupted = efivar.NewError(efivar.Corrupt, "capsule: corrupted capsule")
+
+	byteOrder = binary.LittleEndian
+)
+
+// FMPCapsuleGUID identifies a capsule containing an
+// EFI_FIRMWARE_MANAGEMENT_CAPSULE_HEADER, handled by the firmware's
+// Firmware Management Protocol instances.
+var FMPCapsuleGUID = uuid.MustParse("6dcbd5ed-e82d-4c44-bda1-7194199ad92a")
+
+// Flags control how the firmware processes a capsule.
+type Flags uint32
+
+const (
+	// PersistAcrossReset asks the firmware to keep the capsule in memory
+	// across a warm reset and process it on the next boot.
+	PersistAcrossReset Flags = 0x00010000
+	// PopulateSystemTable asks the firmware to install the capsule in the
+	// EFI configuration table. Requires PersistAcrossReset.
+	PopulateSystemTable Flags = 0x00020000
+	// InitiateReset asks the firmware to reset the system after accepting
+	// the capsule. Requires PersistAcrossReset.
+	InitiateReset Flags = 0x00040000
+)
+
+// headerSize is the size of EFI_CAPSULE_HEADER.
+const headerSize = 16 + 4 + 4 + 4
+
+// Capsule is an EFI_CAPSULE_HEADER and the payload it wraps.
+type Capsule struct {
+	GUID  uuid.UUID
+	Flags Flags
+	// HeaderSize is the offset of the payload. If zero, Bytes uses the size
+	// of the header.
+	HeaderSize uint32
+
+	Payload []byte
+}
+
+// New wraps payload in a capsule header.
+func New(guid uuid.UUID, flags Flags, payload []byte) *Capsule {
+	return &Capsule{GUID: guid, Flags: flags, HeaderSize: headerSize, Payload: payload}
+}
+
+func Parse(b []byte) (*Capsule, error) {
+	if len(b) < headerSize {
+		return nil, ErrCorrupted
+	}
+	c := &Capsule{
+		GUID:       efivar.GUIDFromBytes(b),
+		HeaderSize: byteOrder.Uint32(b[16:20]),
+		Flags:      Flags(byteOrder.Uint32(b[20:24])),
+	}
+	size := byteOrder.Uint32(b[24:28])
+	if c.HeaderSize < headerSize || c.HeaderSize > size || uint64(size) > uint64(len(b)) {
+		return nil, ErrCorrupted
+	}
+	c.Payload = append([]byte(nil), b[c.HeaderSize:size]...)
+	return c, nil
+}
+
+func (c *Capsule) Bytes() []byte {
+	hs := c.HeaderSize
+	if hs < headerSize {
+		hs = headerSize
+	}
+	b := make([]byte, int(hs)+len(c.Payload))
+	copy(b, efivar.GUIDBytes(c.GUID))
+	byteOrder.PutUint32(b[16:20], hs)
+	byteOrder.PutUint32(b[20:24], uint32(c.Flags))
+	byteOrder.PutUint32(b[24:28], uint32(len(b)))
+	copy(b[hs:], c.Payload)
+	return b
+}
+
+const (
+	fmpHeaderVersion = 1
+	fmpHeaderSize    = 4 + 2 + 2
+
+	fmpImageHeaderVersion = 3
+	fmpImageHeaderSize    = 4 + 16 + 1 + 3 + 4 + 4 + 8 + 8
+	// fmpImageHeaderSizeV1 is the size of a version 1 image header, which
+	// lacks UpdateHardwareInstance and ImageCapsuleSupport.
+	fmpImageHeaderSizeV1 = 4 + 16 + 1 + 3 + 4 + 4
+)
+
+// FMPImage is an update for one firmware image, handled by the FMP instance
+// which reports ImageTypeID.
+type FMPImage struct {
+	ImageTypeID uuid.UUID
+	// Index is the 1-based index of the image within the FMP instance.
+	Index uint8
+	// HardwareInstance selects a single device when several share an
+	// ImageTypeID. Zero updates all of them.
+	HardwareInstance    uint64
+	ImageCapsuleSupport uint64
+
+	Image      []byte
+	VendorCode []byte
+}
+
+// FMPCapsule is the payload of a capsule with FMPCapsuleGUID: an
+// EFI_FIRMWARE_MANAGEMENT_CAPSULE_HEADER followed by optional drivers and
+// the images to update.
+type FMPCapsule struct {
+	Drivers [][]byte
+	Images  []*FMPImage
+}
+
+// Capsule wraps the FMP payload in a capsule header.
+func (f *FMPCapsule) Capsule(flags Flags) *Capsule {
+	return New(FMPCapsuleGUID, flags, f.Bytes())
+}
+
+func (f *FMPCapsule) Bytes() []byte {
+	items := make([][]byte, 0, len(f.Drivers)+len(f.Images))
+	items = append(items, f.Drivers...)
+	for _, img := range f.Images {
+		items = append(items, img.bytes())
+	}
+
+	var buf bytes.Buffer
+	binary.Write(&buf, byteOrder, uint32(fmpHeaderVersion))
+	binary.Write(&buf, byteOrder, uint16(len(f.Drivers)))
+	binary.Write(&buf, byteOrder, uint16(len(f.Images)))
+	off := uint64(fmpHeaderSize + 8*len(items))
+	for _, it := range items {
+		binary.Write(&buf, byteOrder, off)
+		off += uint64(len(it))
+	}
+	for _, it := range items {
+		buf.Write(it)
+	}
+	return buf.Bytes()
+}
+
+func (img *FMPImage) bytes() []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, byteOrder, uint32(fmpImageHeaderVersion))
+	buf.Write(efivar.GUIDBytes(img.ImageTypeID))
+	buf.Write([]byte{img.Index, 0, 0, 0})
+	binary.Write(&buf, byteOrder, uint32(len(img.Image)))
+	binary.Write(&buf, byteOrder, uint32(len(img.VendorCode)))
+	binary.Write(&buf, byteOrder, img.HardwareInstance)
+	binary.Write(&buf, byteOrder, img.ImageCapsuleSupport)
+	buf.Write(img.Image)
+	buf.Write(img.VendorCode)
+	return buf.Bytes()
+}
+
+// ParseFMP parses the payload of a capsule with FMPCapsuleGUID.
+func ParseFMP(b []byte) (*FMPCapsule, error) {
+	if len(b) < fmpHeaderSize || byteOrder.Uint32(b[0:4]) != fmpHeaderVersion {
+		return nil, ErrCorrupted
+	}
+	drivers := int(byteOrder.Uint16(b[4:6]))
+	images := int(byteOrder.Uint16(b[6:8]))
+	n := drivers + images
+	if len(b) < fmpHeaderSize+8*n {
+		return nil, ErrCorrupted
+	}
+	offsets := make([]uint64, n+1)
+	for i := 0; i < n; i++ {
+		offsets[i] = byteOrder.Uint64(b[fmpHeaderSize+8*i:])
+		if offsets[i] < uint64(fmpHeaderSize+8*n) || offsets[i] > uint64(len(b)) || (i > 0 && offsets[i] < offsets[i-1]) {
+			return nil, ErrCorrupted
+		}
+	}
+	offsets[n] = uint64(len(b))
+
+	f := &FMPCapsule{}
+	for i := 0; i < n; i++ {
+		item := b[offsets[i]:offsets[i+1]]
+		if i < drivers {
+			f.Drivers = append(f.Drivers, append([]byte(nil), item...))
+			continue
+		}
+		img, err := parseFMPImage(item)
+		if err != nil {
+			return nil, err
+		}
+		f.Images = append(f.Images, img)
+	}
+	return f, nil
+}
+
+func parseFMPImage(b []byte) (*FMPImage, error) {
+	if len(b) < fmpImageHeaderSizeV1 {
+		return nil, ErrCorrupted
+	}
+	hs := fmpImageHeaderSizeV1
+	switch v := byteOrder.Uint32(b[0:4]); {
+	case v == 2:
+		hs += 8
+	case v >= 3:
+		hs += 16
+	}
+	if len(b) < hs {
+		return nil, ErrCorrupted
+	}
+	img := &FMPImage{
+		ImageTypeID: efivar.GUIDFromBytes(b[4:]),
+		Index:       b[20],
+	}
+	imageSize := uint64(byteOrder.Uint32(b[24:28]))
+	vendorSize := uint64(byteOrder.Uint32(b[28:32]))
+	if hs > fmpImageHeaderSizeV1 {
+		img.HardwareInstance = byteOrder.Uint64(b[32:40])
+	}
+	if hs == fmpImageHeaderSize {
+		img.ImageCapsuleSupport = byteOrder.Uint64(b[40:48])
+	}
+	if uint64(hs)+imageSize+vendorSize > uint64(len(b)) {
+		return nil, ErrCorrupted
+	}
+	img.Image = append([]byte(nil), b[hs:uint64(hs)+imageSize]...)
+	if vendorSize > 0 {
+		img.VendorCode = append([]byte(nil), b[uint64(hs)+imageSize:uint64(hs)+imageSize+vendorSize]...)
+	}
+	return img, nil
+}
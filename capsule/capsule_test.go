@@ -0,0 +1,161 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package capsule
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lukegb/goefivar/efitime"
+	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/esrt"
+)
+
+func TestCapsuleRoundtrip(t *testing.T) {
+	c := New(uuid.MustParse("5b92f7de-3a43-4f5b-a4b4-ab3c7f0b4b8a"), PersistAcrossReset|InitiateReset, []byte("firmware"))
+	b := c.Bytes()
+	if len(b) != headerSize+8 {
+		t.Fatalf("len(Bytes()) = %d; want %d", len(b), headerSize+8)
+	}
+	got, err := Parse(b)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if !reflect.DeepEqual(got, c) {
+		t.Errorf("Parse(Bytes()) = %+v; want %+v", got, c)
+	}
+	if _, err := Parse(b[:len(b)-1]); err != ErrCorrupted {
+		t.Errorf("Parse(truncated) = %v; want ErrCorrupted", err)
+	}
+}
+
+func TestFMPRoundtrip(t *testing.T) {
+	f := &FMPCapsule{
+		Drivers: [][]byte{[]byte("driver")},
+		Images: []*FMPImage{{
+			ImageTypeID:      uuid.MustParse("d3a2d1c8-f1a7-4e4a-9f76-0b5b1d43a9e1"),
+			Index:            1,
+			HardwareInstance: 7,
+			Image:            []byte("image one"),
+			VendorCode:       []byte("vendor"),
+		}, {
+			ImageTypeID: uuid.MustParse("5b92f7de-3a43-4f5b-a4b4-ab3c7f0b4b8a"),
+			Index:       2,
+			Image:       []byte("image two"),
+		}},
+	}
+	c, err := Parse(f.Capsule(PersistAcrossReset).Bytes())
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if c.GUID != FMPCapsuleGUID {
+		t.Errorf("GUID = %v; want %v", c.GUID, FMPCapsuleGUID)
+	}
+	got, err := ParseFMP(c.Payload)
+	if err != nil {
+		t.Fatalf("ParseFMP: %v", err)
+	}
+	if !reflect.DeepEqual(got, f) {
+		t.Errorf("ParseFMP = %+v; want %+v", got, f)
+	}
+}
+
+func TestSubmitCapsule(t *testing.T) {
+	dir, err := ioutil.TempDir("", "capsule")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(old string) { capsuleLoader = old }(capsuleLoader)
+
+	capsuleLoader = filepath.Join(dir, "missing")
+	c := New(FMPCapsuleGUID, PersistAcrossReset, bytes.Repeat([]byte{0xa5}, 3*chunkSize+17)).Bytes()
+	if err := SubmitCapsule(bytes.NewReader(c)); err != ErrLoaderUnavailable {
+		t.Errorf("SubmitCapsule with no loader = %v; want ErrLoaderUnavailable", err)
+	}
+
+	capsuleLoader = filepath.Join(dir, "loader")
+	if err := ioutil.WriteFile(capsuleLoader, nil, 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	// Trailing data beyond the declared size is not sent.
+	if err := SubmitCapsule(bytes.NewReader(append(c, "trailer"...))); err != nil {
+		t.Fatalf("SubmitCapsule: %v", err)
+	}
+	got, err := ioutil.ReadFile(capsuleLoader)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if !bytes.Equal(got, c) {
+		t.Errorf("loader received %d bytes; want %d", len(got), len(c))
+	}
+
+	if err := SubmitCapsule(bytes.NewReader(c[:len(c)-1])); err == nil {
+		t.Error("SubmitCapsule(truncated) succeeded")
+	}
+}
+
+func TestMatchESRT(t *testing.T) {
+	system := uuid.MustParse("5b92f7de-3a43-4f5b-a4b4-ab3c7f0b4b8a")
+	device := uuid.MustParse("d3a2d1c8-f1a7-4e4a-9f76-0b5b1d43a9e1")
+	entries := []*esrt.Entry{
+		{FirmwareClass: system, Type: esrt.TypeSystemFirmware},
+		{FirmwareClass: device, Type: esrt.TypeDeviceFirmware},
+	}
+
+	if e, err := MatchESRT(New(system, PersistAcrossReset, nil), entries); err != nil || e != entries[0] {
+		t.Errorf("MatchESRT(system capsule) = %v, %v; want %v", e, err, entries[0])
+	}
+	fmp := (&FMPCapsule{Images: []*FMPImage{{ImageTypeID: device, Index: 1}}}).Capsule(PersistAcrossReset)
+	if e, err := MatchESRT(fmp, entries); err != nil || e != entries[1] {
+		t.Errorf("MatchESRT(FMP capsule) = %v, %v; want %v", e, err, entries[1])
+	}
+	if _, err := MatchESRT(New(uuid.New(), 0, nil), entries); err != ErrNoMatchingResource {
+		t.Errorf("MatchESRT(unknown) = %v; want ErrNoMatchingResource", err)
+	}
+}
+
+func TestParseResult(t *testing.T) {
+	guid := uuid.MustParse("5b92f7de-3a43-4f5b-a4b4-ab3c7f0b4b8a")
+	processed := efitime.FromTime(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC))
+	var b bytes.Buffer
+	binary.Write(&b, byteOrder, uint32(resultHeaderSize))
+	binary.Write(&b, byteOrder, uint32(0))
+	b.Write(efivar.GUIDBytes(guid))
+	b.Write(processed.Bytes())
+	binary.Write(&b, byteOrder, uint64(0x8000000000000015))
+
+	r, err := parseResult("Capsule0001", b.Bytes())
+	if err != nil {
+		t.Fatalf("parseResult: %v", err)
+	}
+	want := &Result{Name: "Capsule0001", GUID: guid, Processed: processed, Status: 0x8000000000000015}
+	if !reflect.DeepEqual(r, want) {
+		t.Errorf("parseResult = %+v; want %+v", r, want)
+	}
+	if r.Succeeded() {
+		t.Errorf("Succeeded() = true for status %#x", r.Status)
+	}
+	if _, err := parseResult("Capsule0001", b.Bytes()[:resultHeaderSize-1]); err != ErrCorrupted {
+		t.Errorf("parseResult(truncated) = %v; want ErrCorrupted", err)
+	}
+}
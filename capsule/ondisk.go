@@ -0,0 +1,248 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package capsule
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/google/uuid"
+	"github.com/lukegb/goefivar/efitime"
+	"github.com/lukegb/goefivar/efivar"
+)
+
+var (
+	OsIndicationsName          = efivar.VariableName{GUID: efivar.GlobalUUID, Name: "OsIndications"}
+	OsIndicationsSupportedName = efivar.VariableName{GUID: efivar.GlobalUUID, Name: "OsIndicationsSupported"}
+
+	// ReportUUID is the vendor GUID of the CapsuleLast and Capsule####
+	// result variables.
+	ReportUUID      = uuid.MustParse("39b68c46-f7fb-441b-b6ec-16b0f69821f3")
+	CapsuleLastName = efivar.VariableName{GUID: ReportUUID, Name: "CapsuleLast"}
+)
+
+// fileCapsuleDeliverySupported is EFI_OS_INDICATIONS_FILE_CAPSULE_DELIVERY_SUPPORTED.
+const fileCapsuleDeliverySupported = 0x4
+
+// updateCapsuleDir is where the firmware looks for capsules on the ESP.
+var updateCapsuleDir = filepath.Join("EFI", "UpdateCapsule")
+
+func readUint64(vn efivar.VariableName) (uint64, error) {
+	v, err := vn.Get()
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	if len(v.Data) != 8 {
+		return 0, ErrCorrupted
+	}
+	return byteOrder.Uint64(v.Data), nil
+}
+
+func setOsIndications(set, clear uint64) error {
+	cur, err := readUint64(OsIndicationsName)
+	if err != nil {
+		return err
+	}
+	next := (cur | set) &^ clear
+	if next == cur {
+		return nil
+	}
+	data := make([]byte, 8)
+	byteOrder.PutUint64(data, next)
+	v := &efivar.Variable{
+		VariableName: OsIndicationsName,
+		Data:         data,
+		Attributes:   efivar.NonVolatile | efivar.BootserviceAccess | efivar.RuntimeAccess,
+	}
+	return v.Set(0644)
+}
+
+// OnDiskSupported reports whether the firmware accepts capsules from the ESP.
+func OnDiskSupported() (bool, error) {
+	s, err := readUint64(OsIndicationsSupportedName)
+	if err != nil {
+		return false, err
+	}
+	return s&fileCapsuleDeliverySupported != 0, nil
+}
+
+// StageOnDisk copies the capsule read from r into \EFI\UpdateCapsule on the
+// ESP mounted at esp, and asks the firmware to process it on the next boot.
+// It returns the path of the staged file.
+func StageOnDisk(esp, name string, r io.Reader) (string, error) {
+	ok, err := OnDiskSupported()
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", ErrUnsupported
+	}
+
+	dir := filepath.Join(esp, updateCapsuleDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, name)
+	if err := writeFileSync(path, r); err != nil {
+		return "", err
+	}
+	if err := setOsIndications(fileCapsuleDeliverySupported, 0); err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
+
+// writeFileSync writes r to path, making sure it's on disk before returning:
+// the firmware reads the ESP without the benefit of our page cache.
+func writeFileSync(path string, r io.Reader) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), ".capsule")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// CleanupOnDisk removes any capsules left in \EFI\UpdateCapsule after the
+// firmware's attempt to process them, and clears the request in
+// OsIndications.
+func CleanupOnDisk(esp string) error {
+	dir := filepath.Join(esp, updateCapsuleDir)
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, fi := range fis {
+		if fi.Mode().IsRegular() {
+			if err := os.Remove(filepath.Join(dir, fi.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return setOsIndications(0, fileCapsuleDeliverySupported)
+}
+
+// resultHeaderSize is the size of EFI_CAPSULE_RESULT_VARIABLE_HEADER.
+const resultHeaderSize = 4 + 4 + 16 + 16 + 8
+
+// Result is the outcome of the firmware's most recent attempt to process
+// a capsule, recorded in a Capsule#### variable.
+type Result struct {
+	Name string
+	GUID uuid.UUID
+	// Processed is when the firmware processed the capsule.
+	Processed efitime.Time
+	// Status is the EFI_STATUS of the attempt; zero means success.
+	Status uint64
+}
+
+func (r *Result) Succeeded() bool { return r.Status == 0 }
+
+func (r *Result) String() string {
+	if r.Succeeded() {
+		return fmt.Sprintf("%v: capsule %v processed successfully at %v", r.Name, r.GUID, r.Processed)
+	}
+	return fmt.Sprintf("%v: capsule %v failed at %v with status %#x", r.Name, r.GUID, r.Processed, r.Status)
+}
+
+// parseResult parses the content of the Capsule#### variable name.
+func parseResult(name string, data []byte) (*Result, error) {
+	if len(data) < resultHeaderSize {
+		return nil, ErrCorrupted
+	}
+	processed, err := efitime.Parse(data[24:40])
+	if err != nil {
+		return nil, ErrCorrupted
+	}
+	return &Result{
+		Name:      name,
+		GUID:      efivar.GUIDFromBytes(data[8:]),
+		Processed: processed,
+		Status:    byteOrder.Uint64(data[40:48]),
+	}, nil
+}
+
+func readResult(name string) (*Result, error) {
+	v, err := efivar.VariableName{GUID: ReportUUID, Name: name}.Get()
+	if err != nil {
+		return nil, err
+	}
+	return parseResult(name, v.Data)
+}
+
+// LastResult returns the result of the last capsule the firmware processed.
+func LastResult() (*Result, error) {
+	v, err := CapsuleLastName.Get()
+	if err != nil {
+		return nil, err
+	}
+	if len(v.Data)%2 != 0 {
+		return nil, ErrCorrupted
+	}
+	u := make([]uint16, len(v.Data)/2)
+	binary.Read(strings.NewReader(string(v.Data)), byteOrder, u)
+	return readResult(strings.TrimRight(string(utf16.Decode(u)), "\x00"))
+}
+
+// resultName matches the names of the Capsule#### result variables.
+var resultName = regexp.MustCompile(`^Capsule[0-9A-F]{4}$`)
+
+// Results returns every capsule result the firmware has kept, oldest
+// first. The firmware reuses the variables once it reaches CapsuleMax, so
+// the order is that of Processed rather than of the names.
+func Results() ([]*Result, error) {
+	vns, err := efivar.Variables()
+	if err != nil {
+		return nil, err
+	}
+	var out []*Result
+	for _, vn := range vns {
+		if vn.GUID != ReportUUID || !resultName.MatchString(vn.Name) {
+			continue
+		}
+		r, err := readResult(vn.Name)
+		switch {
+		case os.IsNotExist(err):
+			continue
+		case err != nil:
+			return nil, fmt.Errorf("capsule: reading %v: %v", vn.Name, err)
+		}
+		out = append(out, r)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Processed.Compare(out[j].Processed) < 0 })
+	return out, nil
+}
@@ -0,0 +1,112 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package capsule
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+
+	"github.com/lukegb/goefivar/efivar"
+)
+
+// capsuleLoader is the kernel's capsule loader device, provided by the
+// capsule-loader module.
+var capsuleLoader = "/dev/efi_capsule_loader"
+
+// chunkSize is the size of each write to the capsule loader.
+const chunkSize = 4096
+
+var (
+	ErrLoaderUnavailable = efivar.NewError(efivar.Unsupported, "capsule: kernel capsule loader unavailable (is the capsule-loader module loaded?)")
+	ErrInvalidCapsule    = errors.New("capsule: capsule rejected as invalid")
+	ErrUnsupported       = efivar.NewError(efivar.Unsupported, "capsule: firmware does not support this capsule")
+	ErrOutOfResources    = errors.New("capsule: firmware out of resources for capsule")
+	ErrSecurityViolation = efivar.NewError(efivar.AuthRequired, "capsule: capsule failed firmware authentication")
+)
+
+// loaderError maps the errno returned by the capsule loader to one of the
+// errors above, where possible.
+func loaderError(err error) error {
+	errno := err
+	switch e := err.(type) {
+	case *os.PathError:
+		errno = e.Err
+	case *os.SyscallError:
+		errno = e.Err
+	}
+	switch errno {
+	case syscall.ENOENT, syscall.ENXIO:
+		return ErrLoaderUnavailable
+	case syscall.EINVAL:
+		return ErrInvalidCapsule
+	case syscall.ENOSYS, syscall.ENODEV:
+		return ErrUnsupported
+	case syscall.ENOSPC, syscall.ENOMEM:
+		return ErrOutOfResources
+	case syscall.EACCES:
+		return ErrSecurityViolation
+	}
+	return err
+}
+
+// SubmitCapsule delivers the capsule read from r to the firmware through the
+// kernel's capsule loader. The capsule is processed on the next reboot.
+func SubmitCapsule(r io.Reader) error {
+	hdr := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return ErrCorrupted
+		}
+		return err
+	}
+	size := int64(byteOrder.Uint32(hdr[24:28]))
+	if hs := int64(byteOrder.Uint32(hdr[16:20])); hs < headerSize || hs > size {
+		return ErrCorrupted
+	}
+
+	f, err := os.OpenFile(capsuleLoader, os.O_WRONLY, 0)
+	if err != nil {
+		return loaderError(err)
+	}
+	// The capsule is only submitted once every byte has been written; closing
+	// the loader early discards it.
+	defer f.Close()
+
+	if _, err := f.Write(hdr); err != nil {
+		return loaderError(err)
+	}
+	buf := make([]byte, chunkSize)
+	remaining := size - headerSize
+	for remaining > 0 {
+		chunk := buf
+		if remaining < int64(len(chunk)) {
+			chunk = chunk[:remaining]
+		}
+		n, err := io.ReadFull(r, chunk)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return fmt.Errorf("capsule: capsule is %d bytes short of its declared size", remaining-int64(n))
+		} else if err != nil {
+			return err
+		}
+		if _, err := f.Write(chunk); err != nil {
+			return loaderError(err)
+		}
+		remaining -= int64(n)
+	}
+	return loaderError(f.Close())
+}
@@ -0,0 +1,231 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package capsule
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/google/uuid"
+	"github.com/lukegb/goefivar/efiboot"
+	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/esrt"
+)
+
+var (
+	ErrNoMatchingResource = errors.New("capsule: no ESRT entry matches the capsule")
+	ErrNoDeliveryMethod   = efivar.NewError(efivar.Unsupported, "capsule: neither the capsule loader nor capsule-on-disk is available")
+	ErrNoESP              = errors.New("capsule: no EFI System Partition found for capsule-on-disk")
+)
+
+// Method is how a capsule is delivered to the firmware.
+type Method int
+
+const (
+	// MethodAuto uses the capsule loader if available, and capsule-on-disk
+	// otherwise.
+	MethodAuto Method = iota
+	MethodLoader
+	MethodOnDisk
+)
+
+func (m Method) String() string {
+	switch m {
+	case MethodAuto:
+		return "auto"
+	case MethodLoader:
+		return "loader"
+	case MethodOnDisk:
+		return "on-disk"
+	}
+	return fmt.Sprintf("Method(%d)", int(m))
+}
+
+// MatchESRT finds the ESRT entry for the resource c updates. For FMP
+// capsules that is the entry for one of the contained images; otherwise it is
+// the entry for the capsule GUID itself.
+func MatchESRT(c *Capsule, entries []*esrt.Entry) (*esrt.Entry, error) {
+	classes := []uuid.UUID{c.GUID}
+	if c.GUID == FMPCapsuleGUID {
+		f, err := ParseFMP(c.Payload)
+		if err != nil {
+			return nil, err
+		}
+		classes = classes[:0]
+		for _, img := range f.Images {
+			classes = append(classes, img.ImageTypeID)
+		}
+	}
+	for _, class := range classes {
+		for _, e := range entries {
+			if e.FirmwareClass == class {
+				return e, nil
+			}
+		}
+	}
+	return nil, ErrNoMatchingResource
+}
+
+// UpdateOptions controls ScheduleUpdate.
+type UpdateOptions struct {
+	Method Method
+	// ESP is the mountpoint of the EFI System Partition for capsule-on-disk.
+	// If empty, the first one found is used.
+	ESP string
+}
+
+// PendingUpdate records a scheduled update, to be checked with CheckUpdate
+// after the reboot which applies it. It is suitable for encoding as JSON so
+// it can be kept across the reboot.
+type PendingUpdate struct {
+	FirmwareClass   uuid.UUID
+	CapsuleGUID     uuid.UUID
+	PreviousVersion uint32
+	Method          Method
+	// ESP and Path locate the staged file for capsule-on-disk.
+	ESP  string `json:",omitempty"`
+	Path string `json:",omitempty"`
+}
+
+func chooseMethod(m Method) (Method, error) {
+	if m != MethodAuto {
+		return m, nil
+	}
+	if _, err := os.Stat(capsuleLoader); err == nil {
+		return MethodLoader, nil
+	}
+	if ok, err := OnDiskSupported(); err != nil {
+		return 0, err
+	} else if ok {
+		return MethodOnDisk, nil
+	}
+	return 0, ErrNoDeliveryMethod
+}
+
+// ScheduleUpdate matches the capsule b to the resource it updates and
+// delivers it to the firmware, to be applied on the next reboot.
+func ScheduleUpdate(b []byte, opts *UpdateOptions) (*PendingUpdate, error) {
+	if opts == nil {
+		opts = &UpdateOptions{}
+	}
+	c, err := Parse(b)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := esrt.Entries()
+	if err != nil {
+		return nil, err
+	}
+	e, err := MatchESRT(c, entries)
+	if err != nil {
+		return nil, err
+	}
+	p := &PendingUpdate{
+		FirmwareClass:   e.FirmwareClass,
+		CapsuleGUID:     c.GUID,
+		PreviousVersion: e.Version,
+	}
+	if p.Method, err = chooseMethod(opts.Method); err != nil {
+		return nil, err
+	}
+
+	switch p.Method {
+	case MethodLoader:
+		if c.Flags&PersistAcrossReset == 0 {
+			return nil, fmt.Errorf("capsule: capsule must have PersistAcrossReset set to survive the reboot")
+		}
+		if err := SubmitCapsule(bytes.NewReader(b)); err != nil {
+			return nil, err
+		}
+	case MethodOnDisk:
+		p.ESP = opts.ESP
+		if p.ESP == "" {
+			esps, err := efiboot.ESPMountpoints()
+			if err != nil {
+				return nil, err
+			}
+			if len(esps) == 0 {
+				return nil, ErrNoESP
+			}
+			p.ESP = esps[0]
+		}
+		if p.Path, err = StageOnDisk(p.ESP, e.FirmwareClass.String()+".cap", bytes.NewReader(b)); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("capsule: unknown delivery method %v", p.Method)
+	}
+	return p, nil
+}
+
+// Outcome is the result of a scheduled update, after the reboot.
+type Outcome struct {
+	Update *PendingUpdate
+	// Entry is the resource's current ESRT entry.
+	Entry *esrt.Entry
+	// Result is the firmware's record of processing the capsule, if any.
+	Result *Result
+
+	Succeeded bool
+}
+
+func (o *Outcome) String() string {
+	switch {
+	case o.Succeeded:
+		return fmt.Sprintf("%v updated from version %d to %d", o.Update.FirmwareClass, o.Update.PreviousVersion, o.Entry.Version)
+	case o.Result != nil && o.Result.GUID == o.Update.CapsuleGUID && !o.Result.Succeeded():
+		return fmt.Sprintf("%v update failed: firmware status %#x", o.Update.FirmwareClass, o.Result.Status)
+	case o.Entry.LastAttemptStatus != esrt.StatusSuccess:
+		return fmt.Sprintf("%v update failed: %v", o.Update.FirmwareClass, o.Entry.LastAttemptStatus)
+	}
+	return fmt.Sprintf("%v update was not applied", o.Update.FirmwareClass)
+}
+
+// CheckUpdate determines whether a scheduled update was applied, from the
+// capsule result variables and the ESRT. Capsules staged on disk are removed.
+func CheckUpdate(p *PendingUpdate) (*Outcome, error) {
+	entries, err := esrt.Entries()
+	if err != nil {
+		return nil, err
+	}
+	o := &Outcome{Update: p}
+	for _, e := range entries {
+		if e.FirmwareClass == p.FirmwareClass {
+			o.Entry = e
+		}
+	}
+	if o.Entry == nil {
+		return nil, ErrNoMatchingResource
+	}
+
+	if r, err := LastResult(); err == nil {
+		o.Result = r
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	resultOK := o.Result != nil && o.Result.GUID == p.CapsuleGUID && o.Result.Succeeded()
+	esrtOK := o.Entry.LastAttemptStatus == esrt.StatusSuccess && o.Entry.Version != p.PreviousVersion
+	o.Succeeded = resultOK || esrtOK
+
+	if p.Method == MethodOnDisk {
+		if err := CleanupOnDisk(p.ESP); err != nil {
+			return o, err
+		}
+	}
+	return o, nil
+}
@@ -0,0 +1,131 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// boot-once selects the boot option to use for the next boot only, by number
+// or by part of its description, and optionally reboots into it.
+//
+//	boot-once -reboot windows
+//	boot-once -firmware-setup -reboot
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/lukegb/goefivar/efiboot"
+	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/internal/completion"
+)
+
+var (
+	reboot        = flag.Bool("reboot", false, "Reboot immediately")
+	firmwareSetup = flag.Bool("firmware-setup", false, "Boot into the firmware's setup menu instead of a boot option")
+	list          = flag.Bool("l", false, "List the boot options and exit")
+)
+
+// findOption returns the number of the boot option target refers to: either
+// its number, such as 0003 or Boot0003, or a case-insensitive substring of
+// its description which matches only one option.
+func findOption(bos []*efiboot.BootOption, target string) (uint16, error) {
+	if n, err := strconv.ParseUint(strings.TrimPrefix(target, "Boot"), 16, 16); err == nil {
+		for _, bo := range bos {
+			if num, err := efiboot.BootOptionNumber(bo.Variable.VariableName); err == nil && num == uint16(n) {
+				return num, nil
+			}
+		}
+	}
+
+	var matches []*efiboot.BootOption
+	for _, bo := range bos {
+		if strings.Contains(strings.ToLower(bo.LoadOpt.Description), strings.ToLower(target)) {
+			matches = append(matches, bo)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return 0, fmt.Errorf("no boot option matches %q", target)
+	case 1:
+		return efiboot.BootOptionNumber(matches[0].Variable.VariableName)
+	}
+	var names []string
+	for _, bo := range matches {
+		names = append(names, fmt.Sprintf("%s (%s)", bo.Variable.Name, bo.LoadOpt.Description))
+	}
+	return 0, fmt.Errorf("%q matches several boot options: %s", target, strings.Join(names, ", "))
+}
+
+func main() {
+	completion.Register(completion.BootVariables)
+	flag.Parse()
+	completion.Run()
+
+	if !efivar.Supported() {
+		fmt.Fprintf(os.Stderr, "EFI variables are not supported on this system.\n")
+		os.Exit(1)
+	}
+
+	bos, err := efiboot.BootOptions()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch {
+	case *list:
+		for _, bo := range bos {
+			fmt.Printf("%s  %s\n", bo.Variable.Name, bo.LoadOpt.Description)
+		}
+		return
+	case *firmwareSetup:
+		if flag.NArg() != 0 {
+			log.Fatalf("-firmware-setup takes no boot option")
+		}
+		ok, err := efiboot.FirmwareSetupSupported()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if !ok {
+			log.Fatalf("The firmware does not support booting into its setup menu on request")
+		}
+		if err := efiboot.SetBootToFirmwareSetup(true); err != nil {
+			log.Fatalf("Setting OsIndications: %v", err)
+		}
+		fmt.Println("The next boot will enter the firmware setup menu.")
+	default:
+		if flag.NArg() != 1 {
+			fmt.Fprintf(os.Stderr, "usage: %s [-reboot] {NUMBER | DESCRIPTION | -firmware-setup | -l}\n", os.Args[0])
+			os.Exit(1)
+		}
+		num, err := findOption(bos, flag.Arg(0))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := efiboot.SetBootNext(num); err != nil {
+			log.Fatalf("Setting BootNext: %v", err)
+		}
+		fmt.Printf("The next boot will use Boot%04X.\n", num)
+	}
+
+	if *reboot {
+		cmd := exec.Command("reboot")
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			log.Fatalf("Rebooting: %v", err)
+		}
+	}
+}
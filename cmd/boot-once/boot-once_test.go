@@ -0,0 +1,60 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/lukegb/goefivar/efiboot"
+	"github.com/lukegb/goefivar/efivar"
+)
+
+func TestFindOption(t *testing.T) {
+	bo := func(num uint16, desc string) *efiboot.BootOption {
+		return &efiboot.BootOption{
+			Variable: &efivar.Variable{VariableName: efiboot.BootOptionName(num)},
+			LoadOpt:  &efiboot.LoadOpt{Description: desc},
+		}
+	}
+	bos := []*efiboot.BootOption{
+		bo(0x0000, "Windows Boot Manager"),
+		bo(0x0001, "Fedora"),
+		bo(0x0002, "Fedora (rescue)"),
+		bo(0x00ad, "UEFI Shell"),
+	}
+
+	for _, tc := range []struct {
+		target string
+		want   uint16
+	}{
+		{"0001", 1},
+		{"Boot0002", 2},
+		{"windows", 0},
+		{"rescue", 2},
+		{"ad", 0xad},
+		{"shell", 0xad},
+	} {
+		got, err := findOption(bos, tc.target)
+		if err != nil || got != tc.want {
+			t.Errorf("findOption(%q) = %#x, %v; want %#x", tc.target, got, err, tc.want)
+		}
+	}
+
+	for _, target := range []string{"fedora", "linux", "0003"} {
+		if got, err := findOption(bos, target); err == nil {
+			t.Errorf("findOption(%q) = %#x; want error", target, got)
+		}
+	}
+}
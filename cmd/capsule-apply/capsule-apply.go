@@ -0,0 +1,234 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// capsule-apply installs a firmware update capsule. It matches the capsule
+// against the ESRT, delivers it to the firmware to be applied on the next
+// reboot, and after that reboot reports whether the update succeeded.
+//
+//	capsule-apply firmware.cap   # then reboot
+//	capsule-apply -check
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/lukegb/goefivar/capsule"
+	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/esrt"
+	"github.com/lukegb/goefivar/internal/completion"
+	"github.com/lukegb/goefivar/internal/output"
+)
+
+var (
+	method    = flag.String("method", "auto", "How to deliver the capsule: auto, loader or on-disk")
+	esp       = flag.String("esp", "", "Mountpoint of the EFI System Partition for -method on-disk; by default the first one found")
+	stateFile = flag.String("state", "/var/lib/capsule-apply/pending.json", "File recording the scheduled update across the reboot")
+	dryRun    = flag.Bool("n", false, "Only show which firmware resource the capsule updates")
+
+	check   = flag.Bool("check", false, "Report the outcome of the scheduled update, after rebooting")
+	results = flag.Bool("results", false, "List the results of the capsules the firmware has processed")
+)
+
+var methods = map[string]capsule.Method{
+	"auto":    capsule.MethodAuto,
+	"loader":  capsule.MethodLoader,
+	"on-disk": capsule.MethodOnDisk,
+}
+
+// result is the JSON and YAML form of a capsule.Result.
+type result struct {
+	Name      string `json:"name"`
+	GUID      string `json:"guid"`
+	Processed string `json:"processed"`
+	Status    uint64 `json:"status"`
+	Succeeded bool   `json:"succeeded"`
+}
+
+func toResult(r *capsule.Result) *result {
+	if r == nil {
+		return nil
+	}
+	return &result{
+		Name:      r.Name,
+		GUID:      r.GUID.String(),
+		Processed: r.Processed.String(),
+		Status:    r.Status,
+		Succeeded: r.Succeeded(),
+	}
+}
+
+// outcome is the JSON and YAML form of a capsule.Outcome.
+type outcome struct {
+	FirmwareClass     string  `json:"firmware_class"`
+	Method            string  `json:"method"`
+	PreviousVersion   uint32  `json:"previous_version"`
+	Version           uint32  `json:"version"`
+	LastAttemptStatus string  `json:"last_attempt_status"`
+	Result            *result `json:"result,omitempty"`
+	Succeeded         bool    `json:"succeeded"`
+	Message           string  `json:"message"`
+}
+
+func readCapsule(path string) ([]byte, *capsule.Capsule, *esrt.Entry, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	c, err := capsule.Parse(b)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%v: %v", path, err)
+	}
+	entries, err := esrt.Entries()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("reading the ESRT: %v", err)
+	}
+	e, err := capsule.MatchESRT(c, entries)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return b, c, e, nil
+}
+
+func schedule(path string) error {
+	m, ok := methods[*method]
+	if !ok {
+		return fmt.Errorf("unknown -method %q; want auto, loader or on-disk", *method)
+	}
+	b, c, e, err := readCapsule(path)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Capsule %v updates %v firmware %v, currently at version %#x\n", c.GUID, e.Type, e.FirmwareClass, e.Version)
+	if *dryRun {
+		return nil
+	}
+
+	if _, err := os.Stat(*stateFile); err == nil {
+		return fmt.Errorf("an update is already scheduled; run with -check after rebooting, or remove %v", *stateFile)
+	}
+	p, err := capsule.ScheduleUpdate(b, &capsule.UpdateOptions{Method: m, ESP: *esp})
+	if err != nil {
+		return err
+	}
+	state, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(*stateFile), 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(*stateFile, state, 0644); err != nil {
+		return fmt.Errorf("recording the scheduled update: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "Delivered the capsule by %v. Reboot to apply it, then run %s -check.\n", p.Method, os.Args[0])
+	return nil
+}
+
+func checkUpdate() error {
+	state, err := ioutil.ReadFile(*stateFile)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("no update is scheduled")
+	} else if err != nil {
+		return err
+	}
+	var p capsule.PendingUpdate
+	if err := json.Unmarshal(state, &p); err != nil {
+		return fmt.Errorf("%v: %v", *stateFile, err)
+	}
+	o, err := capsule.CheckUpdate(&p)
+	if err != nil {
+		return err
+	}
+	out := &outcome{
+		FirmwareClass:     p.FirmwareClass.String(),
+		Method:            p.Method.String(),
+		PreviousVersion:   p.PreviousVersion,
+		Version:           o.Entry.Version,
+		LastAttemptStatus: o.Entry.LastAttemptStatus.String(),
+		Result:            toResult(o.Result),
+		Succeeded:         o.Succeeded,
+		Message:           o.String(),
+	}
+	err = output.Write(os.Stdout, out, func(w io.Writer) error {
+		_, err := fmt.Fprintln(w, o)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(*stateFile); err != nil {
+		return err
+	}
+	if !o.Succeeded {
+		os.Exit(1)
+	}
+	return nil
+}
+
+func listResults() error {
+	rs, err := capsule.Results()
+	if err != nil {
+		return err
+	}
+	out := make([]*result, len(rs))
+	for n, r := range rs {
+		out[n] = toResult(r)
+	}
+	return output.Write(os.Stdout, out, func(w io.Writer) error {
+		if len(rs) == 0 {
+			fmt.Fprintln(w, "No capsule results")
+		}
+		for _, r := range rs {
+			fmt.Fprintln(w, r)
+		}
+		return nil
+	})
+}
+
+func main() {
+	completion.Register(completion.Files)
+	completion.Flag("method", completion.Text)
+	completion.Flag("esp", completion.Files)
+	completion.Flag("state", completion.Files)
+	flag.Parse()
+	completion.Run()
+
+	if !efivar.Supported() {
+		fmt.Fprintf(os.Stderr, "EFI variables are not supported on this system.\n")
+		os.Exit(1)
+	}
+
+	var err error
+	switch {
+	case *check:
+		err = checkUpdate()
+	case *results:
+		err = listResults()
+	case flag.NArg() == 1:
+		err = schedule(flag.Arg(0))
+	default:
+		fmt.Fprintf(os.Stderr, "usage: %s [-method METHOD] [-n] CAPSULE | -check | -results\n", os.Args[0])
+		os.Exit(1)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// clean-boot-entries finds duplicate boot options, and boot options whose
+// loader no longer exists, and deletes them after confirmation, removing
+// them from BootOrder.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/lukegb/goefivar/efiboot"
+	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/internal/completion"
+)
+
+var (
+	yes         = flag.Bool("y", false, "Delete without asking for confirmation")
+	dryRun      = flag.Bool("n", false, "Only show what would be deleted")
+	noDuplicate = flag.Bool("no-duplicates", false, "Don't look for duplicate boot options")
+	noDangling  = flag.Bool("no-dangling", false, "Don't look for boot options whose loader is missing")
+	missingPart = flag.Bool("missing-partitions", false, "Also delete boot options whose partition isn't present, which may only be on an unplugged disk")
+)
+
+func number(bo *efiboot.BootOption) uint16 {
+	n, err := efiboot.BootOptionNumber(bo.Variable.VariableName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return n
+}
+
+func confirm() bool {
+	fmt.Print("Delete these boot options? [y/N] ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	answer := strings.ToLower(strings.TrimSpace(line))
+	return answer == "y" || answer == "yes"
+}
+
+func main() {
+	completion.Register(completion.None)
+	flag.Parse()
+	completion.Run()
+
+	if !efivar.Supported() {
+		fmt.Fprintf(os.Stderr, "EFI variables are not supported on this system.\n")
+		os.Exit(1)
+	}
+
+	bos, err := efiboot.BootOptions()
+	if err != nil {
+		log.Fatal(err)
+	}
+	orderNames, err := efiboot.BootOrder()
+	if err != nil && !os.IsNotExist(err) {
+		log.Fatalf("Reading BootOrder: %v", err)
+	}
+	var order []uint16
+	for _, vn := range orderNames {
+		if n, err := efiboot.BootOptionNumber(vn); err == nil {
+			order = append(order, n)
+		}
+	}
+	current := -1
+	if bc, err := efiboot.BootCurrent(); err == nil {
+		if n, err := efiboot.BootOptionNumber(bc); err == nil {
+			current = int(n)
+		}
+	}
+
+	reasons := make(map[uint16]string)
+	if !*noDuplicate {
+		for _, group := range efiboot.Duplicates(bos, order) {
+			keep := group[0]
+			for _, bo := range group[1:] {
+				if int(number(bo)) == current {
+					continue
+				}
+				reasons[number(bo)] = fmt.Sprintf("duplicate of %s", keep.Variable.Name)
+			}
+		}
+	}
+	if !*noDangling {
+		dangling, err := efiboot.Dangling(bos, &efiboot.DanglingOptions{MissingPartitions: *missingPart})
+		if err != nil {
+			log.Fatalf("Checking for missing loaders: %v", err)
+		}
+		for _, d := range dangling {
+			if int(number(d.Option)) == current {
+				continue
+			}
+			reasons[number(d.Option)] = d.Reason
+		}
+	}
+
+	if len(reasons) == 0 {
+		fmt.Println("Nothing to clean up.")
+		return
+	}
+
+	var nums []uint16
+	for n := range reasons {
+		nums = append(nums, n)
+	}
+	sort.Slice(nums, func(i, j int) bool { return nums[i] < nums[j] })
+	descs := make(map[uint16]string)
+	for _, bo := range bos {
+		descs[number(bo)] = bo.LoadOpt.Description
+	}
+	for _, n := range nums {
+		fmt.Printf("Boot%04X  %-30s %s\n", n, descs[n], reasons[n])
+	}
+
+	if *dryRun || (!*yes && !confirm()) {
+		return
+	}
+	if err := efiboot.RemoveBootOptions(nums); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Deleted %d boot options.\n", len(nums))
+}
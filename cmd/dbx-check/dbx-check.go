@@ -0,0 +1,191 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// dbx-check reports which EFI executables on the mounted EFI System
+// Partitions, or the files given as arguments, are revoked by dbx or would be
+// by a dbx update.
+//
+// It exits with status 2 if the loader of the current boot option is revoked.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lukegb/goefivar/efiboot"
+	"github.com/lukegb/goefivar/efisig"
+	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/internal/completion"
+	"github.com/lukegb/goefivar/internal/output"
+)
+
+// exitBootedRevoked is the exit status when the booted loader is revoked.
+const exitBootedRevoked = 2
+
+var (
+	updateFile = flag.String("update", "", "Also check against the revocations in this dbx update file, e.g. dbxupdate_x64.bin")
+	jsonOutput = flag.Bool("json", false, "Same as -format json")
+	all        = flag.Bool("a", false, "List images which are not revoked, too")
+)
+
+// result is the outcome of checking one image.
+type result struct {
+	Path    string `json:"path"`
+	Booted  bool   `json:"booted"`
+	Revoked bool   `json:"revoked"`
+	Reason  string `json:"reason,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// findImages returns the .efi files below each of dirs.
+func findImages(dirs []string) ([]string, error) {
+	var out []string
+	for _, dir := range dirs {
+		err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if fi.Mode().IsRegular() && strings.EqualFold(filepath.Ext(path), ".efi") {
+				out = append(out, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// revocations returns the database to check against: dbx, plus the update if one was given.
+func revocations() (efisig.Database, error) {
+	dbx, err := efisig.ReadDatabase(efisig.DBXName)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("reading dbx: %v", err)
+	}
+	if *updateFile == "" {
+		return dbx, nil
+	}
+	data, err := ioutil.ReadFile(*updateFile)
+	if err != nil {
+		return nil, err
+	}
+	u, err := efisig.ParseDBXUpdate(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %v: %v", *updateFile, err)
+	}
+	return append(append(efisig.Database(nil), dbx...), u.Database...), nil
+}
+
+func printResults(w io.Writer, results []result) error {
+	for _, r := range results {
+		status := "ok"
+		switch {
+		case r.Error != "":
+			status = "error: " + r.Error
+		case r.Revoked:
+			status = "REVOKED: " + r.Reason
+		}
+		marker := " "
+		if r.Booted {
+			marker = "*"
+		}
+		if _, err := fmt.Fprintf(w, "%s %s: %s\n", marker, r.Path, status); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func main() {
+	efivar.SetReadOnly()
+	completion.Register(completion.Files)
+	flag.Parse()
+	completion.Run()
+
+	if !efivar.Supported() {
+		fmt.Fprintf(os.Stderr, "EFI variables are not supported on this system.\n")
+		os.Exit(1)
+	}
+
+	dbx, err := revocations()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	images := flag.Args()
+	if len(images) == 0 {
+		esps, err := efiboot.ESPMountpoints()
+		if err != nil {
+			log.Fatalf("Finding mounted ESPs: %v", err)
+		}
+		if len(esps) == 0 {
+			log.Fatalf("No mounted ESPs found; name the images to check")
+		}
+		if images, err = findImages(esps); err != nil {
+			log.Fatalf("Scanning ESPs: %v", err)
+		}
+	}
+
+	booted := make(map[string]bool)
+	bootedImages, err := efisig.BootedImages()
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("Locating the booted loader: %v", err)
+	}
+	for _, img := range bootedImages {
+		booted[filepath.Clean(img)] = true
+	}
+
+	var results []result
+	bootedRevoked := false
+	for _, img := range images {
+		r := result{Path: img, Booted: booted[filepath.Clean(img)]}
+		revoked, reason, err := efisig.CheckRevokedFile(img, dbx)
+		switch {
+		case err == efisig.ErrNotPEImage && flag.NArg() == 0:
+			continue
+		case err != nil:
+			r.Error = err.Error()
+		default:
+			r.Revoked, r.Reason = revoked, reason
+		}
+		if r.Booted && r.Revoked {
+			bootedRevoked = true
+		}
+		if r.Revoked || r.Error != "" || r.Booted || *all {
+			results = append(results, r)
+		}
+	}
+
+	if *jsonOutput {
+		output.Set(output.JSON)
+	}
+	if results == nil {
+		results = []result{}
+	}
+	if err := output.Write(os.Stdout, results, func(w io.Writer) error { return printResults(w, results) }); err != nil {
+		log.Fatal(err)
+	}
+
+	if bootedRevoked {
+		fmt.Fprintf(os.Stderr, "The loader of the current boot option would be refused.\n")
+		os.Exit(exitBootedRevoked)
+	}
+}
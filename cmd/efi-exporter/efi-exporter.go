@@ -0,0 +1,220 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// efi-exporter serves Prometheus metrics describing the firmware state: boot
+// entries, the Secure Boot configuration, the space used by variables and
+// the outcome of the last firmware update capsule.
+//
+// The firmware does not expose the free space in its variable store to the
+// OS, so efi_variables_bytes reports the space used instead.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/lukegb/goefivar/capsule"
+	"github.com/lukegb/goefivar/efiboot"
+	"github.com/lukegb/goefivar/efisig"
+	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/internal/completion"
+)
+
+var listen = flag.String("listen", ":9834", "Address to serve metrics on, at /metrics")
+
+// metric is one sample in the Prometheus text format.
+type metric struct {
+	name, help string
+	labels     map[string]string
+	value      float64
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// writeMetrics writes ms as gauges in the Prometheus text exposition format.
+func writeMetrics(w io.Writer, ms []metric) error {
+	var buf bytes.Buffer
+	seen := make(map[string]bool)
+	for _, m := range ms {
+		if !seen[m.name] {
+			seen[m.name] = true
+			fmt.Fprintf(&buf, "# HELP %s %s\n# TYPE %s gauge\n", m.name, m.help, m.name)
+		}
+		buf.WriteString(m.name)
+		if len(m.labels) > 0 {
+			keys := make([]string, 0, len(m.labels))
+			for k := range m.labels {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for n, k := range keys {
+				sep := ","
+				if n == 0 {
+					sep = "{"
+				}
+				fmt.Fprintf(&buf, `%s%s="%s"`, sep, k, escapeLabel(m.labels[k]))
+			}
+			buf.WriteString("}")
+		}
+		fmt.Fprintf(&buf, " %g\n", m.value)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// A collector gathers a group of metrics.
+type collector struct {
+	name    string
+	collect func() ([]metric, error)
+}
+
+var collectors = []collector{
+	{"boot", collectBoot},
+	{"secureboot", collectSecureBoot},
+	{"variables", collectVariables},
+	{"capsule", collectCapsule},
+}
+
+func collectBoot() ([]metric, error) {
+	bos, err := efiboot.BootOptions()
+	if err != nil {
+		return nil, err
+	}
+	dangling, err := efiboot.Dangling(bos, &efiboot.DanglingOptions{MissingPartitions: true})
+	if err != nil {
+		return nil, err
+	}
+	var active int
+	for _, bo := range bos {
+		if bo.LoadOpt.Attributes.Has(efiboot.LoadOptionActive) {
+			active++
+		}
+	}
+	return []metric{
+		{name: "efi_boot_entries", help: "Number of Boot#### entries.", value: float64(len(bos))},
+		{name: "efi_boot_entries_active", help: "Number of Boot#### entries marked active.", value: float64(active)},
+		{name: "efi_boot_entries_dangling", help: "Number of Boot#### entries whose partition or file is missing.", value: float64(len(dangling))},
+	}, nil
+}
+
+func collectSecureBoot() ([]metric, error) {
+	s, err := efisig.CurrentStatus()
+	if err != nil {
+		return nil, err
+	}
+	return []metric{
+		{name: "efi_secure_boot_enabled", help: "Whether Secure Boot is enforced.", value: boolValue(s.SecureBoot)},
+		{name: "efi_setup_mode", help: "Whether the firmware is in Setup Mode, with no Platform Key enrolled.", value: boolValue(s.SetupMode)},
+		{name: "efi_signature_database_entries", help: "Number of entries in each Secure Boot signature database.", labels: map[string]string{"database": "KEK"}, value: float64(s.KEK.Entries)},
+		{name: "efi_signature_database_entries", labels: map[string]string{"database": "db"}, value: float64(s.DB.Entries)},
+		{name: "efi_signature_database_entries", labels: map[string]string{"database": "dbx"}, value: float64(s.DBX.Entries)},
+		{name: "efi_mok_validation_enabled", help: "Whether shim verifies the images it loads.", value: boolValue(s.MOKValidation)},
+	}, nil
+}
+
+func collectVariables() ([]metric, error) {
+	vns, err := efivar.Variables()
+	if err != nil {
+		return nil, err
+	}
+	var size, nv int
+	for _, vn := range vns {
+		v, err := vn.Get()
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if !v.Attributes.Has(efivar.NonVolatile) {
+			continue
+		}
+		nv++
+		// The name is stored as UCS-2 with a terminator.
+		size += len(v.Data) + 2*(len(v.Name)+1)
+	}
+	return []metric{
+		{name: "efi_variables", help: "Number of non-volatile variables.", value: float64(nv)},
+		{name: "efi_variables_bytes", help: "Approximate space used by the names and data of non-volatile variables.", value: float64(size)},
+	}, nil
+}
+
+func collectCapsule() ([]metric, error) {
+	r, err := capsule.LastResult()
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	labels := map[string]string{"guid": r.GUID.String()}
+	return []metric{
+		{name: "efi_capsule_last_success", help: "Whether the last capsule the firmware processed was applied successfully.", labels: labels, value: boolValue(r.Succeeded())},
+		{name: "efi_capsule_last_status", help: "EFI_STATUS of the last capsule the firmware processed.", labels: labels, value: float64(r.Status)},
+		{name: "efi_capsule_last_processed_timestamp_seconds", help: "When the firmware processed the last capsule.", labels: labels, value: float64(r.Processed.Time().Unix())},
+	}, nil
+}
+
+func serveMetrics(w http.ResponseWriter, r *http.Request) {
+	var ms []metric
+	for _, c := range collectors {
+		cms, err := c.collect()
+		if err != nil {
+			log.Printf("Collecting %v metrics: %v", c.name, err)
+		}
+		ms = append(ms, cms...)
+		ms = append(ms, metric{
+			name:   "efi_exporter_collector_success",
+			help:   "Whether each collector succeeded.",
+			labels: map[string]string{"collector": c.name},
+			value:  boolValue(err == nil),
+		})
+	}
+	// Group the samples of each metric, as the format requires.
+	sort.SliceStable(ms, func(i, j int) bool { return ms[i].name < ms[j].name })
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	if err := writeMetrics(w, ms); err != nil {
+		log.Printf("Writing metrics: %v", err)
+	}
+}
+
+func main() {
+	efivar.SetReadOnly()
+	completion.Register(completion.None)
+	completion.Flag("listen", completion.Text)
+	flag.Parse()
+	completion.Run()
+
+	if !efivar.Supported() {
+		fmt.Fprintf(os.Stderr, "EFI variables are not supported on this system.\n")
+		os.Exit(1)
+	}
+
+	http.HandleFunc("/metrics", serveMetrics)
+	log.Fatal(http.ListenAndServe(*listen, nil))
+}
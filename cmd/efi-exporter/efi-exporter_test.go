@@ -0,0 +1,43 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	var buf bytes.Buffer
+	err := writeMetrics(&buf, []metric{
+		{name: "efi_boot_entries", help: "Number of Boot#### entries.", value: 3},
+		{name: "efi_signature_database_entries", help: "Entries.", labels: map[string]string{"database": "db"}, value: 4},
+		{name: "efi_signature_database_entries", labels: map[string]string{"database": "dbx", "quote": `a"b`}, value: 371},
+	})
+	if err != nil {
+		t.Fatalf("writeMetrics: %v", err)
+	}
+	want := `# HELP efi_boot_entries Number of Boot#### entries.
+# TYPE efi_boot_entries gauge
+efi_boot_entries 3
+# HELP efi_signature_database_entries Entries.
+# TYPE efi_signature_database_entries gauge
+efi_signature_database_entries{database="db"} 4
+efi_signature_database_entries{database="dbx",quote="a\"b"} 371
+`
+	if got := buf.String(); got != want {
+		t.Errorf("writeMetrics wrote:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// efibootdump decodes an EFI_LOAD_OPTION, such as the content of a Boot####
+// variable, from a file, standard input or a hex string. It needs neither
+// libefiboot nor an EFI machine.
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/lukegb/goefivar/internal/completion"
+	"github.com/lukegb/goefivar/internal/output"
+	"github.com/lukegb/goefivar/loadopt"
+)
+
+var (
+	file     = flag.String("f", "", "Read the load option from this file; - reads standard input")
+	hexInput = flag.String("x", "", "Decode these hex digits; whitespace is ignored")
+	efivarfs = flag.Bool("efivarfs", false, "The input starts with the four attribute bytes found in efivarfs files")
+)
+
+var attributeNames = []struct {
+	attr uint32
+	name string
+}{
+	{loadopt.Active, "active"},
+	{loadopt.ForceReconnect, "force-reconnect"},
+	{loadopt.Hidden, "hidden"},
+	{loadopt.CategoryApp, "category-app"},
+}
+
+func formatAttributes(a uint32) string {
+	var parts []string
+	for _, an := range attributeNames {
+		if a&an.attr != 0 {
+			parts = append(parts, an.name)
+			a &^= an.attr
+		}
+	}
+	if a != 0 {
+		parts = append(parts, fmt.Sprintf("%#x", a))
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func input() ([]byte, error) {
+	switch {
+	case *file != "" && *hexInput != "":
+		return nil, fmt.Errorf("-f and -x are mutually exclusive")
+	case *file == "-":
+		return ioutil.ReadAll(os.Stdin)
+	case *file != "":
+		return ioutil.ReadFile(*file)
+	case *hexInput != "":
+		return hex.DecodeString(strings.Join(strings.Fields(*hexInput), ""))
+	}
+	return nil, fmt.Errorf("one of -f or -x is required")
+}
+
+// loadOption is the JSON and YAML form of a load option.
+type loadOption struct {
+	Description      string   `json:"description"`
+	Attributes       uint32   `json:"attributes"`
+	AttributeNames   []string `json:"attribute_names"`
+	FilePath         string   `json:"file_path"`
+	OptionalData     string   `json:"optional_data"`
+	OptionalDataUCS2 *string  `json:"optional_data_ucs2,omitempty"`
+}
+
+func toLoadOption(lo *loadopt.LoadOption) loadOption {
+	out := loadOption{
+		Description:    lo.Description,
+		Attributes:     lo.Attributes,
+		AttributeNames: []string{},
+		FilePath:       lo.FilePath.String(),
+		OptionalData:   hex.EncodeToString(lo.OptionalData),
+	}
+	for _, an := range attributeNames {
+		if lo.Attributes&an.attr != 0 {
+			out.AttributeNames = append(out.AttributeNames, an.name)
+		}
+	}
+	if s, ok := lo.OptionalDataUCS2(); ok && len(lo.OptionalData) > 0 {
+		out.OptionalDataUCS2 = &s
+	}
+	return out
+}
+
+func dump(w io.Writer, lo *loadopt.LoadOption) error {
+	fmt.Fprintf(w, "Description:  %s\n", lo.Description)
+	fmt.Fprintf(w, "Attributes:   %s\n", formatAttributes(lo.Attributes))
+	fmt.Fprintf(w, "Path:         %s\n", lo.FilePath)
+	if extra := len(lo.FilePathList) - len(lo.FilePath.Bytes()); extra > 0 {
+		fmt.Fprintf(w, "              (%d more bytes of file path list)\n", extra)
+	}
+	if len(lo.OptionalData) == 0 {
+		return nil
+	}
+	if s, ok := lo.OptionalDataUCS2(); ok {
+		fmt.Fprintf(w, "Optional data (UCS-2): %q\n", s)
+	}
+	_, err := fmt.Fprintf(w, "Optional data:\n%s", hex.Dump(lo.OptionalData))
+	return err
+}
+
+func main() {
+	completion.Register(completion.None)
+	completion.Flag("x", completion.Text)
+	flag.Parse()
+	completion.Run()
+
+	b, err := input()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *efivarfs {
+		if len(b) < 4 {
+			log.Fatalf("Input is too short to have efivarfs attributes")
+		}
+		b = b[4:]
+	}
+	lo, err := loadopt.Parse(b)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := output.Write(os.Stdout, toLoadOption(lo), func(w io.Writer) error { return dump(w, lo) }); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lukegb/goefivar/efivar"
+)
+
+// Backups use the efivarfs layout: the attributes as a little-endian uint32,
+// followed by the data. They are named NAME-GUID-TIMESTAMP.bin.
+const backupTimeFormat = "20060102T150405Z"
+
+func defaultBackupDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "efibootedit")
+}
+
+// backupVariable saves v to a new file in dir, returning its path.
+func backupVariable(dir string, v *efivar.Variable, now time.Time) (string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("%s-%s-%s.bin", v.Name, v.GUID, now.UTC().Format(backupTimeFormat))
+	b := make([]byte, 4+len(v.Data))
+	binary.LittleEndian.PutUint32(b, uint32(v.Attributes))
+	copy(b[4:], v.Data)
+
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, b, 0600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// readBackup reads a file written by backupVariable.
+func readBackup(path string) (*efivar.Variable, error) {
+	base := filepath.Base(path)
+	dash := strings.Index(base, "-")
+	if dash < 0 || len(base) < dash+1+36 {
+		return nil, fmt.Errorf("%v is not named like a backup (NAME-GUID-TIMESTAMP.bin)", base)
+	}
+	guid, err := uuid.Parse(base[dash+1 : dash+1+36])
+	if err != nil {
+		return nil, fmt.Errorf("%v is not named like a backup (NAME-GUID-TIMESTAMP.bin): %v", base, err)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < 4 {
+		return nil, fmt.Errorf("%v is too short to be a backup", path)
+	}
+	return &efivar.Variable{
+		VariableName: efivar.VariableName{GUID: guid, Name: base[:dash]},
+		Attributes:   efivar.Attributes(binary.LittleEndian.Uint32(b)),
+		Data:         b[4:],
+	}, nil
+}
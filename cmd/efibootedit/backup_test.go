@@ -0,0 +1,54 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lukegb/goefivar/efivar"
+)
+
+func TestBackupRoundtrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "efibootedit")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	want := &efivar.Variable{
+		VariableName: efivar.VariableName{GUID: efivar.GlobalUUID, Name: "Boot000C"},
+		Attributes:   efivar.NonVolatile | efivar.BootserviceAccess | efivar.RuntimeAccess,
+		Data:         []byte{1, 0, 0, 0, 'x'},
+	}
+	path, err := backupVariable(filepath.Join(dir, "backups"), want, time.Date(2024, 3, 14, 9, 26, 53, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("backupVariable: %v", err)
+	}
+	if got, wantName := filepath.Base(path), "Boot000C-"+efivar.GlobalUUID.String()+"-20240314T092653Z.bin"; got != wantName {
+		t.Errorf("backup name = %q; want %q", got, wantName)
+	}
+	got, err := readBackup(path)
+	if err != nil {
+		t.Fatalf("readBackup: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("readBackup = %+v; want %+v", got, want)
+	}
+}
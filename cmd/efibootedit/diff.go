@@ -0,0 +1,94 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/lukegb/goefivar/internal/diff"
+)
+
+// hexDump formats b like xxd, one line per 16 bytes.
+func hexDump(b []byte) []string {
+	var out []string
+	for off := 0; off < len(b); off += 16 {
+		end := off + 16
+		if end > len(b) {
+			end = len(b)
+		}
+		line := b[off:end]
+
+		var hex strings.Builder
+		for n := 0; n < 16; n++ {
+			if n < len(line) {
+				fmt.Fprintf(&hex, "%02x", line[n])
+			} else {
+				hex.WriteString("  ")
+			}
+			if n%2 == 1 {
+				hex.WriteByte(' ')
+			}
+		}
+		ascii := make([]byte, len(line))
+		for n, c := range line {
+			ascii[n] = '.'
+			if c >= 0x20 && c < 0x7f {
+				ascii[n] = c
+			}
+		}
+		out = append(out, fmt.Sprintf("%08x: %s %s", off, hex.String(), ascii))
+	}
+	return out
+}
+
+// parseHexDump is the inverse of hexDump. Offsets are ignored, so lines may
+// be inserted or removed freely; the hex column is everything up to the
+// first double space, and the ASCII column after it is ignored.
+func parseHexDump(s string) ([]byte, error) {
+	var out []byte
+	for n, line := range strings.Split(s, "\n") {
+		if colon := strings.Index(line, ":"); colon >= 0 {
+			line = line[colon+1:]
+		}
+		line = strings.TrimLeft(line, " ")
+		if end := strings.Index(line, "  "); end >= 0 {
+			line = line[:end]
+		}
+		b, err := hex.DecodeString(strings.Replace(strings.TrimSpace(line), " ", "", -1))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n+1, err)
+		}
+		out = append(out, b...)
+	}
+	return out, nil
+}
+
+// printPreview shows what an edit would change, both decoded and as raw bytes.
+func printPreview(w io.Writer, name string, before, after string, oldData, newData []byte) {
+	split := func(s string) []string {
+		return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	}
+	fmt.Fprintf(w, "--- %s (decoded)\n+++ %s (decoded, edited)\n", name, name)
+	for _, l := range diff.Lines(split(before), split(after)) {
+		fmt.Fprintln(w, l)
+	}
+	fmt.Fprintf(w, "--- %s (hex)\n+++ %s (hex, edited)\n", name, name)
+	for _, l := range diff.Lines(hexDump(oldData), hexDump(newData)) {
+		fmt.Fprintln(w, l)
+	}
+}
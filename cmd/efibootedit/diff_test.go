@@ -0,0 +1,57 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHexDump(t *testing.T) {
+	got := hexDump([]byte("0123456789abcdefXY\x00"))
+	want := []string{
+		"00000000: 3031 3233 3435 3637 3839 6162 6364 6566  0123456789abcdef",
+		"00000010: 5859 00                                  XY.",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("hexDump = %q; want %q", got, want)
+	}
+}
+
+func TestParseHexDump(t *testing.T) {
+	want := []byte("0123456789abcdefXY\x00")
+	got, err := parseHexDump(strings.Join(hexDump(want), "\n") + "\n")
+	if err != nil {
+		t.Fatalf("parseHexDump: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("parseHexDump(hexDump(%q)) = %q", want, got)
+	}
+
+	// Edited dumps need not keep offsets or the ASCII column in step.
+	got, err = parseHexDump("00000000: 3031 32  ignored\n00000000: ff\n\n4142")
+	if err != nil {
+		t.Fatalf("parseHexDump: %v", err)
+	}
+	if want := []byte("012\xffAB"); !bytes.Equal(got, want) {
+		t.Errorf("parseHexDump = %q; want %q", got, want)
+	}
+
+	if _, err := parseHexDump("00000000: 303  0"); err == nil {
+		t.Errorf("parseHexDump of an odd number of digits succeeded")
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lukegb/goefivar/efiboot"
+	"github.com/lukegb/goefivar/efivar"
+)
+
+// editable is the content of a variable, presented as text for editing.
+type editable interface {
+	// text returns the content as presented in the editor.
+	text() string
+	// setText applies the edited text.
+	setText(s string) error
+	// replaceData replaces just the payload: the optional data of a load
+	// option, or the whole of any other variable.
+	replaceData(s string) error
+	// bytes returns the new content of the variable.
+	bytes() ([]byte, error)
+}
+
+var loadOptionName = regexp.MustCompile(`^(Boot|Driver|SysPrep|PlatformRecovery)[0-9A-F]{4}$`)
+
+// parseVariableArg parses the variable named on the command line: either
+// the name of a global variable, such as Boot0001, or NAME-GUID.
+func parseVariableArg(s string) (efivar.VariableName, error) {
+	vn, err := efivar.ParseVariableName(s)
+	if err != nil && !strings.Contains(s, "-") {
+		return efivar.VariableName{GUID: efivar.GlobalUUID, Name: s}, nil
+	}
+	return vn, err
+}
+
+// newEditable chooses how to present v: as a load option when it is one, and
+// as raw content otherwise.
+func newEditable(v *efivar.Variable) (editable, error) {
+	if v.GUID == efivar.GlobalUUID && loadOptionName.MatchString(v.Name) {
+		lo, err := efiboot.FromVariable(v)
+		if err != nil {
+			return nil, fmt.Errorf("FromVariable: %v", err)
+		}
+		return &loadOptionEdit{lo}, nil
+	}
+	return &rawEdit{append([]byte(nil), v.Data...)}, nil
+}
+
+type loadOptionEdit struct {
+	lo *efiboot.LoadOpt
+}
+
+func (e *loadOptionEdit) text() string {
+	enc := flagEncoding().exact(e.lo.OptionalData)
+	return string((&editFields{
+		Description:  e.lo.Description,
+		Attributes:   e.lo.Attributes,
+		FilePath:     e.lo.FilePath,
+		Encoding:     enc,
+		OptionalData: enc.decode(e.lo.OptionalData),
+	}).format())
+}
+
+func (e *loadOptionEdit) setText(s string) error {
+	fields, err := parseEditFields([]byte(s))
+	if err != nil {
+		return err
+	}
+	data, err := fields.Encoding.encode(fields.OptionalData)
+	if err != nil {
+		return fmt.Errorf("optional data: %v", err)
+	}
+	e.lo.Description = fields.Description
+	e.lo.Attributes = fields.Attributes
+	e.lo.FilePath = fields.FilePath
+	e.lo.OptionalData = data
+	return nil
+}
+
+func (e *loadOptionEdit) replaceData(s string) error {
+	data, err := flagEncoding().encode(s)
+	if err != nil {
+		return err
+	}
+	e.lo.OptionalData = data
+	return nil
+}
+
+func (e *loadOptionEdit) bytes() ([]byte, error) { return e.lo.Bytes() }
+
+// rawEdit presents a variable's content as it is, or as a hex dump with -hex.
+type rawEdit struct {
+	data []byte
+}
+
+func (e *rawEdit) text() string {
+	if *hexMode {
+		return strings.Join(hexDump(e.data), "\n") + "\n"
+	}
+	return string(e.data) + "\n"
+}
+
+func (e *rawEdit) setText(s string) error {
+	return e.replaceData(strings.TrimSuffix(s, "\n"))
+}
+
+func (e *rawEdit) replaceData(s string) error {
+	if !*hexMode {
+		e.data = []byte(s)
+		return nil
+	}
+	b, err := parseHexDump(s)
+	if err != nil {
+		return err
+	}
+	e.data = b
+	return nil
+}
+
+func (e *rawEdit) bytes() ([]byte, error) { return e.data, nil }
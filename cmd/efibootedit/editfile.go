@@ -0,0 +1,217 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/lukegb/goefivar/efiboot"
+)
+
+const dataSeparator = "--- optional data follows this line ---"
+
+// encoding is how optional data is presented as text.
+type encoding string
+
+const (
+	encodingUCS2 encoding = "ucs2"
+	encodingUTF8 encoding = "utf8"
+	encodingHex  encoding = "hex"
+)
+
+// flagEncoding returns the encoding selected by -hex and -unicode_data.
+func flagEncoding() encoding {
+	switch {
+	case *hexMode:
+		return encodingHex
+	case *unicodeArgs:
+		return encodingUCS2
+	}
+	return encodingUTF8
+}
+
+func parseEncoding(s string) (encoding, error) {
+	switch enc := encoding(strings.ToLower(s)); enc {
+	case encodingUCS2, encodingUTF8, encodingHex:
+		return enc, nil
+	}
+	return "", fmt.Errorf("unknown encoding %q; want %v, %v or %v", s, encodingUCS2, encodingUTF8, encodingHex)
+}
+
+func (enc encoding) decode(d []byte) string {
+	switch enc {
+	case encodingHex:
+		return strings.Join(hexDump(d), "\n")
+	case encodingUCS2:
+		return efiboot.OptionalData(d).InterpretAsUCS2()
+	}
+	return string(d)
+}
+
+// exact returns enc if d survives being decoded with enc, edited and encoded
+// again unchanged, and encodingHex otherwise: UCS-2 loses an odd final byte
+// and unpaired surrogates, and an editor may mangle invalid UTF-8.
+func (enc encoding) exact(d []byte) encoding {
+	if enc == encodingUTF8 && !utf8.Valid(d) {
+		return encodingHex
+	}
+	if b, err := enc.encode(enc.decode(d)); err != nil || !bytes.Equal(b, d) {
+		return encodingHex
+	}
+	return enc
+}
+
+func (enc encoding) encode(s string) ([]byte, error) {
+	switch enc {
+	case encodingHex:
+		return parseHexDump(s)
+	case encodingUCS2:
+		d16 := utf16.Encode([]rune(s))
+		dataBytes := make([]byte, len(d16)*2)
+		for n, b16 := range d16 {
+			dataBytes[n*2] = byte(b16 & 0xff)
+			dataBytes[n*2+1] = byte((b16 >> 8) & 0xff)
+		}
+		return dataBytes, nil
+	}
+	return []byte(s), nil
+}
+
+// attributeNames are the load option attributes which may be named in the
+// edit file; any others are written as hex.
+var attributeNames = []struct {
+	name string
+	attr efiboot.Attributes
+}{
+	{"active", efiboot.LoadOptionActive},
+	{"force-reconnect", efiboot.LoadOptionForceReconnect},
+	{"hidden", efiboot.LoadOptionHidden},
+	{"category-app", efiboot.LoadOptionCategoryApp},
+}
+
+func formatAttributes(a efiboot.Attributes) string {
+	var parts []string
+	for _, an := range attributeNames {
+		if a&an.attr != 0 {
+			parts = append(parts, an.name)
+			a &^= an.attr
+		}
+	}
+	if a != 0 {
+		parts = append(parts, fmt.Sprintf("%#x", uint32(a)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func parseAttributes(s string) (efiboot.Attributes, error) {
+	var a efiboot.Attributes
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		found := false
+		for _, an := range attributeNames {
+			if strings.EqualFold(part, an.name) {
+				a |= an.attr
+				found = true
+			}
+		}
+		if found {
+			continue
+		}
+		n, err := strconv.ParseUint(part, 0, 32)
+		if err != nil {
+			return 0, fmt.Errorf("unknown attribute %q", part)
+		}
+		a |= efiboot.Attributes(n)
+	}
+	return a, nil
+}
+
+// editFields are the parts of a load option presented to the editor.
+type editFields struct {
+	Description  string
+	Attributes   efiboot.Attributes
+	FilePath     string
+	Encoding     encoding
+	OptionalData string
+}
+
+func (e *editFields) format() []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# Lines starting with # are ignored. Everything after the separator\n")
+	fmt.Fprintf(&buf, "# line is the optional data, usually the kernel command line.\n")
+	fmt.Fprintf(&buf, "Description: %s\n", e.Description)
+	fmt.Fprintf(&buf, "# Any of active, force-reconnect, hidden, category-app, or a number.\n")
+	fmt.Fprintf(&buf, "Attributes: %s\n", formatAttributes(e.Attributes))
+	fmt.Fprintf(&buf, "FilePath: %s\n", e.FilePath)
+	fmt.Fprintf(&buf, "# How the optional data is written below: %v, %v or %v.\n", encodingUCS2, encodingUTF8, encodingHex)
+	fmt.Fprintf(&buf, "Encoding: %s\n", e.Encoding)
+	fmt.Fprintf(&buf, "%s\n%s\n", dataSeparator, e.OptionalData)
+	return buf.Bytes()
+}
+
+func parseEditFields(b []byte) (*editFields, error) {
+	s := string(b)
+	sep := strings.Index(s, dataSeparator+"\n")
+	if sep < 0 {
+		return nil, fmt.Errorf("missing separator line %q", dataSeparator)
+	}
+	e := &editFields{OptionalData: strings.TrimSuffix(s[sep+len(dataSeparator)+1:], "\n")}
+
+	seen := make(map[string]bool)
+	for n, line := range strings.Split(s[:sep], "\n") {
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		colon := strings.Index(line, ":")
+		if colon < 0 {
+			return nil, fmt.Errorf("line %d: malformed line %q", n+1, line)
+		}
+		key, value := line[:colon], strings.TrimSpace(line[colon+1:])
+		if seen[key] {
+			return nil, fmt.Errorf("line %d: duplicate field %q", n+1, key)
+		}
+		var err error
+		switch key {
+		case "Description":
+			e.Description = value
+		case "Attributes":
+			e.Attributes, err = parseAttributes(value)
+		case "FilePath":
+			e.FilePath = value
+		case "Encoding":
+			e.Encoding, err = parseEncoding(value)
+		default:
+			err = fmt.Errorf("unknown field %q", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n+1, err)
+		}
+		seen[key] = true
+	}
+	for _, key := range []string{"Description", "Attributes", "FilePath", "Encoding"} {
+		if !seen[key] {
+			return nil, fmt.Errorf("missing field %q", key)
+		}
+	}
+	return e, nil
+}
@@ -0,0 +1,166 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/lukegb/goefivar/efiboot"
+	"github.com/lukegb/goefivar/efivar"
+)
+
+func TestEditFieldsRoundtrip(t *testing.T) {
+	want := &editFields{
+		Description:  "Arch Linux",
+		Attributes:   efiboot.LoadOptionActive | efiboot.LoadOptionHidden | 0x10000,
+		FilePath:     `HD(1,GPT,b647c141-bfe9-274c-81c6-174026e79fd0,0x800,0x3a9800)/File(\vmlinuz-linux)`,
+		Encoding:     encodingUTF8,
+		OptionalData: "root=/dev/sda2 rw initrd=\\initramfs-linux.img",
+	}
+	got, err := parseEditFields(want.format())
+	if err != nil {
+		t.Fatalf("parseEditFields: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseEditFields(format()) = %+v; want %+v", got, want)
+	}
+}
+
+func TestParseEditFieldsErrors(t *testing.T) {
+	const fields = "Description: x\nAttributes: active\nFilePath: y\nEncoding: utf8\n"
+	for _, in := range []string{
+		fields,
+		"Description: x\n" + dataSeparator + "\n\n",
+		fields + "Colour: blue\n" + dataSeparator + "\n\n",
+		fields + "Description: z\n" + dataSeparator + "\n\n",
+		"Description: x\nAttributes: shiny\nFilePath: y\nEncoding: utf8\n" + dataSeparator + "\n\n",
+		"Description: x\nAttributes: active\nFilePath: y\nEncoding: ebcdic\n" + dataSeparator + "\n\n",
+	} {
+		if _, err := parseEditFields([]byte(in)); err == nil {
+			t.Errorf("parseEditFields(%q) succeeded", in)
+		}
+	}
+}
+
+func TestParseEditFieldsComments(t *testing.T) {
+	in := "# comment\nDescription: x\n  # indented comment\nAttributes: Active, 0x2\nFilePath: y\nEncoding: UCS2\n" + dataSeparator + "\n# not a comment\n"
+	got, err := parseEditFields([]byte(in))
+	if err != nil {
+		t.Fatalf("parseEditFields: %v", err)
+	}
+	want := &editFields{
+		Description:  "x",
+		Attributes:   efiboot.LoadOptionActive | efiboot.LoadOptionForceReconnect,
+		FilePath:     "y",
+		Encoding:     encodingUCS2,
+		OptionalData: "# not a comment",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseEditFields = %+v; want %+v", got, want)
+	}
+}
+
+func TestEncodingRoundtrip(t *testing.T) {
+	for _, enc := range []encoding{encodingUCS2, encodingUTF8} {
+		const s = "root=/dev/sda2 rw \u00e9"
+		b, err := enc.encode(s)
+		if err != nil {
+			t.Errorf("%v.encode: %v", enc, err)
+			continue
+		}
+		if got := enc.decode(b); got != s {
+			t.Errorf("%v.decode(%v.encode(%q)) = %q", enc, enc, s, got)
+		}
+	}
+	b := []byte{0, 1, 0xfe, 0xff}
+	if got, err := encodingHex.encode(encodingHex.decode(b)); err != nil || !reflect.DeepEqual(got, b) {
+		t.Errorf("hex roundtrip of %x = %x, %v", b, got, err)
+	}
+}
+
+func TestEncodingExact(t *testing.T) {
+	for _, tc := range []struct {
+		enc  encoding
+		data []byte
+		want encoding
+	}{
+		{encodingUCS2, []byte("a\x00b\x00"), encodingUCS2},
+		// Odd length, which decodes to nothing.
+		{encodingUCS2, []byte{0x61, 0x00, 0x62}, encodingHex},
+		// An unpaired surrogate, which decodes to U+FFFD.
+		{encodingUCS2, []byte{0x00, 0xd8, 0x41, 0x00}, encodingHex},
+		{encodingUTF8, []byte("root=/dev/sda2"), encodingUTF8},
+		{encodingUTF8, []byte{0x72, 0xff}, encodingHex},
+		{encodingHex, []byte{0x00, 0xd8, 0x41}, encodingHex},
+	} {
+		if got := tc.enc.exact(tc.data); got != tc.want {
+			t.Errorf("%v.exact(%x) = %v; want %v", tc.enc, tc.data, got, tc.want)
+		}
+	}
+}
+
+// TestLoadOptionEditUnchanged checks that optional data which is not UTF-16
+// survives an edit which leaves it alone.
+func TestLoadOptionEditUnchanged(t *testing.T) {
+	defer func(u bool) { *unicodeArgs = u }(*unicodeArgs)
+	*unicodeArgs = true
+	for _, data := range [][]byte{{0x61, 0x00, 0x62}, {0x00, 0xd8, 0x41, 0x00}} {
+		v := &efivar.Variable{
+			VariableName: efivar.VariableName{GUID: efivar.GlobalUUID, Name: "Boot0001"},
+			// Active, with the description "A", an empty device path,
+			// and then the optional data.
+			Data: append([]byte{1, 0, 0, 0, 4, 0, 'A', 0, 0, 0, 0x7f, 0xff, 0x04, 0x00}, data...),
+		}
+		e, err := newEditable(v)
+		if err != nil {
+			t.Fatalf("newEditable: %v", err)
+		}
+		if err := e.setText(e.text()); err != nil {
+			t.Fatalf("setText(text()): %v", err)
+		}
+		if got, err := e.bytes(); err != nil || !reflect.DeepEqual(got, v.Data) {
+			t.Errorf("bytes after an unchanged edit of %x = %x, %v; want %x", data, got, err, v.Data)
+		}
+	}
+}
+
+func TestParseVariableArg(t *testing.T) {
+	shim := uuid.MustParse("605dab50-e046-4300-abb6-3dd810dd8b23")
+	for _, tc := range []struct {
+		in   string
+		want efivar.VariableName
+	}{
+		{"Boot0001", efivar.VariableName{GUID: efivar.GlobalUUID, Name: "Boot0001"}},
+		{"MokListRT-605dab50-e046-4300-abb6-3dd810dd8b23", efivar.VariableName{GUID: shim, Name: "MokListRT"}},
+		{"fwupd-ddc0ee61-e7f0-4e7d-acc5-c070a398838e-0-0abba7dc-e516-4167-bbf5-4d9d1c739416",
+			efivar.VariableName{GUID: uuid.MustParse("0abba7dc-e516-4167-bbf5-4d9d1c739416"), Name: "fwupd-ddc0ee61-e7f0-4e7d-acc5-c070a398838e-0"}},
+	} {
+		got, err := parseVariableArg(tc.in)
+		if err != nil {
+			t.Errorf("parseVariableArg(%q): %v", tc.in, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("parseVariableArg(%q) = %v; want %v", tc.in, got, tc.want)
+		}
+	}
+	for _, in := range []string{"Boot-0001", "MokListRT-605dab50"} {
+		if got, err := parseVariableArg(in); err == nil {
+			t.Errorf("parseVariableArg(%q) = %v; want error", in, got)
+		}
+	}
+}
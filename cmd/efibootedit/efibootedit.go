@@ -15,113 +15,246 @@
 package main
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"os"
 	"os/exec"
-	"unicode/utf16"
+	"strings"
+	"time"
 
 	"github.com/lukegb/goefivar/efiboot"
 	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/internal/completion"
+	"github.com/lukegb/goefivar/internal/varstoreflag"
 )
 
 var (
 	unicodeArgs = flag.Bool("unicode_data", true, "Treat optional data as UCS-2/UTF-16")
+	hexMode     = flag.Bool("hex", false, "Edit the optional data (or the content of a variable which is not a load option) as an xxd-style hex dump")
+
+	setDataFile  = flag.String("set-data-file", "", "Replace the optional data (or the content of a variable which is not a load option) with the contents of this file, without running an editor")
+	setDataStdin = flag.Bool("set-data-stdin", false, "Replace the optional data (or the content of a variable which is not a load option) with standard input, without running an editor")
+
+	backupDir = flag.String("backup-dir", defaultBackupDir(), "Directory in which to save the original variable before writing")
+	restore   = flag.String("restore", "", "Write back the variable saved in this backup file, and exit")
+
+	dryRun = flag.Bool("dry-run", false, "Show how the variable would change, without writing it")
 )
 
+// runEditor lets the user edit the file at fpath with $VISUAL or $EDITOR.
+func runEditor(fpath string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vim" // sorry.
+	}
+	cmd := exec.Command(editor, fpath)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("running editor %v: %v", cmd.Args, err)
+	}
+	return nil
+}
+
+// editText lets the user edit text in an editor, and returns the result.
+func editText(text string) (string, error) {
+	f, err := ioutil.TempFile("", "efibootedit")
+	if err != nil {
+		return "", err
+	}
+	fpath := f.Name()
+	defer os.Remove(fpath)
+
+	if _, err := io.WriteString(f, text); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	if err := runEditor(fpath); err != nil {
+		return "", err
+	}
+
+	edited, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		return "", err
+	}
+	return string(edited), nil
+}
+
+// replaceData replaces e's data with the text read from r. A single
+// trailing newline is dropped, as when editing.
+func replaceData(e editable, r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return e.replaceData(strings.TrimSuffix(string(data), "\n"))
+}
+
+// Exit codes, so that efibootedit can be used from scripts.
+const (
+	exitWritten     = 0 // the variable was written (or would be, with -dry-run)
+	exitFailure     = 1 // any failure not listed below
+	exitUsage       = 2 // bad command line
+	exitUnchanged   = 3 // the content was not changed, so nothing was written
+	exitNotFound    = 4 // the variable does not exist
+	exitInvalid     = 5 // the new content could not be parsed or encoded
+	exitWriteFailed = 6 // backing up or writing the variable failed
+	exitUnsupported = 7 // EFI variables are not available
+)
+
+// fatalf logs a message and exits with code.
+func fatalf(code int, format string, args ...interface{}) {
+	log.Printf(format, args...)
+	os.Exit(code)
+}
+
+// unchanged says that the variable name is not written, and exits with
+// exitUnchanged.
+func unchanged(name string) {
+	fmt.Fprintf(os.Stderr, "%v is unchanged; not writing\n", name)
+	os.Exit(exitUnchanged)
+}
+
+func usage() {
+	bos, err := efiboot.BootOptions()
+	if err != nil {
+		fatalf(exitFailure, "BootOptions: %v", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "%s [BootXXXX | NAME-GUID]\n\nAvailable boot options:\n", os.Args[0])
+	for _, bo := range bos {
+		fmt.Fprintf(os.Stderr, "  - %s (%s)\n", bo.Variable.Name, bo.LoadOpt.Description)
+	}
+	fmt.Fprintf(os.Stderr, "\nExit status is %d if the variable was written, %d if it was left unchanged,\n%d if it does not exist, %d if the new content is invalid, %d if writing failed,\nand %d for other errors.\n", exitWritten, exitUnchanged, exitNotFound, exitInvalid, exitWriteFailed, exitFailure)
+
+	os.Exit(exitUsage)
+}
+
 func main() {
+	completion.Register(completion.Variables)
 	flag.Parse()
+	completion.Run()
+	if err := varstoreflag.Apply(); err != nil {
+		fatalf(exitFailure, "%v", err)
+	}
 
 	if !efivar.Supported() {
 		fmt.Fprintf(os.Stderr, "EFI variables are not supported on this system.\n")
-		os.Exit(1)
+		os.Exit(exitUnsupported)
 	}
 
-	if flag.NArg() != 1 {
-		bos, err := efiboot.BootOptions()
+	if *restore != "" {
+		v, err := readBackup(*restore)
 		if err != nil {
-			log.Fatalf("BootOptions: %v", err)
+			fatalf(exitInvalid, "Reading backup: %v", err)
 		}
-
-		fmt.Fprintf(os.Stderr, "%s [BootXXXX]\n\nAvailable boot options:\n", os.Args[0])
-		for _, bo := range bos {
-			fmt.Fprintf(os.Stderr, "  - %s (%s)\n", bo.Variable.Name, bo.LoadOpt.Description)
+		if cur, err := v.VariableName.Get(); err == nil && cur.Attributes == v.Attributes && bytes.Equal(cur.Data, v.Data) {
+			fmt.Fprintf(os.Stderr, "%v already matches %v; not writing\n", v.Name, *restore)
+			os.Exit(exitUnchanged)
+		}
+		if *dryRun {
+			fmt.Fprintf(os.Stderr, "Would restore %v from %v\n", v.Name, *restore)
+			return
+		}
+		if err := v.Set(0644); err != nil {
+			fatalf(exitWriteFailed, "Set: %v", err)
 		}
+		fmt.Fprintf(os.Stderr, "Restored %v from %v\n", v.Name, *restore)
+		return
+	}
 
-		os.Exit(1)
+	if flag.NArg() != 1 {
+		usage()
+	}
+	if *setDataFile != "" && *setDataStdin {
+		fatalf(exitUsage, "-set-data-file and -set-data-stdin are mutually exclusive")
 	}
 
-	v, err := efivar.Get(efivar.GlobalUUID, flag.Arg(0))
+	vn, err := parseVariableArg(flag.Arg(0))
+	if err != nil {
+		fatalf(exitUsage, "%v", err)
+	}
+	v, err := vn.Get()
 	switch {
 	case os.IsNotExist(err):
-		log.Fatalf("No such boot option %v", flag.Arg(0))
+		fatalf(exitNotFound, "No such variable %v", flag.Arg(0))
 	case err != nil:
-		log.Fatalf("Get(%v, %q): %v", efivar.GlobalUUID, flag.Arg(0), err)
+		fatalf(exitFailure, "Get(%v, %q): %v", vn.GUID, vn.Name, err)
 	}
 
-	lo, err := efiboot.FromVariable(v)
+	e, err := newEditable(v)
 	if err != nil {
-		log.Fatalf("FromVariable: %v", err)
+		fatalf(exitInvalid, "%v", err)
 	}
+	before := e.text()
 
-	f, err := ioutil.TempFile("", "efibootedit")
-	if err != nil {
-		log.Fatalf("TempFile: %v", err)
-	}
-	fpath := f.Name()
-	defer os.Remove(fpath)
-
-	data := lo.OptionalData.InterpretAsUTF8()
-	if *unicodeArgs {
-		data = lo.OptionalData.InterpretAsUCS2()
-	}
-
-	if _, err := f.Write(append([]byte(data), '\n')); err != nil {
-		log.Fatalf("Write: %v", err)
-	}
-	if err := f.Close(); err != nil {
-		log.Fatalf("Close: %v", err)
+	switch {
+	case *setDataStdin:
+		if err := replaceData(e, os.Stdin); err != nil {
+			fatalf(exitInvalid, "Reading standard input: %v", err)
+		}
+	case *setDataFile != "":
+		f, err := os.Open(*setDataFile)
+		if err != nil {
+			fatalf(exitFailure, "Open: %v", err)
+		}
+		err = replaceData(e, f)
+		f.Close()
+		if err != nil {
+			fatalf(exitInvalid, "Reading %v: %v", *setDataFile, err)
+		}
+	default:
+		edited, err := editText(before)
+		if err != nil {
+			fatalf(exitFailure, "%v", err)
+		}
+		// Compare the text rather than the bytes it encodes to, which
+		// need not be the variable's exactly: a description with trailing
+		// spaces, say, is trimmed.
+		if edited == before {
+			unchanged(v.Name)
+		}
+		if err := e.setText(edited); err != nil {
+			fatalf(exitInvalid, "Parsing edited file: %v", err)
+		}
 	}
 
-	editor := os.Getenv("EDITOR")
-	if editor == "" {
-		editor = "vim" // sorry.
+	b, err := e.bytes()
+	if err != nil {
+		fatalf(exitInvalid, "Encoding %v: %v", v.Name, err)
 	}
-	cmd := exec.Command(editor, fpath)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
-		log.Fatalf("Running editor %v: %v", cmd.Args, err)
+	if bytes.Equal(b, v.Data) {
+		unchanged(v.Name)
 	}
 
-	newData, err := ioutil.ReadFile(fpath)
-	if err != nil {
-		log.Fatalf("ReadFile: %v", err)
+	if *dryRun {
+		printPreview(os.Stdout, v.Name, before, e.text(), v.Data, b)
+		return
 	}
-	newData = newData[:len(newData)-1] // strip trailing newline
 
-	if *unicodeArgs {
-		d16 := utf16.Encode([]rune(string(newData)))
-		dataBytes := make([]byte, len(d16)*2)
-		for n, b16 := range d16 {
-			dataBytes[n*2] = byte(b16 & 0xff)
-			dataBytes[n*2+1] = byte((b16 >> 8) & 0xff)
-		}
-		lo.OptionalData = efiboot.OptionalData(dataBytes)
-	} else {
-		lo.OptionalData = efiboot.OptionalData(newData)
+	if *backupDir == "" {
+		fatalf(exitUsage, "No backup directory; pass -backup-dir")
 	}
-
-	b, err := lo.Bytes()
+	backup, err := backupVariable(*backupDir, v, time.Now())
 	if err != nil {
-		log.Fatalf("lo.Bytes: %v", err)
+		fatalf(exitWriteFailed, "Backing up %v: %v", v.Name, err)
 	}
+	fmt.Fprintf(os.Stderr, "Saved original %v to %v; undo with -restore\n", v.Name, backup)
 
 	v.Data = b
 	if err := v.Set(0644); err != nil {
-		log.Fatalf("Set: %v", err)
+		fatalf(exitWriteFailed, "Set: %v", err)
 	}
 }
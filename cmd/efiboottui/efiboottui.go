@@ -0,0 +1,221 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// efiboottui is a full-screen terminal interface for managing UEFI boot
+// options, usable over a serial console.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/lukegb/goefivar/efiboot"
+	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/internal/completion"
+)
+
+const help = "↑/↓ select  -/+ move  a active  h hidden  o in BootOrder  n BootNext  w write  q quit"
+
+// Keys, with escape sequences for arrows folded into single values.
+const (
+	keyUp = -1 - iota
+	keyDown
+)
+
+func readKey(r *bufio.Reader) (int, error) {
+	c, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if c != 0x1b {
+		return int(c), nil
+	}
+	// An escape sequence: ESC [ A for up, ESC [ B for down.
+	if b, err := r.ReadByte(); err != nil || b != '[' {
+		return 0x1b, err
+	}
+	switch b, err := r.ReadByte(); {
+	case err != nil:
+		return 0, err
+	case b == 'A':
+		return keyUp, nil
+	case b == 'B':
+		return keyDown, nil
+	}
+	return 0x1b, nil
+}
+
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
+
+func render(w io.Writer, m *model, status string, width, height int) {
+	var b strings.Builder
+	b.WriteString("\x1b[H\x1b[2J")
+	fmt.Fprintf(&b, "\x1b[1m%s\x1b[0m\r\n", truncate(help, width))
+	bootNext := "(none)"
+	if m.bootNext != nil {
+		bootNext = fmt.Sprintf("Boot%04X", *m.bootNext)
+	}
+	fmt.Fprintf(&b, "BootNext: %s\r\n\r\n", bootNext)
+
+	// Leave room for the header, the preview and the status line.
+	rows := height - 8
+	if rows < 1 {
+		rows = 1
+	}
+	first := 0
+	if m.cursor >= rows {
+		first = m.cursor - rows + 1
+	}
+	for i := first; i < len(m.entries) && i < first+rows; i++ {
+		e := m.entries[i]
+		flags := []byte("   ")
+		if e.bo.LoadOpt.Attributes.Has(efiboot.LoadOptionActive) {
+			flags[0] = '*'
+		}
+		if e.bo.LoadOpt.Attributes.Has(efiboot.LoadOptionHidden) {
+			flags[1] = 'H'
+		}
+		if m.bootNext != nil && *m.bootNext == e.num {
+			flags[2] = 'N'
+		}
+		line := fmt.Sprintf("Boot%04X %s %s", e.num, flags, e.bo.LoadOpt.Description)
+		if !e.inOrder {
+			line += " (not in BootOrder)"
+		}
+		line = truncate(line, width-2)
+		if i == m.cursor {
+			fmt.Fprintf(&b, "> \x1b[7m%s\x1b[0m\r\n", line)
+		} else {
+			fmt.Fprintf(&b, "  %s\r\n", line)
+		}
+	}
+
+	if e := m.selected(); e != nil {
+		fmt.Fprintf(&b, "\r\n%s\r\n", truncate(e.bo.LoadOpt.FilePath, width))
+		if len(e.bo.LoadOpt.OptionalData) > 0 {
+			fmt.Fprintf(&b, "%s\r\n", truncate(e.bo.LoadOpt.OptionalData.String(), width))
+		}
+	}
+	if m.dirty() {
+		status = "[modified] " + status
+	}
+	fmt.Fprintf(&b, "\r\n%s", truncate(status, width))
+	io.WriteString(w, b.String())
+}
+
+func loadModel() (*model, error) {
+	bos, err := efiboot.BootOptions()
+	if err != nil {
+		return nil, err
+	}
+	var order []uint16
+	if vns, err := efiboot.BootOrder(); err == nil {
+		for _, vn := range vns {
+			if n, err := efiboot.BootOptionNumber(vn); err == nil {
+				order = append(order, n)
+			}
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	var bootNext *uint16
+	if vn, err := efiboot.BootNext(); err == nil {
+		if n, err := efiboot.BootOptionNumber(vn); err == nil {
+			bootNext = &n
+		}
+	}
+	return newModel(bos, order, bootNext), nil
+}
+
+func main() {
+	completion.Register(completion.None)
+	flag.Parse()
+	completion.Run()
+
+	if !efivar.Supported() {
+		fmt.Fprintf(os.Stderr, "EFI variables are not supported on this system.\n")
+		os.Exit(1)
+	}
+	m, err := loadModel()
+	if err != nil {
+		log.Fatalf("Reading boot options: %v", err)
+	}
+
+	term, err := makeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		log.Fatalf("Setting terminal mode: %v", err)
+	}
+	defer func() {
+		term.Close()
+		fmt.Print("\x1b[H\x1b[2J")
+	}()
+
+	in := bufio.NewReader(os.Stdin)
+	status := ""
+	quitting := false
+	for {
+		width, height := size(int(os.Stdout.Fd()))
+		render(os.Stdout, m, status, width, height)
+		status = ""
+
+		k, err := readKey(in)
+		if err != nil {
+			return
+		}
+		if k != 'q' {
+			quitting = false
+		}
+		switch k {
+		case keyUp, 'k':
+			m.moveCursor(-1)
+		case keyDown, 'j':
+			m.moveCursor(1)
+		case '-', 'K':
+			m.moveEntry(-1)
+		case '+', 'J':
+			m.moveEntry(1)
+		case 'a':
+			m.toggleAttribute(efiboot.LoadOptionActive)
+		case 'h':
+			m.toggleAttribute(efiboot.LoadOptionHidden)
+		case 'o':
+			m.toggleInOrder()
+		case 'n':
+			m.toggleBootNext()
+		case 'w':
+			if err := m.save(); err != nil {
+				status = fmt.Sprintf("Writing failed: %v", err)
+			} else {
+				status = "Written."
+			}
+		case 'q', 0x03:
+			if m.dirty() && !quitting && k == 'q' {
+				status = "Unwritten changes; press q again to discard them."
+				quitting = true
+				continue
+			}
+			return
+		}
+	}
+}
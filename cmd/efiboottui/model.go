@@ -0,0 +1,181 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"github.com/lukegb/goefivar/efiboot"
+)
+
+// entry is a boot option as shown in the list.
+type entry struct {
+	num     uint16
+	bo      *efiboot.BootOption
+	inOrder bool
+	// dirty is set when bo's attributes have changed.
+	dirty bool
+}
+
+// model is the editing state, independent of how it's displayed.
+type model struct {
+	entries []*entry
+	cursor  int
+
+	bootNext      *uint16
+	orderDirty    bool
+	bootNextDirty bool
+}
+
+// newModel lists the options in BootOrder first, in order, followed by the
+// rest.
+func newModel(bos []*efiboot.BootOption, order []uint16, bootNext *uint16) *model {
+	m := &model{bootNext: bootNext}
+	byNum := make(map[uint16]*efiboot.BootOption)
+	var nums []uint16
+	for _, bo := range bos {
+		n, err := efiboot.BootOptionNumber(bo.Variable.VariableName)
+		if err != nil {
+			continue
+		}
+		byNum[n] = bo
+		nums = append(nums, n)
+	}
+	seen := make(map[uint16]bool)
+	for _, n := range order {
+		if bo, ok := byNum[n]; ok && !seen[n] {
+			m.entries = append(m.entries, &entry{num: n, bo: bo, inOrder: true})
+			seen[n] = true
+		}
+	}
+	for _, n := range nums {
+		if !seen[n] {
+			m.entries = append(m.entries, &entry{num: n, bo: byNum[n]})
+		}
+	}
+	return m
+}
+
+func (m *model) selected() *entry {
+	if len(m.entries) == 0 {
+		return nil
+	}
+	return m.entries[m.cursor]
+}
+
+func (m *model) moveCursor(delta int) {
+	m.cursor += delta
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+	if m.cursor >= len(m.entries) {
+		m.cursor = len(m.entries) - 1
+	}
+}
+
+// moveEntry moves the selected entry up (negative delta) or down the order.
+func (m *model) moveEntry(delta int) {
+	to := m.cursor + delta
+	if to < 0 || to >= len(m.entries) {
+		return
+	}
+	m.entries[m.cursor], m.entries[to] = m.entries[to], m.entries[m.cursor]
+	m.cursor = to
+	m.orderDirty = true
+}
+
+func (m *model) toggleAttribute(a efiboot.Attributes) {
+	e := m.selected()
+	if e == nil {
+		return
+	}
+	e.bo.LoadOpt.Attributes ^= a
+	e.dirty = true
+}
+
+func (m *model) toggleInOrder() {
+	if e := m.selected(); e != nil {
+		e.inOrder = !e.inOrder
+		m.orderDirty = true
+	}
+}
+
+// toggleBootNext sets BootNext to the selected entry, or clears it if it
+// already is.
+func (m *model) toggleBootNext() {
+	e := m.selected()
+	if e == nil {
+		return
+	}
+	if m.bootNext != nil && *m.bootNext == e.num {
+		m.bootNext = nil
+	} else {
+		n := e.num
+		m.bootNext = &n
+	}
+	m.bootNextDirty = true
+}
+
+func (m *model) order() []uint16 {
+	var out []uint16
+	for _, e := range m.entries {
+		if e.inOrder {
+			out = append(out, e.num)
+		}
+	}
+	return out
+}
+
+func (m *model) dirty() bool {
+	if m.orderDirty || m.bootNextDirty {
+		return true
+	}
+	for _, e := range m.entries {
+		if e.dirty {
+			return true
+		}
+	}
+	return false
+}
+
+// save writes the changes to the firmware.
+func (m *model) save() error {
+	for _, e := range m.entries {
+		if !e.dirty {
+			continue
+		}
+		if err := e.bo.Save(); err != nil {
+			return err
+		}
+		e.dirty = false
+	}
+	if m.orderDirty {
+		if err := efiboot.SetBootOrder(m.order()); err != nil {
+			return err
+		}
+		m.orderDirty = false
+	}
+	if m.bootNextDirty {
+		var err error
+		if m.bootNext != nil {
+			err = efiboot.SetBootNext(*m.bootNext)
+		} else {
+			err = efiboot.ClearBootNext()
+		}
+		if err != nil {
+			return err
+		}
+		m.bootNextDirty = false
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lukegb/goefivar/efiboot"
+	"github.com/lukegb/goefivar/efivar"
+)
+
+func testOptions(nums ...uint16) []*efiboot.BootOption {
+	var out []*efiboot.BootOption
+	for _, n := range nums {
+		out = append(out, &efiboot.BootOption{
+			Variable: &efivar.Variable{VariableName: efiboot.BootOptionName(n)},
+			LoadOpt:  &efiboot.LoadOpt{Attributes: efiboot.LoadOptionActive},
+		})
+	}
+	return out
+}
+
+func TestModel(t *testing.T) {
+	m := newModel(testOptions(0, 1, 2, 3), []uint16{2, 0, 9}, nil)
+	if got, want := m.order(), []uint16{2, 0}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("initial order = %v; want %v", got, want)
+	}
+	if m.dirty() {
+		t.Error("new model is dirty")
+	}
+
+	m.moveCursor(1)
+	m.moveEntry(-1)
+	if got, want := m.order(), []uint16{0, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("order after moving Boot0000 up = %v; want %v", got, want)
+	}
+
+	m.moveCursor(10)
+	m.toggleInOrder()
+	if got, want := m.order(), []uint16{0, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("order after adding Boot0003 = %v; want %v", got, want)
+	}
+
+	m.toggleAttribute(efiboot.LoadOptionActive)
+	if e := m.selected(); e.bo.LoadOpt.Attributes&efiboot.LoadOptionActive != 0 || !e.dirty {
+		t.Errorf("after toggling active, attributes = %#x, dirty = %v", e.bo.LoadOpt.Attributes, e.dirty)
+	}
+
+	m.toggleBootNext()
+	if m.bootNext == nil || *m.bootNext != 3 {
+		t.Errorf("BootNext = %v; want 3", m.bootNext)
+	}
+	m.toggleBootNext()
+	if m.bootNext != nil {
+		t.Errorf("BootNext = %v after toggling twice; want nil", *m.bootNext)
+	}
+	if !m.dirty() {
+		t.Error("model with changes is not dirty")
+	}
+}
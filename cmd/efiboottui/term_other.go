@@ -0,0 +1,33 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !linux && !darwin && !freebsd
+// +build !linux,!darwin,!freebsd
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+type terminal struct{}
+
+func makeRaw(fd int) (*terminal, error) {
+	return nil, fmt.Errorf("terminals are not supported on %v", runtime.GOOS)
+}
+
+func (t *terminal) Close() error { return nil }
+
+func size(fd int) (int, int) { return 80, 24 }
@@ -0,0 +1,251 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// efivar-backup keeps rotating snapshots of the EFI variables, and restores
+// them selectively. It is meant to be run from cron or a systemd timer.
+//
+//	efivar-backup                          # take a snapshot, keeping the last -keep
+//	efivar-backup -list
+//	efivar-backup -restore latest -name 'Boot*'
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/internal/completion"
+	"github.com/lukegb/goefivar/vardump"
+)
+
+var (
+	dir  = flag.String("dir", "/var/backups/efivar", "Directory holding the snapshots")
+	keep = flag.Int("keep", 10, "Number of snapshots to keep; older ones are deleted after taking a new one")
+
+	list    = flag.Bool("list", false, "List the snapshots, oldest first")
+	restore = flag.String("restore", "", "Restore variables from this snapshot: a file name from -list, a path, or \"latest\"")
+	dryRun  = flag.Bool("n", false, "With -restore, only show which variables would be written")
+
+	guidFilter = flag.String("guid", "", "Only restore variables whose vendor GUID matches this shell pattern")
+	nameFilter = flag.String("name", "", "Only restore variables whose names match this shell pattern, e.g. Boot*")
+)
+
+const (
+	snapshotPrefix = "efivars-"
+	snapshotSuffix = ".tar.gz"
+	// snapshotTime is the layout of the time in snapshot names, chosen so
+	// that they sort chronologically.
+	snapshotTime = "20060102T150405Z"
+)
+
+func snapshotName(t time.Time) string {
+	return snapshotPrefix + t.UTC().Format(snapshotTime) + snapshotSuffix
+}
+
+// snapshots returns the names of the snapshots in dir, oldest first.
+func snapshots(dir string) ([]string, error) {
+	fis, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, fi := range fis {
+		name := fi.Name()
+		if !fi.Mode().IsRegular() || !strings.HasPrefix(name, snapshotPrefix) || !strings.HasSuffix(name, snapshotSuffix) {
+			continue
+		}
+		if _, err := time.Parse(snapshotTime, strings.TrimSuffix(strings.TrimPrefix(name, snapshotPrefix), snapshotSuffix)); err != nil {
+			continue
+		}
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// prune deletes all but the newest keep snapshots in dir, and returns the
+// names of those it deleted.
+func prune(dir string, keep int) ([]string, error) {
+	names, err := snapshots(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) <= keep {
+		return nil, nil
+	}
+	old := names[:len(names)-keep]
+	for _, name := range old {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return nil, err
+		}
+	}
+	return old, nil
+}
+
+func takeSnapshot() error {
+	if *keep < 1 {
+		return fmt.Errorf("-keep must be at least 1")
+	}
+	vs, err := vardump.Capture(nil)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(*dir, 0700); err != nil {
+		return err
+	}
+	p := filepath.Join(*dir, snapshotName(time.Now()))
+	if err := vardump.Save(p, vardump.Tar, vs); err != nil {
+		return fmt.Errorf("writing %v: %v", p, err)
+	}
+	fmt.Fprintf(os.Stderr, "Saved %d variables to %v\n", len(vs), p)
+
+	old, err := prune(*dir, *keep)
+	if err != nil {
+		return fmt.Errorf("deleting old snapshots: %v", err)
+	}
+	for _, name := range old {
+		fmt.Fprintf(os.Stderr, "Deleted %v\n", name)
+	}
+	return nil
+}
+
+// resolveSnapshot returns the path of the snapshot named by s.
+func resolveSnapshot(s string) (string, error) {
+	if s != "latest" {
+		if strings.ContainsRune(s, filepath.Separator) {
+			return s, nil
+		}
+		return filepath.Join(*dir, s), nil
+	}
+	names, err := snapshots(*dir)
+	if err != nil {
+		return "", err
+	}
+	if len(names) == 0 {
+		return "", fmt.Errorf("no snapshots in %v", *dir)
+	}
+	return filepath.Join(*dir, names[len(names)-1]), nil
+}
+
+func match(vn efivar.VariableName) bool {
+	if ok, _ := path.Match(*guidFilter, vn.GUID.String()); *guidFilter != "" && !ok {
+		return false
+	}
+	if ok, _ := path.Match(*nameFilter, vn.Name); *nameFilter != "" && !ok {
+		return false
+	}
+	return true
+}
+
+func restoreSnapshot() error {
+	p, err := resolveSnapshot(*restore)
+	if err != nil {
+		return err
+	}
+	vs, err := vardump.Load(p)
+	if err != nil {
+		return err
+	}
+
+	var written, failed int
+	for _, v := range vs {
+		if !match(v.VariableName) {
+			continue
+		}
+		id := vardump.FileName(v.VariableName)
+		switch {
+		case !v.Attributes.Has(efivar.NonVolatile):
+			// Volatile variables are recreated by the firmware on every boot.
+			continue
+		case v.Attributes&(efivar.AuthenticatedWriteAccess|efivar.TimeBasedAuthenticatedWriteAccess) != 0:
+			fmt.Fprintf(os.Stderr, "Skipping %v: authenticated variables cannot be restored without a signature\n", id)
+			continue
+		}
+		cur, err := v.VariableName.Get()
+		if err == nil && cur.Attributes == v.Attributes && bytes.Equal(cur.Data, v.Data) {
+			continue
+		}
+		if *dryRun {
+			fmt.Printf("Would restore %v\n", id)
+			continue
+		}
+		if err := v.Set(0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Restoring %v: %v\n", id, err)
+			failed++
+			continue
+		}
+		fmt.Printf("Restored %v\n", id)
+		written++
+	}
+	if !*dryRun {
+		fmt.Fprintf(os.Stderr, "Restored %d variables from %v\n", written, p)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d variables could not be restored", failed)
+	}
+	return nil
+}
+
+func main() {
+	completion.Register(completion.None)
+	completion.Flag("dir", completion.Files)
+	completion.Flag("restore", completion.Files)
+	completion.Flag("guid", completion.Text)
+	completion.Flag("name", completion.Text)
+	flag.Parse()
+	completion.Run()
+
+	for _, pattern := range []string{*guidFilter, *nameFilter} {
+		if _, err := path.Match(pattern, ""); err != nil {
+			log.Fatalf("Invalid pattern %q: %v", pattern, err)
+		}
+	}
+
+	if *list {
+		names, err := snapshots(*dir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, name := range names {
+			fmt.Println(name)
+		}
+		return
+	}
+
+	if !efivar.Supported() {
+		fmt.Fprintf(os.Stderr, "EFI variables are not supported on this system.\n")
+		os.Exit(1)
+	}
+
+	var err error
+	if *restore != "" {
+		err = restoreSnapshot()
+	} else {
+		err = takeSnapshot()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
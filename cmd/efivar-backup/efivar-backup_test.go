@@ -0,0 +1,60 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPrune(t *testing.T) {
+	dir, err := ioutil.TempDir("", "efivar-backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	base := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	var names []string
+	for n := 0; n < 4; n++ {
+		names = append(names, snapshotName(base.Add(time.Duration(n)*time.Hour)))
+	}
+	// Written out of order, along with files which are not snapshots.
+	for _, name := range []string{names[2], names[0], names[3], names[1], "efivars-notatime.tar.gz", "README"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := snapshots(dir)
+	if err != nil || !reflect.DeepEqual(got, names) {
+		t.Errorf("snapshots = %v, %v; want %v", got, err, names)
+	}
+
+	deleted, err := prune(dir, 2)
+	if err != nil || !reflect.DeepEqual(deleted, names[:2]) {
+		t.Errorf("prune = %v, %v; want %v", deleted, err, names[:2])
+	}
+	if got, _ := snapshots(dir); !reflect.DeepEqual(got, names[2:]) {
+		t.Errorf("after prune, snapshots = %v; want %v", got, names[2:])
+	}
+	if _, err := os.Stat(filepath.Join(dir, "README")); err != nil {
+		t.Errorf("prune deleted an unrelated file: %v", err)
+	}
+}
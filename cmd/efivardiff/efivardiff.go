@@ -0,0 +1,186 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// efivardiff compares two dumps written by efivardump, or a dump with the
+// variables of the running system, and prints the variables which were
+// added, removed or changed. Changes to variables with a known layout are
+// shown decoded; others as a hex dump.
+//
+//	efivardiff before.json            # compare with the running system
+//	efivardiff before.json after.tar
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/internal/completion"
+	"github.com/lukegb/goefivar/internal/diff"
+	"github.com/lukegb/goefivar/internal/output"
+	"github.com/lukegb/goefivar/vardecode"
+	"github.com/lukegb/goefivar/vardump"
+	"github.com/lukegb/goefivar/varwatch"
+)
+
+var quiet = flag.Bool("q", false, "Only list the names of the variables which differ")
+
+func snapshot(vs []*efivar.Variable) varwatch.Snapshot {
+	s := make(varwatch.Snapshot, len(vs))
+	for _, v := range vs {
+		s[v.VariableName] = v
+	}
+	return s
+}
+
+// render returns the lines describing v: its attributes, then its decoded
+// fields, or a hex dump if it has no known layout.
+func render(v *efivar.Variable) []string {
+	lines := []string{fmt.Sprintf("Attributes: %#x", uint32(v.Attributes))}
+	fields, ok, err := vardecode.Decode(v)
+	if ok && err == nil {
+		for _, f := range fields {
+			lines = append(lines, fmt.Sprintf("%s: %s", f.Name, f.Value))
+		}
+		return lines
+	}
+	if ok {
+		lines = append(lines, fmt.Sprintf("(cannot decode: %v)", err))
+	}
+	return append(lines, strings.Split(strings.TrimSuffix(hex.Dump(v.Data), "\n"), "\n")...)
+}
+
+// variable is the JSON and YAML form of one side of a difference.
+type variable struct {
+	Attributes uint32            `json:"attributes"`
+	Data       string            `json:"data"`
+	Fields     map[string]string `json:"fields,omitempty"`
+}
+
+// difference is the JSON and YAML form of a varwatch.Event.
+type difference struct {
+	Change string    `json:"change"`
+	Name   string    `json:"name"`
+	GUID   string    `json:"guid"`
+	Old    *variable `json:"old,omitempty"`
+	New    *variable `json:"new,omitempty"`
+}
+
+func toVariable(v *efivar.Variable) *variable {
+	if v == nil {
+		return nil
+	}
+	out := &variable{
+		Attributes: uint32(v.Attributes),
+		Data:       hex.EncodeToString(v.Data),
+	}
+	if fields, ok, err := vardecode.Decode(v); ok && err == nil {
+		out.Fields = make(map[string]string, len(fields))
+		for _, f := range fields {
+			out.Fields[f.Name] = f.Value
+		}
+	}
+	return out
+}
+
+var changeNames = map[varwatch.Op]string{
+	varwatch.Create: "added",
+	varwatch.Delete: "removed",
+	varwatch.Modify: "changed",
+}
+
+func toDifference(ev varwatch.Event) difference {
+	return difference{
+		Change: changeNames[ev.Op],
+		Name:   ev.Name.Name,
+		GUID:   ev.Name.GUID.String(),
+		Old:    toVariable(ev.Old),
+		New:    toVariable(ev.New),
+	}
+}
+
+func printEvent(w io.Writer, ev varwatch.Event) {
+	name := ev.Name.String()
+	var old, new []string
+	switch ev.Op {
+	case varwatch.Create:
+		fmt.Fprintf(w, "added %s\n", name)
+		new = render(ev.New)
+	case varwatch.Delete:
+		fmt.Fprintf(w, "removed %s\n", name)
+		old = render(ev.Old)
+	case varwatch.Modify:
+		fmt.Fprintf(w, "changed %s\n", name)
+		old, new = render(ev.Old), render(ev.New)
+	}
+	if *quiet {
+		return
+	}
+	for _, l := range diff.Lines(old, new) {
+		fmt.Fprintf(w, "  %s\n", l)
+	}
+}
+
+func main() {
+	efivar.SetReadOnly()
+	completion.Register(completion.Files)
+	flag.Parse()
+	completion.Run()
+
+	if flag.NArg() < 1 || flag.NArg() > 2 {
+		fmt.Fprintf(os.Stderr, "usage: %s [-q] OLD [NEW]\n\nWithout NEW, OLD is compared with the running system.\n", os.Args[0])
+		os.Exit(1)
+	}
+
+	old, err := vardump.Load(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	var new []*efivar.Variable
+	if flag.NArg() == 2 {
+		new, err = vardump.Load(flag.Arg(1))
+	} else {
+		if !efivar.Supported() {
+			fmt.Fprintf(os.Stderr, "EFI variables are not supported on this system.\n")
+			os.Exit(1)
+		}
+		new, err = vardump.Capture(nil)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	evs := varwatch.Diff(snapshot(old), snapshot(new))
+	diffs := make([]difference, len(evs))
+	for n, ev := range evs {
+		diffs[n] = toDifference(ev)
+	}
+	err = output.Write(os.Stdout, diffs, func(w io.Writer) error {
+		for _, ev := range evs {
+			printEvent(w, ev)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(evs) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// efivardump exports EFI variables, with their attributes, to a directory
+// laid out like efivarfs, a tar archive, or a JSON document.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/google/uuid"
+	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/internal/completion"
+	"github.com/lukegb/goefivar/internal/varstoreflag"
+	"github.com/lukegb/goefivar/vardump"
+)
+
+var (
+	output = flag.String("o", "-", "Where to write the dump; - writes to standard output")
+	format = flag.String("format", "", "Format of the dump: dir, tar or json (default: guessed from -o, or json for standard output)")
+
+	guidFilter = flag.String("guid", "", "Only dump variables with this vendor GUID")
+	nameFilter = flag.String("name", "", "Only dump variables matching this shell pattern, e.g. Boot* or Mok*-605dab50-*")
+)
+
+func main() {
+	efivar.SetReadOnly()
+	completion.Register(completion.None)
+	completion.Flag("format", completion.Text)
+	completion.Flag("guid", completion.Text)
+	completion.Flag("name", completion.Text)
+	flag.Parse()
+	completion.Run()
+	if err := varstoreflag.Apply(); err != nil {
+		log.Fatal(err)
+	}
+
+	if !efivar.Supported() {
+		fmt.Fprintf(os.Stderr, "EFI variables are not supported on this system.\n")
+		os.Exit(1)
+	}
+	if flag.NArg() != 0 {
+		fmt.Fprintf(os.Stderr, "usage: %s [-o PATH] [-format dir|tar|json] [-guid GUID] [-name PATTERN]\n", os.Args[0])
+		os.Exit(1)
+	}
+
+	f := vardump.JSON
+	if *output != "-" {
+		f = vardump.FormatOf(*output)
+	}
+	if *format != "" {
+		var err error
+		if f, err = vardump.ParseFormat(*format); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var guid uuid.UUID
+	if *guidFilter != "" {
+		var err error
+		if guid, err = uuid.Parse(*guidFilter); err != nil {
+			log.Fatalf("Invalid -guid: %v", err)
+		}
+	}
+	names, err := efivar.NewGlob(*nameFilter)
+	if err != nil {
+		log.Fatalf("Invalid -name: %v", err)
+	}
+	match := func(vn efivar.VariableName) bool {
+		if *guidFilter != "" && vn.GUID != guid {
+			return false
+		}
+		return *nameFilter == "" || names.Match(vn)
+	}
+
+	vs, err := vardump.Capture(match)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *output != "-" {
+		if err := vardump.Save(*output, f, vs); err != nil {
+			log.Fatalf("Writing %v: %v", *output, err)
+		}
+		fmt.Fprintf(os.Stderr, "Dumped %d variables to %v\n", len(vs), *output)
+		return
+	}
+	switch f {
+	case vardump.JSON:
+		err = vardump.WriteJSON(os.Stdout, vs)
+	case vardump.Tar:
+		err = vardump.WriteTar(os.Stdout, vs)
+	default:
+		log.Fatalf("Cannot write a %v dump to standard output", f)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// efivarset writes an EFI variable from a file or a literal, with explicit
+// attributes, or deletes it with -delete. Writing no data would delete the
+// variable too, so efivarset refuses to.
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/internal/completion"
+)
+
+var (
+	file     = flag.String("file", "", "Read the data from this file; - reads standard input")
+	hexData  = flag.String("hex", "", "Use these hex digits as the data")
+	utf8Data = flag.String("utf8", "", "Use this string, encoded as UTF-8, as the data")
+	ucs2Data = flag.String("ucs2", "", "Use this string, encoded as UCS-2, as the data")
+	nul      = flag.Bool("nul", false, "Terminate a -utf8 or -ucs2 string with NUL")
+
+	attrs    = flag.String("attrs", "nv,bs,rt", "Attributes of the variable, joined with commas or +: any of nv, bs, rt, hr, at, tat, aw, or a number")
+	appendTo = flag.Bool("append", false, "Append the data to the variable instead of replacing it")
+	del      = flag.Bool("delete", false, "Delete the variable instead of writing it")
+)
+
+// parseVariableName accepts NAME-GUID, or the bare name of a global variable.
+func parseVariableName(s string) (efivar.VariableName, error) {
+	vn, err := efivar.ParseVariableName(s)
+	if err != nil && !strings.Contains(s, "-") {
+		return efivar.VariableName{GUID: efivar.GlobalUUID, Name: s}, nil
+	}
+	return vn, err
+}
+
+func encodeUCS2(s string) []byte {
+	d16 := utf16.Encode([]rune(s))
+	b := make([]byte, 2*len(d16))
+	for n, c := range d16 {
+		b[2*n], b[2*n+1] = byte(c), byte(c>>8)
+	}
+	return b
+}
+
+// dataSources returns the names of the data flags given.
+func dataSources() []string {
+	var sources []string
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "file", "hex", "utf8", "ucs2":
+			sources = append(sources, f.Name)
+		}
+	})
+	return sources
+}
+
+// data returns the data selected by the flags.
+func data() ([]byte, error) {
+	sources := dataSources()
+	if len(sources) != 1 {
+		return nil, fmt.Errorf("exactly one of -file, -hex, -utf8 or -ucs2 is required")
+	}
+	return readData(sources[0])
+}
+
+// readData returns the data given by the flag source, which must not be
+// empty: writing no data deletes a variable.
+func readData(source string) ([]byte, error) {
+	var (
+		d   []byte
+		err error
+	)
+	switch source {
+	case "file":
+		if *file == "-" {
+			d, err = ioutil.ReadAll(os.Stdin)
+		} else {
+			d, err = ioutil.ReadFile(*file)
+		}
+	case "hex":
+		d, err = hex.DecodeString(strings.Join(strings.Fields(*hexData), ""))
+	case "utf8", "ucs2":
+		s := *utf8Data + *ucs2Data
+		if *nul {
+			s += "\x00"
+		}
+		d = []byte(s)
+		if source == "ucs2" {
+			d = encodeUCS2(s)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(d) == 0 {
+		return nil, fmt.Errorf("-%s gives no data, which would delete the variable; use -delete for that", source)
+	}
+	return d, nil
+}
+
+func main() {
+	completion.Register(completion.Variables)
+	completion.Flag("attrs", completion.Text)
+	completion.Flag("hex", completion.Text)
+	completion.Flag("ucs2", completion.Text)
+	completion.Flag("utf8", completion.Text)
+	flag.Parse()
+	completion.Run()
+
+	if !efivar.Supported() {
+		fmt.Fprintf(os.Stderr, "EFI variables are not supported on this system.\n")
+		os.Exit(1)
+	}
+	if flag.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "usage: %s [-attrs nv,bs,rt] [-append] {-file PATH | -hex HEX | -utf8 STRING | -ucs2 STRING} NAME[-GUID]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s -delete NAME[-GUID]\n", os.Args[0])
+		os.Exit(1)
+	}
+
+	vn, err := parseVariableName(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *del {
+		if *appendTo || len(dataSources()) > 0 {
+			log.Fatal("-delete takes no data and no -append")
+		}
+		if err := vn.Delete(); err != nil {
+			log.Fatalf("Deleting %v: %v", vn, err)
+		}
+		return
+	}
+	a, err := efivar.ParseAttributes(*attrs)
+	if err != nil {
+		log.Fatalf("Invalid -attrs: %v", err)
+	}
+	if *appendTo {
+		a |= efivar.AppendWrite
+	}
+	d, err := data()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	v := &efivar.Variable{VariableName: vn, Data: d, Attributes: a}
+	if err := v.Set(0644); err != nil {
+		log.Fatalf("Writing %v: %v", vn, err)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/lukegb/goefivar/efivar"
+)
+
+func TestParseVariableName(t *testing.T) {
+	vn, err := parseVariableName("BootNext")
+	if err != nil || vn != (efivar.VariableName{GUID: efivar.GlobalUUID, Name: "BootNext"}) {
+		t.Errorf("parseVariableName(BootNext) = %v, %v", vn, err)
+	}
+	vn, err = parseVariableName("LoaderEntryOneShot-4a67b082-0a4c-41cf-b6c7-440b29bb8c4f")
+	if err != nil || vn.Name != "LoaderEntryOneShot" || vn.GUID.String() != "4a67b082-0a4c-41cf-b6c7-440b29bb8c4f" {
+		t.Errorf("parseVariableName(LoaderEntryOneShot-...) = %v, %v", vn, err)
+	}
+	if _, err := parseVariableName("Foo-not-a-guid"); err == nil {
+		t.Errorf("parseVariableName(Foo-not-a-guid) succeeded")
+	}
+}
+
+func TestEncodeUCS2(t *testing.T) {
+	if got, want := string(encodeUCS2("aé\x00")), "a\x00\xe9\x00\x00\x00"; got != want {
+		t.Errorf("encodeUCS2 = %q; want %q", got, want)
+	}
+}
+
+func TestEmptyData(t *testing.T) {
+	empty, err := ioutil.TempFile("", "efivarset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(empty.Name())
+	empty.Close()
+	*file = empty.Name()
+	defer func() { *file = "" }()
+	for _, source := range []string{"file", "hex", "utf8", "ucs2"} {
+		if d, err := readData(source); err == nil {
+			t.Errorf("readData(%q) of nothing = %x; want an error", source, d)
+		}
+	}
+	*nul = true
+	defer func() { *nul = false }()
+	if d, err := readData("ucs2"); err != nil || string(d) != "\x00\x00" {
+		t.Errorf("readData(ucs2) of an empty -nul string = %x, %v; want 0000", d, err)
+	}
+}
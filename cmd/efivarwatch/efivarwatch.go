@@ -0,0 +1,148 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// efivarwatch prints changes to EFI variables as they happen.
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/internal/completion"
+	"github.com/lukegb/goefivar/internal/output"
+	"github.com/lukegb/goefivar/varwatch"
+)
+
+var (
+	interval   = flag.Duration("interval", time.Second, "How often to check for changes")
+	jsonOutput = flag.Bool("json", false, "Same as -format json")
+
+	guidFilter = flag.String("guid", "", "Only watch variables with this vendor GUID")
+	nameFilter = flag.String("name", "", "Only watch variables matching this shell pattern, e.g. Boot* or Mok*-605dab50-*")
+)
+
+// event is the JSON form of a varwatch.Event.
+type event struct {
+	Time          time.Time `json:"time"`
+	Op            string    `json:"op"`
+	Name          string    `json:"name"`
+	GUID          uuid.UUID `json:"guid"`
+	OldAttributes *uint32   `json:"old_attributes,omitempty"`
+	OldData       []byte    `json:"old_data,omitempty"`
+	Attributes    *uint32   `json:"attributes,omitempty"`
+	Data          []byte    `json:"data,omitempty"`
+}
+
+func toEvent(now time.Time, ev varwatch.Event) event {
+	e := event{Time: now, Op: ev.Op.String(), Name: ev.Name.Name, GUID: ev.Name.GUID}
+	if ev.Old != nil {
+		a := uint32(ev.Old.Attributes)
+		e.OldAttributes, e.OldData = &a, ev.Old.Data
+	}
+	if ev.New != nil {
+		a := uint32(ev.New.Attributes)
+		e.Attributes, e.Data = &a, ev.New.Data
+	}
+	return e
+}
+
+func printText(w io.Writer, now time.Time, ev varwatch.Event) error {
+	fmt.Fprintf(w, "%s %-6s %s\n", now.Format(time.RFC3339), ev.Op, ev.Name)
+	if ev.Old != nil {
+		fmt.Fprintf(w, "  - attributes %#x, data %s\n", uint32(ev.Old.Attributes), hex.EncodeToString(ev.Old.Data))
+	}
+	if ev.New != nil {
+		fmt.Fprintf(w, "  + attributes %#x, data %s\n", uint32(ev.New.Attributes), hex.EncodeToString(ev.New.Data))
+	}
+	return nil
+}
+
+func main() {
+	efivar.SetReadOnly()
+	completion.Register(completion.None)
+	completion.Flag("guid", completion.Text)
+	completion.Flag("interval", completion.Text)
+	completion.Flag("name", completion.Text)
+	flag.Parse()
+	completion.Run()
+
+	if !efivar.Supported() {
+		fmt.Fprintf(os.Stderr, "EFI variables are not supported on this system.\n")
+		os.Exit(1)
+	}
+
+	var guid uuid.UUID
+	if *guidFilter != "" {
+		var err error
+		if guid, err = uuid.Parse(*guidFilter); err != nil {
+			log.Fatalf("Invalid -guid: %v", err)
+		}
+	}
+	names, err := efivar.NewGlob(*nameFilter)
+	if err != nil {
+		log.Fatalf("Invalid -name: %v", err)
+	}
+	match := func(vn efivar.VariableName) bool {
+		if *guidFilter != "" && vn.GUID != guid {
+			return false
+		}
+		return *nameFilter == "" || names.Match(vn)
+	}
+
+	if *jsonOutput {
+		output.Set(output.JSON)
+	}
+	if _, err := output.Selected(); err != nil {
+		log.Fatal(err)
+	}
+
+	w, err := varwatch.NewWatcher(match)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	go func() {
+		<-sigs
+		close(stop)
+	}()
+
+	events := make(chan varwatch.Event)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- w.Run(*interval, events, stop)
+		close(events)
+	}()
+
+	for ev := range events {
+		now := time.Now()
+		err := output.WriteRecord(os.Stdout, toEvent(now, ev), func(w io.Writer) error { return printText(w, now, ev) })
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := <-errs; err != nil {
+		log.Fatal(err)
+	}
+}
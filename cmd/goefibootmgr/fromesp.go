@@ -0,0 +1,132 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/lukegb/goefivar/efiboot"
+)
+
+// parseSelection parses a list of 1-based candidate numbers such as "1,3 4",
+// or "all". Each candidate is returned once, in the order first given.
+func parseSelection(s string, n int) ([]int, error) {
+	s = strings.TrimSpace(s)
+	if s == "all" {
+		out := make([]int, n)
+		for i := range out {
+			out[i] = i
+		}
+		return out, nil
+	}
+	var out []int
+	seen := make(map[int]bool)
+	for _, f := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		i, err := strconv.Atoi(f)
+		if err != nil || i < 1 || i > n {
+			return nil, fmt.Errorf("invalid selection %q; want numbers from 1 to %d", f, n)
+		}
+		if !seen[i] {
+			seen[i] = true
+			out = append(out, i-1)
+		}
+	}
+	return out, nil
+}
+
+// createFromESP proposes boot options for the loaders found on the mounted
+// ESPs which don't already have one, and creates those the user selects.
+func createFromESP(all bool) {
+	esps, err := efiboot.ESPMountpoints()
+	if err != nil {
+		log.Fatalf("ESPMountpoints: %v", err)
+	}
+	found, err := efiboot.FindLoaders(esps)
+	if err != nil {
+		log.Fatalf("FindLoaders: %v", err)
+	}
+
+	bos, err := efiboot.BootOptions()
+	if err != nil {
+		log.Fatalf("BootOptions: %v", err)
+	}
+	existing := make(map[string]bool)
+	for _, bo := range bos {
+		existing[strings.ToLower(bo.LoadOpt.PathName())] = true
+	}
+	var candidates []*efiboot.FoundLoader
+	for _, l := range found {
+		if !existing[strings.ToLower(l.PathName)] {
+			candidates = append(candidates, l)
+		}
+	}
+	if len(candidates) == 0 {
+		fmt.Println("Every loader found on the mounted ESPs already has a boot option.")
+		return
+	}
+
+	for i, l := range candidates {
+		fmt.Printf("%2d. %-30s %s (%s on %s)\n", i+1, l.Label, l.PathName, l.Kind, l.ESP)
+	}
+	sel := make([]int, len(candidates))
+	for i := range sel {
+		sel[i] = i
+	}
+	if !all {
+		fmt.Print("Create which boot options? (e.g. 1,3 or all; empty for none) ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil {
+			log.Fatalf("Reading selection: %v", err)
+		}
+		if sel, err = parseSelection(line, len(candidates)); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	order, err := currentOrder()
+	if err != nil {
+		log.Fatalf("BootOrder: %v", err)
+	}
+	for _, i := range sel {
+		l := candidates[i]
+		dp, err := efiboot.FileDevicePath(l.File)
+		if err != nil {
+			log.Fatalf("FileDevicePath: %v", err)
+		}
+		lo, err := efiboot.NewLoadOpt(efiboot.LoadOptionActive, l.Label, dp, nil)
+		if err != nil {
+			log.Fatalf("NewLoadOpt: %v", err)
+		}
+		num, err := efiboot.FreeBootNumber()
+		if err != nil {
+			log.Fatalf("FreeBootNumber: %v", err)
+		}
+		if err := efiboot.SetBootOption(num, lo); err != nil {
+			log.Fatalf("SetBootOption: %v", err)
+		}
+		fmt.Printf("Created Boot%04X %s\n", num, l.Label)
+		order = append(order, num)
+	}
+	if !*noOrder && len(sel) > 0 {
+		if err := efiboot.SetBootOrder(order); err != nil {
+			log.Fatalf("SetBootOrder: %v", err)
+		}
+	}
+}
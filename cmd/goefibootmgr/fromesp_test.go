@@ -0,0 +1,44 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSelection(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want []int
+	}{
+		{"1,3", []int{0, 2}},
+		{" 2 3\n", []int{1, 2}},
+		{"all", []int{0, 1, 2}},
+		{"", nil},
+		{"1,1", []int{0}},
+		{"3,1 3,2,1", []int{2, 0, 1}},
+	} {
+		got, err := parseSelection(tc.in, 3)
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("parseSelection(%q, 3) = %v, %v; want %v", tc.in, got, err, tc.want)
+		}
+	}
+	for _, in := range []string{"0", "4", "one"} {
+		if _, err := parseSelection(in, 3); err == nil {
+			t.Errorf("parseSelection(%q, 3) succeeded", in)
+		}
+	}
+}
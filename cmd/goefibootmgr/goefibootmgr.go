@@ -0,0 +1,299 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// goefibootmgr manipulates the UEFI boot manager's variables, covering the
+// commonly used parts of efibootmgr.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/lukegb/goefivar/efiboot"
+	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/internal/completion"
+	"github.com/lukegb/goefivar/internal/output"
+	"github.com/lukegb/goefivar/internal/varstoreflag"
+)
+
+var (
+	verbose    = flag.Bool("v", false, "Print the device path and optional data of each boot option")
+	jsonOutput = flag.Bool("json", false, "Same as -format json")
+
+	bootNum = flag.String("b", "", "Boot option number (hex) to act on")
+	create  = flag.Bool("c", false, "Create a new boot option")
+	remove  = flag.Bool("B", false, "Delete the boot option selected by -b")
+	active  = flag.Bool("a", false, "Activate the boot option selected by -b")
+	inact   = flag.Bool("A", false, "Deactivate the boot option selected by -b")
+
+	disk    = flag.String("d", "/dev/sda", "Disk containing the loader, for -c")
+	part    = flag.Int("p", 1, "Partition number containing the loader, for -c")
+	loader  = flag.String("l", `\EFI\BOOT\BOOTX64.EFI`, "Path of the loader on the partition, for -c")
+	label   = flag.String("L", "Linux", "Description of the new boot option, for -c")
+	unicode = flag.Bool("u", false, "Pass remaining arguments to the loader as UCS-2 rather than ASCII, for -c")
+	argFile = flag.String("@", "", "Append the content of this file to the loader's arguments, for -c")
+	noOrder = flag.Bool("no-order", false, "Don't add a created option to BootOrder")
+
+	createFromESPs = flag.Bool("create-from-esp", false, "Offer to create boot options for the loaders found on the mounted ESPs")
+	yes            = flag.Bool("y", false, "With -create-from-esp, create every proposed boot option without asking")
+
+	bootNext    = flag.String("n", "", "Set BootNext to this boot option number (hex)")
+	delBootNext = flag.Bool("N", false, "Delete BootNext")
+	bootOrder   = flag.String("o", "", "Set BootOrder to this comma-separated list of boot option numbers (hex)")
+	timeout     = flag.Int("t", -1, "Set the boot manager timeout, in seconds")
+	delTimeout  = flag.Bool("T", false, "Delete the boot manager timeout")
+)
+
+func parseBootNum(s string) (uint16, error) {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "Boot"), "boot")
+	n, err := strconv.ParseUint(s, 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid boot option number %q", s)
+	}
+	return uint16(n), nil
+}
+
+// encodeArgs returns the optional data for a new boot option: args, then
+// the content of -@, as efibootmgr builds it.
+func encodeArgs(args []string) (efiboot.OptionalData, error) {
+	s := strings.Join(args, " ")
+	encode := efiboot.OptionalDataUTF8
+	if *unicode {
+		encode = efiboot.OptionalDataUCS2
+	}
+	d, err := encode(s)
+	if err != nil || *argFile == "" {
+		return d, err
+	}
+	extra, err := efiboot.OptionalDataFromFile(*argFile)
+	if err != nil {
+		return nil, err
+	}
+	return append(d, extra...), nil
+}
+
+func findOption(num uint16) *efiboot.BootOption {
+	bos, err := efiboot.BootOptions()
+	if err != nil {
+		log.Fatalf("BootOptions: %v", err)
+	}
+	want := efiboot.BootOptionName(num)
+	for _, bo := range bos {
+		if bo.Variable.VariableName == want {
+			return bo
+		}
+	}
+	log.Fatalf("No such boot option %v", want.Name)
+	return nil
+}
+
+func createOption() uint16 {
+	dp, err := efiboot.ESPFileDevicePath(*disk, *part, *loader)
+	if err != nil {
+		log.Fatalf("ESPFileDevicePath: %v", err)
+	}
+	args, err := encodeArgs(flag.Args())
+	if err != nil {
+		log.Fatal(err)
+	}
+	lo, err := efiboot.NewLoadOpt(efiboot.LoadOptionActive, *label, dp, args)
+	if err != nil {
+		log.Fatalf("NewLoadOpt: %v", err)
+	}
+	var num uint16
+	if *bootNum != "" {
+		if num, err = parseBootNum(*bootNum); err != nil {
+			log.Fatal(err)
+		}
+		// As efibootmgr does, refuse to replace an existing option.
+		if ok, err := efiboot.BootOptionName(num).Exists(); err != nil {
+			log.Fatal(err)
+		} else if ok {
+			log.Fatalf("Boot%04X already exists", num)
+		}
+	} else if num, err = efiboot.FreeBootNumber(); err != nil {
+		log.Fatalf("FreeBootNumber: %v", err)
+	}
+	if err := efiboot.SetBootOption(num, lo); err != nil {
+		log.Fatalf("SetBootOption: %v", err)
+	}
+	if !*noOrder {
+		order, err := currentOrder()
+		if err != nil {
+			log.Fatalf("BootOrder: %v", err)
+		}
+		if err := efiboot.SetBootOrder(append([]uint16{num}, order...)); err != nil {
+			log.Fatalf("SetBootOrder: %v", err)
+		}
+	}
+	return num
+}
+
+func currentOrder() ([]uint16, error) {
+	vns, err := efiboot.BootOrder()
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	out := make([]uint16, 0, len(vns))
+	for _, vn := range vns {
+		n, err := efiboot.BootOptionNumber(vn)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+func deleteOption(num uint16) {
+	if err := efiboot.BootOptionName(num).Delete(); err != nil {
+		log.Fatalf("Deleting Boot%04X: %v", num, err)
+	}
+	order, err := currentOrder()
+	if err != nil {
+		log.Fatalf("BootOrder: %v", err)
+	}
+	var kept []uint16
+	for _, n := range order {
+		if n != num {
+			kept = append(kept, n)
+		}
+	}
+	if len(kept) != len(order) {
+		if err := efiboot.SetBootOrder(kept); err != nil {
+			log.Fatalf("SetBootOrder: %v", err)
+		}
+	}
+}
+
+func setActive(num uint16, on bool) {
+	bo := findOption(num)
+	if on {
+		bo.LoadOpt.Attributes = bo.LoadOpt.Attributes.With(efiboot.LoadOptionActive)
+	} else {
+		bo.LoadOpt.Attributes = bo.LoadOpt.Attributes.Without(efiboot.LoadOptionActive)
+	}
+	if err := bo.Save(); err != nil {
+		log.Fatalf("Saving %v: %v", bo.Variable.Name, err)
+	}
+}
+
+func main() {
+	completion.Register(completion.None)
+	completion.Flag("L", completion.Text)
+	completion.Flag("b", completion.BootNumbers)
+	completion.Flag("l", completion.Text)
+	completion.Flag("n", completion.BootNumbers)
+	completion.Flag("o", completion.BootNumbers)
+	completion.Flag("p", completion.Text)
+	completion.Flag("t", completion.Text)
+	flag.Parse()
+	completion.Run()
+	if err := varstoreflag.Apply(); err != nil {
+		log.Fatal(err)
+	}
+
+	if !efivar.Supported() {
+		fmt.Fprintf(os.Stderr, "EFI variables are not supported on this system.\n")
+		os.Exit(1)
+	}
+
+	var num uint16
+	if *bootNum != "" {
+		var err error
+		if num, err = parseBootNum(*bootNum); err != nil {
+			log.Fatal(err)
+		}
+	}
+	needNum := func(what string) {
+		if *bootNum == "" {
+			log.Fatalf("%s requires a boot option number (-b)", what)
+		}
+	}
+
+	switch {
+	case *create:
+		createOption()
+	case *createFromESPs:
+		createFromESP(*yes)
+	case *remove:
+		needNum("-B")
+		deleteOption(num)
+	case *active:
+		needNum("-a")
+		setActive(num, true)
+	case *inact:
+		needNum("-A")
+		setActive(num, false)
+	}
+
+	if *bootNext != "" {
+		n, err := parseBootNum(*bootNext)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := efiboot.SetBootNext(n); err != nil {
+			log.Fatalf("SetBootNext: %v", err)
+		}
+	}
+	if *delBootNext {
+		if err := efiboot.ClearBootNext(); err != nil && !os.IsNotExist(err) {
+			log.Fatalf("ClearBootNext: %v", err)
+		}
+	}
+	if *bootOrder != "" {
+		var order []uint16
+		for _, s := range strings.Split(*bootOrder, ",") {
+			n, err := parseBootNum(s)
+			if err != nil {
+				log.Fatal(err)
+			}
+			order = append(order, n)
+		}
+		if err := efiboot.SetBootOrder(order); err != nil {
+			log.Fatalf("SetBootOrder: %v", err)
+		}
+	}
+	if *timeout >= 0 {
+		if *timeout > 0xffff {
+			log.Fatalf("Timeout %d is too long", *timeout)
+		}
+		if err := efiboot.SetTimeout(uint16(*timeout)); err != nil {
+			log.Fatalf("SetTimeout: %v", err)
+		}
+	}
+	if *delTimeout {
+		if err := efiboot.DeleteTimeout(); err != nil {
+			log.Fatalf("DeleteTimeout: %v", err)
+		}
+	}
+
+	st, err := readState()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *jsonOutput {
+		output.Set(output.JSON)
+	}
+	if err := output.Write(os.Stdout, st, func(w io.Writer) error { return st.print(w, *verbose) }); err != nil {
+		log.Fatal(err)
+	}
+}
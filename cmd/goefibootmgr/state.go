@@ -0,0 +1,118 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/lukegb/goefivar/efiboot"
+	"github.com/lukegb/goefivar/efivar"
+)
+
+// The JSON field names are part of goefibootmgr's interface; don't change them.
+
+type option struct {
+	Number      string `json:"number"`
+	Description string `json:"description"`
+	Active      bool   `json:"active"`
+	Hidden      bool   `json:"hidden"`
+	FilePath    string `json:"file_path"`
+	// OptionalData is base64-encoded, as it may not be text.
+	OptionalData []byte `json:"optional_data,omitempty"`
+}
+
+type state struct {
+	BootCurrent string   `json:"boot_current,omitempty"`
+	BootNext    string   `json:"boot_next,omitempty"`
+	Timeout     *uint16  `json:"timeout,omitempty"`
+	BootOrder   []string `json:"boot_order"`
+	Options     []option `json:"options"`
+}
+
+func number(vn efivar.VariableName) string { return strings.TrimPrefix(vn.Name, "Boot") }
+
+func readState() (*state, error) {
+	st := &state{BootOrder: []string{}, Options: []option{}}
+
+	if vn, err := efiboot.BootCurrent(); err == nil {
+		st.BootCurrent = number(vn)
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("BootCurrent: %v", err)
+	}
+	if vn, err := efiboot.BootNext(); err == nil {
+		st.BootNext = number(vn)
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("BootNext: %v", err)
+	}
+	if t, err := efiboot.Timeout(); err == nil {
+		st.Timeout = &t
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("Timeout: %v", err)
+	}
+	if vns, err := efiboot.BootOrder(); err == nil {
+		for _, vn := range vns {
+			st.BootOrder = append(st.BootOrder, number(vn))
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("BootOrder: %v", err)
+	}
+
+	bos, err := efiboot.BootOptions()
+	if err != nil {
+		return nil, fmt.Errorf("BootOptions: %v", err)
+	}
+	for _, bo := range bos {
+		st.Options = append(st.Options, option{
+			Number:       number(bo.Variable.VariableName),
+			Description:  bo.LoadOpt.Description,
+			Active:       bo.LoadOpt.Attributes.Has(efiboot.LoadOptionActive),
+			Hidden:       bo.LoadOpt.Attributes.Has(efiboot.LoadOptionHidden),
+			FilePath:     bo.LoadOpt.FilePath,
+			OptionalData: bo.LoadOpt.OptionalData,
+		})
+	}
+	return st, nil
+}
+
+func (st *state) print(w io.Writer, verbose bool) error {
+	if st.BootCurrent != "" {
+		fmt.Fprintf(w, "BootCurrent: %s\n", st.BootCurrent)
+	}
+	if st.BootNext != "" {
+		fmt.Fprintf(w, "BootNext: %s\n", st.BootNext)
+	}
+	if st.Timeout != nil {
+		fmt.Fprintf(w, "Timeout: %d seconds\n", *st.Timeout)
+	}
+	fmt.Fprintf(w, "BootOrder: %s\n", strings.Join(st.BootOrder, ","))
+	for _, o := range st.Options {
+		mark := " "
+		if o.Active {
+			mark = "*"
+		}
+		fmt.Fprintf(w, "Boot%s%s %s", o.Number, mark, o.Description)
+		if verbose {
+			fmt.Fprintf(w, "\t%s", o.FilePath)
+			if len(o.OptionalData) > 0 {
+				fmt.Fprintf(w, "%s", efiboot.OptionalData(o.OptionalData))
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
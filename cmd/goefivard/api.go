@@ -0,0 +1,294 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/lukegb/goefivar/efiboot"
+	"github.com/lukegb/goefivar/efipb"
+	"github.com/lukegb/goefivar/efisig"
+	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/vardump"
+)
+
+type variableName struct {
+	GUID string `json:"guid"`
+	Name string `json:"name"`
+}
+
+type variable struct {
+	GUID       string `json:"guid"`
+	Name       string `json:"name"`
+	Attributes uint32 `json:"attributes"`
+	Data       []byte `json:"data"`
+}
+
+type bootEntry struct {
+	Number       uint16 `json:"number"`
+	Description  string `json:"description"`
+	FilePath     string `json:"file_path"`
+	Active       bool   `json:"active"`
+	OptionalData []byte `json:"optional_data"`
+}
+
+type bootState struct {
+	Entries []bootEntry `json:"entries"`
+	Order   []uint16    `json:"order"`
+	Next    *uint16     `json:"next,omitempty"`
+	Current *uint16     `json:"current,omitempty"`
+}
+
+type apiError struct {
+	Error string `json:"error"`
+	// Code is efivar.CodeOf(err), such as NOT_FOUND.
+	Code efivar.Code `json:"code"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// protoType is the media type of responses in the efipb wire format.
+const protoType = "application/x-protobuf"
+
+// wantsProto reports whether the client asked for protobuf rather than JSON.
+// Handlers which support it return an efipb message instead of a JSON value.
+func wantsProto(r *http.Request) bool {
+	for _, t := range strings.Split(r.Header.Get("Accept"), ",") {
+		if strings.TrimSpace(strings.SplitN(t, ";", 2)[0]) == protoType {
+			return true
+		}
+	}
+	return false
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, apiError{err.Error(), efivar.CodeOf(err)})
+}
+
+// errorStatus picks the HTTP status for an error from the library.
+func errorStatus(err error) int {
+	switch efivar.CodeOf(err) {
+	case efivar.NotFound:
+		return http.StatusNotFound
+	case efivar.Permission, efivar.AuthRequired:
+		return http.StatusForbidden
+	case efivar.NVRAMFull:
+		return http.StatusInsufficientStorage
+	case efivar.Unsupported:
+		return http.StatusNotImplemented
+	}
+	return http.StatusInternalServerError
+}
+
+// newAPI returns the handler for the API, without authorization.
+func newAPI() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/variables", methods{http.MethodGet: listVariables}.serve)
+	mux.HandleFunc("/v1/variables/", methods{http.MethodGet: getVariable}.serve)
+	mux.HandleFunc("/v1/boot", methods{http.MethodGet: getBoot}.serve)
+	mux.HandleFunc("/v1/boot/next", methods{http.MethodPut: setBootNext, http.MethodDelete: clearBootNext}.serve)
+	mux.HandleFunc("/v1/boot/order", methods{http.MethodPut: setBootOrder}.serve)
+	mux.HandleFunc("/v1/secureboot", methods{http.MethodGet: getSecureBoot}.serve)
+	return mux
+}
+
+// methods dispatches a request on its method.
+type methods map[string]func(*http.Request) (int, interface{}, error)
+
+func (m methods) serve(w http.ResponseWriter, r *http.Request) {
+	f, ok := m[r.Method]
+	if !ok {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %v not allowed", r.Method))
+		return
+	}
+	status, v, err := f(r)
+	if err != nil {
+		writeError(w, status, err)
+		return
+	}
+	if v == nil {
+		w.WriteHeader(status)
+		return
+	}
+	if m, ok := v.(interface{ Marshal() []byte }); ok {
+		w.Header().Set("Content-Type", protoType)
+		w.WriteHeader(status)
+		w.Write(m.Marshal())
+		return
+	}
+	writeJSON(w, status, v)
+}
+
+func listVariables(r *http.Request) (int, interface{}, error) {
+	vns, err := efivar.Variables()
+	if err != nil {
+		return errorStatus(err), nil, err
+	}
+	if wantsProto(r) {
+		out := &efipb.VariableNames{Names: make([]*efipb.VariableName, len(vns))}
+		for n, vn := range vns {
+			out.Names[n] = efipb.NewVariableName(vn)
+		}
+		return http.StatusOK, out, nil
+	}
+	out := make([]variableName, len(vns))
+	for n, vn := range vns {
+		out[n] = variableName{vn.GUID.String(), vn.Name}
+	}
+	return http.StatusOK, out, nil
+}
+
+func getVariable(r *http.Request) (int, interface{}, error) {
+	vn, err := vardump.ParseFileName(strings.TrimPrefix(r.URL.Path, "/v1/variables/"))
+	if err != nil {
+		return http.StatusBadRequest, nil, err
+	}
+	v, err := vn.Get()
+	if err != nil {
+		return errorStatus(err), nil, err
+	}
+	if wantsProto(r) {
+		return http.StatusOK, efipb.NewVariable(v), nil
+	}
+	return http.StatusOK, variable{v.GUID.String(), v.Name, uint32(v.Attributes), v.Data}, nil
+}
+
+// bootNumber returns the number of the Boot#### variable read by f, or nil
+// if it is not set.
+func bootNumber(f func() (efivar.VariableName, error)) (*uint16, error) {
+	vn, err := f()
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	n, err := efiboot.BootOptionNumber(vn)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+func getBoot(r *http.Request) (int, interface{}, error) {
+	bos, err := efiboot.BootOptions()
+	if err != nil {
+		return errorStatus(err), nil, err
+	}
+	s := bootState{Entries: []bootEntry{}, Order: []uint16{}}
+	for _, bo := range bos {
+		n, err := efiboot.BootOptionNumber(bo.Variable.VariableName)
+		if err != nil {
+			continue
+		}
+		s.Entries = append(s.Entries, bootEntry{
+			Number:       n,
+			Description:  bo.LoadOpt.Description,
+			FilePath:     bo.LoadOpt.FilePath,
+			Active:       bo.LoadOpt.Attributes.Has(efiboot.LoadOptionActive),
+			OptionalData: bo.LoadOpt.OptionalData,
+		})
+	}
+	order, err := efiboot.BootOrder()
+	if err != nil && !os.IsNotExist(err) {
+		return errorStatus(err), nil, err
+	}
+	for _, vn := range order {
+		if n, err := efiboot.BootOptionNumber(vn); err == nil {
+			s.Order = append(s.Order, n)
+		}
+	}
+	if s.Next, err = bootNumber(efiboot.BootNext); err != nil {
+		return errorStatus(err), nil, err
+	}
+	if s.Current, err = bootNumber(efiboot.BootCurrent); err != nil {
+		return errorStatus(err), nil, err
+	}
+	return http.StatusOK, s, nil
+}
+
+func decode(r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// checkBootNumbers returns an error unless every one of nums is an existing boot entry.
+func checkBootNumbers(nums []uint16) (int, error) {
+	for _, n := range nums {
+		ok, err := efiboot.BootOptionName(n).Exists()
+		if err != nil {
+			return errorStatus(err), err
+		}
+		if !ok {
+			return http.StatusBadRequest, fmt.Errorf("there is no Boot%04X", n)
+		}
+	}
+	return 0, nil
+}
+
+func setBootNext(r *http.Request) (int, interface{}, error) {
+	var req struct {
+		Number *uint16 `json:"number"`
+	}
+	if err := decode(r, &req); err != nil || req.Number == nil {
+		return http.StatusBadRequest, nil, fmt.Errorf("want {\"number\": N}")
+	}
+	if status, err := checkBootNumbers([]uint16{*req.Number}); err != nil {
+		return status, nil, err
+	}
+	if err := efiboot.SetBootNext(*req.Number); err != nil {
+		return errorStatus(err), nil, err
+	}
+	return http.StatusNoContent, nil, nil
+}
+
+func clearBootNext(r *http.Request) (int, interface{}, error) {
+	if err := efiboot.ClearBootNext(); err != nil && !os.IsNotExist(err) {
+		return errorStatus(err), nil, err
+	}
+	return http.StatusNoContent, nil, nil
+}
+
+func setBootOrder(r *http.Request) (int, interface{}, error) {
+	var req struct {
+		Order []uint16 `json:"order"`
+	}
+	if err := decode(r, &req); err != nil || req.Order == nil {
+		return http.StatusBadRequest, nil, fmt.Errorf("want {\"order\": [N, ...]}")
+	}
+	if status, err := checkBootNumbers(req.Order); err != nil {
+		return status, nil, err
+	}
+	if err := efiboot.SetBootOrder(req.Order); err != nil {
+		return errorStatus(err), nil, err
+	}
+	return http.StatusNoContent, nil, nil
+}
+
+func getSecureBoot(r *http.Request) (int, interface{}, error) {
+	s, err := efisig.CurrentStatus()
+	if err != nil {
+		return errorStatus(err), nil, err
+	}
+	return http.StatusOK, s, nil
+}
@@ -0,0 +1,162 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/lukegb/goefivar/efipb"
+	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/vardump"
+	"github.com/lukegb/goefivar/varstore"
+)
+
+func TestAPI(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goefivard")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	timeout := &efivar.Variable{
+		VariableName: efivar.VariableName{GUID: efivar.GlobalUUID, Name: "Timeout"},
+		Data:         []byte{5, 0},
+		Attributes:   efivar.NonVolatile | efivar.BootserviceAccess | efivar.RuntimeAccess,
+	}
+	if err := vardump.WriteDir(dir, []*efivar.Variable{timeout}); err != nil {
+		t.Fatal(err)
+	}
+	efivar.UseStore(&varstore.Dir{Path: dir})
+	defer efivar.UseStore(nil)
+
+	p, err := newPolicy("1000", "", "")
+	if err != nil {
+		t.Fatalf("newPolicy: %v", err)
+	}
+	h := p.wrap(newAPI())
+	get := func(method, path string, remote *creds) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, nil)
+		if remote != nil {
+			r = r.WithContext(context.WithValue(r.Context(), credsKey{}, *remote))
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+	user, other := &creds{1, 1000, 1000}, &creds{2, 1001, 1001}
+
+	w := get(http.MethodGet, "/v1/variables", other)
+	var vns []variableName
+	if err := json.Unmarshal(w.Body.Bytes(), &vns); w.Code != http.StatusOK || err != nil {
+		t.Fatalf("GET /v1/variables = %v %s", w.Code, w.Body)
+	}
+	if len(vns) != 1 || vns[0].Name != "Timeout" {
+		t.Errorf("GET /v1/variables = %+v; want just Timeout", vns)
+	}
+
+	w = get(http.MethodGet, "/v1/variables/"+vardump.FileName(timeout.VariableName), other)
+	var v variable
+	if err := json.Unmarshal(w.Body.Bytes(), &v); w.Code != http.StatusOK || err != nil || string(v.Data) != string(timeout.Data) {
+		t.Errorf("GET Timeout = %v %s", w.Code, w.Body)
+	}
+	r := httptest.NewRequest(http.MethodGet, "/v1/variables/"+vardump.FileName(timeout.VariableName), nil)
+	r = r.WithContext(context.WithValue(r.Context(), credsKey{}, *other))
+	r.Header.Set("Accept", "application/x-protobuf")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	var pv efipb.Variable
+	if err := pv.Unmarshal(w.Body.Bytes()); w.Code != http.StatusOK || err != nil || w.Header().Get("Content-Type") != protoType {
+		t.Errorf("GET Timeout as protobuf = %v %v %q", w.Code, w.Header().Get("Content-Type"), w.Body)
+	} else if got, err := pv.EFI(); err != nil || got.Name != "Timeout" || string(got.Data) != string(timeout.Data) || got.Attributes != timeout.Attributes {
+		t.Errorf("GET Timeout as protobuf = %+v, %v; want %+v", got, err, timeout)
+	}
+	w = get(http.MethodGet, "/v1/variables/Missing-8be4df61-93ca-11d2-aa0d-00e098032b8c", other)
+	if want := `"code":"NOT_FOUND"`; w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), want) {
+		t.Errorf("GET missing variable = %v %s; want %v with %s", w.Code, w.Body, http.StatusNotFound, want)
+	}
+
+	for _, tc := range []struct {
+		remote *creds
+		want   int
+	}{
+		{other, http.StatusForbidden},
+		{nil, http.StatusForbidden},
+		{user, http.StatusNoContent},
+		{&creds{3, 0, 0}, http.StatusNoContent},
+	} {
+		if w := get(http.MethodDelete, "/v1/boot/next", tc.remote); w.Code != tc.want {
+			t.Errorf("DELETE /v1/boot/next from %+v = %v %s; want %v", tc.remote, w.Code, w.Body, tc.want)
+		}
+	}
+
+	// Vendors' variables may hold secrets, so only those who may make
+	// changes may read them.
+	mokAuth := "/v1/variables/MokAuth-605dab50-e046-4300-abb6-3dd810dd8b23"
+	for _, tc := range []struct {
+		remote *creds
+		want   int
+	}{
+		{other, http.StatusForbidden},
+		{user, http.StatusNotFound},
+	} {
+		if w := get(http.MethodGet, mokAuth, tc.remote); w.Code != tc.want {
+			t.Errorf("GET MokAuth from %+v = %v %s; want %v", tc.remote, w.Code, w.Body, tc.want)
+		}
+	}
+	if w := get(http.MethodPost, "/v1/boot/next", user); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /v1/boot/next = %v; want %v", w.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestConnContext(t *testing.T) {
+	want := creds{1, 1000, 1000}
+	if got, ok := credsFrom(connContext(context.Background(), credConn{nil, want})); !ok || got != want {
+		t.Errorf("creds of a credConn's requests = %+v, %v; want %+v", got, ok, want)
+	}
+	if got, ok := credsFrom(connContext(context.Background(), nil)); ok {
+		t.Errorf("creds of another connection's requests = %+v; want none", got)
+	}
+}
+
+func TestAuthorizeGroups(t *testing.T) {
+	p, err := newPolicy("", "50", "")
+	if err != nil {
+		t.Fatalf("newPolicy: %v", err)
+	}
+	p.groups = func(uid int) ([]int, error) {
+		if uid == 1001 {
+			return []int{1001, 50}, nil
+		}
+		return []int{uid}, nil
+	}
+	for _, tc := range []struct {
+		c    creds
+		want bool
+	}{
+		{creds{1, 1000, 50}, true},
+		{creds{2, 1001, 1001}, true},
+		{creds{3, 1002, 1002}, false},
+	} {
+		if err := p.authorize(tc.c, http.MethodDelete, "/v1/boot/next"); (err == nil) != tc.want {
+			t.Errorf("authorize(%+v) = %v; want allowed %v", tc.c, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"strings"
+
+	"github.com/lukegb/goefivar/efivar"
+)
+
+// creds identifies the process at the other end of a connection.
+type creds struct {
+	PID, UID, GID int
+}
+
+// credsKey is the context key of the peer's creds in each request.
+type credsKey struct{}
+
+// credsFrom returns the creds of the peer which sent a request with ctx.
+func credsFrom(ctx context.Context) (creds, bool) {
+	c, ok := ctx.Value(credsKey{}).(creds)
+	return c, ok
+}
+
+// connContext, as an http.Server's ConnContext, gives the requests on a
+// connection accepted by credListener the peer's creds.
+func connContext(ctx context.Context, c net.Conn) context.Context {
+	if cc, ok := c.(credConn); ok {
+		return context.WithValue(ctx, credsKey{}, cc.creds)
+	}
+	return ctx
+}
+
+type credConn struct {
+	net.Conn
+	creds creds
+}
+
+// credListener records the credentials of each peer as it connects.
+type credListener struct {
+	net.Listener
+}
+
+func (l credListener) Accept() (net.Conn, error) {
+	for {
+		c, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		cr, err := peerCreds(c)
+		if err != nil {
+			log.Printf("Rejecting connection: reading peer credentials: %v", err)
+			c.Close()
+			continue
+		}
+		return credConn{c, cr}, nil
+	}
+}
+
+// policy decides which requests are allowed.
+type policy struct {
+	writeUIDs, writeGIDs map[int]bool
+	// hook, if set, is run for requests the rest of the policy allows.
+	hook string
+	// groups returns the supplementary groups of a user.
+	groups func(uid int) ([]int, error)
+}
+
+// userGroups returns the groups which the user database lists uid in.
+func userGroups(uid int) ([]int, error) {
+	u, err := user.LookupId(strconv.Itoa(uid))
+	if err != nil {
+		return nil, err
+	}
+	ids, err := u.GroupIds()
+	if err != nil {
+		return nil, err
+	}
+	gids := make([]int, 0, len(ids))
+	for _, id := range ids {
+		gid, err := strconv.Atoi(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid group ID %q", id)
+		}
+		gids = append(gids, gid)
+	}
+	return gids, nil
+}
+
+func parseIDs(s string) (map[int]bool, error) {
+	ids := make(map[int]bool)
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f == "" {
+			continue
+		}
+		id, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ID %q", f)
+		}
+		ids[id] = true
+	}
+	return ids, nil
+}
+
+func newPolicy(uids, gids, hook string) (*policy, error) {
+	p := &policy{hook: hook, groups: userGroups}
+	var err error
+	if p.writeUIDs, err = parseIDs(uids); err != nil {
+		return nil, fmt.Errorf("-write-uid: %v", err)
+	}
+	if p.writeGIDs, err = parseIDs(gids); err != nil {
+		return nil, fmt.Errorf("-write-gid: %v", err)
+	}
+	return p, nil
+}
+
+func isRead(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead
+}
+
+// isVendorVariable reports whether path reads a variable which is not one
+// of the global variables defined by the UEFI specification. Vendors' own
+// variables may hold secrets, such as shim's MokAuth.
+func isVendorVariable(path string) bool {
+	if !strings.HasPrefix(path, "/v1/variables/") {
+		return false
+	}
+	vn, err := efivar.ParseVariableName(strings.TrimPrefix(path, "/v1/variables/"))
+	return err == nil && vn.GUID != efivar.GlobalUUID
+}
+
+// inWriteGroup reports whether the primary group of c, or one of its user's
+// supplementary groups, may make changes.
+func (p *policy) inWriteGroup(c creds) bool {
+	if len(p.writeGIDs) == 0 {
+		return false
+	}
+	if p.writeGIDs[c.GID] {
+		return true
+	}
+	gids, err := p.groups(c.UID)
+	if err != nil {
+		log.Printf("Looking up the groups of uid %d: %v", c.UID, err)
+		return false
+	}
+	for _, gid := range gids {
+		if p.writeGIDs[gid] {
+			return true
+		}
+	}
+	return false
+}
+
+// authorize returns an error if c may not make the request.
+func (p *policy) authorize(c creds, method, path string) error {
+	if c.UID != 0 && !p.writeUIDs[c.UID] && !p.inWriteGroup(c) {
+		if !isRead(method) {
+			return fmt.Errorf("uid %d may not make changes", c.UID)
+		}
+		if isVendorVariable(path) {
+			return fmt.Errorf("uid %d may not read vendor variables", c.UID)
+		}
+	}
+	if p.hook == "" {
+		return nil
+	}
+	cmd := exec.Command(p.hook)
+	cmd.Env = append(os.Environ(),
+		"GOEFIVARD_PID="+strconv.Itoa(c.PID),
+		"GOEFIVARD_UID="+strconv.Itoa(c.UID),
+		"GOEFIVARD_GID="+strconv.Itoa(c.GID),
+		"GOEFIVARD_METHOD="+method,
+		"GOEFIVARD_PATH="+path,
+	)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("denied by %v: %v", p.hook, err)
+	}
+	return nil
+}
+
+// wrap returns a handler which authorizes each request before passing it to h.
+func (p *policy) wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, ok := credsFrom(r.Context())
+		err := fmt.Errorf("no peer credentials")
+		if ok {
+			err = p.authorize(c, r.Method, r.URL.Path)
+		}
+		if err != nil {
+			log.Printf("%v %v from pid %d, uid %d: %v", r.Method, r.URL.Path, c.PID, c.UID, err)
+			writeJSON(w, http.StatusForbidden, apiError{err.Error(), efivar.Permission})
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,111 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// goefivard serves a JSON API over a unix socket for listing variables,
+// managing boot entries and reading the Secure Boot status, so that agents
+// can manage firmware settings without running as root themselves.
+//
+// Each request is checked against the credentials of the connecting
+// process. By default the socket is only open to its owner and group. Anyone
+// who can connect may list variables and read the global variables defined
+// by the UEFI specification; only root and the users and groups given with
+// -write-uid and -write-gid may read vendors' variables, which may hold
+// secrets, or make changes. -authz-hook names a program that can veto any
+// request.
+//
+//	GET    /v1/variables
+//	GET    /v1/variables/NAME-GUID
+//	GET    /v1/boot
+//	PUT    /v1/boot/next       {"number": 1}
+//	DELETE /v1/boot/next
+//	PUT    /v1/boot/order      {"order": [1, 0]}
+//	GET    /v1/secureboot
+//
+// The variable endpoints answer with the messages in efipb/efivar.proto
+// instead of JSON when the request has "Accept: application/x-protobuf".
+//
+// The API is JSON over HTTP rather than gRPC: gRPC would make grpc-go and
+// its dependencies dependencies of the whole goefivar module, and a JSON API
+// can be used from a shell script with curl --unix-socket. The protobuf
+// responses give RPC clients the same wire format gRPC would.
+//
+// goefivard needs Go 1.13 or later.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/internal/completion"
+)
+
+var (
+	socket   = flag.String("socket", "/run/goefivard.sock", "Path of the unix socket to listen on")
+	mode     = flag.Uint("mode", 0660, "Permissions of the socket")
+	writeUID = flag.String("write-uid", "", "Comma-separated user IDs, besides root, allowed to make changes")
+	writeGID = flag.String("write-gid", "", "Comma-separated group IDs whose members, by primary or supplementary group, may make changes")
+	authHook = flag.String("authz-hook", "", "Program run to authorize each request that the built-in policy allows; a non-zero exit status denies it")
+)
+
+func main() {
+	completion.Register(completion.None)
+	completion.Flag("socket", completion.Files)
+	completion.Flag("write-uid", completion.Text)
+	completion.Flag("write-gid", completion.Text)
+	completion.Flag("authz-hook", completion.Files)
+	flag.Parse()
+	completion.Run()
+
+	if !efivar.Supported() {
+		fmt.Fprintf(os.Stderr, "EFI variables are not supported on this system.\n")
+		os.Exit(1)
+	}
+
+	p, err := newPolicy(*writeUID, *writeGID, *authHook)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := os.Remove(*socket); err != nil && !os.IsNotExist(err) {
+		log.Fatal(err)
+	}
+	l, err := net.Listen("unix", *socket)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.Chmod(*socket, os.FileMode(*mode)); err != nil {
+		log.Fatal(err)
+	}
+
+	srv := &http.Server{Handler: p.wrap(newAPI()), ConnContext: connContext}
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		srv.Close()
+	}()
+
+	log.Printf("Listening on %v", *socket)
+	if err := srv.Serve(credListener{l}); err != nil && err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+	os.Remove(*socket)
+}
@@ -0,0 +1,98 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// goesrt lists the firmware resources in the EFI System Resource Table, with
+// their versions and the outcome of the last update attempt.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/google/uuid"
+	"github.com/lukegb/goefivar/esrt"
+	"github.com/lukegb/goefivar/internal/completion"
+	"github.com/lukegb/goefivar/internal/output"
+)
+
+var jsonOutput = flag.Bool("json", false, "Same as -format json")
+
+// entry is the JSON form of an esrt.Entry.
+type entry struct {
+	FirmwareClass          uuid.UUID `json:"firmware_class"`
+	Name                   string    `json:"name"`
+	DevicePath             string    `json:"device_path,omitempty"`
+	Type                   string    `json:"type"`
+	Version                uint32    `json:"version"`
+	LowestSupportedVersion uint32    `json:"lowest_supported_version"`
+	CapsuleFlags           uint32    `json:"capsule_flags"`
+	LastAttemptVersion     uint32    `json:"last_attempt_version"`
+	LastAttemptStatus      string    `json:"last_attempt_status"`
+}
+
+func printTable(w io.Writer, es []entry) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tTYPE\tVERSION\tLOWEST\tLAST ATTEMPT\tSTATUS\tFIRMWARE CLASS")
+	for _, e := range es {
+		fmt.Fprintf(tw, "%s\t%s\t%#x\t%#x\t%#x\t%s\t%v\n", e.Name, e.Type, e.Version, e.LowestSupportedVersion, e.LastAttemptVersion, e.LastAttemptStatus, e.FirmwareClass)
+	}
+	return tw.Flush()
+}
+
+func main() {
+	completion.Register(completion.None)
+	flag.Parse()
+	completion.Run()
+
+	if !esrt.Supported() {
+		fmt.Fprintf(os.Stderr, "This system has no EFI System Resource Table.\n")
+		os.Exit(1)
+	}
+
+	es, err := esrt.Entries()
+	if err != nil {
+		log.Fatal(err)
+	}
+	r, err := esrt.NewResolver()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	out := make([]entry, len(es))
+	for n, e := range es {
+		d := r.Resolve(e)
+		out[n] = entry{
+			FirmwareClass:          e.FirmwareClass,
+			Name:                   d.Name,
+			DevicePath:             d.Path,
+			Type:                   e.Type.String(),
+			Version:                e.Version,
+			LowestSupportedVersion: e.LowestSupportedVersion,
+			CapsuleFlags:           e.CapsuleFlags,
+			LastAttemptVersion:     e.LastAttemptVersion,
+			LastAttemptStatus:      e.LastAttemptStatus.String(),
+		}
+	}
+
+	if *jsonOutput {
+		output.Set(output.JSON)
+	}
+	if err := output.Write(os.Stdout, out, func(w io.Writer) error { return printTable(w, out) }); err != nil {
+		log.Fatal(err)
+	}
+}
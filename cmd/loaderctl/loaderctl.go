@@ -0,0 +1,253 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// loaderctl shows and changes the settings that systemd-boot, and other
+// loaders implementing the Boot Loader Interface, read from EFI variables.
+//
+//	loaderctl
+//	loaderctl -list
+//	loaderctl -set-oneshot arch-fallback.conf
+//	loaderctl -set-timeout menu-force
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/internal/completion"
+	"github.com/lukegb/goefivar/internal/output"
+	"github.com/lukegb/goefivar/loader"
+)
+
+var (
+	list = flag.Bool("list", false, "List the boot entries the loader offered")
+
+	setDefault        = flag.String("set-default", "", "Make this entry the default; an empty value returns to the loader's configuration")
+	setOneShot        = flag.String("set-oneshot", "", "Boot this entry on the next boot only; an empty value cancels a previous request")
+	setTimeout        = flag.String("set-timeout", "", "Set the menu timeout: seconds, menu-force, menu-hidden or menu-disabled; an empty value returns to the loader's configuration")
+	setTimeoutOneShot = flag.String("set-timeout-oneshot", "", "Set the menu timeout for the next boot only")
+)
+
+// status is the JSON and YAML form of the loader settings.
+type status struct {
+	Loader         string   `json:"loader,omitempty"`
+	Firmware       string   `json:"firmware,omitempty"`
+	FirmwareType   string   `json:"firmware_type,omitempty"`
+	Selected       string   `json:"selected,omitempty"`
+	Default        string   `json:"default,omitempty"`
+	OneShot        string   `json:"oneshot,omitempty"`
+	Timeout        string   `json:"timeout,omitempty"`
+	TimeoutOneShot string   `json:"timeout_oneshot,omitempty"`
+	Entries        []string `json:"entries"`
+}
+
+// optional returns the result of a read, treating a missing variable as empty.
+func optional(s string, err error) (string, error) {
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	return s, err
+}
+
+func optionalTimeout(t loader.Timeout, err error) (string, error) {
+	if err != nil {
+		return optional("", err)
+	}
+	return t.String(), nil
+}
+
+func readStatus() (*status, error) {
+	s := new(status)
+	for _, f := range []struct {
+		dst  *string
+		read func() (string, error)
+	}{
+		{&s.Loader, loader.Info},
+		{&s.Firmware, loader.FirmwareInfo},
+		{&s.FirmwareType, loader.FirmwareType},
+		{&s.Selected, loader.EntrySelected},
+		{&s.Default, loader.EntryDefault},
+		{&s.OneShot, loader.EntryOneShot},
+		{&s.Timeout, func() (string, error) { return optionalTimeout(loader.ConfigTimeout()) }},
+		{&s.TimeoutOneShot, func() (string, error) { return optionalTimeout(loader.ConfigTimeoutOneShot()) }},
+	} {
+		v, err := optional(f.read())
+		if err != nil {
+			return nil, err
+		}
+		*f.dst = v
+	}
+	entries, err := loader.Entries()
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	s.Entries = entries
+	if s.Entries == nil {
+		s.Entries = []string{}
+	}
+	return s, nil
+}
+
+func orUnset(s string) string {
+	if s == "" {
+		return "(not set)"
+	}
+	return s
+}
+
+func printStatus(w io.Writer, s *status) error {
+	if s.Loader == "" {
+		fmt.Fprintln(w, "No loader implementing the Boot Loader Interface was used to boot.")
+	}
+	fmt.Fprintf(w, "Loader:           %s\n", orUnset(s.Loader))
+	fmt.Fprintf(w, "Firmware:         %s\n", orUnset(s.Firmware))
+	fmt.Fprintf(w, "Firmware type:    %s\n", orUnset(s.FirmwareType))
+	fmt.Fprintf(w, "Selected entry:   %s\n", orUnset(s.Selected))
+	fmt.Fprintf(w, "Default entry:    %s\n", orUnset(s.Default))
+	fmt.Fprintf(w, "One-shot entry:   %s\n", orUnset(s.OneShot))
+	fmt.Fprintf(w, "Timeout:          %s\n", orUnset(s.Timeout))
+	fmt.Fprintf(w, "One-shot timeout: %s\n", orUnset(s.TimeoutOneShot))
+	return nil
+}
+
+func printEntries(w io.Writer, s *status) error {
+	for _, e := range s.Entries {
+		var marks []string
+		for _, m := range []struct {
+			id, mark string
+		}{
+			{s.Selected, "selected"},
+			{s.Default, "default"},
+			{s.OneShot, "oneshot"},
+		} {
+			if m.id == e {
+				marks = append(marks, m.mark)
+			}
+		}
+		if len(marks) > 0 {
+			fmt.Fprintf(w, "%s %v\n", e, marks)
+		} else {
+			fmt.Fprintln(w, e)
+		}
+	}
+	return nil
+}
+
+// isSet reports whether the flag name was given on the command line, so
+// that an empty value can be told apart from its absence.
+func isSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// checkEntry warns if id is not one of the entries the loader offered.
+func checkEntry(s *status, id string) {
+	if id == "" || len(s.Entries) == 0 {
+		return
+	}
+	for _, e := range s.Entries {
+		if e == id {
+			return
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Warning: %q is not one of the entries the loader offered on this boot\n", id)
+}
+
+// optionalDelete ignores the error from clearing a setting which was not set.
+func optionalDelete(err error) error {
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func setTimeoutVariable(value string, set func(loader.Timeout) error, clear func() error) error {
+	if value == "" {
+		return optionalDelete(clear())
+	}
+	t, err := loader.ParseTimeout(value)
+	if err != nil {
+		return err
+	}
+	return set(t)
+}
+
+func main() {
+	completion.Register(completion.None)
+	for _, name := range []string{"set-default", "set-oneshot", "set-timeout", "set-timeout-oneshot"} {
+		completion.Flag(name, completion.Text)
+	}
+	flag.Parse()
+	completion.Run()
+
+	if !efivar.Supported() {
+		fmt.Fprintf(os.Stderr, "EFI variables are not supported on this system.\n")
+		os.Exit(1)
+	}
+
+	s, err := readStatus()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	changed := false
+	for _, c := range []struct {
+		flag string
+		set  func() error
+	}{
+		{"set-default", func() error {
+			checkEntry(s, *setDefault)
+			return optionalDelete(loader.SetEntryDefault(*setDefault))
+		}},
+		{"set-oneshot", func() error {
+			checkEntry(s, *setOneShot)
+			return optionalDelete(loader.SetEntryOneShot(*setOneShot))
+		}},
+		{"set-timeout", func() error {
+			return setTimeoutVariable(*setTimeout, loader.SetConfigTimeout, loader.ClearConfigTimeout)
+		}},
+		{"set-timeout-oneshot", func() error {
+			return setTimeoutVariable(*setTimeoutOneShot, loader.SetConfigTimeoutOneShot, loader.ClearConfigTimeoutOneShot)
+		}},
+	} {
+		if !isSet(c.flag) {
+			continue
+		}
+		if err := c.set(); err != nil {
+			log.Fatalf("-%s: %v", c.flag, err)
+		}
+		changed = true
+	}
+	if changed {
+		return
+	}
+
+	text := printStatus
+	var v interface{} = s
+	if *list {
+		text, v = printEntries, s.Entries
+	}
+	if err := output.Write(os.Stdout, v, func(w io.Writer) error { return text(w, s) }); err != nil {
+		log.Fatal(err)
+	}
+}
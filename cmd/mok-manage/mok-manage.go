@@ -0,0 +1,292 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// mok-manage manages shim's Machine Owner Keys, in the manner of mokutil. It
+// lists the enrolled keys and the pending requests, and stages requests to
+// enroll or delete certificates. Requests take effect once they are confirmed
+// in MokManager, with the password given here, on the next boot.
+//
+//	mok-manage -list-enrolled
+//	mok-manage -import signing.der
+//	mok-manage -list-new
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/lukegb/goefivar/efisig"
+	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/internal/completion"
+	"github.com/lukegb/goefivar/internal/output"
+)
+
+var (
+	listEnrolled = flag.Bool("list-enrolled", false, "List the enrolled keys")
+	listNew      = flag.Bool("list-new", false, "List the keys staged for enrollment")
+	listDelete   = flag.Bool("list-delete", false, "List the keys staged for deletion")
+	importKeys   = flag.Bool("import", false, "Stage the certificates named on the command line for enrollment")
+	deleteKeys   = flag.Bool("delete", false, "Stage the certificates named on the command line for deletion")
+	revokeImport = flag.Bool("revoke-import", false, "Cancel the pending enrollment request")
+	revokeDelete = flag.Bool("revoke-delete", false, "Cancel the pending deletion request")
+
+	passwordFile = flag.String("password-file", "", "Read the request password from the first line of this file, rather than prompting for it")
+)
+
+// key is the JSON and YAML form of a MOK.
+type key struct {
+	SHA1     string    `json:"sha1"`
+	Subject  string    `json:"subject"`
+	Issuer   string    `json:"issuer"`
+	NotAfter time.Time `json:"not_after"`
+}
+
+func toKeys(certs []*x509.Certificate) []key {
+	out := make([]key, len(certs))
+	for n, cert := range certs {
+		sum := sha1.Sum(cert.Raw)
+		out[n] = key{
+			SHA1:     hex.EncodeToString(sum[:]),
+			Subject:  cert.Subject.String(),
+			Issuer:   cert.Issuer.String(),
+			NotAfter: cert.NotAfter,
+		}
+	}
+	return out
+}
+
+func printKeys(w io.Writer, keys []key) error {
+	if len(keys) == 0 {
+		fmt.Fprintln(w, "No keys")
+	}
+	for n, k := range keys {
+		fmt.Fprintf(w, "[key %d]\n", n+1)
+		fmt.Fprintf(w, "  SHA1:    %s\n", k.SHA1)
+		fmt.Fprintf(w, "  Subject: %s\n", k.Subject)
+		fmt.Fprintf(w, "  Issuer:  %s\n", k.Issuer)
+		fmt.Fprintf(w, "  Expires: %s\n", k.NotAfter.Format("2006-01-02"))
+	}
+	return nil
+}
+
+func list(read func() (efisig.Database, error)) error {
+	db, err := read()
+	if os.IsNotExist(err) {
+		db, err = nil, nil
+	}
+	if err != nil {
+		return err
+	}
+	certs, err := db.Certificates()
+	if err != nil {
+		return err
+	}
+	keys := toKeys(certs)
+	return output.Write(os.Stdout, keys, func(w io.Writer) error { return printKeys(w, keys) })
+}
+
+// parseCertificates parses the certificates in data, which may be DER or PEM.
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	rest := data
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if certs != nil {
+		return certs, nil
+	}
+	cert, err := x509.ParseCertificate(data)
+	if err != nil {
+		return nil, err
+	}
+	return []*x509.Certificate{cert}, nil
+}
+
+func readCertificates(paths []string) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for _, p := range paths {
+		data, err := ioutil.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		cs, err := parseCertificates(data)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", p, err)
+		}
+		certs = append(certs, cs...)
+	}
+	return certs, nil
+}
+
+// stty runs stty on the terminal connected to standard input.
+func stty(args ...string) error {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	return cmd.Run()
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// readPassword returns the password protecting a new request. On a terminal
+// it is prompted for twice, without echoing it.
+func readPassword() (string, error) {
+	if *passwordFile != "" {
+		f, err := os.Open(*passwordFile)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		return readLine(bufio.NewReader(f))
+	}
+
+	stdin := bufio.NewReader(os.Stdin)
+	if fi, err := os.Stdin.Stat(); err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return readLine(stdin)
+	}
+	if err := stty("-echo"); err != nil {
+		return "", fmt.Errorf("disabling terminal echo: %v", err)
+	}
+	defer stty("echo")
+
+	var pws [2]string
+	for n, prompt := range []string{"Password: ", "Repeat password: "} {
+		fmt.Fprint(os.Stderr, prompt)
+		pw, err := readLine(stdin)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", err
+		}
+		pws[n] = pw
+	}
+	if pws[0] != pws[1] {
+		return "", fmt.Errorf("passwords do not match")
+	}
+	return pws[0], nil
+}
+
+// stage stages a request for the certificates named on the command line.
+// Certificates which are already in the desired state are skipped.
+func stage(req efisig.MOKRequest, wantEnrolled bool) error {
+	if flag.NArg() == 0 {
+		return fmt.Errorf("no certificates given")
+	}
+	certs, err := readCertificates(flag.Args())
+	if err != nil {
+		return err
+	}
+	enrolled, err := efisig.MOKList()
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("reading the enrolled keys: %v", err)
+	}
+
+	var todo []*x509.Certificate
+	for _, cert := range certs {
+		if enrolled.Contains(efisig.CertX509UUID, cert.Raw) == wantEnrolled {
+			state := "not enrolled"
+			if wantEnrolled {
+				state = "already enrolled"
+			}
+			fmt.Fprintf(os.Stderr, "Skipping %v: %s\n", cert.Subject, state)
+			continue
+		}
+		todo = append(todo, cert)
+	}
+	if len(todo) == 0 {
+		return nil
+	}
+
+	pw, err := readPassword()
+	if err != nil {
+		return fmt.Errorf("reading password: %v", err)
+	}
+	if err := req.Stage(todo, pw); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Staged %d key(s). Reboot and confirm the request in MokManager with the same password.\n", len(todo))
+	return nil
+}
+
+func main() {
+	completion.Register(completion.Files)
+	completion.Flag("password-file", completion.Files)
+	flag.Parse()
+	completion.Run()
+
+	actions := 0
+	for _, b := range []bool{*listEnrolled, *listNew, *listDelete, *importKeys, *deleteKeys, *revokeImport, *revokeDelete} {
+		if b {
+			actions++
+		}
+	}
+	if actions != 1 {
+		fmt.Fprintf(os.Stderr, "usage: %s -list-enrolled | -list-new | -list-delete | -import CERT... | -delete CERT... | -revoke-import | -revoke-delete\n", os.Args[0])
+		os.Exit(1)
+	}
+
+	if !efivar.Supported() {
+		fmt.Fprintf(os.Stderr, "EFI variables are not supported on this system.\n")
+		os.Exit(1)
+	}
+
+	var err error
+	switch {
+	case *listEnrolled:
+		err = list(efisig.MOKList)
+	case *listNew:
+		err = list(efisig.MOKImportRequest.Pending)
+	case *listDelete:
+		err = list(efisig.MOKDeleteRequest.Pending)
+	case *importKeys:
+		err = stage(efisig.MOKImportRequest, true)
+	case *deleteKeys:
+		err = stage(efisig.MOKDeleteRequest, false)
+	case *revokeImport:
+		err = efisig.MOKImportRequest.Cancel()
+	case *revokeDelete:
+		err = efisig.MOKDeleteRequest.Cancel()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
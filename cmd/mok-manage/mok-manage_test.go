@@ -0,0 +1,56 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/lukegb/goefivar/efisig"
+)
+
+func TestParseCertificates(t *testing.T) {
+	h, err := efisig.GenerateKeyHierarchy(&efisig.KeyOptions{Algorithm: efisig.ECDSAP256, Organization: "Test"})
+	if err != nil {
+		t.Fatalf("GenerateKeyHierarchy: %v", err)
+	}
+	pemData := append(h.PK.CertificatePEM(), h.DB.CertificatePEM()...)
+
+	for _, tc := range []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"DER", h.PK.Certificate.Raw, 1},
+		{"PEM", pemData, 2},
+	} {
+		certs, err := parseCertificates(tc.data)
+		if err != nil {
+			t.Errorf("%v: parseCertificates: %v", tc.name, err)
+			continue
+		}
+		if len(certs) != tc.want {
+			t.Errorf("%v: parseCertificates returned %d certificates; want %d", tc.name, len(certs), tc.want)
+			continue
+		}
+		if !bytes.Equal(certs[0].Raw, h.PK.Certificate.Raw) {
+			t.Errorf("%v: parseCertificates returned the wrong certificate", tc.name)
+		}
+	}
+
+	if _, err := parseCertificates([]byte("not a certificate")); err == nil {
+		t.Errorf("parseCertificates(garbage) returned no error")
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// sbkeys takes ownership of Secure Boot with your own keys, in the manner of
+// sbctl. It creates a PK, KEK and db, shows whether they are enrolled, and
+// enrolls them while the firmware is in Setup Mode.
+//
+//	sbkeys -create
+//	sbkeys -enroll -keep-existing
+//	sbkeys
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/lukegb/goefivar/efisig"
+	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/internal/completion"
+	"github.com/lukegb/goefivar/internal/output"
+)
+
+var (
+	dir = flag.String("dir", "/var/lib/sbkeys", "Directory holding the keys")
+
+	create       = flag.Bool("create", false, "Create a new key hierarchy in -dir")
+	organization = flag.String("organization", "sbkeys", "Organization named in the certificates created by -create")
+	algorithm    = flag.String("algorithm", "rsa2048", "Key algorithm used by -create: rsa2048, rsa4096, ecdsa-p256 or ecdsa-p384. Much firmware only accepts rsa2048")
+
+	enroll       = flag.Bool("enroll", false, "Enroll the keys in -dir; the firmware must be in Setup Mode")
+	keepExisting = flag.Bool("keep-existing", false, "With -enroll, keep the certificates already in KEK and db, such as Microsoft's")
+	force        = flag.Bool("force", false, "With -enroll, enroll a db which lacks a CA for option ROMs. This can leave a machine unable to display anything, or to boot at all")
+)
+
+var algorithms = map[string]efisig.KeyAlgorithm{
+	"rsa2048":    efisig.RSA2048,
+	"rsa4096":    efisig.RSA4096,
+	"ecdsa-p256": efisig.ECDSAP256,
+	"ecdsa-p384": efisig.ECDSAP384,
+}
+
+// status is the JSON and YAML form of the enrollment state.
+type status struct {
+	SecureBoot  bool   `json:"secure_boot"`
+	SetupMode   bool   `json:"setup_mode"`
+	PKSubject   string `json:"pk_subject,omitempty"`
+	KeysDir     string `json:"keys_dir"`
+	KeysCreated bool   `json:"keys_created"`
+	Owner       string `json:"owner,omitempty"`
+	Enrolled    bool   `json:"enrolled"`
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func printStatus(w io.Writer, s *status) error {
+	fmt.Fprintf(w, "Secure Boot:  %s\n", yesNo(s.SecureBoot))
+	fmt.Fprintf(w, "Setup Mode:   %s\n", yesNo(s.SetupMode))
+	pk := s.PKSubject
+	if pk == "" {
+		pk = "(none)"
+	}
+	fmt.Fprintf(w, "Platform Key: %s\n", pk)
+	if !s.KeysCreated {
+		fmt.Fprintf(w, "Keys:         none in %s; create them with -create\n", s.KeysDir)
+		return nil
+	}
+	fmt.Fprintf(w, "Keys:         %s (owner %s)\n", s.KeysDir, s.Owner)
+	fmt.Fprintf(w, "Enrolled:     %s\n", yesNo(s.Enrolled))
+	return nil
+}
+
+// enrolled reports whether h's PK is the enrolled Platform Key.
+func enrolled(h *efisig.KeyHierarchy) (bool, error) {
+	pk, err := efisig.ReadDatabase(efisig.PKName)
+	switch {
+	case os.IsNotExist(err):
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+	return pk.Contains(efisig.CertX509UUID, h.PK.Certificate.Raw), nil
+}
+
+func showStatus() error {
+	cur, err := efisig.CurrentStatus()
+	if err != nil {
+		return err
+	}
+	s := &status{
+		SecureBoot: cur.SecureBoot,
+		SetupMode:  cur.SetupMode,
+		PKSubject:  cur.PKSubject,
+		KeysDir:    *dir,
+	}
+	h, err := efisig.LoadKeyHierarchy(*dir)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return err
+	default:
+		defer h.Destroy()
+		s.KeysCreated = true
+		s.Owner = h.PK.Owner.String()
+		if s.Enrolled, err = enrolled(h); err != nil {
+			return err
+		}
+	}
+	return output.Write(os.Stdout, s, func(w io.Writer) error { return printStatus(w, s) })
+}
+
+func createKeys() error {
+	alg, ok := algorithms[*algorithm]
+	if !ok {
+		return fmt.Errorf("unknown -algorithm %q", *algorithm)
+	}
+	if h, err := efisig.LoadKeyHierarchy(*dir); err == nil {
+		h.Destroy()
+		return fmt.Errorf("%v already holds keys; not overwriting them", *dir)
+	}
+	if err := os.MkdirAll(*dir, 0700); err != nil {
+		return err
+	}
+	h, err := efisig.GenerateKeyHierarchy(&efisig.KeyOptions{Algorithm: alg, Organization: *organization})
+	if err != nil {
+		return err
+	}
+	defer h.Destroy()
+	if err := h.WriteFiles(*dir); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Created keys in %v with owner %v\n", *dir, h.PK.Owner)
+	return nil
+}
+
+// readExisting returns the content of vn, or nil if it does not exist.
+func readExisting(vn efivar.VariableName) (efisig.Database, error) {
+	db, err := efisig.ReadDatabase(vn)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return db, err
+}
+
+func enrollKeys() error {
+	h, err := efisig.LoadKeyHierarchy(*dir)
+	if err != nil {
+		return fmt.Errorf("loading keys: %v", err)
+	}
+	defer h.Destroy()
+	opts := &efisig.EnrollOptions{}
+	if *keepExisting {
+		if opts.ExtraKEK, err = readExisting(efisig.KEKName); err != nil {
+			return fmt.Errorf("reading KEK: %v", err)
+		}
+		if opts.ExtraDB, err = readExisting(efisig.DBName); err != nil {
+			return fmt.Errorf("reading db: %v", err)
+		}
+	}
+
+	cas, err := efisig.CheckMicrosoftCAs(opts.ExtraDB)
+	if err != nil {
+		return err
+	}
+	if !cas.OptionROM && !*force {
+		msg := []string{
+			"The new db would not contain a CA for option ROMs, so firmware for graphics",
+			"cards, network cards and storage controllers may not load, and the machine",
+			"may be left unable to boot. Pass -keep-existing to keep the certificates",
+			"already in db, or -force if you are sure this machine needs no option ROMs.",
+		}
+		return fmt.Errorf("refusing to enroll:\n%s", strings.Join(msg, "\n"))
+	}
+
+	if err := efisig.EnrollAllKeys(h, opts); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Enrolled the keys in %v. Enable Secure Boot in the firmware setup.\n", *dir)
+	return nil
+}
+
+func main() {
+	completion.Register(completion.None)
+	completion.Flag("dir", completion.Files)
+	completion.Flag("organization", completion.Text)
+	completion.Flag("algorithm", completion.Text)
+	flag.Parse()
+	completion.Run()
+
+	if *create && *enroll {
+		log.Fatalf("-create and -enroll are mutually exclusive")
+	}
+	if *create {
+		if err := createKeys(); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if !efivar.Supported() {
+		fmt.Fprintf(os.Stderr, "EFI variables are not supported on this system.\n")
+		os.Exit(1)
+	}
+
+	var err error
+	if *enroll {
+		err = enrollKeys()
+	} else {
+		err = showStatus()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// secureboot-status prints a summary of the Secure Boot configuration: its
+// state, the enrolled keys, shim's MOK state and the SBAT revocation level.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/lukegb/goefivar/efisig"
+	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/internal/completion"
+	"github.com/lukegb/goefivar/internal/output"
+)
+
+var jsonOutput = flag.Bool("json", false, "Same as -format json")
+
+type database struct {
+	Entries int      `json:"entries"`
+	Issuers []string `json:"issuers"`
+}
+
+type sbatGeneration struct {
+	Component  string `json:"component"`
+	Generation int    `json:"generation"`
+}
+
+type sbatLevel struct {
+	Version     int              `json:"version"`
+	Datestamp   string           `json:"datestamp"`
+	Generations []sbatGeneration `json:"generations"`
+}
+
+// status is the JSON form of efisig.Status.
+type status struct {
+	SecureBoot    bool       `json:"secure_boot"`
+	SetupMode     bool       `json:"setup_mode"`
+	PKSubject     string     `json:"pk_subject,omitempty"`
+	KEK           database   `json:"kek"`
+	DB            database   `json:"db"`
+	DBX           database   `json:"dbx"`
+	MOKValidation bool       `json:"mok_validation"`
+	MOKEntries    int        `json:"mok_entries"`
+	SBATLevel     *sbatLevel `json:"sbat_level,omitempty"`
+}
+
+func toDatabase(ds efisig.DatabaseStatus) database {
+	d := database{Entries: ds.Entries, Issuers: ds.Issuers}
+	if d.Issuers == nil {
+		d.Issuers = []string{}
+	}
+	return d
+}
+
+func toStatus(s *efisig.Status) *status {
+	out := &status{
+		SecureBoot:    s.SecureBoot,
+		SetupMode:     s.SetupMode,
+		PKSubject:     s.PKSubject,
+		KEK:           toDatabase(s.KEK),
+		DB:            toDatabase(s.DB),
+		DBX:           toDatabase(s.DBX),
+		MOKValidation: s.MOKValidation,
+		MOKEntries:    s.MOKEntries,
+	}
+	if l := s.SBATLevel; l != nil {
+		out.SBATLevel = &sbatLevel{Version: l.Version, Datestamp: l.Datestamp, Generations: []sbatGeneration{}}
+		for _, g := range l.Generations {
+			out.SBATLevel.Generations = append(out.SBATLevel.Generations, sbatGeneration{g.Component, g.Generation})
+		}
+	}
+	return out
+}
+
+func enabled(b bool) string {
+	if b {
+		return "enabled"
+	}
+	return "disabled"
+}
+
+func printDatabase(w io.Writer, name string, ds efisig.DatabaseStatus) {
+	fmt.Fprintf(w, "%-16s%d entries\n", name+":", ds.Entries)
+	for _, issuer := range ds.Issuers {
+		fmt.Fprintf(w, "%16s%s\n", "", issuer)
+	}
+}
+
+func printStatus(w io.Writer, s *efisig.Status) {
+	fmt.Fprintf(w, "%-16s%s\n", "SecureBoot:", enabled(s.SecureBoot))
+	fmt.Fprintf(w, "%-16s%s\n", "SetupMode:", enabled(s.SetupMode))
+	pk := s.PKSubject
+	if pk == "" {
+		pk = "(none enrolled)"
+	}
+	fmt.Fprintf(w, "%-16s%s\n", "PK:", pk)
+	printDatabase(w, "KEK", s.KEK)
+	printDatabase(w, "db", s.DB)
+	printDatabase(w, "dbx", s.DBX)
+	fmt.Fprintf(w, "%-16s%s\n", "MOK validation:", enabled(s.MOKValidation))
+	fmt.Fprintf(w, "%-16s%d entries\n", "MokList:", s.MOKEntries)
+	if l := s.SBATLevel; l != nil {
+		fmt.Fprintf(w, "%-16ssbat,%d,%s\n", "SBAT level:", l.Version, l.Datestamp)
+		for _, g := range l.Generations {
+			fmt.Fprintf(w, "%16s%s,%d\n", "", g.Component, g.Generation)
+		}
+	} else {
+		fmt.Fprintf(w, "%-16s%s\n", "SBAT level:", "(not published)")
+	}
+}
+
+func main() {
+	efivar.SetReadOnly()
+	completion.Register(completion.None)
+	flag.Parse()
+	completion.Run()
+
+	if !efivar.Supported() {
+		fmt.Fprintf(os.Stderr, "EFI variables are not supported on this system.\n")
+		os.Exit(1)
+	}
+
+	s, err := efisig.CurrentStatus()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *jsonOutput {
+		output.Set(output.JSON)
+	}
+	err = output.Write(os.Stdout, toStatus(s), func(w io.Writer) error {
+		printStatus(w, s)
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efiboot
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lukegb/goefivar/internal/mounts"
+)
+
+var gptPartitionNode = regexp.MustCompile(`HD\([0-9]+,GPT,([0-9a-fA-F-]{36})`)
+
+// Duplicates groups the boot options which load the same file with the same
+// optional data. In each group the option to keep comes first: the one
+// earliest in order, or the lowest numbered if none of them is in order.
+func Duplicates(bos []*BootOption, order []uint16) [][]*BootOption {
+	rank := func(bo *BootOption) int {
+		num, err := BootOptionNumber(bo.Variable.VariableName)
+		if err != nil {
+			return 1 << 30
+		}
+		for i, n := range order {
+			if n == num {
+				return i
+			}
+		}
+		return len(order) + int(num)
+	}
+
+	groups := make(map[string][]*BootOption)
+	var keys []string
+	for _, bo := range bos {
+		var key bytes.Buffer
+		key.WriteString(bo.LoadOpt.FilePath)
+		key.WriteByte(0)
+		key.Write(bo.LoadOpt.OptionalData)
+		k := key.String()
+		if _, ok := groups[k]; !ok {
+			keys = append(keys, k)
+		}
+		groups[k] = append(groups[k], bo)
+	}
+
+	var out [][]*BootOption
+	for _, k := range keys {
+		g := groups[k]
+		if len(g) < 2 {
+			continue
+		}
+		sort.SliceStable(g, func(i, j int) bool { return rank(g[i]) < rank(g[j]) })
+		out = append(out, g)
+	}
+	return out
+}
+
+// DanglingOption is a boot option which can no longer work.
+type DanglingOption struct {
+	Option *BootOption
+	Reason string
+}
+
+// partitionMounts maps the devices listed in mounts.File to their
+// mountpoints.
+func partitionMounts() (map[string]string, error) {
+	ms, err := mounts.Read()
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]string)
+	for _, m := range ms {
+		if !strings.HasPrefix(m.Source, "/") {
+			continue
+		}
+		dev, err := filepath.EvalSymlinks(m.Source)
+		if err != nil {
+			dev = m.Source
+		}
+		if _, ok := out[dev]; !ok {
+			out[dev] = m.Dir
+		}
+	}
+	return out, nil
+}
+
+// DanglingOptions changes what Dangling reports.
+type DanglingOptions struct {
+	// MissingPartitions also reports options on GPT partitions which
+	// aren't present. The partition may have been deleted, but it may
+	// equally be on a disk which is unplugged, so by default such
+	// options are left alone.
+	MissingPartitions bool
+}
+
+// Dangling returns the boot options which load a file from a mounted
+// partition which no longer holds the file, and, if opts asks, those which
+// load from a GPT partition which isn't present. Options on partitions which
+// exist but aren't mounted can't be checked, and aren't reported. opts may
+// be nil.
+//
+// Partitions are found through udev's /dev/disk/by-partuuid. Without it,
+// as in containers and on systems without udev, Dangling returns an error
+// rather than take every partition to be missing.
+func Dangling(bos []*BootOption, opts *DanglingOptions) ([]*DanglingOption, error) {
+	if opts == nil {
+		opts = &DanglingOptions{}
+	}
+	mounted, err := partitionMounts()
+	if err != nil {
+		return nil, err
+	}
+
+	var out []*DanglingOption
+	checked := false
+	for _, bo := range bos {
+		m := gptPartitionNode.FindStringSubmatch(bo.LoadOpt.FilePath)
+		if m == nil {
+			continue
+		}
+		part, err := uuid.Parse(m[1])
+		if err != nil {
+			continue
+		}
+		if !checked {
+			if _, err := os.Stat(mounts.PartUUIDDir); err != nil {
+				return nil, fmt.Errorf("efiboot: can't find partitions: %v", err)
+			}
+			checked = true
+		}
+		dev, err := mounts.Partition(part)
+		if os.IsNotExist(err) {
+			if opts.MissingPartitions {
+				out = append(out, &DanglingOption{bo, fmt.Sprintf("partition %v does not exist", part)})
+			}
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		mnt, ok := mounted[dev]
+		pathName := bo.LoadOpt.PathName()
+		if !ok || pathName == "" {
+			continue
+		}
+		file := filepath.Join(mnt, filepath.FromSlash(strings.Replace(pathName, `\`, "/", -1)))
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			out = append(out, &DanglingOption{bo, fmt.Sprintf("%v does not exist", file)})
+		}
+	}
+	return out, nil
+}
+
+// RemoveBootOptions deletes the given boot options, removing them from
+// BootOrder and cancelling BootNext if it selects one of them.
+func RemoveBootOptions(nums []uint16) error {
+	remove := make(map[uint16]bool)
+	for _, n := range nums {
+		remove[n] = true
+	}
+
+	order, err := BootOrder()
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("efiboot: reading BootOrder: %v", err)
+	}
+	var newOrder []uint16
+	changed := false
+	for _, vn := range order {
+		n, err := BootOptionNumber(vn)
+		if err != nil {
+			return err
+		}
+		if remove[n] {
+			changed = true
+			continue
+		}
+		newOrder = append(newOrder, n)
+	}
+	if changed {
+		if err := SetBootOrder(newOrder); err != nil {
+			return fmt.Errorf("efiboot: writing BootOrder: %v", err)
+		}
+	}
+
+	if next, err := BootNext(); err == nil {
+		if n, err := BootOptionNumber(next); err == nil && remove[n] {
+			if err := ClearBootNext(); err != nil {
+				return fmt.Errorf("efiboot: deleting BootNext: %v", err)
+			}
+		}
+	}
+
+	for _, n := range nums {
+		if err := BootOptionName(n).Delete(); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("efiboot: deleting Boot%04X: %v", n, err)
+		}
+	}
+	return nil
+}
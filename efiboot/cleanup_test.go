@@ -0,0 +1,144 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efiboot
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/internal/mounts"
+)
+
+func testBootOption(num uint16, filePath, data string) *BootOption {
+	return &BootOption{
+		Variable: &efivar.Variable{VariableName: BootOptionName(num)},
+		LoadOpt:  &LoadOpt{Description: fmt.Sprintf("Option %d", num), FilePath: filePath, OptionalData: OptionalData(data)},
+	}
+}
+
+func numbers(bos []*BootOption) []uint16 {
+	var out []uint16
+	for _, bo := range bos {
+		n, _ := BootOptionNumber(bo.Variable.VariableName)
+		out = append(out, n)
+	}
+	return out
+}
+
+func TestDuplicates(t *testing.T) {
+	const shim = `HD(1,GPT,b647c141-bfe9-274c-81c6-174026e79fd0,0x800,0x3a9800)/File(\EFI\fedora\shimx64.efi)`
+	bos := []*BootOption{
+		testBootOption(1, shim, ""),
+		testBootOption(2, shim, "x"),
+		testBootOption(3, shim, ""),
+		testBootOption(4, shim, ""),
+		testBootOption(5, `PciRoot(0x0)/Pci(0x1f,0x2)`, ""),
+	}
+	got := Duplicates(bos, []uint16{5, 3})
+	if len(got) != 1 {
+		t.Fatalf("Duplicates returned %d groups; want 1", len(got))
+	}
+	if got, want := fmt.Sprint(numbers(got[0])), "[3 1 4]"; got != want {
+		t.Errorf("Duplicates group = %v; want %v", got, want)
+	}
+}
+
+func TestDangling(t *testing.T) {
+	dir, err := ioutil.TempDir("", "efiboot")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	const (
+		mounted   = "b647c141-bfe9-274c-81c6-174026e79fd0"
+		unmounted = "0d7b6c6a-3f44-4c5b-9a3e-56d7e3b4b7a1"
+		missing   = "9c1ac16f-b2b1-4d0e-8f10-1b1c6a2d2f6e"
+	)
+	byPartUUID := filepath.Join(dir, "by-partuuid")
+	esp := filepath.Join(dir, "esp")
+	for _, d := range []string{byPartUUID, filepath.Join(esp, "EFI", "fedora")} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatalf("MkdirAll: %v", err)
+		}
+	}
+	for _, dev := range []string{"sda1", "sdb1"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, dev), nil, 0644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(esp, "EFI", "fedora", "shimx64.efi"), nil, 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "sda1"), filepath.Join(byPartUUID, mounted)); err != nil {
+		t.Fatalf("Symlink: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "sdb1"), filepath.Join(byPartUUID, unmounted)); err != nil {
+		t.Fatalf("Symlink: %v", err)
+	}
+	mountsFile := filepath.Join(dir, "mounts")
+	if err := ioutil.WriteFile(mountsFile, []byte(fmt.Sprintf("%s %s vfat rw 0 0\n", filepath.Join(dir, "sda1"), esp)), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	defer func(old string) { mounts.File = old }(mounts.File)
+	mounts.File = mountsFile
+	defer func(old string) { mounts.PartUUIDDir = old }(mounts.PartUUIDDir)
+	mounts.PartUUIDDir = byPartUUID
+
+	hd := func(part, file string) string {
+		return fmt.Sprintf(`HD(1,GPT,%s,0x800,0x100000)/File(%s)`, part, file)
+	}
+	bos := []*BootOption{
+		testBootOption(1, hd(mounted, `\EFI\fedora\shimx64.efi`), ""),
+		testBootOption(2, hd(mounted, `\EFI\ubuntu\shimx64.efi`), ""),
+		testBootOption(3, hd(unmounted, `\EFI\ubuntu\shimx64.efi`), ""),
+		testBootOption(4, hd(missing, `\EFI\BOOT\BOOTX64.EFI`), ""),
+		testBootOption(5, `PciRoot(0x0)/Pci(0x1f,0x2)`, ""),
+	}
+	for _, tc := range []struct {
+		opts *DanglingOptions
+		want string
+	}{
+		{nil, "[2]"},
+		{&DanglingOptions{MissingPartitions: true}, "[2 4]"},
+	} {
+		got, err := Dangling(bos, tc.opts)
+		if err != nil {
+			t.Fatalf("Dangling: %v", err)
+		}
+		var gotNums []*BootOption
+		for _, d := range got {
+			gotNums = append(gotNums, d.Option)
+		}
+		if got := fmt.Sprint(numbers(gotNums)); got != tc.want {
+			t.Errorf("Dangling(%+v) = %v; want %v", tc.opts, got, tc.want)
+		}
+	}
+
+	// Without by-partuuid, no partition can be found, which mustn't make
+	// every option dangling.
+	mounts.PartUUIDDir = filepath.Join(dir, "no-such-dir")
+	if got, err := Dangling(bos, &DanglingOptions{MissingPartitions: true}); err == nil {
+		t.Errorf("Dangling without %s = %v; want an error", mounts.PartUUIDDir, got)
+	}
+	if got, err := Dangling(bos[4:], nil); err != nil || len(got) != 0 {
+		t.Errorf("Dangling of a non-disk option without %s = %v, %v; want none", mounts.PartUUIDDir, got, err)
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efiboot
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/internal/corpus"
+)
+
+// TestCorpus checks libefiboot's decoding of the load option corpus against
+// the golden files written by the loadopt tests. It does not compare file
+// paths, whose text libefivar formats in its own way for some nodes.
+func TestCorpus(t *testing.T) {
+	los, err := corpus.LoadOptions()
+	if err != nil {
+		t.Fatalf("corpus.LoadOptions: %v", err)
+	}
+	for _, c := range los {
+		lo, err := FromVariable(&efivar.Variable{
+			VariableName: efivar.VariableName{GUID: efivar.GlobalUUID, Name: "Boot0000"},
+			Data:         c.Data,
+		})
+		if err != nil {
+			t.Errorf("%v: FromVariable: %v", c.Name, err)
+			continue
+		}
+		want := func(key string) string {
+			v, _ := c.Field(key)
+			return v
+		}
+		if got := fmt.Sprintf("%#x", uint32(lo.Attributes)); got != want("attributes") {
+			t.Errorf("%v: Attributes = %v; want %v", c.Name, got, want("attributes"))
+		}
+		if lo.Description != want("description") {
+			t.Errorf("%v: Description = %q; want %q", c.Name, lo.Description, want("description"))
+		}
+		if got := hex.EncodeToString(lo.OptionalData); got != want("optional_data") {
+			t.Errorf("%v: OptionalData = %v; want %v", c.Name, got, want("optional_data"))
+		}
+		b, err := lo.Bytes()
+		if err != nil {
+			t.Errorf("%v: Bytes: %v", c.Name, err)
+			continue
+		}
+		if !bytes.Equal(b, c.Data) {
+			t.Errorf("%v: Bytes() = %x; want %x", c.Name, b, c.Data)
+		}
+	}
+}
+
+// TestCorpusPureGo checks that the pure-Go load option code decodes and
+// encodes the corpus exactly as the implementation in use does.
+func TestCorpusPureGo(t *testing.T) {
+	los, err := corpus.LoadOptions()
+	if err != nil {
+		t.Fatalf("corpus.LoadOptions: %v", err)
+	}
+	for _, c := range los {
+		want, err := decodeLoadOpt(c.Data)
+		if err != nil {
+			t.Errorf("%v: decodeLoadOpt: %v", c.Name, err)
+			continue
+		}
+		got, err := decodeLoadOptGo(c.Data)
+		if err != nil {
+			t.Errorf("%v: decodeLoadOptGo: %v", c.Name, err)
+			continue
+		}
+		if fmt.Sprintf("%+v", got) != fmt.Sprintf("%+v", want) {
+			t.Errorf("%v: decodeLoadOptGo = %+v; want %+v", c.Name, got, want)
+		}
+		b, err := encodeLoadOptGo(got, got.rawFilePath)
+		if err != nil || !bytes.Equal(b, c.Data) {
+			t.Errorf("%v: encodeLoadOptGo = %x, %v; want %x", c.Name, b, err, c.Data)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efiboot
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LoaderKind identifies the kind of a loader found on an ESP.
+type LoaderKind string
+
+const (
+	LoaderShim        LoaderKind = "shim"
+	LoaderGRUB        LoaderKind = "grub"
+	LoaderSystemdBoot LoaderKind = "systemd-boot"
+	LoaderWindows     LoaderKind = "windows"
+	LoaderUKI         LoaderKind = "uki"
+)
+
+// FoundLoader is a loader found on a mounted ESP, with a suggested
+// description for a boot option that starts it.
+type FoundLoader struct {
+	Kind  LoaderKind
+	Label string
+	// ESP is the mountpoint of the ESP.
+	ESP string
+	// File is the path of the loader in the filesystem.
+	File string
+	// PathName is the path of the loader within the ESP, e.g. `\EFI\fedora\shimx64.efi`.
+	PathName string
+}
+
+// vendorLabels are the descriptions distributions give their boot options,
+// by the name of their directory under \EFI.
+var vendorLabels = map[string]string{
+	"arch":     "Arch Linux",
+	"centos":   "CentOS",
+	"debian":   "Debian",
+	"fedora":   "Fedora",
+	"opensuse": "openSUSE",
+	"redhat":   "Red Hat Enterprise Linux",
+	"rocky":    "Rocky Linux",
+	"ubuntu":   "Ubuntu",
+}
+
+func vendorLabel(dir string) string {
+	if l, ok := vendorLabels[strings.ToLower(dir)]; ok {
+		return l
+	}
+	return strings.Title(dir)
+}
+
+// readDirFold returns the entries of dir, keyed by their lower-cased names,
+// since FAT filesystems are case-insensitive but case-preserving.
+func readDirFold(dir string) (map[string]os.FileInfo, error) {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]os.FileInfo)
+	for _, fi := range fis {
+		out[strings.ToLower(fi.Name())] = fi
+	}
+	return out, nil
+}
+
+func efiFiles(dir string, prefix string) []string {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var out []string
+	for _, fi := range fis {
+		name := strings.ToLower(fi.Name())
+		if fi.Mode().IsRegular() && strings.HasPrefix(name, prefix) && strings.HasSuffix(name, ".efi") {
+			out = append(out, fi.Name())
+		}
+	}
+	return out
+}
+
+// FindLoaders looks for the loaders installed on the ESPs mounted at esps:
+// shim (or GRUB, where there is no shim) in each vendor directory,
+// systemd-boot, the Windows Boot Manager, and unified kernel images in
+// \EFI\Linux. The removable-media fallback in \EFI\BOOT is ignored.
+func FindLoaders(esps []string) ([]*FoundLoader, error) {
+	var out []*FoundLoader
+	for _, esp := range esps {
+		top, err := readDirFold(esp)
+		if err != nil {
+			return nil, err
+		}
+		fi, ok := top["efi"]
+		if !ok || !fi.IsDir() {
+			continue
+		}
+		efiDir := filepath.Join(esp, fi.Name())
+
+		add := func(kind LoaderKind, label string, rel ...string) {
+			file := filepath.Join(append([]string{efiDir}, rel...)...)
+			out = append(out, &FoundLoader{
+				Kind:     kind,
+				Label:    label,
+				ESP:      esp,
+				File:     file,
+				PathName: `\` + fi.Name() + `\` + strings.Join(rel, `\`),
+			})
+		}
+
+		vendors, err := readDirFold(efiDir)
+		if err != nil {
+			return nil, err
+		}
+		var names []string
+		for name := range vendors {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			vfi := vendors[name]
+			if !vfi.IsDir() {
+				continue
+			}
+			dir := filepath.Join(efiDir, vfi.Name())
+			switch name {
+			case "boot":
+			case "microsoft":
+				boot, err := readDirFold(dir)
+				if err != nil {
+					return nil, err
+				}
+				if bfi, ok := boot["boot"]; ok && bfi.IsDir() {
+					for _, f := range efiFiles(filepath.Join(dir, bfi.Name()), "bootmgfw") {
+						add(LoaderWindows, "Windows Boot Manager", vfi.Name(), bfi.Name(), f)
+					}
+				}
+			case "systemd":
+				for _, f := range efiFiles(dir, "systemd-boot") {
+					add(LoaderSystemdBoot, "Linux Boot Manager", vfi.Name(), f)
+				}
+			case "linux":
+				for _, f := range efiFiles(dir, "") {
+					add(LoaderUKI, strings.TrimSuffix(f, filepath.Ext(f)), vfi.Name(), f)
+				}
+			default:
+				if shims := efiFiles(dir, "shim"); len(shims) > 0 {
+					add(LoaderShim, vendorLabel(vfi.Name()), vfi.Name(), shims[0])
+				} else if grubs := efiFiles(dir, "grub"); len(grubs) > 0 {
+					add(LoaderGRUB, vendorLabel(vfi.Name()), vfi.Name(), grubs[0])
+				}
+			}
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efiboot
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindLoaders(t *testing.T) {
+	dir, err := ioutil.TempDir("", "efiboot")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, f := range []string{
+		"EFI/BOOT/BOOTX64.EFI",
+		"EFI/fedora/shimx64.efi",
+		"EFI/fedora/grubx64.efi",
+		"EFI/debian/grubx64.efi",
+		"EFI/Microsoft/Boot/bootmgfw.efi",
+		"EFI/systemd/systemd-bootx64.efi",
+		"EFI/Linux/arch-linux.efi",
+		"EFI/Dell/logs.txt",
+	} {
+		p := filepath.Join(dir, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("MkdirAll: %v", err)
+		}
+		if err := ioutil.WriteFile(p, nil, 0644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+
+	got, err := FindLoaders([]string{dir})
+	if err != nil {
+		t.Fatalf("FindLoaders: %v", err)
+	}
+	var gotS []string
+	for _, l := range got {
+		gotS = append(gotS, fmt.Sprintf("%s %q %s", l.Kind, l.Label, l.PathName))
+	}
+	want := []string{
+		`grub "Debian" \EFI\debian\grubx64.efi`,
+		`shim "Fedora" \EFI\fedora\shimx64.efi`,
+		`uki "arch-linux" \EFI\Linux\arch-linux.efi`,
+		`windows "Windows Boot Manager" \EFI\Microsoft\Boot\bootmgfw.efi`,
+		`systemd-boot "Linux Boot Manager" \EFI\systemd\systemd-bootx64.efi`,
+	}
+	if fmt.Sprint(gotS) != fmt.Sprint(want) {
+		t.Errorf("FindLoaders =\n%v\nwant\n%v", strings.Join(gotS, "\n"), strings.Join(want, "\n"))
+	}
+	if got[0].File != filepath.Join(dir, "EFI", "debian", "grubx64.efi") {
+		t.Errorf("FindLoaders()[0].File = %v", got[0].File)
+	}
+}
@@ -0,0 +1,30 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package efiboot reads and writes boot options and the variables which
+// order them, using libefiboot.
+//
+// Where package efivar uses its pure-Go implementation, this package
+// encodes and decodes load options with package loadopt instead, and
+// FileDevicePath and ESPFileDevicePath are unsupported unless the program
+// was built with libefiboot.
+//
+// # Concurrency
+//
+// The functions in this package may be used from multiple goroutines, with
+// calls into libefiboot serialised as in package efivar. A LoadOpt is not
+// safe to change while another goroutine uses it. Changes to BootOrder and
+// the other shared variables are not atomic: two processes or goroutines
+// adding boot options at once can each overwrite the other's change.
+package efiboot
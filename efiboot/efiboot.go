@@ -14,30 +14,27 @@
 
 package efiboot
 
-// #cgo pkg-config: efiboot
-// #include <efiboot.h>
-// #include <stdlib.h>
-import "C"
-
 import (
-	"errors"
 	"fmt"
+	"regexp"
 	"sort"
 	"strconv"
-	"strings"
 	"unicode/utf16"
 	"unicode/utf8"
 	"unsafe"
 
 	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/loadopt"
 )
 
 var (
-	ErrVariableCorrupted = errors.New("efiboot: variable content is not valid")
+	ErrVariableCorrupted = efivar.NewError(efivar.Corrupt, "efiboot: variable content is not valid")
+
+	BootCurrentName = efivar.VariableName{GUID: efivar.GlobalUUID, Name: "BootCurrent"}
+	BootNextName    = efivar.VariableName{GUID: efivar.GlobalUUID, Name: "BootNext"}
+	BootOrderName   = efivar.VariableName{GUID: efivar.GlobalUUID, Name: "BootOrder"}
 
-	BootCurrentName = efivar.VariableName{efivar.GlobalUUID, "BootCurrent"}
-	BootNextName    = efivar.VariableName{efivar.GlobalUUID, "BootNext"}
-	BootOrderName   = efivar.VariableName{efivar.GlobalUUID, "BootOrder"}
+	filePathNode = regexp.MustCompile(`File\(([^)]*)\)`)
 )
 
 type Attributes uint32
@@ -53,7 +50,7 @@ func (d OptionalData) InterpretAsUCS2() string {
 	}
 	d16 := make([]uint16, len(d)/2)
 	for n := 0; n < len(d); n += 2 {
-		d16[n/2] = uint16(d[n]) | uint16(d[n+1]<<8)
+		d16[n/2] = uint16(d[n]) | uint16(d[n+1])<<8
 	}
 	return string(utf16.Decode(d16))
 }
@@ -77,81 +74,84 @@ type LoadOpt struct {
 	OptionalData OptionalData
 }
 
-func (lo *LoadOpt) Bytes() ([]byte, error) {
-	dpBytes := C.CBytes(lo.rawFilePath)
-	defer C.free(dpBytes)
-
-	dpStr, err := efivar.DevicePathToString(unsafe.Pointer(dpBytes), len(lo.rawFilePath))
-	if err != nil {
-		return nil, fmt.Errorf("DevicePathToString: %v", err)
-	}
-	if dpStr != lo.FilePath {
-		return nil, fmt.Errorf("changing device path is unimplemented")
-	}
-
-	descriptionBytes := C.CString(lo.Description)
-	defer C.free(unsafe.Pointer(descriptionBytes))
-
-	optionalDataBytes := C.CBytes([]byte(lo.OptionalData))
-	defer C.free(optionalDataBytes)
-
-	sz := C.efi_loadopt_create(nil, 0, C.uint32_t(lo.Attributes), C.efidp(dpBytes), C.ssize_t(len(lo.rawFilePath)), (*C.uint8_t)(unsafe.Pointer(descriptionBytes)), (*C.uint8_t)(optionalDataBytes), C.size_t(len(lo.OptionalData)))
-	if sz < 0 {
-		return nil, fmt.Errorf("finding size of output buffer: efi_loadopt_create errored (rc = %d)", sz)
+// PathName returns the file path of the loader within its partition, e.g. `\EFI\BOOT\BOOTX64.EFI`.
+// It returns an empty string if FilePath does not name a file.
+func (lo *LoadOpt) PathName() string {
+	m := filePathNode.FindStringSubmatch(lo.FilePath)
+	if m == nil {
+		return ""
 	}
+	return m[1]
+}
 
-	buf := C.malloc(C.size_t(sz))
-	defer C.free(buf)
-
-	rc := C.efi_loadopt_create((*C.uint8_t)(buf), C.ssize_t(sz), C.uint32_t(lo.Attributes), C.efidp(dpBytes), C.ssize_t(len(lo.rawFilePath)), (*C.uint8_t)(unsafe.Pointer(descriptionBytes)), (*C.uint8_t)(optionalDataBytes), C.size_t(len(lo.OptionalData)))
-	if rc < 0 {
-		return nil, fmt.Errorf("formatting output buffer: efi_loadopt_create errored (rc = %d)", rc)
+// devicePath returns the binary form of FilePath, parsing it if it has been changed.
+func (lo *LoadOpt) devicePath() ([]byte, error) {
+	if len(lo.rawFilePath) > 0 {
+		dpStr, err := efivar.DevicePathToString(unsafe.Pointer(&lo.rawFilePath[0]), len(lo.rawFilePath))
+		if err != nil {
+			return nil, fmt.Errorf("DevicePathToString: %v", err)
+		}
+		if dpStr == lo.FilePath {
+			return lo.rawFilePath, nil
+		}
 	}
-
-	return C.GoBytes(buf, C.int(sz)), nil
+	return efivar.ParseDevicePath(lo.FilePath)
 }
 
-func FromBytes(bs []byte) (*LoadOpt, error) {
-	dataPtr := C.CBytes(bs)
-	defer C.free(dataPtr)
+// encodeLoadOpt and decodeLoadOpt use libefiboot if the program uses
+// libefivar, and package loadopt otherwise; see efivar.Implementation.
+// decodeLoadOpt leaves FilePath empty.
+var (
+	encodeLoadOpt = encodeLoadOptGo
+	decodeLoadOpt = decodeLoadOptGo
+)
 
-	loadOpt := (*C.efi_load_option)(dataPtr)
-	loadOptSz := C.size_t(len(bs))
-	ok := C.efi_loadopt_is_valid(loadOpt, loadOptSz)
-	if ok != 1 {
-		return nil, ErrVariableCorrupted
+func (lo *LoadOpt) Bytes() ([]byte, error) {
+	rawFilePath, err := lo.devicePath()
+	if err != nil {
+		return nil, err
 	}
-
-	var optionalData *C.uint8_t
-	var optionalDataSz C.size_t
-	if C.efi_loadopt_optional_data(loadOpt, loadOptSz, &optionalData, &optionalDataSz) < 0 {
+	if len(rawFilePath) == 0 {
 		return nil, ErrVariableCorrupted
 	}
+	return encodeLoadOpt(lo, rawFilePath)
+}
 
-	dp := C.efi_loadopt_path(loadOpt, C.ssize_t(loadOptSz))
-	if dp == nil {
-		return nil, ErrVariableCorrupted
+func encodeLoadOptGo(lo *LoadOpt, rawFilePath []byte) ([]byte, error) {
+	out := &loadopt.LoadOption{
+		Attributes:   uint32(lo.Attributes),
+		Description:  lo.Description,
+		FilePathList: rawFilePath,
+		OptionalData: lo.OptionalData,
 	}
-	dpSz := C.efi_loadopt_pathlen(loadOpt, C.ssize_t(loadOptSz))
+	return out.Bytes(), nil
+}
 
-	dpStr, err := efivar.DevicePathToString(unsafe.Pointer(dp), int(dpSz))
+func FromBytes(bs []byte) (*LoadOpt, error) {
+	out, err := decodeLoadOpt(bs)
+	if err != nil {
+		return nil, err
+	}
+	// DevicePathToString takes the library lock itself.
+	dpStr, err := efivar.DevicePathToString(unsafe.Pointer(&out.rawFilePath[0]), len(out.rawFilePath))
 	if err != nil {
 		return nil, fmt.Errorf(" DevicePathToString: %v", err)
 	}
+	out.FilePath = dpStr
+	return out, nil
+}
 
-	descPtr := C.efi_loadopt_desc(loadOpt, C.ssize_t(loadOptSz))
-	if descPtr == nil {
+func decodeLoadOptGo(bs []byte) (*LoadOpt, error) {
+	lo, err := loadopt.Parse(bs)
+	if err != nil || len(lo.FilePathList) == 0 {
 		return nil, ErrVariableCorrupted
 	}
-
-	out := &LoadOpt{
-		Attributes:   Attributes(C.efi_loadopt_attrs(loadOpt)),
-		Description:  C.GoString((*C.char)(unsafe.Pointer(descPtr))),
-		FilePath:     dpStr,
-		rawFilePath:  C.GoBytes(unsafe.Pointer(dp), C.int(dpSz)),
-		OptionalData: OptionalData(C.GoBytes(unsafe.Pointer(optionalData), C.int(optionalDataSz))),
-	}
-	return out, nil
+	return &LoadOpt{
+		Attributes:   Attributes(lo.Attributes),
+		Description:  lo.Description,
+		rawFilePath:  lo.FilePathList,
+		OptionalData: OptionalData(lo.OptionalData),
+	}, nil
 }
 
 type BootOption struct {
@@ -161,20 +161,17 @@ type BootOption struct {
 
 func BootOptions() ([]*BootOption, error) {
 	var bos []*BootOption
-	vns, err := efivar.Variables()
+	vns, err := efivar.VariablesByGUID(efivar.GlobalUUID, "Boot")
 	if err != nil {
 		return nil, fmt.Errorf("efiboot: listing variables: %v", err)
 	}
 	for _, vn := range vns {
-		if vn.GUID != efivar.GlobalUUID {
-			continue
-		}
-		if !strings.HasPrefix(vn.Name, "Boot") || len(vn.Name) != len("Boot0000") || vn.Name == "BootNext" {
+		if len(vn.Name) != len("Boot0000") || vn.Name == "BootNext" {
 			continue
 		}
 		v, err := vn.Get()
 		if err != nil {
-			return nil, fmt.Errorf("efiboot: getting variable %q: %v", v.Name, err)
+			return nil, fmt.Errorf("efiboot: getting variable %q: %v", vn.Name, err)
 		}
 		lo, err := FromVariable(v)
 		if err != nil {
@@ -226,3 +223,17 @@ func varInOtherVar(vn efivar.VariableName) (efivar.VariableName, error) {
 func BootCurrent() (efivar.VariableName, error) { return varInOtherVar(BootCurrentName) }
 func BootNext() (efivar.VariableName, error)    { return varInOtherVar(BootNextName) }
 func BootOrder() ([]efivar.VariableName, error) { return varsInOtherVar(BootOrderName) }
+
+// SetBootNext selects the boot option to use on the next boot only,
+// overriding BootOrder.
+func SetBootNext(num uint16) error {
+	v := &efivar.Variable{
+		VariableName: BootNextName,
+		Data:         encodeUint16s([]uint16{num}),
+		Attributes:   bootVariableAttributes,
+	}
+	return v.Set(0644)
+}
+
+// ClearBootNext cancels a previous SetBootNext.
+func ClearBootNext() error { return BootNextName.Delete() }
@@ -16,10 +16,15 @@ package efiboot
 
 import (
 	"encoding/hex"
+	"fmt"
+	"os"
+	"reflect"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/internal/testfixture"
 )
 
 var (
@@ -48,22 +53,23 @@ func mustDecodeString(s string) []byte {
 }
 
 func TestBootOptions(t *testing.T) {
-	if !efivar.Supported() {
-		t.Skip("efivar is not supported")
-	}
-	los, err := BootOptions()
+	defer testfixture.Use(t, "laptop")()
+	bos, err := BootOptions()
 	if err != nil {
 		t.Fatalf("BootOptions: %v", err)
 	}
-	if len(los) == 0 {
-		t.Fatal("BootOptions returned empty slice; want at least one element")
+	var got []string
+	for _, bo := range bos {
+		got = append(got, fmt.Sprintf("%v: %v", bo.Variable.Name, bo.LoadOpt.Description))
+	}
+	want := []string{"Boot0000: Windows Boot Manager", "Boot000C: Arch Linux"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("BootOptions = %q; want %q", got, want)
 	}
 }
 
 func TestFromVariable(t *testing.T) {
-	if !efivar.Supported() {
-		t.Skip("efivar is not supported")
-	}
+	defer testfixture.Use(t, "laptop")()
 	bc, err := BootCurrent()
 	if err != nil {
 		t.Fatalf("BootCurrent: %v", err)
@@ -76,8 +82,52 @@ func TestFromVariable(t *testing.T) {
 	if err != nil {
 		t.Fatalf("FromVariable: %v", err)
 	}
-	if len(lo.Description) == 0 {
-		t.Errorf("lo.Description is empty; want non-empty")
+	if lo.Description != "Arch Linux" {
+		t.Errorf("lo.Description = %q; want %q", lo.Description, "Arch Linux")
+	}
+}
+
+func TestBootNextAndOrder(t *testing.T) {
+	defer testfixture.Use(t, "laptop")()
+	if _, err := BootNext(); !os.IsNotExist(err) {
+		t.Errorf("BootNext before SetBootNext = %v; want a not-exist error", err)
+	}
+	if err := SetBootNext(0); err != nil {
+		t.Fatalf("SetBootNext: %v", err)
+	}
+	if next, err := BootNext(); err != nil || next.Name != "Boot0000" {
+		t.Errorf("BootNext = %v, %v; want Boot0000", next, err)
+	}
+	if err := ClearBootNext(); err != nil {
+		t.Fatalf("ClearBootNext: %v", err)
+	}
+
+	if err := SetBootOrder([]uint16{0, 0xc}); err != nil {
+		t.Fatalf("SetBootOrder: %v", err)
+	}
+	order, err := BootOrder()
+	if err != nil {
+		t.Fatalf("BootOrder: %v", err)
+	}
+	if len(order) != 2 || order[0].Name != "Boot0000" || order[1].Name != "Boot000C" {
+		t.Errorf("BootOrder = %v; want [Boot0000 Boot000C]", order)
+	}
+}
+
+func TestInterpretAsUCS2(t *testing.T) {
+	for _, tc := range []struct {
+		data OptionalData
+		want string
+	}{
+		{OptionalData{}, ""},
+		{OptionalData{'a', 0, 'b', 0}, "ab"},
+		{OptionalData{0xac, 0x20}, "€"},
+		{OptionalData{'a', 0, 0x3d, 0xd8, 0x00, 0xde}, "a\U0001f600"},
+		{OptionalData{'a', 0, 'b'}, ""},
+	} {
+		if got := tc.data.InterpretAsUCS2(); got != tc.want {
+			t.Errorf("OptionalData(%x).InterpretAsUCS2() = %q; want %q", []byte(tc.data), got, tc.want)
+		}
 	}
 }
 
@@ -136,7 +186,7 @@ func TestTweakEntry(t *testing.T) {
 	}
 }
 
-func TestTweakEntryByFilePathNotImplemented(t *testing.T) {
+func TestTweakEntryByFilePathInvalid(t *testing.T) {
 	lo, err := FromBytes(archBootOptBytes)
 	if err != nil {
 		t.Fatalf("FromBytes: %v", err)
@@ -145,6 +195,132 @@ func TestTweakEntryByFilePathNotImplemented(t *testing.T) {
 	lo.FilePath = "foo"
 
 	if _, err := lo.Bytes(); err == nil {
-		t.Fatalf("lo.Bytes returned no error; was expecting 'not implemented'")
+		t.Fatalf("lo.Bytes returned no error; was expecting a device path parsing error")
+	}
+}
+
+func TestPathName(t *testing.T) {
+	for _, tc := range []struct {
+		filePath string
+		want     string
+	}{
+		{`HD(1,GPT,b647c141-bfe9-274c-81c6-174026e79fd0,0x800,0x3a9800)/File(\vmlinuz-linux)`, `\vmlinuz-linux`},
+		{`HD(1,GPT,b647c141-bfe9-274c-81c6-174026e79fd0,0x800,0x3a9800)/File(\EFI\BOOT\BOOTX64.EFI)`, `\EFI\BOOT\BOOTX64.EFI`},
+		{`PciRoot(0x0)/Pci(0x1f,0x2)/Sata(0,65535,0)`, ``},
+	} {
+		lo := &LoadOpt{FilePath: tc.filePath}
+		if got := lo.PathName(); got != tc.want {
+			t.Errorf("LoadOpt{FilePath: %q}.PathName() = %q; want %q", tc.filePath, got, tc.want)
+		}
+	}
+}
+
+func TestNewLoadOpt(t *testing.T) {
+	orig, err := FromBytes(archBootOptBytes)
+	if err != nil {
+		t.Fatalf("FromBytes: %v", err)
+	}
+	lo, err := NewLoadOpt(orig.Attributes, orig.Description, orig.rawFilePath, orig.OptionalData)
+	if err != nil {
+		t.Fatalf("NewLoadOpt: %v", err)
+	}
+	if lo.FilePath != orig.FilePath {
+		t.Errorf("NewLoadOpt FilePath = %q; want %q", lo.FilePath, orig.FilePath)
+	}
+	b, err := lo.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes: %v", err)
+	}
+	if got, want := hex.EncodeToString(b), hex.EncodeToString(archBootOptBytes); got != want {
+		t.Errorf("NewLoadOpt(...).Bytes() = %v; want %v", got, want)
+	}
+}
+
+func TestAttributesHelpers(t *testing.T) {
+	a := LoadOptionActive.With(LoadOptionHidden)
+	if !a.Has(LoadOptionActive) || !a.Has(LoadOptionHidden) || a.Has(LoadOptionCategoryApp) {
+		t.Errorf("Has on %#x gave the wrong answer", uint32(a))
+	}
+	if got := a.Without(LoadOptionActive); got != LoadOptionHidden {
+		t.Errorf("%#x.Without(LoadOptionActive) = %#x", uint32(a), uint32(got))
+	}
+}
+
+func TestBootOptionNumber(t *testing.T) {
+	for _, n := range []uint16{0, 0xc, 0xbeef} {
+		got, err := BootOptionNumber(BootOptionName(n))
+		if err != nil || got != n {
+			t.Errorf("BootOptionNumber(BootOptionName(%#x)) = %#x, %v", n, got, err)
+		}
+	}
+	for _, name := range []string{"BootNext", "Boot00G0", "Driver0001"} {
+		if _, err := BootOptionNumber(efivar.VariableName{GUID: efivar.GlobalUUID, Name: name}); err == nil {
+			t.Errorf("BootOptionNumber(%v) succeeded", name)
+		}
+	}
+}
+
+func BenchmarkFromBytes(b *testing.B) {
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		if _, err := FromBytes(archBootOptBytes); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLoadOptBytes(b *testing.B) {
+	lo, err := FromBytes(archBootOptBytes)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if _, err := lo.Bytes(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBootOptions(b *testing.B) {
+	defer testfixture.Use(b, "laptop")()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if _, err := BootOptions(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// TestConcurrentBootOptions is most useful with -race.
+func TestConcurrentBootOptions(t *testing.T) {
+	defer testfixture.Use(t, "laptop")()
+	var wg sync.WaitGroup
+	for n := 0; n < 8; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				if _, err := BootOptions(); err != nil {
+					t.Errorf("BootOptions: %v", err)
+					return
+				}
+				lo, err := FromBytes(archBootOptBytes)
+				if err != nil {
+					t.Errorf("FromBytes: %v", err)
+					return
+				}
+				if lo.Description != "Arch Linux" {
+					t.Errorf("FromBytes(archBootOptBytes).Description = %q; want %q", lo.Description, "Arch Linux")
+				}
+				if _, err := lo.Bytes(); err != nil {
+					t.Errorf("Bytes: %v", err)
+					return
+				}
+			}
+		}()
 	}
+	wg.Wait()
 }
@@ -0,0 +1,44 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efiboot
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/lukegb/goefivar/internal/mounts"
+)
+
+// ESPMountpoints returns the mountpoints of the mounted EFI System Partitions:
+// FAT filesystems which contain an EFI directory.
+func ESPMountpoints() ([]string, error) {
+	ms, err := mounts.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	var out []string
+	seen := make(map[string]bool)
+	for _, m := range ms {
+		if (m.Type != "vfat" && m.Type != "msdos") || seen[m.Dir] {
+			continue
+		}
+		if fi, err := os.Stat(filepath.Join(m.Dir, "EFI")); err == nil && fi.IsDir() {
+			seen[m.Dir] = true
+			out = append(out, m.Dir)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,59 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efiboot
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/lukegb/goefivar/internal/mounts"
+)
+
+func TestESPMountpoints(t *testing.T) {
+	dir, err := ioutil.TempDir("", "efiboot")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	esp := filepath.Join(dir, "boot efi")
+	notESP := filepath.Join(dir, "usb")
+	for _, d := range []string{filepath.Join(esp, "EFI"), notESP} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatalf("MkdirAll: %v", err)
+		}
+	}
+	escapedESP := filepath.Join(dir, `boot\040efi`)
+	mountsFile := filepath.Join(dir, "mounts")
+	content := fmt.Sprintf("/dev/sda2 / ext4 rw 0 0\n/dev/sda1 %s vfat rw 0 0\n/dev/sdb1 %s vfat rw 0 0\n", escapedESP, notESP)
+	if err := ioutil.WriteFile(mountsFile, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	defer func(old string) { mounts.File = old }(mounts.File)
+	mounts.File = mountsFile
+
+	got, err := ESPMountpoints()
+	if err != nil {
+		t.Fatalf("ESPMountpoints: %v", err)
+	}
+	if want := []string{esp}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ESPMountpoints() = %v; want %v", got, want)
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efiboot
+
+import (
+	"encoding/binary"
+	"os"
+
+	"github.com/lukegb/goefivar/efivar"
+)
+
+var (
+	OsIndicationsName          = efivar.VariableName{GUID: efivar.GlobalUUID, Name: "OsIndications"}
+	OsIndicationsSupportedName = efivar.VariableName{GUID: efivar.GlobalUUID, Name: "OsIndicationsSupported"}
+)
+
+// bootToFirmwareUI is EFI_OS_INDICATIONS_BOOT_TO_FW_UI.
+const bootToFirmwareUI = 0x1
+
+func readOsIndications(vn efivar.VariableName) (uint64, error) {
+	v, err := vn.Get()
+	if err != nil {
+		return 0, err
+	}
+	if len(v.Data) != 8 {
+		return 0, ErrVariableCorrupted
+	}
+	return binary.LittleEndian.Uint64(v.Data), nil
+}
+
+// FirmwareSetupSupported reports whether the firmware can be asked to stop
+// in its setup menu on the next boot.
+func FirmwareSetupSupported() (bool, error) {
+	s, err := readOsIndications(OsIndicationsSupportedName)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return s&bootToFirmwareUI != 0, err
+}
+
+// SetBootToFirmwareSetup asks the firmware to stop in its setup menu on the
+// next boot, or cancels a previous request.
+func SetBootToFirmwareSetup(on bool) error {
+	cur, err := readOsIndications(OsIndicationsName)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if on {
+		cur |= bootToFirmwareUI
+	} else {
+		cur &^= bootToFirmwareUI
+	}
+	v := &efivar.Variable{
+		VariableName: OsIndicationsName,
+		Data:         make([]byte, 8),
+		Attributes:   bootVariableAttributes,
+	}
+	binary.LittleEndian.PutUint64(v.Data, cur)
+	return v.Set(0644)
+}
@@ -0,0 +1,57 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efiboot
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/efivar/efivartest"
+)
+
+func osIndications(vn efivar.VariableName, v uint64) *efivar.Variable {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, v)
+	return &efivar.Variable{VariableName: vn, Data: b, Attributes: bootVariableAttributes}
+}
+
+func TestFirmwareSetup(t *testing.T) {
+	b := efivartest.New()
+	defer b.Use()()
+
+	if ok, err := FirmwareSetupSupported(); ok || err != nil {
+		t.Errorf("FirmwareSetupSupported without OsIndicationsSupported = %v, %v; want false, nil", ok, err)
+	}
+	b.Add(osIndications(OsIndicationsSupportedName, bootToFirmwareUI), osIndications(OsIndicationsName, 0x4))
+	if ok, err := FirmwareSetupSupported(); !ok || err != nil {
+		t.Errorf("FirmwareSetupSupported = %v, %v; want true, nil", ok, err)
+	}
+
+	for _, tc := range []struct {
+		on   bool
+		want uint64
+	}{
+		{true, 0x4 | bootToFirmwareUI},
+		{false, 0x4},
+	} {
+		if err := SetBootToFirmwareSetup(tc.on); err != nil {
+			t.Fatalf("SetBootToFirmwareSetup(%v): %v", tc.on, err)
+		}
+		if got, err := readOsIndications(OsIndicationsName); got != tc.want || err != nil {
+			t.Errorf("after SetBootToFirmwareSetup(%v), OsIndications = %#x, %v; want %#x", tc.on, got, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build cgo && !purego && !darwin
+// +build cgo,!purego,!darwin
+
+package efiboot
+
+// #cgo pkg-config: efiboot
+// #include <efiboot.h>
+// #include <stdlib.h>
+//
+// // cgo can't call variadic functions; neither needs the variadic
+// // arguments unless EFIBOOT_ABBREV_EDD10 is given.
+// static ssize_t goefiboot_file_dp(uint8_t *buf, ssize_t size, const char *path, uint32_t options) {
+//   return efi_generate_file_device_path(buf, size, path, options);
+// }
+// static ssize_t goefiboot_file_dp_from_esp(uint8_t *buf, ssize_t size, const char *dev, int part, const char *rel, uint32_t options) {
+//   return efi_generate_file_device_path_from_esp(buf, size, dev, part, rel, options);
+// }
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/internal/liblock"
+)
+
+func init() {
+	if efivar.Implementation() == efivar.ImplLibefivar {
+		encodeLoadOpt, decodeLoadOpt = encodeLoadOptC, decodeLoadOptC
+		argsFromFile, argsAsUTF8, argsAsUCS2 = argsFromFileC, argsAsUTF8C, argsAsUCS2C
+	}
+}
+
+// abbrevHD is EFIBOOT_ABBREV_HD: generate a device path starting at the
+// partition, as efibootmgr does, so the entry survives moving the disk.
+const abbrevHD = 0x2
+
+func encodeLoadOptC(lo *LoadOpt, rawFilePath []byte) ([]byte, error) {
+	// The arguments and the result are all Go memory: none of them hold Go
+	// pointers, and libefiboot does not keep them after the call.
+	dpBytes := (C.efidp)(unsafe.Pointer(&rawFilePath[0]))
+	descriptionBytes := append([]byte(lo.Description), 0)
+	description := (*C.uint8_t)(unsafe.Pointer(&descriptionBytes[0]))
+	var optionalData *C.uint8_t
+	if len(lo.OptionalData) > 0 {
+		optionalData = (*C.uint8_t)(unsafe.Pointer(&lo.OptionalData[0]))
+	}
+
+	liblock.Lock()
+	defer liblock.Unlock()
+	sz := C.efi_loadopt_create(nil, 0, C.uint32_t(lo.Attributes), dpBytes, C.ssize_t(len(rawFilePath)), description, optionalData, C.size_t(len(lo.OptionalData)))
+	if sz < 0 {
+		return nil, fmt.Errorf("finding size of output buffer: efi_loadopt_create errored (rc = %d)", sz)
+	}
+
+	buf := make([]byte, sz)
+	rc := C.efi_loadopt_create((*C.uint8_t)(unsafe.Pointer(&buf[0])), C.ssize_t(sz), C.uint32_t(lo.Attributes), dpBytes, C.ssize_t(len(rawFilePath)), description, optionalData, C.size_t(len(lo.OptionalData)))
+	if rc < 0 {
+		return nil, fmt.Errorf("formatting output buffer: efi_loadopt_create errored (rc = %d)", rc)
+	}
+	return buf, nil
+}
+
+// decodeLoadOptC holds the library lock: efi_loadopt_desc returns a buffer shared by every caller.
+func decodeLoadOptC(bs []byte) (*LoadOpt, error) {
+	dataPtr := C.CBytes(bs)
+	defer C.free(dataPtr)
+
+	liblock.Lock()
+	defer liblock.Unlock()
+
+	loadOpt := (*C.efi_load_option)(dataPtr)
+	loadOptSz := C.size_t(len(bs))
+	ok := C.efi_loadopt_is_valid(loadOpt, loadOptSz)
+	if ok != 1 {
+		return nil, ErrVariableCorrupted
+	}
+
+	var optionalData *C.uint8_t
+	var optionalDataSz C.size_t
+	if C.efi_loadopt_optional_data(loadOpt, loadOptSz, &optionalData, &optionalDataSz) < 0 {
+		return nil, ErrVariableCorrupted
+	}
+
+	dp := C.efi_loadopt_path(loadOpt, C.ssize_t(loadOptSz))
+	if dp == nil {
+		return nil, ErrVariableCorrupted
+	}
+	dpSz := C.efi_loadopt_pathlen(loadOpt, C.ssize_t(loadOptSz))
+	if dpSz == 0 {
+		return nil, ErrVariableCorrupted
+	}
+
+	descPtr := C.efi_loadopt_desc(loadOpt, C.ssize_t(loadOptSz))
+	if descPtr == nil {
+		return nil, ErrVariableCorrupted
+	}
+
+	out := &LoadOpt{
+		Attributes:   Attributes(C.efi_loadopt_attrs(loadOpt)),
+		Description:  C.GoString((*C.char)(unsafe.Pointer(descPtr))),
+		rawFilePath:  C.GoBytes(unsafe.Pointer(dp), C.int(dpSz)),
+		OptionalData: OptionalData(C.GoBytes(unsafe.Pointer(optionalData), C.int(optionalDataSz))),
+	}
+	return out, nil
+}
+
+// FileDevicePath returns the device path of a file on a mounted filesystem.
+func FileDevicePath(path string) ([]byte, error) {
+	cPath := C.CString(path)
+	defer C.free(unsafe.Pointer(cPath))
+
+	liblock.Lock()
+	defer liblock.Unlock()
+	sz, err := C.goefiboot_file_dp(nil, 0, cPath, abbrevHD)
+	if sz < 0 {
+		return nil, fmt.Errorf("efiboot: generating device path for %v: %v", path, err)
+	}
+	buf := C.malloc(C.size_t(sz))
+	defer C.free(buf)
+	if rc, err := C.goefiboot_file_dp((*C.uint8_t)(buf), sz, cPath, abbrevHD); rc < 0 {
+		return nil, fmt.Errorf("efiboot: generating device path for %v: %v", path, err)
+	}
+	return C.GoBytes(buf, C.int(sz)), nil
+}
+
+// ESPFileDevicePath returns the device path of the file at path (e.g.
+// `\EFI\debian\shimx64.efi`) on partition part of the disk device.
+func ESPFileDevicePath(device string, part int, path string) ([]byte, error) {
+	cDev := C.CString(device)
+	defer C.free(unsafe.Pointer(cDev))
+	cPath := C.CString(path)
+	defer C.free(unsafe.Pointer(cPath))
+
+	liblock.Lock()
+	defer liblock.Unlock()
+	sz, err := C.goefiboot_file_dp_from_esp(nil, 0, cDev, C.int(part), cPath, abbrevHD)
+	if sz < 0 {
+		return nil, fmt.Errorf("efiboot: generating device path for %v on %v partition %d: %v", path, device, part, err)
+	}
+	buf := C.malloc(C.size_t(sz))
+	defer C.free(buf)
+	if rc, err := C.goefiboot_file_dp_from_esp((*C.uint8_t)(buf), sz, cDev, C.int(part), cPath, abbrevHD); rc < 0 {
+		return nil, fmt.Errorf("efiboot: generating device path for %v on %v partition %d: %v", path, device, part, err)
+	}
+	return C.GoBytes(buf, C.int(sz)), nil
+}
+
+// optionalData runs one of libefiboot's efi_loadopt_args functions f twice,
+// first to find the size of the result and then to fill it in.
+func optionalData(f func(buf unsafe.Pointer, size C.ssize_t) (C.ssize_t, error)) (OptionalData, error) {
+	liblock.Lock()
+	defer liblock.Unlock()
+	sz, err := f(nil, 0)
+	if sz < 0 {
+		return nil, err
+	}
+	if sz == 0 {
+		return nil, nil
+	}
+	buf := make(OptionalData, sz)
+	if rc, err := f(unsafe.Pointer(&buf[0]), sz); rc < 0 {
+		return nil, err
+	}
+	return buf, nil
+}
+
+func argsFromFileC(path string) (OptionalData, error) {
+	cPath := C.CString(path)
+	defer C.free(unsafe.Pointer(cPath))
+	d, err := optionalData(func(buf unsafe.Pointer, size C.ssize_t) (C.ssize_t, error) {
+		rc, err := C.efi_loadopt_args_from_file((*C.uint8_t)(buf), size, cPath)
+		return rc, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("efiboot: reading optional data from %v: %v", path, err)
+	}
+	return d, nil
+}
+
+func argsAsUTF8C(args string) (OptionalData, error) {
+	cArgs := C.CString(args)
+	defer C.free(unsafe.Pointer(cArgs))
+	d, err := optionalData(func(buf unsafe.Pointer, size C.ssize_t) (C.ssize_t, error) {
+		rc, err := C.efi_loadopt_args_as_utf8((*C.uint8_t)(buf), size, (*C.uint8_t)(unsafe.Pointer(cArgs)))
+		return rc, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("efiboot: encoding optional data: %v", err)
+	}
+	return d, nil
+}
+
+func argsAsUCS2C(args string) (OptionalData, error) {
+	cArgs := C.CString(args)
+	defer C.free(unsafe.Pointer(cArgs))
+	d, err := optionalData(func(buf unsafe.Pointer, size C.ssize_t) (C.ssize_t, error) {
+		rc, err := C.efi_loadopt_args_as_ucs2((*C.uint16_t)(buf), size, (*C.uint8_t)(unsafe.Pointer(cArgs)))
+		return rc, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("efiboot: encoding optional data: %v", err)
+	}
+	return d, nil
+}
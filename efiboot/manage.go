@@ -0,0 +1,166 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efiboot
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"unsafe"
+
+	"github.com/lukegb/goefivar/efivar"
+)
+
+// Load option attributes.
+const (
+	LoadOptionActive         Attributes = 0x00000001
+	LoadOptionForceReconnect Attributes = 0x00000002
+	LoadOptionHidden         Attributes = 0x00000008
+	LoadOptionCategoryApp    Attributes = 0x00000100
+)
+
+// Has reports whether a has every bit of b.
+func (a Attributes) Has(b Attributes) bool {
+	return a&b == b
+}
+
+// With returns a with the bits of b set.
+func (a Attributes) With(b Attributes) Attributes {
+	return a | b
+}
+
+// Without returns a with the bits of b cleared.
+func (a Attributes) Without(b Attributes) Attributes {
+	return a &^ b
+}
+
+var TimeoutName = efivar.VariableName{GUID: efivar.GlobalUUID, Name: "Timeout"}
+
+const bootVariableAttributes = efivar.NonVolatile | efivar.BootserviceAccess | efivar.RuntimeAccess
+
+// NewLoadOpt builds a load option from a binary device path, such as one
+// returned by FileDevicePath.
+func NewLoadOpt(attrs Attributes, description string, devicePath []byte, optionalData OptionalData) (*LoadOpt, error) {
+	if len(devicePath) == 0 {
+		return nil, fmt.Errorf("efiboot: empty device path")
+	}
+	dpStr, err := efivar.DevicePathToString(unsafe.Pointer(&devicePath[0]), len(devicePath))
+	if err != nil {
+		return nil, fmt.Errorf("DevicePathToString: %v", err)
+	}
+	return &LoadOpt{
+		Attributes:   attrs,
+		Description:  description,
+		FilePath:     dpStr,
+		rawFilePath:  append([]byte(nil), devicePath...),
+		OptionalData: optionalData,
+	}, nil
+}
+
+// BootOptionName returns the name of the Boot#### variable for num.
+func BootOptionName(num uint16) efivar.VariableName {
+	return efivar.VariableName{GUID: efivar.GlobalUUID, Name: fmt.Sprintf("Boot%04X", num)}
+}
+
+// BootOptionNumber returns the number of a Boot#### variable.
+func BootOptionNumber(vn efivar.VariableName) (uint16, error) {
+	if vn.GUID != efivar.GlobalUUID || len(vn.Name) != len("Boot0000") || vn.Name[:4] != "Boot" {
+		return 0, fmt.Errorf("efiboot: %v is not a boot option", vn.Name)
+	}
+	n, err := strconv.ParseUint(vn.Name[4:], 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("efiboot: %v is not a boot option", vn.Name)
+	}
+	return uint16(n), nil
+}
+
+// FreeBootNumber returns the lowest boot option number not in use.
+func FreeBootNumber() (uint16, error) {
+	bos, err := BootOptions()
+	if err != nil {
+		return 0, err
+	}
+	used := make(map[uint16]bool)
+	for _, bo := range bos {
+		if n, err := BootOptionNumber(bo.Variable.VariableName); err == nil {
+			used[n] = true
+		}
+	}
+	for n := 0; n <= 0xffff; n++ {
+		if !used[uint16(n)] {
+			return uint16(n), nil
+		}
+	}
+	return 0, fmt.Errorf("efiboot: no free boot option numbers")
+}
+
+// SetBootOption writes lo to Boot#### for num.
+func SetBootOption(num uint16, lo *LoadOpt) error {
+	b, err := lo.Bytes()
+	if err != nil {
+		return err
+	}
+	v := &efivar.Variable{VariableName: BootOptionName(num), Data: b, Attributes: bootVariableAttributes}
+	return v.Set(0644)
+}
+
+// Save writes back a boot option after changes to its LoadOpt.
+func (bo *BootOption) Save() error {
+	b, err := bo.LoadOpt.Bytes()
+	if err != nil {
+		return err
+	}
+	bo.Variable.Data = b
+	return bo.Variable.Set(0644)
+}
+
+func encodeUint16s(ns []uint16) []byte {
+	b := make([]byte, 2*len(ns))
+	for i, n := range ns {
+		b[2*i], b[2*i+1] = byte(n), byte(n>>8)
+	}
+	return b
+}
+
+// SetBootOrder sets the order in which the firmware tries boot options.
+func SetBootOrder(order []uint16) error {
+	v := &efivar.Variable{VariableName: BootOrderName, Data: encodeUint16s(order), Attributes: bootVariableAttributes}
+	return v.Set(0644)
+}
+
+// Timeout returns the number of seconds the firmware waits before booting
+// the first option in BootOrder.
+func Timeout() (uint16, error) {
+	v, err := TimeoutName.Get()
+	if err != nil {
+		return 0, err
+	}
+	if len(v.Data) != 2 {
+		return 0, ErrVariableCorrupted
+	}
+	return uint16(v.Data[0]) | uint16(v.Data[1])<<8, nil
+}
+
+func SetTimeout(seconds uint16) error {
+	v := &efivar.Variable{VariableName: TimeoutName, Data: encodeUint16s([]uint16{seconds}), Attributes: bootVariableAttributes}
+	return v.Set(0644)
+}
+
+func DeleteTimeout() error {
+	if err := TimeoutName.Delete(); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
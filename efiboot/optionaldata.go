@@ -0,0 +1,70 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efiboot
+
+import (
+	"io/ioutil"
+	"unicode/utf16"
+)
+
+// argsFromFile, argsAsUTF8 and argsAsUCS2 use libefiboot if the program
+// uses libefivar, like encodeLoadOpt.
+var (
+	argsFromFile = argsFromFileGo
+	argsAsUTF8   = argsAsUTF8Go
+	argsAsUCS2   = argsAsUCS2Go
+)
+
+// OptionalDataFromFile returns the content of the file at path as optional
+// data, as efibootmgr's -@ option does.
+func OptionalDataFromFile(path string) (OptionalData, error) {
+	return argsFromFile(path)
+}
+
+// OptionalDataUTF8 encodes args as optional data, unchanged, as efibootmgr
+// does by default.
+func OptionalDataUTF8(args string) (OptionalData, error) {
+	return argsAsUTF8(args)
+}
+
+// OptionalDataUCS2 encodes args as optional data in UCS-2 with a trailing
+// NUL, as efibootmgr's -u option does and as Linux's EFI stub expects its
+// command line.
+func OptionalDataUCS2(args string) (OptionalData, error) {
+	return argsAsUCS2(args)
+}
+
+func argsFromFileGo(path string) (OptionalData, error) {
+	return ioutil.ReadFile(path)
+}
+
+func argsAsUTF8Go(args string) (OptionalData, error) {
+	if args == "" {
+		return nil, nil
+	}
+	return OptionalData(args), nil
+}
+
+func argsAsUCS2Go(args string) (OptionalData, error) {
+	if args == "" {
+		return nil, nil
+	}
+	s := utf16.Encode([]rune(args + "\x00"))
+	d := make(OptionalData, 2*len(s))
+	for n, c := range s {
+		d[2*n], d[2*n+1] = byte(c), byte(c>>8)
+	}
+	return d, nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efiboot
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOptionalDataFromFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "efiboot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "args")
+	want := []byte("r\x00o\x00o\x00t\x00=\x00/\x00\x00\x00\xff")
+	if err := ioutil.WriteFile(path, want, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for name, f := range map[string]func(string) (OptionalData, error){
+		"OptionalDataFromFile": OptionalDataFromFile,
+		"argsFromFileGo":       argsFromFileGo,
+	} {
+		if got, err := f(path); err != nil || !bytes.Equal(got, want) {
+			t.Errorf("%v(%v) = %q, %v; want %q", name, path, got, err, want)
+		}
+		if _, err := f(filepath.Join(dir, "missing")); err == nil {
+			t.Errorf("%v of a missing file succeeded", name)
+		}
+	}
+}
+
+func TestOptionalDataEncodings(t *testing.T) {
+	for _, args := range []string{"root=/dev/sda1 quiet", "initrd=\\initramfs-linux.img", ""} {
+		for name, f := range map[string]func(string) (OptionalData, error){
+			"OptionalDataUTF8": OptionalDataUTF8,
+			"argsAsUTF8Go":     argsAsUTF8Go,
+		} {
+			if got, err := f(args); err != nil || got.InterpretAsUTF8() != args {
+				t.Errorf("%v(%q) = %q, %v", name, args, got, err)
+			}
+		}
+		for name, f := range map[string]func(string) (OptionalData, error){
+			"OptionalDataUCS2": OptionalDataUCS2,
+			"argsAsUCS2Go":     argsAsUCS2Go,
+		} {
+			if got, err := f(args); err != nil || strings.TrimSuffix(got.InterpretAsUCS2(), "\x00") != args {
+				t.Errorf("%v(%q) = %q, %v", name, args, got, err)
+			}
+		}
+	}
+	want := OptionalData("a\x00\xe9\x00\x00\x00")
+	if got, _ := argsAsUCS2Go("aé"); !bytes.Equal(got, want) {
+		t.Errorf("argsAsUCS2Go(\"aé\") = %q; want %q", got, want)
+	}
+}
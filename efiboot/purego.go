@@ -0,0 +1,37 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !cgo || purego || darwin
+// +build !cgo purego darwin
+
+package efiboot
+
+import (
+	"fmt"
+
+	"github.com/lukegb/goefivar/efivar"
+)
+
+// FileDevicePath returns the device path of a file on a mounted filesystem.
+// It needs libefiboot, so fails in programs built without it.
+func FileDevicePath(path string) ([]byte, error) {
+	return nil, efivar.NewError(efivar.Unsupported, fmt.Sprintf("efiboot: generating device path for %v needs libefiboot", path))
+}
+
+// ESPFileDevicePath returns the device path of the file at path (e.g.
+// `\EFI\debian\shimx64.efi`) on partition part of the disk device. It needs
+// libefiboot, so fails in programs built without it.
+func ESPFileDevicePath(device string, part int, path string) ([]byte, error) {
+	return nil, efivar.NewError(efivar.Unsupported, fmt.Sprintf("efiboot: generating device path for %v on %v partition %d needs libefiboot", path, device, part))
+}
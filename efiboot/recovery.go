@@ -0,0 +1,136 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efiboot
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lukegb/goefivar/efivar"
+)
+
+var OSRecoveryOrderName = efivar.VariableName{GUID: efivar.GlobalUUID, Name: "OsRecoveryOrder"}
+
+// osRecoveryAttributes are the attributes required of OsRecoveryOrder and OsRecovery####.
+const osRecoveryAttributes = efivar.NonVolatile | efivar.BootserviceAccess | efivar.RuntimeAccess | efivar.TimeBasedAuthenticatedWriteAccess
+
+// VariableSigner produces the payload for an authenticated write of data to vn,
+// i.e. an EFI_VARIABLE_AUTHENTICATION_2 descriptor followed by data.
+// efisig.Key implements this interface.
+type VariableSigner interface {
+	SignedPayload(vn efivar.VariableName, attrs efivar.Attributes, data []byte) ([]byte, error)
+}
+
+// OSRecoveryOption is an OsRecovery#### load option. Each is namespaced by
+// the vendor GUID listed in OsRecoveryOrder that it was found under.
+type OSRecoveryOption struct {
+	Variable *efivar.Variable
+	LoadOpt  *LoadOpt
+}
+
+func parseGUIDList(data []byte) ([]uuid.UUID, error) {
+	if len(data)%16 != 0 {
+		return nil, ErrVariableCorrupted
+	}
+	out := make([]uuid.UUID, len(data)/16)
+	for n := range out {
+		out[n] = efivar.GUIDFromBytes(data[n*16:])
+	}
+	return out, nil
+}
+
+// OSRecoveryOrder returns the vendor GUIDs whose OsRecovery#### options the firmware tries, in order.
+func OSRecoveryOrder() ([]uuid.UUID, error) {
+	v, err := OSRecoveryOrderName.Get()
+	if err != nil {
+		return nil, err
+	}
+	return parseGUIDList(v.Data)
+}
+
+func isOSRecoveryName(name string) bool {
+	return strings.HasPrefix(name, "OsRecovery") && len(name) == len("OsRecovery0000") && name != "OsRecoveryOrder"
+}
+
+// OSRecoveryOptions returns the OsRecovery#### options of every vendor in OsRecoveryOrder, in order.
+func OSRecoveryOptions() ([]*OSRecoveryOption, error) {
+	vendors, err := OSRecoveryOrder()
+	switch {
+	case os.IsNotExist(err):
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("efiboot: reading OsRecoveryOrder: %v", err)
+	}
+	var out []*OSRecoveryOption
+	for _, vendor := range vendors {
+		vns, err := efivar.VariablesByGUID(vendor, "OsRecovery")
+		if err != nil {
+			return nil, fmt.Errorf("efiboot: listing variables: %v", err)
+		}
+		var opts []*OSRecoveryOption
+		for _, vn := range vns {
+			if !isOSRecoveryName(vn.Name) {
+				continue
+			}
+			v, err := vn.Get()
+			if err != nil {
+				return nil, fmt.Errorf("efiboot: getting variable %q: %v", vn.Name, err)
+			}
+			lo, err := FromVariable(v)
+			if err != nil {
+				return nil, fmt.Errorf("efiboot: parsing variable %q: %v", vn.Name, err)
+			}
+			opts = append(opts, &OSRecoveryOption{v, lo})
+		}
+		sort.Slice(opts, func(i, j int) bool { return opts[i].Variable.Name < opts[j].Variable.Name })
+		out = append(out, opts...)
+	}
+	return out, nil
+}
+
+func writeSigned(vn efivar.VariableName, data []byte, signer VariableSigner) error {
+	payload, err := signer.SignedPayload(vn, osRecoveryAttributes, data)
+	if err != nil {
+		return fmt.Errorf("efiboot: signing %v: %v", vn.Name, err)
+	}
+	v := &efivar.Variable{
+		VariableName: vn,
+		Data:         payload,
+		Attributes:   osRecoveryAttributes,
+	}
+	return v.Set(0644)
+}
+
+// SetOSRecoveryOrder replaces OsRecoveryOrder with vendors, signed by signer.
+func SetOSRecoveryOrder(vendors []uuid.UUID, signer VariableSigner) error {
+	var data []byte
+	for _, v := range vendors {
+		data = append(data, efivar.GUIDBytes(v)...)
+	}
+	return writeSigned(OSRecoveryOrderName, data, signer)
+}
+
+// SetOSRecoveryOption writes lo as OsRecovery#### in vendor's namespace, signed by signer.
+func SetOSRecoveryOption(vendor uuid.UUID, num uint16, lo *LoadOpt, signer VariableSigner) error {
+	data, err := lo.Bytes()
+	if err != nil {
+		return err
+	}
+	vn := efivar.VariableName{GUID: vendor, Name: fmt.Sprintf("OsRecovery%04X", num)}
+	return writeSigned(vn, data, signer)
+}
@@ -0,0 +1,55 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efiboot
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/lukegb/goefivar/efivar"
+)
+
+func TestParseGUIDList(t *testing.T) {
+	want := []uuid.UUID{
+		uuid.MustParse("74552304-ce9f-4e52-89a0-f6c6fa47deac"),
+		efivar.GlobalUUID,
+	}
+	data := append(efivar.GUIDBytes(want[0]), efivar.GUIDBytes(want[1])...)
+	got, err := parseGUIDList(data)
+	if err != nil {
+		t.Fatalf("parseGUIDList: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseGUIDList = %v; want %v", got, want)
+	}
+
+	if _, err := parseGUIDList(data[:20]); err != ErrVariableCorrupted {
+		t.Errorf("parseGUIDList(truncated) = %v; want ErrVariableCorrupted", err)
+	}
+}
+
+func TestIsOSRecoveryName(t *testing.T) {
+	for name, want := range map[string]bool{
+		"OsRecovery0000":  true,
+		"OsRecovery00A1":  true,
+		"OsRecoveryOrder": false,
+		"Boot0000":        false,
+	} {
+		if got := isOSRecoveryName(name); got != want {
+			t.Errorf("isOSRecoveryName(%q) = %v; want %v", name, got, want)
+		}
+	}
+}
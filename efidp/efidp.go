@@ -0,0 +1,333 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package efidp parses and formats UEFI device paths in pure Go, without
+// libefivar. It is intended for tools which decode data captured elsewhere;
+// the text it produces follows the UEFI specification's device path text
+// representation, as libefivar's does, for the common node types.
+package efidp
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalid = errors.New("efidp: device path is not valid")
+
+// Node types.
+const (
+	HardwareType  = 0x01
+	ACPIType      = 0x02
+	MessagingType = 0x03
+	MediaType     = 0x04
+	BIOSBootType  = 0x05
+	EndType       = 0x7f
+)
+
+// End node subtypes.
+const (
+	EndInstanceSubType = 0x01
+	EndEntireSubType   = 0xff
+)
+
+const nodeHeaderSize = 4
+
+var byteOrder = binary.LittleEndian
+
+// Node is a single device path node.
+type Node struct {
+	Type    uint8
+	SubType uint8
+	// Data is the content of the node following its four-byte header.
+	Data []byte
+}
+
+// DevicePath is a sequence of nodes, including the terminating End node.
+type DevicePath []Node
+
+// Parse parses the binary device path at the start of b. Any bytes after the
+// End Entire node are ignored.
+func Parse(b []byte) (DevicePath, error) {
+	var dp DevicePath
+	for {
+		if len(b) < nodeHeaderSize {
+			return nil, ErrInvalid
+		}
+		n := int(byteOrder.Uint16(b[2:4]))
+		if n < nodeHeaderSize || n > len(b) {
+			return nil, ErrInvalid
+		}
+		node := Node{Type: b[0], SubType: b[1], Data: append([]byte(nil), b[nodeHeaderSize:n]...)}
+		dp = append(dp, node)
+		if node.Type == EndType && node.SubType == EndEntireSubType {
+			return dp, nil
+		}
+		b = b[n:]
+	}
+}
+
+func (n Node) Bytes() []byte {
+	b := make([]byte, nodeHeaderSize+len(n.Data))
+	b[0], b[1] = n.Type, n.SubType
+	byteOrder.PutUint16(b[2:4], uint16(len(b)))
+	copy(b[nodeHeaderSize:], n.Data)
+	return b
+}
+
+func (dp DevicePath) Bytes() []byte {
+	var b []byte
+	for _, n := range dp {
+		b = append(b, n.Bytes()...)
+	}
+	return b
+}
+
+// String formats dp as text, such as
+// HD(1,GPT,41c147b6-e9bf-4c27-81c6-174026e79fd0,0x800,0x3a9800)/File(\vmlinuz-linux).
+func (dp DevicePath) String() string {
+	var buf strings.Builder
+	sep := ""
+	for _, n := range dp {
+		if n.Type == EndType {
+			if n.SubType == EndInstanceSubType {
+				sep = ","
+				buf.WriteString(sep)
+				sep = ""
+			}
+			continue
+		}
+		buf.WriteString(sep)
+		buf.WriteString(n.String())
+		sep = "/"
+	}
+	return buf.String()
+}
+
+// Find returns the first node of the given type and subtype.
+func (dp DevicePath) Find(typ, subType uint8) (Node, bool) {
+	for _, n := range dp {
+		if n.Type == typ && n.SubType == subType {
+			return n, true
+		}
+	}
+	return Node{}, false
+}
+
+// FilePath returns the path named by the File node, such as
+// \EFI\BOOT\BOOTX64.EFI, or an empty string if there is none.
+func (dp DevicePath) FilePath() string {
+	n, ok := dp.Find(MediaType, mediaFilePath)
+	if !ok {
+		return ""
+	}
+	return decodeUCS2(n.Data)
+}
+
+// guid decodes a GUID stored in the mixed-endian EFI layout.
+func guid(b []byte) uuid.UUID {
+	var u uuid.UUID
+	copy(u[:], b)
+	u[0], u[1], u[2], u[3] = u[3], u[2], u[1], u[0]
+	u[4], u[5] = u[5], u[4]
+	u[6], u[7] = u[7], u[6]
+	return u
+}
+
+// decodeUCS2 decodes a NUL-terminated UCS-2 string.
+func decodeUCS2(b []byte) string {
+	d16 := make([]uint16, 0, len(b)/2)
+	for n := 0; n+1 < len(b); n += 2 {
+		c := byteOrder.Uint16(b[n:])
+		if c == 0 {
+			break
+		}
+		d16 = append(d16, c)
+	}
+	return string(utf16.Decode(d16))
+}
+
+// Hardware node subtypes.
+const (
+	hwPCI    = 0x01
+	hwVendor = 0x04
+)
+
+// ACPI node subtypes.
+const (
+	acpiDevice = 0x01
+)
+
+// Messaging node subtypes.
+const (
+	msgATAPI  = 0x01
+	msgSCSI   = 0x02
+	msgUSB    = 0x05
+	msgVendor = 0x0a
+	msgMAC    = 0x0b
+	msgIPv4   = 0x0c
+	msgIPv6   = 0x0d
+	msgSATA   = 0x12
+	msgNVMe   = 0x17
+	msgURI    = 0x18
+)
+
+// Media node subtypes.
+const (
+	mediaHardDrive    = 0x01
+	mediaCDROM        = 0x02
+	mediaVendor       = 0x03
+	mediaFilePath     = 0x04
+	mediaFvFile       = 0x06
+	mediaFv           = 0x07
+	mediaOffsetRange  = 0x08
+	mediaPartitionMBR = 0x01
+	mediaPartitionGPT = 0x02
+)
+
+// String formats a single node.
+func (n Node) String() string {
+	if s, ok := n.format(); ok {
+		return s
+	}
+	return fmt.Sprintf("Path(%d,%d,%s)", n.Type, n.SubType, hex.EncodeToString(n.Data))
+}
+
+// format formats the node types it knows, returning false for any other
+// node or for a node too short for its type.
+func (n Node) format() (string, bool) {
+	d := n.Data
+	has := func(size int) bool { return len(d) >= size }
+	u16 := func(off int) uint16 { return byteOrder.Uint16(d[off:]) }
+	u32 := func(off int) uint32 { return byteOrder.Uint32(d[off:]) }
+	u64 := func(off int) uint64 { return byteOrder.Uint64(d[off:]) }
+
+	switch n.Type {
+	case HardwareType:
+		switch {
+		case n.SubType == hwPCI && has(2):
+			return fmt.Sprintf("Pci(0x%x,0x%x)", d[1], d[0]), true
+		case n.SubType == hwVendor && has(16):
+			return vendor("VenHw", d), true
+		}
+	case ACPIType:
+		if n.SubType == acpiDevice && has(8) {
+			hid, uid := u32(0), u32(4)
+			switch hid {
+			case 0x0a0341d0:
+				return fmt.Sprintf("PciRoot(0x%x)", uid), true
+			case 0x0a0841d0:
+				return fmt.Sprintf("PcieRoot(0x%x)", uid), true
+			}
+			return fmt.Sprintf("Acpi(%s,0x%x)", eisaID(hid), uid), true
+		}
+	case MessagingType:
+		switch {
+		case n.SubType == msgATAPI && has(4):
+			return fmt.Sprintf("Ata(%d,%d,%d)", d[0], d[1], u16(2)), true
+		case n.SubType == msgSCSI && has(4):
+			return fmt.Sprintf("Scsi(%d,%d)", u16(0), u16(2)), true
+		case n.SubType == msgUSB && has(2):
+			return fmt.Sprintf("USB(%d,%d)", d[0], d[1]), true
+		case n.SubType == msgVendor && has(16):
+			return vendor("VenMsg", d), true
+		case n.SubType == msgMAC && has(33):
+			size := 6
+			if d[32] != 0x00 && d[32] != 0x01 {
+				size = 32
+			}
+			return fmt.Sprintf("MAC(%s,0x%x)", hex.EncodeToString(d[:size]), d[32]), true
+		case n.SubType == msgIPv4 && has(15):
+			return fmt.Sprintf("IPv4(%v,%v,%v)", net.IP(d[4:8]), protocol(u16(12)), net.IP(d[0:4])), true
+		case n.SubType == msgIPv6 && has(38):
+			return fmt.Sprintf("IPv6(%v,%v,%v)", net.IP(d[16:32]), protocol(u16(36)), net.IP(d[0:16])), true
+		case n.SubType == msgSATA && has(6):
+			return fmt.Sprintf("Sata(%d,%d,%d)", u16(0), u16(2), u16(4)), true
+		case n.SubType == msgNVMe && has(12):
+			eui := make([]string, 8)
+			for i := range eui {
+				eui[i] = fmt.Sprintf("%02x", d[4+i])
+			}
+			return fmt.Sprintf("NVMe(0x%x,%s)", u32(0), strings.Join(eui, "-")), true
+		case n.SubType == msgURI:
+			return fmt.Sprintf("Uri(%s)", d), true
+		}
+	case MediaType:
+		switch {
+		case n.SubType == mediaHardDrive && has(38):
+			part, start, size := u32(0), u64(4), u64(12)
+			sig := d[20:36]
+			switch d[37] {
+			case mediaPartitionGPT:
+				return fmt.Sprintf("HD(%d,GPT,%v,0x%x,0x%x)", part, guid(sig), start, size), true
+			case mediaPartitionMBR:
+				return fmt.Sprintf("HD(%d,MBR,0x%08x,0x%x,0x%x)", part, byteOrder.Uint32(sig), start, size), true
+			}
+			return fmt.Sprintf("HD(%d,%d,0,0x%x,0x%x)", part, d[37], start, size), true
+		case n.SubType == mediaCDROM && has(20):
+			return fmt.Sprintf("CDROM(%d,0x%x,0x%x)", u32(0), u64(4), u64(12)), true
+		case n.SubType == mediaVendor && has(16):
+			return vendor("VenMedia", d), true
+		case n.SubType == mediaFilePath:
+			return fmt.Sprintf("File(%s)", decodeUCS2(d)), true
+		case n.SubType == mediaFvFile && has(16):
+			return fmt.Sprintf("FvFile(%v)", guid(d)), true
+		case n.SubType == mediaFv && has(16):
+			return fmt.Sprintf("Fv(%v)", guid(d)), true
+		case n.SubType == mediaOffsetRange && has(20):
+			return fmt.Sprintf("Offset(0x%x,0x%x)", u64(4), u64(12)), true
+		}
+	case BIOSBootType:
+		if has(4) {
+			return fmt.Sprintf("BBS(%d,%s,0x%x)", u16(0), decodeASCIIZ(d[4:]), u16(2)), true
+		}
+	}
+	return "", false
+}
+
+func vendor(name string, d []byte) string {
+	if len(d) == 16 {
+		return fmt.Sprintf("%s(%v)", name, guid(d))
+	}
+	return fmt.Sprintf("%s(%v,%s)", name, guid(d), hex.EncodeToString(d[16:]))
+}
+
+// eisaID formats a compressed EISA ID such as PNP0A03.
+func eisaID(id uint32) string {
+	return fmt.Sprintf("%c%c%c%04X",
+		'@'+byte(id>>10&0x1f), '@'+byte(id>>5&0x1f), '@'+byte(id&0x1f), id>>16)
+}
+
+func protocol(p uint16) string {
+	switch p {
+	case 6:
+		return "TCP"
+	case 17:
+		return "UDP"
+	}
+	return fmt.Sprintf("0x%x", p)
+}
+
+func decodeASCIIZ(b []byte) string {
+	if i := strings.IndexByte(string(b), 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
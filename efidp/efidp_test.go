@@ -0,0 +1,91 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efidp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func mustDecodeString(s string) []byte {
+	bs, err := hex.DecodeString(strings.Replace(s, "\n", "", -1))
+	if err != nil {
+		panic(err)
+	}
+	return bs
+}
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		hex      string
+		want     string
+		filePath string
+	}{
+		{
+			// The file path of an efibootmgr-created Arch Linux boot option.
+			hex: `04012a0001000000000800000000000000983a0000000000b647c141bfe9274c81c6174026e79fd00202
+04042200
+5c0076006d006c0069006e0075007a002d006c0069006e00750078000000
+7fff0400`,
+			want:     `HD(1,GPT,41c147b6-e9bf-4c27-81c6-174026e79fd0,0x800,0x3a9800)/File(\vmlinuz-linux)`,
+			filePath: `\vmlinuz-linux`,
+		},
+		{
+			hex:  `02010c00d041030a00000000 01010600021f 03120a000000ffff0000 7fff0400`,
+			want: `PciRoot(0x0)/Pci(0x1f,0x2)/Sata(0,65535,0)`,
+		},
+		{
+			hex:  `02010c00d041030a00000000 0101060000 1c 7f010400 02010c00d041030a01000000 7fff0400`,
+			want: `PciRoot(0x0)/Pci(0x1c,0x0),PciRoot(0x1)`,
+		},
+		{
+			hex:  `cc01060001ff 7fff0400`,
+			want: `Path(204,1,01ff)`,
+		},
+	} {
+		b := mustDecodeString(strings.Replace(tc.hex, " ", "", -1))
+		dp, err := Parse(append(b, "trailing"...))
+		if err != nil {
+			t.Errorf("Parse(%v): %v", tc.hex, err)
+			continue
+		}
+		if got := dp.String(); got != tc.want {
+			t.Errorf("Parse(%v).String() = %q; want %q", tc.hex, got, tc.want)
+		}
+		if got := dp.FilePath(); got != tc.filePath {
+			t.Errorf("Parse(%v).FilePath() = %q; want %q", tc.hex, got, tc.filePath)
+		}
+		if got := dp.Bytes(); !bytes.Equal(got, b) {
+			t.Errorf("Parse(%v).Bytes() = %x", tc.hex, got)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"040104",
+		"0401ff002a",
+		"04010200",
+		// Missing End node.
+		"01010600021f",
+	} {
+		if _, err := Parse(mustDecodeString(s)); err == nil {
+			t.Errorf("Parse(%v) succeeded", s)
+		}
+	}
+}
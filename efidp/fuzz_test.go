@@ -0,0 +1,52 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package efidp
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func FuzzParse(f *testing.F) {
+	for _, s := range []string{
+		`04012a0001000000000800000000000000983a0000000000b647c141bfe9274c81c6174026e79fd0020204042200` +
+			`5c0076006d006c0069006e0075007a002d006c0069006e00750078000000` + `7fff0400`,
+		`02010c00d041030a00000000 01010600021f 03120a000000ffff0000 7fff0400`,
+		`02010c00d041030a00000000 01010600001c 7f010400 02010c00d041030a01000000 7fff0400`,
+		`02010c00d041030a00000000 01010600061f 030b2500020000000001000000000000000000000000000000000000000000000000000001 030c1b00000000000000000000000000060000000000000000000000000000 7fff0400`,
+		`0304180000000000000000000000000000000000 05010e0002000000486172640000 7fff0400`,
+	} {
+		f.Add(mustDecodeString(strings.Replace(s, " ", "", -1)))
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		dp, err := Parse(b)
+		if err != nil {
+			return
+		}
+		_ = dp.String()
+		_ = dp.FilePath()
+		got, err := Parse(dp.Bytes())
+		if err != nil {
+			t.Fatalf("Parse(Parse(%x).Bytes()): %v", b, err)
+		}
+		if !reflect.DeepEqual(got, dp) {
+			t.Errorf("Parse(Parse(%x).Bytes()) = %v; want %v", b, got, dp)
+		}
+	})
+}
@@ -0,0 +1,356 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package efipb encodes variables, boot options and device paths in the
+// protobuf wire format defined by efivar.proto, so that goefivard and other
+// RPC services share one format. The message types follow the schema
+// field for field; the New functions and EFI methods convert them to and
+// from this module's types.
+package efipb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lukegb/goefivar/efidp"
+	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/loadopt"
+)
+
+// VariableName is the goefivar.VariableName message.
+type VariableName struct {
+	GUID string
+	Name string
+}
+
+func NewVariableName(vn efivar.VariableName) *VariableName {
+	return &VariableName{GUID: vn.GUID.String(), Name: vn.Name}
+}
+
+// EFI returns m as an efivar.VariableName.
+func (m *VariableName) EFI() (efivar.VariableName, error) {
+	u, err := uuid.Parse(m.GUID)
+	if err != nil {
+		return efivar.VariableName{}, fmt.Errorf("efipb: %v", err)
+	}
+	return efivar.VariableName{GUID: u, Name: m.Name}, nil
+}
+
+func (m *VariableName) Marshal() []byte {
+	var e encoder
+	e.string(1, m.GUID)
+	e.string(2, m.Name)
+	return e.b
+}
+
+func (m *VariableName) Unmarshal(b []byte) error {
+	*m = VariableName{}
+	return decode(b, func(f field) (err error) {
+		switch f.num {
+		case 1:
+			m.GUID, err = f.string()
+		case 2:
+			m.Name, err = f.string()
+		}
+		return err
+	})
+}
+
+// Variable is the goefivar.Variable message.
+type Variable struct {
+	Name       *VariableName
+	Attributes uint32
+	Data       []byte
+}
+
+func NewVariable(v *efivar.Variable) *Variable {
+	return &Variable{
+		Name:       NewVariableName(v.VariableName),
+		Attributes: uint32(v.Attributes),
+		Data:       v.Data,
+	}
+}
+
+// EFI returns m as an efivar.Variable.
+func (m *Variable) EFI() (*efivar.Variable, error) {
+	if m.Name == nil {
+		return nil, fmt.Errorf("efipb: variable has no name")
+	}
+	vn, err := m.Name.EFI()
+	if err != nil {
+		return nil, err
+	}
+	return &efivar.Variable{VariableName: vn, Attributes: efivar.Attributes(m.Attributes), Data: m.Data}, nil
+}
+
+func (m *Variable) Marshal() []byte {
+	var e encoder
+	if m.Name != nil {
+		e.message(1, m.Name)
+	}
+	e.uint(2, uint64(m.Attributes))
+	e.bytes(3, m.Data)
+	return e.b
+}
+
+func (m *Variable) Unmarshal(b []byte) error {
+	*m = Variable{}
+	return decode(b, func(f field) (err error) {
+		switch f.num {
+		case 1:
+			m.Name = new(VariableName)
+			err = f.message(m.Name)
+		case 2:
+			m.Attributes, err = f.uint32()
+		case 3:
+			m.Data, err = f.bytes()
+		}
+		return err
+	})
+}
+
+// VariableNames is the goefivar.VariableNames message.
+type VariableNames struct {
+	Names []*VariableName
+}
+
+// Marshal encodes m. Nil names are left out.
+func (m *VariableNames) Marshal() []byte {
+	var e encoder
+	for _, n := range m.Names {
+		if n != nil {
+			e.message(1, n)
+		}
+	}
+	return e.b
+}
+
+func (m *VariableNames) Unmarshal(b []byte) error {
+	*m = VariableNames{}
+	return decode(b, func(f field) error {
+		if f.num != 1 {
+			return nil
+		}
+		n := new(VariableName)
+		m.Names = append(m.Names, n)
+		return f.message(n)
+	})
+}
+
+// DevicePathNode is the goefivar.DevicePath.Node message.
+type DevicePathNode struct {
+	Type    uint32
+	SubType uint32
+	Data    []byte
+}
+
+func (m *DevicePathNode) Marshal() []byte {
+	var e encoder
+	e.uint(1, uint64(m.Type))
+	e.uint(2, uint64(m.SubType))
+	e.bytes(3, m.Data)
+	return e.b
+}
+
+func (m *DevicePathNode) Unmarshal(b []byte) error {
+	*m = DevicePathNode{}
+	return decode(b, func(f field) (err error) {
+		switch f.num {
+		case 1:
+			m.Type, err = f.uint32()
+		case 2:
+			m.SubType, err = f.uint32()
+		case 3:
+			m.Data, err = f.bytes()
+		}
+		return err
+	})
+}
+
+// DevicePath is the goefivar.DevicePath message. Text is informational
+// and carries the libefivar rendering of the path when known.
+type DevicePath struct {
+	Nodes []*DevicePathNode
+	Text  string
+}
+
+func NewDevicePath(dp efidp.DevicePath) *DevicePath {
+	m := &DevicePath{Text: dp.String()}
+	for _, n := range dp {
+		m.Nodes = append(m.Nodes, &DevicePathNode{Type: uint32(n.Type), SubType: uint32(n.SubType), Data: n.Data})
+	}
+	return m
+}
+
+// EFI returns m as an efidp.DevicePath. Text is ignored.
+func (m *DevicePath) EFI() (efidp.DevicePath, error) {
+	dp := make(efidp.DevicePath, len(m.Nodes))
+	for i, n := range m.Nodes {
+		if n.Type > 0xff || n.SubType > 0xff {
+			return nil, fmt.Errorf("efipb: device path node type %d.%d out of range", n.Type, n.SubType)
+		}
+		dp[i] = efidp.Node{Type: uint8(n.Type), SubType: uint8(n.SubType), Data: n.Data}
+	}
+	return dp, nil
+}
+
+// Marshal encodes m. Nil nodes are left out.
+func (m *DevicePath) Marshal() []byte {
+	var e encoder
+	for _, n := range m.Nodes {
+		if n != nil {
+			e.message(1, n)
+		}
+	}
+	e.string(2, m.Text)
+	return e.b
+}
+
+func (m *DevicePath) Unmarshal(b []byte) error {
+	*m = DevicePath{}
+	return decode(b, func(f field) (err error) {
+		switch f.num {
+		case 1:
+			n := new(DevicePathNode)
+			m.Nodes = append(m.Nodes, n)
+			err = f.message(n)
+		case 2:
+			m.Text, err = f.string()
+		}
+		return err
+	})
+}
+
+// LoadOption is the goefivar.LoadOption message.
+type LoadOption struct {
+	Attributes   uint32
+	Description  string
+	FilePath     *DevicePath
+	FilePathList []byte
+	OptionalData []byte
+}
+
+func NewLoadOption(lo *loadopt.LoadOption) *LoadOption {
+	return &LoadOption{
+		Attributes:   lo.Attributes,
+		Description:  lo.Description,
+		FilePath:     NewDevicePath(lo.FilePath),
+		FilePathList: lo.FilePathList,
+		OptionalData: lo.OptionalData,
+	}
+}
+
+// EFI returns m as a loadopt.LoadOption.
+func (m *LoadOption) EFI() (*loadopt.LoadOption, error) {
+	lo := &loadopt.LoadOption{
+		Attributes:   m.Attributes,
+		Description:  m.Description,
+		FilePathList: m.FilePathList,
+		OptionalData: m.OptionalData,
+	}
+	if m.FilePath != nil {
+		dp, err := m.FilePath.EFI()
+		if err != nil {
+			return nil, err
+		}
+		lo.FilePath = dp
+	}
+	return lo, nil
+}
+
+func (m *LoadOption) Marshal() []byte {
+	var e encoder
+	e.uint(1, uint64(m.Attributes))
+	e.string(2, m.Description)
+	if m.FilePath != nil {
+		e.message(3, m.FilePath)
+	}
+	e.bytes(4, m.FilePathList)
+	e.bytes(5, m.OptionalData)
+	return e.b
+}
+
+func (m *LoadOption) Unmarshal(b []byte) error {
+	*m = LoadOption{}
+	return decode(b, func(f field) (err error) {
+		switch f.num {
+		case 1:
+			m.Attributes, err = f.uint32()
+		case 2:
+			m.Description, err = f.string()
+		case 3:
+			m.FilePath = new(DevicePath)
+			err = f.message(m.FilePath)
+		case 4:
+			m.FilePathList, err = f.bytes()
+		case 5:
+			m.OptionalData, err = f.bytes()
+		}
+		return err
+	})
+}
+
+// BootOption is the goefivar.BootOption message.
+type BootOption struct {
+	Number     uint32
+	Variable   *Variable
+	LoadOption *LoadOption
+}
+
+// NewBootOption decodes the Boot#### variable v.
+func NewBootOption(v *efivar.Variable) (*BootOption, error) {
+	if v.GUID != efivar.GlobalUUID || len(v.Name) != len("Boot0000") || !strings.HasPrefix(v.Name, "Boot") {
+		return nil, fmt.Errorf("efipb: %v is not a boot option", v.Name)
+	}
+	n, err := strconv.ParseUint(v.Name[4:], 16, 16)
+	if err != nil {
+		return nil, fmt.Errorf("efipb: %v is not a boot option", v.Name)
+	}
+	lo, err := loadopt.Parse(v.Data)
+	if err != nil {
+		return nil, err
+	}
+	return &BootOption{Number: uint32(n), Variable: NewVariable(v), LoadOption: NewLoadOption(lo)}, nil
+}
+
+func (m *BootOption) Marshal() []byte {
+	var e encoder
+	e.uint(1, uint64(m.Number))
+	if m.Variable != nil {
+		e.message(2, m.Variable)
+	}
+	if m.LoadOption != nil {
+		e.message(3, m.LoadOption)
+	}
+	return e.b
+}
+
+func (m *BootOption) Unmarshal(b []byte) error {
+	*m = BootOption{}
+	return decode(b, func(f field) (err error) {
+		switch f.num {
+		case 1:
+			m.Number, err = f.uint32()
+		case 2:
+			m.Variable = new(Variable)
+			err = f.message(m.Variable)
+		case 3:
+			m.LoadOption = new(LoadOption)
+			err = f.message(m.LoadOption)
+		}
+		return err
+	})
+}
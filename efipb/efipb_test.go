@@ -0,0 +1,243 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efipb
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/lukegb/goefivar/efidp"
+	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/loadopt"
+)
+
+func TestVariableNameEncoding(t *testing.T) {
+	// Field 1 (tag 0x0a), 36 bytes; field 2 (tag 0x12), 7 bytes.
+	m := &VariableName{GUID: "8be4df61-93ca-11d2-aa0d-00e098032b8c", Name: "Timeout"}
+	want := "0a2438626534646636312d393363612d313164322d616130642d303065303938303332623863" + "120754696d656f7574"
+	if got := hex.EncodeToString(m.Marshal()); got != want {
+		t.Errorf("Marshal() = %v; want %v", got, want)
+	}
+}
+
+func TestVariableRoundTrip(t *testing.T) {
+	v := &efivar.Variable{
+		VariableName: efivar.VariableName{GUID: efivar.GlobalUUID, Name: "BootOrder"},
+		Attributes:   efivar.NonVolatile | efivar.BootserviceAccess | efivar.RuntimeAccess,
+		Data:         []byte{0x0c, 0, 0, 0},
+	}
+	var m Variable
+	if err := m.Unmarshal(NewVariable(v).Marshal()); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	got, err := m.EFI()
+	if err != nil {
+		t.Fatalf("EFI: %v", err)
+	}
+	if !reflect.DeepEqual(got, v) {
+		t.Errorf("round trip = %+v; want %+v", got, v)
+	}
+}
+
+func TestVariableNames(t *testing.T) {
+	in := &VariableNames{Names: []*VariableName{{GUID: "a", Name: "A"}, {GUID: "b", Name: "B"}, {}}}
+	var out VariableNames
+	if err := out.Unmarshal(in.Marshal()); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if !reflect.DeepEqual(&out, in) {
+		t.Errorf("round trip = %+v; want %+v", out, in)
+	}
+}
+
+func TestBootOption(t *testing.T) {
+	b, _ := hex.DecodeString("02010c00d041030a00000000" + "0101060000" + "1c" + "7fff0400")
+	dp, err := efidp.Parse(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lo := &loadopt.LoadOption{
+		Attributes:   loadopt.Active,
+		Description:  "Network",
+		FilePath:     dp,
+		FilePathList: b,
+		OptionalData: []byte("args"),
+	}
+	v := &efivar.Variable{
+		VariableName: efivar.VariableName{GUID: efivar.GlobalUUID, Name: "Boot000A"},
+		Attributes:   efivar.NonVolatile,
+		Data:         lo.Bytes(),
+	}
+	bo, err := NewBootOption(v)
+	if err != nil {
+		t.Fatalf("NewBootOption: %v", err)
+	}
+	if bo.Number != 0xa || bo.LoadOption.FilePath.Text != "PciRoot(0x0)/Pci(0x1c,0x0)" {
+		t.Errorf("NewBootOption = number %v, path %q", bo.Number, bo.LoadOption.FilePath.Text)
+	}
+	var m BootOption
+	if err := m.Unmarshal(bo.Marshal()); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if !reflect.DeepEqual(&m, bo) {
+		t.Errorf("round trip = %+v; want %+v", m, bo)
+	}
+	got, err := m.LoadOption.EFI()
+	if err != nil {
+		t.Fatalf("EFI: %v", err)
+	}
+	if !bytes.Equal(got.Bytes(), v.Data) {
+		t.Errorf("EFI().Bytes() = %x; want %x", got.Bytes(), v.Data)
+	}
+
+	for _, name := range []string{"BootOrder", "Boot00G0", "Driver0001"} {
+		v := &efivar.Variable{VariableName: efivar.VariableName{GUID: efivar.GlobalUUID, Name: name}, Data: v.Data}
+		if _, err := NewBootOption(v); err == nil {
+			t.Errorf("NewBootOption(%v) succeeded", name)
+		}
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		hex  string
+		ok   bool
+	}{
+		{"unknown fields", "0a0161" + "1801" + "2201ff" + "2d00000000" + "290000000000000000" + "120142", true},
+		{"truncated tag", "80", false},
+		{"truncated length", "0a05ab", false},
+		{"wrong wire type", "0801", false},
+		{"group", "0b", false},
+	} {
+		b, _ := hex.DecodeString(tc.hex)
+		var m VariableName
+		if err := m.Unmarshal(b); (err == nil) != tc.ok {
+			t.Errorf("%v: Unmarshal(%v) = %v; want ok %v", tc.name, tc.hex, err, tc.ok)
+		}
+	}
+	var m VariableName
+	m.Unmarshal([]byte("\x0a\x01a\x18\x01\x12\x01B"))
+	if m.GUID != "a" || m.Name != "B" {
+		t.Errorf("Unmarshal skipping unknown fields = %+v", m)
+	}
+}
+
+// goldens are the messages in testdata. Each NAME.bin there is what
+// protoc --encode makes of NAME.txtpb, which TestGoldenProtoc checks when
+// protoc is installed, so the hand-written codec is held to efivar.proto.
+var goldens = []struct {
+	name, typ string
+	m         interface {
+		Marshal() []byte
+		Unmarshal([]byte) error
+	}
+}{
+	{"variable_name", "VariableName", &VariableName{GUID: "8be4df61-93ca-11d2-aa0d-00e098032b8c", Name: "Timeout"}},
+	{"variable", "Variable", &Variable{
+		Name:       &VariableName{GUID: "8be4df61-93ca-11d2-aa0d-00e098032b8c", Name: "BootOrder"},
+		Attributes: 0x80000007,
+		Data:       []byte{0x0c, 0, 1, 0},
+	}},
+	{"variable_names", "VariableNames", &VariableNames{Names: []*VariableName{
+		{GUID: "8be4df61-93ca-11d2-aa0d-00e098032b8c", Name: "Timeout"},
+		{GUID: "605dab50-e046-4300-abb6-3dd810dd8b23", Name: "MokListRT"},
+		{},
+	}}},
+	{"device_path", "DevicePath", &DevicePath{
+		Nodes: []*DevicePathNode{
+			{Type: 2, SubType: 1, Data: []byte{0xd0, 0x41, 0x03, 0x0a, 0, 0, 0, 0}},
+			{Type: 1, SubType: 1, Data: []byte{0, 0x1c}},
+			{Type: 0x7f, SubType: 0xff},
+		},
+		Text: "PciRoot(0x0)/Pci(0x1c,0x0)",
+	}},
+	{"boot_option", "BootOption", &BootOption{
+		Number: 1,
+		Variable: &Variable{
+			Name:       &VariableName{GUID: "8be4df61-93ca-11d2-aa0d-00e098032b8c", Name: "Boot0001"},
+			Attributes: 7,
+			Data:       []byte{1, 0},
+		},
+		LoadOption: &LoadOption{
+			Attributes:   1,
+			Description:  "Network",
+			FilePath:     &DevicePath{Nodes: []*DevicePathNode{{Type: 0x7f, SubType: 0xff}}, Text: "End"},
+			FilePathList: []byte{0x7f, 0xff, 4, 0},
+			OptionalData: []byte("args"),
+		},
+	}},
+}
+
+func TestGolden(t *testing.T) {
+	for _, g := range goldens {
+		want, err := ioutil.ReadFile(filepath.Join("testdata", g.name+".bin"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := g.m.Marshal(); !bytes.Equal(got, want) {
+			t.Errorf("%v: Marshal() = %x; want %x", g.name, got, want)
+		}
+		m := reflect.New(reflect.TypeOf(g.m).Elem()).Interface().(interface{ Unmarshal([]byte) error })
+		if err := m.Unmarshal(want); err != nil || !reflect.DeepEqual(m, g.m) {
+			t.Errorf("%v: Unmarshal = %+v, %v; want %+v", g.name, m, err, g.m)
+		}
+	}
+}
+
+func TestGoldenProtoc(t *testing.T) {
+	protoc, err := exec.LookPath("protoc")
+	if err != nil {
+		t.Skip("protoc is not installed")
+	}
+	for _, g := range goldens {
+		in, err := os.Open(filepath.Join("testdata", g.name+".txtpb"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer in.Close()
+		cmd := exec.Command(protoc, "--proto_path=.", "--encode=goefivar."+g.typ, "efivar.proto")
+		cmd.Stdin = in
+		cmd.Stderr = os.Stderr
+		got, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("%v: protoc: %v", g.name, err)
+		}
+		want, err := ioutil.ReadFile(filepath.Join("testdata", g.name+".bin"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%v: protoc --encode gives %x; testdata has %x", g.name, got, want)
+		}
+	}
+}
+
+func TestMarshalNilElements(t *testing.T) {
+	names := &VariableNames{Names: []*VariableName{nil, {Name: "A"}}}
+	if got, want := names.Marshal(), (&VariableNames{Names: []*VariableName{{Name: "A"}}}).Marshal(); !bytes.Equal(got, want) {
+		t.Errorf("VariableNames with a nil name Marshal() = %x; want %x", got, want)
+	}
+	dp := &DevicePath{Nodes: []*DevicePathNode{{Type: 1}, nil}}
+	if got, want := dp.Marshal(), (&DevicePath{Nodes: []*DevicePathNode{{Type: 1}}}).Marshal(); !bytes.Equal(got, want) {
+		t.Errorf("DevicePath with a nil node Marshal() = %x; want %x", got, want)
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efipb
+
+import (
+	"encoding/binary"
+
+	"github.com/lukegb/goefivar/efivar"
+)
+
+// ErrInvalid is returned when a message is not valid protobuf.
+var ErrInvalid = efivar.NewError(efivar.Corrupt, "efipb: message is not valid")
+
+// Wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+// encoder appends fields to a message. As in proto3, fields holding their
+// zero value are left out.
+type encoder struct {
+	b []byte
+}
+
+func (e *encoder) tag(field, wire int) {
+	e.b = appendUvarint(e.b, uint64(field)<<3|uint64(wire))
+}
+
+func (e *encoder) uint(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	e.tag(field, wireVarint)
+	e.b = appendUvarint(e.b, v)
+}
+
+func (e *encoder) bytes(field int, b []byte) {
+	if len(b) == 0 {
+		return
+	}
+	e.tag(field, wireBytes)
+	e.b = appendUvarint(e.b, uint64(len(b)))
+	e.b = append(e.b, b...)
+}
+
+func (e *encoder) string(field int, s string) { e.bytes(field, []byte(s)) }
+
+// message appends m, even if it is empty, unless it is nil.
+func (e *encoder) message(field int, m message) {
+	if m == nil {
+		return
+	}
+	b := m.Marshal()
+	e.tag(field, wireBytes)
+	e.b = appendUvarint(e.b, uint64(len(b)))
+	e.b = append(e.b, b...)
+}
+
+type message interface {
+	Marshal() []byte
+	Unmarshal([]byte) error
+}
+
+// field is one field of a message being decoded. For wireBytes, data is
+// the content; otherwise value is.
+type field struct {
+	num   int
+	wire  int
+	value uint64
+	data  []byte
+}
+
+func (f field) uint32() (uint32, error) {
+	if f.wire != wireVarint {
+		return 0, ErrInvalid
+	}
+	return uint32(f.value), nil
+}
+
+func (f field) bytes() ([]byte, error) {
+	if f.wire != wireBytes {
+		return nil, ErrInvalid
+	}
+	return append([]byte(nil), f.data...), nil
+}
+
+func (f field) string() (string, error) {
+	if f.wire != wireBytes {
+		return "", ErrInvalid
+	}
+	return string(f.data), nil
+}
+
+func (f field) message(m message) error {
+	if f.wire != wireBytes {
+		return ErrInvalid
+	}
+	return m.Unmarshal(f.data)
+}
+
+// decode calls fn for each field of b. Fields fn does not know should be
+// ignored, as protobuf requires.
+func decode(b []byte, fn func(field) error) error {
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 || tag>>3 == 0 {
+			return ErrInvalid
+		}
+		b = b[n:]
+		f := field{num: int(tag >> 3), wire: int(tag & 7)}
+		switch f.wire {
+		case wireVarint:
+			if f.value, n = binary.Uvarint(b); n <= 0 {
+				return ErrInvalid
+			}
+			b = b[n:]
+		case wireFixed64:
+			if len(b) < 8 {
+				return ErrInvalid
+			}
+			f.value, b = binary.LittleEndian.Uint64(b), b[8:]
+		case wireFixed32:
+			if len(b) < 4 {
+				return ErrInvalid
+			}
+			f.value, b = uint64(binary.LittleEndian.Uint32(b)), b[4:]
+		case wireBytes:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || l > uint64(len(b)-n) {
+				return ErrInvalid
+			}
+			f.data, b = b[n:n+int(l)], b[n+int(l):]
+		default:
+			return ErrInvalid
+		}
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efisig
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// AuditEntry is the result of checking one PE image against dbx.
+type AuditEntry struct {
+	Path    string
+	Revoked bool
+	Reason  string
+}
+
+type AuditOptions struct {
+	// DBX is the revocation database to check against, such as the
+	// Database of a proposed DBXUpdate. If nil, the current dbx is used.
+	DBX Database
+
+	// Roots are the directories to scan. If nil, every mounted ESP is scanned.
+	Roots []string
+}
+
+// AuditESP checks every PE image found on the mounted EFI System Partitions
+// against dbx, reporting which would be blocked. Files which are not PE images are skipped.
+func AuditESP(opts *AuditOptions) ([]*AuditEntry, error) {
+	if opts == nil {
+		opts = &AuditOptions{}
+	}
+	dbx := opts.DBX
+	if dbx == nil {
+		var err error
+		dbx, err = ReadDatabase(DBXName)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("efisig: reading dbx: %v", err)
+		}
+	}
+	roots := opts.Roots
+	if roots == nil {
+		roots = espMountpoints()
+	}
+
+	var out []*AuditEntry
+	for _, root := range roots {
+		err := filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) && path == root {
+					return filepath.SkipDir
+				}
+				return err
+			}
+			if !fi.Mode().IsRegular() {
+				return nil
+			}
+			e, err := auditFile(path, fi.Size(), dbx)
+			if err != nil {
+				return fmt.Errorf("efisig: auditing %v: %v", path, err)
+			}
+			if e != nil {
+				out = append(out, e)
+			}
+			return nil
+		})
+		if err != nil {
+			return out, err
+		}
+	}
+	return out, nil
+}
+
+func auditFile(path string, size int64, dbx Database) (*AuditEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	revoked, reason, err := CheckRevoked(f, size, dbx)
+	switch {
+	case err == ErrNotPEImage:
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	return &AuditEntry{Path: path, Revoked: revoked, Reason: reason}, nil
+}
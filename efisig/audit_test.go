@@ -0,0 +1,65 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efisig
+
+import (
+	"crypto"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAuditESP(t *testing.T) {
+	dir, err := ioutil.TempDir("", "efisig")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	boot := filepath.Join(dir, "EFI", "BOOT")
+	if err := os.MkdirAll(boot, 0755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	files := map[string][]byte{
+		filepath.Join(boot, "BOOTX64.EFI"): buildPE([]byte("revoked"), nil),
+		filepath.Join(boot, "grubx64.efi"): buildPE([]byte("fine"), nil),
+		filepath.Join(boot, "grub.cfg"):    []byte("set timeout=5\n"),
+	}
+	for p, data := range files {
+		if err := ioutil.WriteFile(p, data, 0644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+	revoked := filepath.Join(boot, "BOOTX64.EFI")
+	digest, err := FileDigest(revoked, crypto.SHA256)
+	if err != nil {
+		t.Fatalf("FileDigest: %v", err)
+	}
+	dbx := Database{{Type: CertSHA256UUID, Signatures: []SignatureData{{Owner: testOwner, Data: digest}}}}
+
+	entries, err := AuditESP(&AuditOptions{DBX: dbx, Roots: []string{dir, filepath.Join(dir, "missing")}})
+	if err != nil {
+		t.Fatalf("AuditESP: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("AuditESP returned %d entries; want 2", len(entries))
+	}
+	for _, e := range entries {
+		if want := e.Path == revoked; e.Revoked != want {
+			t.Errorf("%v: Revoked = %v; want %v", e.Path, e.Revoked, want)
+		}
+	}
+}
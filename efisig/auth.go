@@ -0,0 +1,143 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efisig
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/binary"
+	"time"
+	"unicode/utf16"
+
+	"github.com/google/uuid"
+	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/internal/zero"
+)
+
+// CertTypePKCS7UUID identifies a PKCS#7 SignedData in a WIN_CERTIFICATE_UEFI_GUID.
+var CertTypePKCS7UUID = uuid.MustParse("4aafd29d-68df-49ee-8aa9-347d375665a7")
+
+const (
+	winCertificateSize   = 4 + 2 + 2
+	winCertRevision      = 0x0200
+	winCertTypeEFIGUID   = 0x0ef1
+	authDescriptorHeader = efiTimeSize + winCertificateSize + 16
+)
+
+// AuthenticatedData is the content written to a time-based authenticated variable:
+// an EFI_VARIABLE_AUTHENTICATION_2 descriptor followed by the new variable data.
+type AuthenticatedData struct {
+	// Timestamp is used to prevent replay of older updates.
+	Timestamp EFITime
+
+	// CertType identifies the format of CertData, normally CertTypePKCS7UUID.
+	CertType uuid.UUID
+	CertData []byte
+
+	Payload []byte
+}
+
+func ParseAuthenticatedData(data []byte) (*AuthenticatedData, error) {
+	if len(data) < authDescriptorHeader {
+		return nil, ErrVariableCorrupted
+	}
+	ts, err := ParseEFITime(data)
+	if err != nil {
+		return nil, err
+	}
+	a := &AuthenticatedData{Timestamp: ts}
+
+	cert := data[efiTimeSize:]
+	length := int(byteOrder.Uint32(cert[0:4]))
+	if byteOrder.Uint16(cert[4:6]) != winCertRevision || byteOrder.Uint16(cert[6:8]) != winCertTypeEFIGUID {
+		return nil, ErrVariableCorrupted
+	}
+	if length < winCertificateSize+16 || length > len(cert) {
+		return nil, ErrVariableCorrupted
+	}
+	a.CertType = efivar.GUIDFromBytes(cert[winCertificateSize:])
+	a.CertData = append([]byte(nil), cert[winCertificateSize+16:length]...)
+	a.Payload = append([]byte(nil), cert[length:]...)
+	return a, nil
+}
+
+func (a *AuthenticatedData) Bytes() []byte {
+	var buf bytes.Buffer
+	buf.Write(a.Timestamp.Bytes())
+	binary.Write(&buf, byteOrder, uint32(winCertificateSize+16+len(a.CertData)))
+	binary.Write(&buf, byteOrder, uint16(winCertRevision))
+	binary.Write(&buf, byteOrder, uint16(winCertTypeEFIGUID))
+	buf.Write(efivar.GUIDBytes(a.CertType))
+	buf.Write(a.CertData)
+	buf.Write(a.Payload)
+	return buf.Bytes()
+}
+
+// signedContent returns the data covered by the signature of an authenticated variable write.
+func signedContent(vn efivar.VariableName, attrs efivar.Attributes, ts EFITime, data []byte) []byte {
+	var buf bytes.Buffer
+	// Allocate once, so that growing the buffer leaves no stray copies of
+	// data behind.
+	buf.Grow(4*len(vn.Name) + 16 + 4 + efiTimeSize + len(data))
+	for _, c := range utf16.Encode([]rune(vn.Name)) {
+		binary.Write(&buf, byteOrder, c)
+	}
+	buf.Write(efivar.GUIDBytes(vn.GUID))
+	binary.Write(&buf, byteOrder, uint32(attrs))
+	buf.Write(ts.Bytes())
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+// SignVariable produces the authenticated payload which writes data to vn with
+// the given attributes, signed by signer whose certificate is cert.
+func SignVariable(vn efivar.VariableName, attrs efivar.Attributes, timestamp time.Time, data []byte, cert *x509.Certificate, signer crypto.Signer) (*AuthenticatedData, error) {
+	ts := EFITimeFromTime(timestamp)
+	// The signed content is a copy of data, which may be secret until the
+	// firmware has it.
+	content := signedContent(vn, attrs, ts, data)
+	defer zero.Bytes(content)
+	sig, err := signDetached(content, cert, signer)
+	if err != nil {
+		return nil, err
+	}
+	return &AuthenticatedData{
+		Timestamp: ts,
+		CertType:  CertTypePKCS7UUID,
+		CertData:  sig,
+		Payload:   data,
+	}, nil
+}
+
+// UnsignedVariable produces an authenticated payload carrying no signature.
+// Firmware accepts these for the key databases only while in Setup Mode or custom mode.
+func UnsignedVariable(timestamp time.Time, data []byte) *AuthenticatedData {
+	return &AuthenticatedData{
+		Timestamp: EFITimeFromTime(timestamp),
+		CertType:  CertTypePKCS7UUID,
+		Payload:   data,
+	}
+}
+
+// writeAuthenticated performs an authenticated write of a to vn.
+func writeAuthenticated(vn efivar.VariableName, attrs efivar.Attributes, a *AuthenticatedData) error {
+	v := &efivar.Variable{
+		VariableName: vn,
+		Data:         a.Bytes(),
+		Attributes:   attrs,
+	}
+	return v.Set(0644)
+}
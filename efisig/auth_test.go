@@ -0,0 +1,40 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efisig
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestUnsignedVariableRoundtrip(t *testing.T) {
+	ts := time.Date(2021, 3, 2, 18, 0, 0, 0, time.UTC)
+	data := Database{{Type: CertSHA256UUID, Signatures: []SignatureData{{Owner: testOwner, Data: make([]byte, 32)}}}}.Bytes()
+	a := UnsignedVariable(ts, data)
+
+	got, err := ParseAuthenticatedData(a.Bytes())
+	if err != nil {
+		t.Fatalf("ParseAuthenticatedData: %v", err)
+	}
+	want := &AuthenticatedData{
+		Timestamp: EFITime{Year: 2021, Month: 3, Day: 2, Hour: 18},
+		CertType:  CertTypePKCS7UUID,
+		Payload:   data,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseAuthenticatedData(UnsignedVariable(...).Bytes()) = %+v; want %+v", got, want)
+	}
+}
@@ -0,0 +1,221 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efisig
+
+import (
+	"crypto"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+var ErrNotPEImage = errors.New("efisig: not a valid PE image")
+
+type peSection struct {
+	offset, size int64
+}
+
+// peImage records the layout of a PE image needed to compute its Authenticode digest.
+type peImage struct {
+	size          int64
+	checksumOff   int64
+	certDirOff    int64
+	sizeOfHeaders int64
+	certOff       int64
+	certSize      int64
+	sections      []peSection
+}
+
+func readAt(r io.ReaderAt, off int64, n int) ([]byte, error) {
+	buf := make([]byte, n)
+	if _, err := r.ReadAt(buf, off); err != nil {
+		if err == io.EOF {
+			return nil, ErrNotPEImage
+		}
+		return nil, err
+	}
+	return buf, nil
+}
+
+func parsePEImage(r io.ReaderAt, size int64) (*peImage, error) {
+	dos, err := readAt(r, 0, 0x40)
+	if err != nil {
+		return nil, err
+	}
+	if dos[0] != 'M' || dos[1] != 'Z' {
+		return nil, ErrNotPEImage
+	}
+	peOff := int64(binary.LittleEndian.Uint32(dos[0x3c:]))
+
+	coff, err := readAt(r, peOff, 24)
+	if err != nil {
+		return nil, err
+	}
+	if string(coff[0:4]) != "PE\x00\x00" {
+		return nil, ErrNotPEImage
+	}
+	numSections := int(binary.LittleEndian.Uint16(coff[6:]))
+	optSize := int(binary.LittleEndian.Uint16(coff[20:]))
+	optOff := peOff + 24
+
+	opt, err := readAt(r, optOff, optSize)
+	if err != nil {
+		return nil, err
+	}
+	if len(opt) < 2 {
+		return nil, ErrNotPEImage
+	}
+	var dirOff int
+	switch binary.LittleEndian.Uint16(opt[0:]) {
+	case 0x10b: // PE32
+		dirOff = 96
+	case 0x20b: // PE32+
+		dirOff = 112
+	default:
+		return nil, ErrNotPEImage
+	}
+	if len(opt) < dirOff {
+		return nil, ErrNotPEImage
+	}
+	img := &peImage{
+		size:          size,
+		checksumOff:   optOff + 64,
+		certDirOff:    optOff + int64(dirOff) + 4*8,
+		sizeOfHeaders: int64(binary.LittleEndian.Uint32(opt[60:])),
+	}
+	if numDirs := binary.LittleEndian.Uint32(opt[dirOff-4:]); numDirs > 4 && len(opt) >= dirOff+5*8 {
+		img.certOff = int64(binary.LittleEndian.Uint32(opt[dirOff+4*8:]))
+		img.certSize = int64(binary.LittleEndian.Uint32(opt[dirOff+4*8+4:]))
+	} else {
+		img.certDirOff = 0
+	}
+	if img.sizeOfHeaders > size || img.certOff+img.certSize > size {
+		return nil, ErrNotPEImage
+	}
+
+	sectionTable, err := readAt(r, optOff+int64(optSize), numSections*40)
+	if err != nil {
+		return nil, err
+	}
+	for n := 0; n < numSections; n++ {
+		s := sectionTable[n*40:]
+		sec := peSection{
+			size:   int64(binary.LittleEndian.Uint32(s[16:])),
+			offset: int64(binary.LittleEndian.Uint32(s[20:])),
+		}
+		if sec.size == 0 {
+			continue
+		}
+		if sec.offset+sec.size > size {
+			return nil, ErrNotPEImage
+		}
+		img.sections = append(img.sections, sec)
+	}
+	sort.Slice(img.sections, func(i, j int) bool { return img.sections[i].offset < img.sections[j].offset })
+	return img, nil
+}
+
+// signatures returns the PKCS #7 signatures in the image's attribute certificate table.
+func (img *peImage) signatures(r io.ReaderAt) ([][]byte, error) {
+	if img.certSize == 0 {
+		return nil, nil
+	}
+	table, err := readAt(r, img.certOff, int(img.certSize))
+	if err != nil {
+		return nil, err
+	}
+	var out [][]byte
+	for len(table) >= winCertificateSize {
+		length := int(binary.LittleEndian.Uint32(table[0:]))
+		if length < winCertificateSize || length > len(table) {
+			return nil, ErrNotPEImage
+		}
+		if binary.LittleEndian.Uint16(table[6:]) == winCertTypePKCSSignedData {
+			out = append(out, table[winCertificateSize:length])
+		}
+		// Entries are padded to an 8 byte boundary.
+		length = (length + 7) &^ 7
+		if length > len(table) {
+			break
+		}
+		table = table[length:]
+	}
+	return out, nil
+}
+
+// ImageDigest computes the Authenticode digest of the PE image in r using h.
+// This is the value that firmware compares against hash entries in db and dbx.
+func ImageDigest(r io.ReaderAt, size int64, h crypto.Hash) ([]byte, error) {
+	img, err := parsePEImage(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return img.digest(r, h)
+}
+
+func (img *peImage) digest(r io.ReaderAt, h crypto.Hash) ([]byte, error) {
+	if !h.Available() {
+		return nil, fmt.Errorf("efisig: hash %v is not available", h)
+	}
+	d := h.New()
+	hashRange := func(from, to int64) error {
+		if to <= from {
+			return nil
+		}
+		_, err := io.Copy(d, io.NewSectionReader(r, from, to-from))
+		return err
+	}
+
+	// The checksum and certificate table directory entry are excluded from the digest.
+	ranges := [][2]int64{{0, img.checksumOff}}
+	if img.certDirOff != 0 {
+		ranges = append(ranges, [2]int64{img.checksumOff + 4, img.certDirOff}, [2]int64{img.certDirOff + 8, img.sizeOfHeaders})
+	} else {
+		ranges = append(ranges, [2]int64{img.checksumOff + 4, img.sizeOfHeaders})
+	}
+	hashed := img.sizeOfHeaders
+	for _, s := range img.sections {
+		ranges = append(ranges, [2]int64{s.offset, s.offset + s.size})
+		hashed += s.size
+	}
+	// Anything trailing the sections, other than the certificate table, is hashed too.
+	if end := img.size - img.certSize; end > hashed {
+		ranges = append(ranges, [2]int64{hashed, end})
+	}
+
+	for _, rng := range ranges {
+		if err := hashRange(rng[0], rng[1]); err != nil {
+			return nil, err
+		}
+	}
+	return d.Sum(nil), nil
+}
+
+// FileDigest computes the Authenticode digest of the PE image stored at path.
+func FileDigest(path string, h crypto.Hash) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return ImageDigest(f, fi.Size(), h)
+}
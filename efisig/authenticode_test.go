@@ -0,0 +1,94 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efisig
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"encoding/binary"
+	"testing"
+)
+
+const (
+	testPEOptOff     = 0x40 + 24
+	testPEHeaderSize = 0x200
+)
+
+// buildPE assembles a minimal PE32+ image with one section holding text,
+// followed by certTable as its attribute certificate table.
+func buildPE(text, certTable []byte) []byte {
+	le := binary.LittleEndian
+	textSize := (len(text) + 0x1ff) &^ 0x1ff
+	img := make([]byte, testPEHeaderSize+textSize, testPEHeaderSize+textSize+len(certTable))
+	copy(img, "MZ")
+	le.PutUint32(img[0x3c:], 0x40)
+	copy(img[0x40:], "PE\x00\x00")
+	le.PutUint16(img[0x44:], 0x8664)
+	le.PutUint16(img[0x46:], 1)
+	le.PutUint16(img[0x54:], 240)
+
+	opt := img[testPEOptOff:]
+	le.PutUint16(opt[0:], 0x20b)
+	le.PutUint32(opt[60:], testPEHeaderSize)
+	le.PutUint32(opt[64:], 0xdeadbeef) // checksum
+	le.PutUint32(opt[108:], 16)
+	if len(certTable) > 0 {
+		le.PutUint32(opt[112+4*8:], uint32(len(img)))
+		le.PutUint32(opt[112+4*8+4:], uint32(len(certTable)))
+	}
+
+	sec := img[testPEOptOff+240:]
+	copy(sec, ".text")
+	le.PutUint32(sec[16:], uint32(textSize))
+	le.PutUint32(sec[20:], testPEHeaderSize)
+	copy(img[testPEHeaderSize:], text)
+
+	return append(img, certTable...)
+}
+
+func TestImageDigest(t *testing.T) {
+	img := buildPE([]byte("hello world"), nil)
+	signed := buildPE([]byte("hello world"), []byte("pretend this is a signature"))
+
+	certDirOff := testPEOptOff + 112 + 4*8
+	h := sha256.New()
+	h.Write(img[:testPEOptOff+64])
+	h.Write(img[testPEOptOff+68 : certDirOff])
+	h.Write(img[certDirOff+8:])
+	want := h.Sum(nil)
+
+	for name, bs := range map[string][]byte{"unsigned": img, "signed": signed} {
+		got, err := ImageDigest(bytes.NewReader(bs), int64(len(bs)), crypto.SHA256)
+		if err != nil {
+			t.Fatalf("%s: ImageDigest: %v", name, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: ImageDigest = %x; want %x", name, got, want)
+		}
+	}
+}
+
+func TestImageDigestNotPE(t *testing.T) {
+	for _, bs := range [][]byte{
+		[]byte("MZ"),
+		make([]byte, 0x100),
+		buildPE(nil, nil)[:0x50],
+	} {
+		if _, err := ImageDigest(bytes.NewReader(bs), int64(len(bs)), crypto.SHA256); err != ErrNotPEImage {
+			t.Errorf("ImageDigest(%x...) = %v; want ErrNotPEImage", bs[:2], err)
+		}
+	}
+}
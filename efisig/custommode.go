@@ -0,0 +1,69 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efisig
+
+import (
+	"os"
+
+	"github.com/google/uuid"
+	"github.com/lukegb/goefivar/efivar"
+)
+
+var (
+	ErrCustomModeUnsupported = efivar.NewError(efivar.Unsupported, "efisig: firmware does not expose CustomMode")
+
+	// CustomModeEnableUUID is the vendor GUID used by EDK2-derived firmware for CustomMode.
+	CustomModeEnableUUID = uuid.MustParse("c076ec0c-7028-4399-a072-71ee5c448b9f")
+
+	CustomModeName = efivar.VariableName{GUID: CustomModeEnableUUID, Name: "CustomMode"}
+	VendorKeysName = efivar.VariableName{GUID: efivar.GlobalUUID, Name: "VendorKeys"}
+)
+
+// CustomModeSupported reports whether the firmware exposes the EDK2 CustomMode
+// variable to the OS. Most firmware only makes it available in its own setup UI.
+func CustomModeSupported() (bool, error) {
+	return CustomModeName.Exists()
+}
+
+// CustomMode reports whether the firmware is in custom Secure Boot mode, in
+// which the key databases may be modified without being signed.
+func CustomMode() (bool, error) {
+	enabled, err := readBool(CustomModeName)
+	if os.IsNotExist(err) {
+		return false, ErrCustomModeUnsupported
+	}
+	return enabled, err
+}
+
+// SetCustomMode switches between standard and custom Secure Boot mode.
+func SetCustomMode(enabled bool) error {
+	v, err := CustomModeName.Get()
+	switch {
+	case os.IsNotExist(err):
+		return ErrCustomModeUnsupported
+	case err != nil:
+		return err
+	}
+	v.Data = []byte{0}
+	if enabled {
+		v.Data[0] = 1
+	}
+	return v.Set(0644)
+}
+
+// VendorKeys reports whether the enrolled keys are still those shipped by the platform vendor.
+func VendorKeys() (bool, error) {
+	return readBool(VendorKeysName)
+}
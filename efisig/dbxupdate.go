@@ -0,0 +1,211 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efisig
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lukegb/goefivar/efiboot"
+	"github.com/lukegb/goefivar/efivar"
+)
+
+var (
+	ErrDBXUpToDate        = errors.New("efisig: dbx already contains every entry in the update")
+	ErrRevokesBootedImage = errors.New("efisig: dbx update would revoke a booted image")
+
+	// defaultESPMountpoints are the conventional places an EFI System Partition is mounted.
+	defaultESPMountpoints = []string{"/boot/efi", "/efi", "/boot"}
+)
+
+// espMountpoints returns the mounted ESPs, or the conventional mountpoints if none can be found.
+func espMountpoints() []string {
+	mnts, err := efiboot.ESPMountpoints()
+	if err != nil || len(mnts) == 0 {
+		return defaultESPMountpoints
+	}
+	return mnts
+}
+
+// DBXUpdate is a signed dbx revocation release, such as the dbxupdate_*.bin
+// files published by the UEFI Forum.
+type DBXUpdate struct {
+	*AuthenticatedData
+
+	// Database is the set of revocations carried in the update.
+	Database Database
+}
+
+func ParseDBXUpdate(data []byte) (*DBXUpdate, error) {
+	a, err := ParseAuthenticatedData(data)
+	if err != nil {
+		return nil, err
+	}
+	if a.CertType != CertTypePKCS7UUID || len(a.CertData) == 0 {
+		return nil, fmt.Errorf("efisig: dbx update is not signed")
+	}
+	db, err := ParseDatabase(a.Payload)
+	if err != nil {
+		return nil, err
+	}
+	if db.Len() == 0 {
+		return nil, fmt.Errorf("efisig: dbx update contains no entries")
+	}
+	for _, sl := range db {
+		if _, ok := hashTypes[sl.Type]; !ok && sl.Type != CertX509UUID {
+			return nil, fmt.Errorf("efisig: dbx update contains unexpected signature type %v", sl.Type)
+		}
+	}
+	return &DBXUpdate{a, db}, nil
+}
+
+// NewEntries returns the entries of the update which are not already present in current.
+func (u *DBXUpdate) NewEntries(current Database) Database {
+	var out Database
+	for _, sl := range u.Database {
+		var missing []SignatureData
+		for _, s := range sl.Signatures {
+			if !current.Contains(sl.Type, s.Data) {
+				missing = append(missing, s)
+			}
+		}
+		if len(missing) > 0 {
+			out = append(out, &SignatureList{Type: sl.Type, Header: sl.Header, Signatures: missing})
+		}
+	}
+	return out
+}
+
+// Revokes reports whether the image at path would be refused because of the update.
+func (u *DBXUpdate) Revokes(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+	revoked, _, err := CheckRevoked(f, fi.Size(), u.Database)
+	return revoked, err
+}
+
+type DBXUpdateOptions struct {
+	// Images lists the PE images to check against the update.
+	// If nil, the loader of the currently booted boot option is checked.
+	Images []string
+
+	// Force applies the update even if it would revoke one of Images.
+	Force bool
+}
+
+type DBXUpdateResult struct {
+	// NewEntries is the number of revocations not already present in dbx.
+	NewEntries int
+
+	// RevokedImages lists the checked images which the update revokes.
+	RevokedImages []string
+}
+
+// BootedImages locates the loader of the current boot option on a mounted ESP.
+// It returns nothing if the boot option doesn't name a file, or the file can't be found.
+func BootedImages() ([]string, error) {
+	bc, err := efiboot.BootCurrent()
+	if err != nil {
+		return nil, err
+	}
+	v, err := bc.Get()
+	if err != nil {
+		return nil, err
+	}
+	lo, err := efiboot.FromVariable(v)
+	if err != nil {
+		return nil, err
+	}
+	pathName := lo.PathName()
+	if pathName == "" {
+		return nil, nil
+	}
+	rel := filepath.FromSlash(strings.Replace(pathName, `\`, "/", -1))
+	for _, mnt := range espMountpoints() {
+		p := filepath.Join(mnt, rel)
+		if _, err := os.Stat(p); err == nil {
+			return []string{p}, nil
+		}
+	}
+	return nil, nil
+}
+
+// ApplyDBXUpdate validates the dbx update stored at path and appends it to dbx.
+//
+// The update is refused with ErrDBXUpToDate if dbx already holds every entry,
+// and with ErrRevokesBootedImage if it would revoke one of the checked images.
+// In both cases the returned result describes what was found.
+func ApplyDBXUpdate(path string, opts *DBXUpdateOptions) (*DBXUpdateResult, error) {
+	if opts == nil {
+		opts = &DBXUpdateOptions{}
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	u, err := ParseDBXUpdate(data)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := ReadDatabase(DBXName)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("efisig: reading dbx: %v", err)
+	}
+	res := &DBXUpdateResult{NewEntries: u.NewEntries(current).Len()}
+	if res.NewEntries == 0 {
+		return res, ErrDBXUpToDate
+	}
+
+	images := opts.Images
+	if images == nil {
+		if images, err = BootedImages(); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("efisig: locating booted image: %v", err)
+		}
+	}
+	for _, img := range images {
+		revoked, err := u.Revokes(img)
+		if err != nil {
+			return nil, fmt.Errorf("efisig: checking %v: %v", img, err)
+		}
+		if revoked {
+			res.RevokedImages = append(res.RevokedImages, img)
+		}
+	}
+	if len(res.RevokedImages) > 0 && !opts.Force {
+		return res, ErrRevokesBootedImage
+	}
+
+	v := &efivar.Variable{
+		VariableName: DBXName,
+		Data:         data,
+		Attributes:   efivar.NonVolatile | efivar.BootserviceAccess | efivar.RuntimeAccess | efivar.TimeBasedAuthenticatedWriteAccess | efivar.AppendWrite,
+	}
+	if err := v.Set(0644); err != nil {
+		return res, fmt.Errorf("efisig: writing dbx: %v", err)
+	}
+	return res, nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efisig
+
+import (
+	"bytes"
+	"crypto"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDBXUpdate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "efisig")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	revoked := filepath.Join(dir, "revoked.efi")
+	if err := ioutil.WriteFile(revoked, buildPE([]byte("bad loader"), nil), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	fine := filepath.Join(dir, "fine.efi")
+	if err := ioutil.WriteFile(fine, buildPE([]byte("good loader"), nil), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	digest, err := FileDigest(revoked, crypto.SHA256)
+	if err != nil {
+		t.Fatalf("FileDigest: %v", err)
+	}
+
+	old := SignatureData{Owner: testOwner, Data: bytes.Repeat([]byte{1}, 32)}
+	update := &AuthenticatedData{
+		CertType: CertTypePKCS7UUID,
+		CertData: []byte("signature"),
+		Payload: Database{{
+			Type:       CertSHA256UUID,
+			Signatures: []SignatureData{old, {Owner: testOwner, Data: digest}},
+		}}.Bytes(),
+	}
+
+	u, err := ParseDBXUpdate(update.Bytes())
+	if err != nil {
+		t.Fatalf("ParseDBXUpdate: %v", err)
+	}
+
+	current := Database{{Type: CertSHA256UUID, Signatures: []SignatureData{old}}}
+	want := Database{{Type: CertSHA256UUID, Signatures: []SignatureData{{Owner: testOwner, Data: digest}}}}
+	if got := u.NewEntries(current); !reflect.DeepEqual(got, want) {
+		t.Errorf("NewEntries = %v; want %v", got, want)
+	}
+
+	for path, want := range map[string]bool{revoked: true, fine: false} {
+		got, err := u.Revokes(path)
+		if err != nil {
+			t.Fatalf("Revokes(%q): %v", path, err)
+		}
+		if got != want {
+			t.Errorf("Revokes(%q) = %v; want %v", path, got, want)
+		}
+	}
+}
+
+func TestParseDBXUpdateUnsigned(t *testing.T) {
+	update := &AuthenticatedData{
+		CertType: CertTypePKCS7UUID,
+		Payload:  Database{{Type: CertSHA256UUID, Signatures: []SignatureData{{Owner: testOwner, Data: make([]byte, 32)}}}}.Bytes(),
+	}
+	if _, err := ParseDBXUpdate(update.Bytes()); err == nil {
+		t.Errorf("ParseDBXUpdate of unsigned update returned no error")
+	}
+}
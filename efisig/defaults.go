@@ -0,0 +1,88 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efisig
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/lukegb/goefivar/efivar"
+)
+
+var (
+	ErrNotInSetupMode = efivar.NewError(efivar.AuthRequired, "efisig: firmware is not in Setup Mode")
+
+	PKDefaultName  = efivar.VariableName{GUID: efivar.GlobalUUID, Name: "PKDefault"}
+	KEKDefaultName = efivar.VariableName{GUID: efivar.GlobalUUID, Name: "KEKDefault"}
+	DBDefaultName  = efivar.VariableName{GUID: efivar.GlobalUUID, Name: "dbDefault"}
+	DBXDefaultName = efivar.VariableName{GUID: efivar.GlobalUUID, Name: "dbxDefault"}
+
+	// restoreOrder lists the factory default variables and the database each
+	// one restores. PK must come last, as enrolling it leaves Setup Mode.
+	restoreOrder = []struct{ from, to efivar.VariableName }{
+		{DBXDefaultName, DBXName},
+		{DBDefaultName, DBName},
+		{KEKDefaultName, KEKName},
+		{PKDefaultName, PKName},
+	}
+)
+
+// canWriteUnsigned reports whether the firmware will accept unsigned key database writes.
+func canWriteUnsigned() (bool, error) {
+	setup, err := readBool(SetupModeName)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	if setup {
+		return true, nil
+	}
+	custom, err := CustomMode()
+	if err == ErrCustomModeUnsupported {
+		return false, nil
+	}
+	return custom, err
+}
+
+// RestoreDefaults re-enrolls the platform vendor's factory default keys.
+// The firmware must be in Setup Mode (or custom mode), usually after clearing
+// the keys from the firmware setup UI; otherwise ErrNotInSetupMode is returned.
+// Databases for which no default is provided are left untouched.
+func RestoreDefaults() error {
+	ok, err := canWriteUnsigned()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotInSetupMode
+	}
+
+	for _, r := range restoreOrder {
+		v, err := r.from.Get()
+		switch {
+		case os.IsNotExist(err):
+			continue
+		case err != nil:
+			return fmt.Errorf("efisig: reading %v: %v", r.from.Name, err)
+		}
+		if _, err := ParseDatabase(v.Data); err != nil {
+			return fmt.Errorf("efisig: %v: %v", r.from.Name, err)
+		}
+		if err := writeAuthenticated(r.to, authenticatedAttributes, UnsignedVariable(time.Now(), v.Data)); err != nil {
+			return fmt.Errorf("efisig: restoring %v: %v", r.to.Name, err)
+		}
+	}
+	return nil
+}
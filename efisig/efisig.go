@@ -0,0 +1,220 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package efisig provides access to the UEFI Secure Boot databases and the
+// related variables maintained by shim.
+package efisig
+
+import (
+	"bytes"
+	"crypto"
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/x509"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/lukegb/goefivar/efivar"
+)
+
+var (
+	ErrVariableCorrupted = efivar.NewError(efivar.Corrupt, "efisig: variable content is not valid")
+
+	// ShimLockUUID is the vendor GUID used by shim for its variables.
+	ShimLockUUID = uuid.MustParse("605dab50-e046-4300-abb6-3dd810dd8b23")
+
+	// ImageSecurityDatabaseUUID is the vendor GUID of db, dbx and friends.
+	ImageSecurityDatabaseUUID = uuid.MustParse("d719b2cb-3d3a-4596-a3bc-dad00e67656f")
+
+	PKName  = efivar.VariableName{GUID: efivar.GlobalUUID, Name: "PK"}
+	KEKName = efivar.VariableName{GUID: efivar.GlobalUUID, Name: "KEK"}
+	DBName  = efivar.VariableName{GUID: ImageSecurityDatabaseUUID, Name: "db"}
+	DBXName = efivar.VariableName{GUID: ImageSecurityDatabaseUUID, Name: "dbx"}
+
+	SecureBootName = efivar.VariableName{GUID: efivar.GlobalUUID, Name: "SecureBoot"}
+	SetupModeName  = efivar.VariableName{GUID: efivar.GlobalUUID, Name: "SetupMode"}
+)
+
+// Signature types defined by the UEFI specification.
+var (
+	CertSHA1UUID       = uuid.MustParse("826ca512-cf10-4ac9-b187-be01496631bd")
+	CertSHA224UUID     = uuid.MustParse("0b6e5233-a65c-44c9-9407-d9ab83bfc8bd")
+	CertSHA256UUID     = uuid.MustParse("c1c41626-504c-4092-aca9-41f936934328")
+	CertSHA384UUID     = uuid.MustParse("ff3e5307-9fd0-48c9-85f1-8ad56c701e01")
+	CertSHA512UUID     = uuid.MustParse("093e0fae-a6c4-4f50-9f1b-d41e2b89c19a")
+	CertRSA2048UUID    = uuid.MustParse("3c5766e8-269c-4e34-aa14-ed776e85b3b6")
+	CertX509UUID       = uuid.MustParse("a5c059a1-94e4-4aa7-87b5-ab155c2bf072")
+	CertX509SHA256UUID = uuid.MustParse("3bd2a492-96c0-4079-b420-fcf98ef103ed")
+	CertX509SHA384UUID = uuid.MustParse("7076876e-80c2-4ee6-aad2-28b349a6865b")
+	CertX509SHA512UUID = uuid.MustParse("446dbf63-2502-4cda-bcfa-2465d2b0fe9d")
+)
+
+// hashTypes maps the hash signature types to the digest they contain.
+var hashTypes = map[uuid.UUID]crypto.Hash{
+	CertSHA1UUID:   crypto.SHA1,
+	CertSHA224UUID: crypto.SHA224,
+	CertSHA256UUID: crypto.SHA256,
+	CertSHA384UUID: crypto.SHA384,
+	CertSHA512UUID: crypto.SHA512,
+}
+
+var byteOrder = binary.LittleEndian
+
+// SignatureData is a single entry of an EFI_SIGNATURE_LIST.
+type SignatureData struct {
+	// Owner identifies the agent which added this signature.
+	Owner uuid.UUID
+	Data  []byte
+}
+
+// SignatureList is an EFI_SIGNATURE_LIST: a set of same-typed, same-sized signatures.
+type SignatureList struct {
+	Type       uuid.UUID
+	Header     []byte
+	Signatures []SignatureData
+}
+
+const signatureListHeaderSize = 16 + 4 + 4 + 4
+
+func (sl *SignatureList) signatureSize() int {
+	if len(sl.Signatures) == 0 {
+		return 16
+	}
+	return 16 + len(sl.Signatures[0].Data)
+}
+
+func (sl *SignatureList) Bytes() []byte {
+	sigSize := sl.signatureSize()
+	listSize := signatureListHeaderSize + len(sl.Header) + len(sl.Signatures)*sigSize
+
+	buf := bytes.NewBuffer(make([]byte, 0, listSize))
+	buf.Write(efivar.GUIDBytes(sl.Type))
+	binary.Write(buf, byteOrder, uint32(listSize))
+	binary.Write(buf, byteOrder, uint32(len(sl.Header)))
+	binary.Write(buf, byteOrder, uint32(sigSize))
+	buf.Write(sl.Header)
+	for _, s := range sl.Signatures {
+		buf.Write(efivar.GUIDBytes(s.Owner))
+		buf.Write(s.Data)
+	}
+	return buf.Bytes()
+}
+
+// Database is the content of a signature database variable such as db or KEK.
+type Database []*SignatureList
+
+func ParseDatabase(data []byte) (Database, error) {
+	var db Database
+	for len(data) > 0 {
+		if len(data) < signatureListHeaderSize {
+			return nil, ErrVariableCorrupted
+		}
+		listSize := int(byteOrder.Uint32(data[16:20]))
+		headerSize := int(byteOrder.Uint32(data[20:24]))
+		sigSize := int(byteOrder.Uint32(data[24:28]))
+		if listSize < signatureListHeaderSize || listSize > len(data) || sigSize < 16 || headerSize > listSize-signatureListHeaderSize {
+			return nil, ErrVariableCorrupted
+		}
+		body := data[signatureListHeaderSize+headerSize : listSize]
+		if len(body)%sigSize != 0 {
+			return nil, ErrVariableCorrupted
+		}
+
+		sl := &SignatureList{
+			Type:   efivar.GUIDFromBytes(data[0:16]),
+			Header: append([]byte(nil), data[signatureListHeaderSize:signatureListHeaderSize+headerSize]...),
+		}
+		for ; len(body) > 0; body = body[sigSize:] {
+			sl.Signatures = append(sl.Signatures, SignatureData{
+				Owner: efivar.GUIDFromBytes(body[0:16]),
+				Data:  append([]byte(nil), body[16:sigSize]...),
+			})
+		}
+		db = append(db, sl)
+		data = data[listSize:]
+	}
+	return db, nil
+}
+
+func (d Database) Bytes() []byte {
+	var out []byte
+	for _, sl := range d {
+		out = append(out, sl.Bytes()...)
+	}
+	return out
+}
+
+// Len returns the total number of signatures across all lists.
+func (d Database) Len() int {
+	var n int
+	for _, sl := range d {
+		n += len(sl.Signatures)
+	}
+	return n
+}
+
+// Contains reports whether the database holds a signature of type typ with the given data.
+func (d Database) Contains(typ uuid.UUID, data []byte) bool {
+	for _, sl := range d {
+		if sl.Type != typ {
+			continue
+		}
+		for _, s := range sl.Signatures {
+			if bytes.Equal(s.Data, data) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Certificates parses every X.509 entry in the database.
+func (d Database) Certificates() ([]*x509.Certificate, error) {
+	var out []*x509.Certificate
+	for _, sl := range d {
+		if sl.Type != CertX509UUID {
+			continue
+		}
+		for _, s := range sl.Signatures {
+			cert, err := x509.ParseCertificate(s.Data)
+			if err != nil {
+				return nil, fmt.Errorf("efisig: parsing certificate owned by %v: %v", s.Owner, err)
+			}
+			out = append(out, cert)
+		}
+	}
+	return out, nil
+}
+
+// ReadDatabase reads and parses the signature database stored in vn.
+func ReadDatabase(vn efivar.VariableName) (Database, error) {
+	v, err := vn.Get()
+	if err != nil {
+		return nil, err
+	}
+	return ParseDatabase(v.Data)
+}
+
+func readBool(vn efivar.VariableName) (bool, error) {
+	v, err := vn.Get()
+	if err != nil {
+		return false, err
+	}
+	if len(v.Data) != 1 {
+		return false, ErrVariableCorrupted
+	}
+	return v.Data[0] == 1, nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efisig
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var testOwner = uuid.MustParse("74552304-ce9f-4e52-89a0-f6c6fa47deac")
+
+func mustCertificate(t *testing.T, cn string) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate: %v", err)
+	}
+	return cert
+}
+
+func TestDatabaseRoundtrip(t *testing.T) {
+	cert := mustCertificate(t, "efisig test")
+	db := Database{
+		{
+			Type:       CertX509UUID,
+			Signatures: []SignatureData{{Owner: testOwner, Data: cert.Raw}},
+		},
+		{
+			Type: CertSHA256UUID,
+			Signatures: []SignatureData{
+				{Owner: testOwner, Data: bytes.Repeat([]byte{1}, 32)},
+				{Owner: testOwner, Data: bytes.Repeat([]byte{2}, 32)},
+			},
+		},
+	}
+
+	got, err := ParseDatabase(db.Bytes())
+	if err != nil {
+		t.Fatalf("ParseDatabase: %v", err)
+	}
+	if !bytes.Equal(got.Bytes(), db.Bytes()) {
+		t.Errorf("ParseDatabase(db.Bytes()).Bytes() != db.Bytes()")
+	}
+	if got.Len() != 3 {
+		t.Errorf("Len() = %d; want 3", got.Len())
+	}
+
+	certs, err := got.Certificates()
+	if err != nil {
+		t.Fatalf("Certificates: %v", err)
+	}
+	if len(certs) != 1 || !certs[0].Equal(cert) {
+		t.Errorf("Certificates() = %v; want [%v]", certs, cert)
+	}
+}
+
+func TestParseDatabaseCorrupted(t *testing.T) {
+	db := Database{{
+		Type:       CertSHA256UUID,
+		Signatures: []SignatureData{{Owner: testOwner, Data: make([]byte, 32)}},
+	}}
+	bs := db.Bytes()
+	for _, in := range [][]byte{
+		bs[:10],
+		bs[:len(bs)-1],
+		append(bs, 0),
+	} {
+		if _, err := ParseDatabase(in); err != ErrVariableCorrupted {
+			t.Errorf("ParseDatabase(%x) = %v; want ErrVariableCorrupted", in, err)
+		}
+	}
+}
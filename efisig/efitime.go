@@ -0,0 +1,65 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efisig
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lukegb/goefivar/efitime"
+)
+
+// EFITime is an EFI_TIME, as used for the timestamp of authenticated variable updates.
+type EFITime = efitime.Time
+
+const efiTimeSize = efitime.Size
+
+func ParseEFITime(b []byte) (EFITime, error) {
+	t, err := efitime.Parse(b)
+	if err != nil {
+		return EFITime{}, ErrVariableCorrupted
+	}
+	return t, nil
+}
+
+// EFITimeFromTime converts t to an EFITime in UTC, suitable for an authentication descriptor.
+func EFITimeFromTime(t time.Time) EFITime {
+	return efitime.FromTime(t.UTC().Truncate(time.Second))
+}
+
+// RollbackError explains why firmware would refuse an authenticated update
+// whose timestamp is not later than the one stored with the variable.
+type RollbackError struct {
+	Stored EFITime
+	Update EFITime
+}
+
+func (e *RollbackError) Error() string {
+	return fmt.Sprintf("efisig: update timestamp %v is not later than the stored timestamp %v; firmware will reject it as a rollback", e.Update, e.Stored)
+}
+
+// CheckTimestamp checks whether firmware would accept the timestamp of a, given
+// the timestamp stored with the variable by its last update. Firmware does not
+// expose stored timestamps to the OS, so callers must track them, e.g. from the
+// last payload applied. Appending writes are exempt from the monotonicity check.
+func (a *AuthenticatedData) CheckTimestamp(stored EFITime, appendWrite bool) error {
+	if err := a.Timestamp.ValidForAuthentication(); err != nil {
+		return err
+	}
+	if !appendWrite && a.Timestamp.Compare(stored) <= 0 {
+		return &RollbackError{Stored: stored, Update: a.Timestamp}
+	}
+	return nil
+}
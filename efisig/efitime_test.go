@@ -0,0 +1,63 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efisig
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEFITimeRoundtrip(t *testing.T) {
+	want := EFITime{Year: 2010, Month: 3, Day: 6, Hour: 19, Minute: 17, Second: 21, Nanosecond: 5, TimeZone: -60, Daylight: 1}
+	got, err := ParseEFITime(want.Bytes())
+	if err != nil {
+		t.Fatalf("ParseEFITime: %v", err)
+	}
+	if got != want {
+		t.Errorf("ParseEFITime(%v.Bytes()) = %+v; want %+v", want, got, want)
+	}
+	if _, err := ParseEFITime(want.Bytes()[:15]); err == nil {
+		t.Errorf("ParseEFITime of 15 bytes returned no error")
+	}
+
+	tt := time.Date(2010, 3, 6, 19, 17, 21, 0, time.UTC)
+	if got := EFITimeFromTime(tt).Time(); !got.Equal(tt) {
+		t.Errorf("EFITimeFromTime(%v).Time() = %v", tt, got)
+	}
+	if got, want := EFITimeFromTime(tt).String(), "2010-03-06T19:17:21Z"; got != want {
+		t.Errorf("String() = %q; want %q", got, want)
+	}
+}
+
+func TestCheckTimestamp(t *testing.T) {
+	stored := EFITime{Year: 2020, Month: 1, Day: 1}
+	for _, tc := range []struct {
+		ts          EFITime
+		appendWrite bool
+		wantErr     bool
+	}{
+		{EFITime{Year: 2021, Month: 1, Day: 1}, false, false},
+		{EFITime{Year: 2020, Month: 1, Day: 1}, false, true},
+		{EFITime{Year: 2019, Month: 12, Day: 31, Hour: 23}, false, true},
+		{EFITime{Year: 2019, Month: 12, Day: 31}, true, false},
+		{EFITime{Year: 2021, Month: 1, Day: 1, TimeZone: 60}, false, true},
+	} {
+		a := &AuthenticatedData{Timestamp: tc.ts}
+		err := a.CheckTimestamp(stored, tc.appendWrite)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("CheckTimestamp(%v, append=%v) = %v; want error: %v", tc.ts, tc.appendWrite, err, tc.wantErr)
+		}
+	}
+}
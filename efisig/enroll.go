@@ -0,0 +1,147 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efisig
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/lukegb/goefivar/efivar"
+)
+
+// EnrollOptions controls EnrollAllKeys.
+type EnrollOptions struct {
+	// ExtraKEK and ExtraDB are merged into the KEK and db being enrolled,
+	// for example to keep Microsoft's keys alongside your own.
+	ExtraKEK Database
+	ExtraDB  Database
+}
+
+// enrollStep is one variable written by EnrollAllKeys.
+type enrollStep struct {
+	vn     efivar.VariableName
+	data   []byte
+	signer *Key
+
+	// previous holds the content before enrollment, for rolling back.
+	previous []byte
+	existed  bool
+}
+
+func readSetupMode() (bool, error) {
+	setup, err := readBool(SetupModeName)
+	if err != nil {
+		return false, fmt.Errorf("efisig: reading SetupMode: %v", err)
+	}
+	return setup, nil
+}
+
+// EnrollAllKeys takes ownership of Secure Boot with the keys in h.
+//
+// The firmware must be in Setup Mode. db is enrolled first, then KEK, and PK
+// last, since enrolling PK leaves Setup Mode. After each step the variable is
+// read back and the Setup Mode state checked. If any step fails, the
+// variables already written are restored to their previous content.
+func EnrollAllKeys(h *KeyHierarchy, opts *EnrollOptions) error {
+	if opts == nil {
+		opts = &EnrollOptions{}
+	}
+	setup, err := readSetupMode()
+	if err != nil {
+		return err
+	}
+	if !setup {
+		return ErrNotInSetupMode
+	}
+
+	steps := []*enrollStep{
+		{vn: DBName, data: Merge(h.DB.Database(), opts.ExtraDB).Bytes(), signer: h.KEK},
+		{vn: KEKName, data: Merge(h.KEK.Database(), opts.ExtraKEK).Bytes(), signer: h.PK},
+		{vn: PKName, data: h.PK.Database().Bytes(), signer: h.PK},
+	}
+	for _, s := range steps {
+		v, err := s.vn.Get()
+		switch {
+		case os.IsNotExist(err):
+		case err != nil:
+			return fmt.Errorf("efisig: reading %v: %v", s.vn.Name, err)
+		default:
+			s.previous, s.existed = v.Data, true
+		}
+	}
+
+	for n, s := range steps {
+		if err := s.enroll(s.vn == PKName); err != nil {
+			return rollback(steps[:n+1], err)
+		}
+	}
+	return nil
+}
+
+func (s *enrollStep) enroll(leavesSetupMode bool) error {
+	a, err := s.signer.Sign(s.vn, time.Now(), s.data)
+	if err != nil {
+		return err
+	}
+	if err := writeAuthenticated(s.vn, authenticatedAttributes, a); err != nil {
+		return fmt.Errorf("efisig: enrolling %v: %v", s.vn.Name, err)
+	}
+
+	v, err := s.vn.Get()
+	if err != nil {
+		return fmt.Errorf("efisig: reading back %v: %v", s.vn.Name, err)
+	}
+	if !bytes.Equal(v.Data, s.data) {
+		return fmt.Errorf("efisig: %v does not contain the enrolled keys after writing", s.vn.Name)
+	}
+
+	setup, err := readSetupMode()
+	if err != nil {
+		return err
+	}
+	if setup == leavesSetupMode {
+		return fmt.Errorf("efisig: after enrolling %v, SetupMode is %v; want %v", s.vn.Name, setup, !leavesSetupMode)
+	}
+	return nil
+}
+
+// rollback restores the variables written by steps, most recent first.
+func rollback(steps []*enrollStep, cause error) error {
+	for n := len(steps) - 1; n >= 0; n-- {
+		s := steps[n]
+		data := s.previous
+		if !s.existed {
+			// An authenticated write of no data deletes the variable.
+			data = nil
+		}
+		var a *AuthenticatedData
+		var err error
+		if s.vn == PKName {
+			// Once PK is enrolled, clearing it must be signed by it.
+			a, err = s.signer.Sign(s.vn, time.Now(), data)
+		} else {
+			a = UnsignedVariable(time.Now(), data)
+		}
+		if err == nil {
+			err = writeAuthenticated(s.vn, authenticatedAttributes, a)
+		}
+		if err != nil && !(s.vn == PKName && os.IsNotExist(err)) {
+			return fmt.Errorf("%v; additionally, rolling back %v failed: %v", cause, s.vn.Name, err)
+		}
+	}
+	return cause
+}
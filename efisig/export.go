@@ -0,0 +1,64 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efisig
+
+import (
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// PEM returns the certificate encoded as a PEM block.
+func (e *KeyEntry) PEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: e.Certificate.Raw})
+}
+
+// fileName returns a descriptive file name for the n'th certificate of its database.
+func (e *KeyEntry) fileName(n int) string {
+	name := e.Certificate.Subject.CommonName
+	if name == "" {
+		name = e.Certificate.SerialNumber.String()
+	}
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '.':
+			return r
+		}
+		return '_'
+	}, name)
+	return fmt.Sprintf("%s-%02d-%s.pem", e.Database, n, name)
+}
+
+// ExportPEM writes every certificate enrolled in PK, KEK, db and the MOK list
+// to dir as individual PEM files, returning the paths written.
+func ExportPEM(dir string) ([]string, error) {
+	entries, err := KeyInventory()
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	count := make(map[string]int)
+	for _, e := range entries {
+		p := filepath.Join(dir, e.fileName(count[e.Database]))
+		count[e.Database]++
+		if err := ioutil.WriteFile(p, e.PEM(), 0644); err != nil {
+			return paths, err
+		}
+		paths = append(paths, p)
+	}
+	return paths, nil
+}
@@ -0,0 +1,48 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package efisig
+
+import (
+	"bytes"
+	"testing"
+)
+
+func FuzzParseDatabase(f *testing.F) {
+	f.Add(Database{{
+		Type:       CertSHA256UUID,
+		Signatures: []SignatureData{{Owner: testOwner, Data: make([]byte, 32)}, {Owner: testOwner, Data: bytes.Repeat([]byte{1}, 32)}},
+	}}.Bytes())
+	f.Add(Database{
+		{Type: CertX509UUID, Signatures: []SignatureData{{Owner: testOwner, Data: []byte("not really a certificate")}}},
+		{Type: CertSHA256UUID, Header: []byte{1, 2, 3, 4}},
+	}.Bytes())
+	f.Fuzz(func(t *testing.T, b []byte) {
+		db, err := ParseDatabase(b)
+		if err != nil {
+			return
+		}
+		_, _ = db.Certificates()
+		got, err := ParseDatabase(db.Bytes())
+		if err != nil {
+			t.Fatalf("ParseDatabase(ParseDatabase(%x).Bytes()): %v", b, err)
+		}
+		if !bytes.Equal(got.Bytes(), db.Bytes()) || got.Len() != db.Len() {
+			t.Errorf("ParseDatabase(%x) does not survive a roundtrip", b)
+		}
+	})
+}
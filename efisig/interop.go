@@ -0,0 +1,57 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efisig
+
+import (
+	"io"
+	"io/ioutil"
+)
+
+// The functions in this file read and write the standard binary encodings
+// through io.Reader and io.Writer, as other UEFI libraries such as
+// github.com/foxboron/go-uefi do, so that values can be passed between them
+// without either depending on the other.
+
+// WriteTo writes d as a sequence of EFI_SIGNATURE_LISTs.
+func (d Database) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(d.Bytes())
+	return int64(n), err
+}
+
+// ReadDatabaseFrom reads a signature database to the end of r.
+func ReadDatabaseFrom(r io.Reader) (Database, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDatabase(b)
+}
+
+// WriteTo writes a as an EFI_VARIABLE_AUTHENTICATION_2 descriptor followed
+// by the payload.
+func (a *AuthenticatedData) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(a.Bytes())
+	return int64(n), err
+}
+
+// ReadAuthenticatedDataFrom reads an authenticated variable write, such as
+// a .auth file produced by sign-efi-sig-list or sbkeysync, to the end of r.
+func ReadAuthenticatedDataFrom(r io.Reader) (*AuthenticatedData, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return ParseAuthenticatedData(b)
+}
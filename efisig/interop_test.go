@@ -0,0 +1,55 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efisig
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDatabaseReaderWriter(t *testing.T) {
+	db := Database{{
+		Type:       CertSHA256UUID,
+		Signatures: []SignatureData{{Owner: testOwner, Data: bytes.Repeat([]byte{1}, 32)}},
+	}}
+	var buf bytes.Buffer
+	if n, err := db.WriteTo(&buf); err != nil || n != int64(len(db.Bytes())) {
+		t.Fatalf("WriteTo = %d, %v; want %d, nil", n, err, len(db.Bytes()))
+	}
+	got, err := ReadDatabaseFrom(&buf)
+	if err != nil {
+		t.Fatalf("ReadDatabaseFrom: %v", err)
+	}
+	if !reflect.DeepEqual(got, db) {
+		t.Errorf("ReadDatabaseFrom(WriteTo(db)) = %v; want %v", got, db)
+	}
+}
+
+func TestAuthenticatedDataReaderWriter(t *testing.T) {
+	a := UnsignedVariable(time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC), []byte("payload"))
+	var buf bytes.Buffer
+	if _, err := a.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	got, err := ReadAuthenticatedDataFrom(&buf)
+	if err != nil {
+		t.Fatalf("ReadAuthenticatedDataFrom: %v", err)
+	}
+	if !bytes.Equal(got.Bytes(), a.Bytes()) {
+		t.Errorf("ReadAuthenticatedDataFrom(WriteTo(a)) = %+v; want %+v", got, a)
+	}
+}
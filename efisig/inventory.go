@@ -0,0 +1,127 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efisig
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lukegb/goefivar/efivar"
+)
+
+// minRSABits is the smallest RSA modulus not reported as weak.
+const minRSABits = 2048
+
+// KeyEntry describes one certificate enrolled in a key database.
+type KeyEntry struct {
+	// Database is the name of the variable the certificate was found in, e.g. "db" or "MokListRT".
+	Database string
+	Owner    uuid.UUID
+
+	Subject      string
+	Issuer       string
+	NotAfter     time.Time
+	KeyAlgorithm x509.PublicKeyAlgorithm
+	KeySize      int
+
+	// Expired is set if NotAfter has passed. Firmware does not enforce
+	// certificate expiry, but compliance policies frequently do.
+	Expired bool
+
+	// Weak is set for RSA keys shorter than 2048 bits.
+	Weak bool
+
+	Certificate *x509.Certificate
+}
+
+func keySize(pub interface{}) int {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return k.N.BitLen()
+	case *ecdsa.PublicKey:
+		return k.Curve.Params().BitSize
+	}
+	return 0
+}
+
+func inventory(name string, db Database, now time.Time) ([]*KeyEntry, error) {
+	var out []*KeyEntry
+	for _, sl := range db {
+		if sl.Type != CertX509UUID {
+			continue
+		}
+		for _, s := range sl.Signatures {
+			cert, err := x509.ParseCertificate(s.Data)
+			if err != nil {
+				return nil, fmt.Errorf("efisig: %v: parsing certificate owned by %v: %v", name, s.Owner, err)
+			}
+			e := &KeyEntry{
+				Database:     name,
+				Owner:        s.Owner,
+				Subject:      cert.Subject.String(),
+				Issuer:       cert.Issuer.String(),
+				NotAfter:     cert.NotAfter,
+				KeyAlgorithm: cert.PublicKeyAlgorithm,
+				KeySize:      keySize(cert.PublicKey),
+				Expired:      now.After(cert.NotAfter),
+				Certificate:  cert,
+			}
+			e.Weak = e.KeyAlgorithm == x509.RSA && e.KeySize < minRSABits
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+// KeyInventory lists every certificate enrolled in PK, KEK, db and the MOK list,
+// flagging expired and weak keys.
+func KeyInventory() ([]*KeyEntry, error) {
+	now := time.Now()
+	var out []*KeyEntry
+	for _, src := range []struct {
+		vn   efivar.VariableName
+		read func() (Database, error)
+	}{
+		{PKName, nil},
+		{KEKName, nil},
+		{DBName, nil},
+		{MOKListRTName, MOKList},
+	} {
+		var db Database
+		var err error
+		if src.read != nil {
+			db, err = src.read()
+		} else {
+			db, err = ReadDatabase(src.vn)
+		}
+		switch {
+		case os.IsNotExist(err):
+			continue
+		case err != nil:
+			return nil, fmt.Errorf("efisig: reading %v: %v", src.vn.Name, err)
+		}
+		entries, err := inventory(src.vn.Name, db, now)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, entries...)
+	}
+	return out, nil
+}
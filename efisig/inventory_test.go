@@ -0,0 +1,98 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efisig
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func mustRSACertificate(t *testing.T, cn string, bits int, notAfter time.Time) *x509.Certificate {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    notAfter.Add(-24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate: %v", err)
+	}
+	return cert
+}
+
+func TestInventory(t *testing.T) {
+	now := time.Now()
+	good := mustRSACertificate(t, "good", 2048, now.Add(time.Hour))
+	expired := mustRSACertificate(t, "expired", 2048, now.Add(-time.Hour))
+	db := Database{{
+		Type: CertX509UUID,
+		Signatures: []SignatureData{
+			{Owner: testOwner, Data: good.Raw},
+			{Owner: testOwner, Data: expired.Raw},
+		},
+	}}
+
+	entries, err := inventory("db", db, now)
+	if err != nil {
+		t.Fatalf("inventory: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("inventory returned %d entries; want 2", len(entries))
+	}
+	for _, e := range entries {
+		if e.Database != "db" || e.KeySize != 2048 || e.Weak {
+			t.Errorf("entry %v: Database=%q KeySize=%d Weak=%v; want db, 2048, false", e.Subject, e.Database, e.KeySize, e.Weak)
+		}
+		if want := e.Subject == "CN=expired"; e.Expired != want {
+			t.Errorf("entry %v: Expired = %v; want %v", e.Subject, e.Expired, want)
+		}
+	}
+}
+
+func TestInventoryWeakKey(t *testing.T) {
+	now := time.Now()
+	weak := mustRSACertificate(t, "weak", 1024, now.Add(time.Hour))
+	db := Database{{Type: CertX509UUID, Signatures: []SignatureData{{Owner: testOwner, Data: weak.Raw}}}}
+	entries, err := inventory("KEK", db, now)
+	if err != nil {
+		t.Fatalf("inventory: %v", err)
+	}
+	if len(entries) != 1 || !entries[0].Weak {
+		t.Errorf("inventory of RSA-1024 certificate did not flag it as weak")
+	}
+}
+
+func TestKeyEntryFileName(t *testing.T) {
+	cert := mustCertificate(t, "Example Corp. UEFI CA 2011")
+	e := &KeyEntry{Database: "db", Certificate: cert}
+	if got, want := e.fileName(3), "db-03-Example_Corp._UEFI_CA_2011.pem"; got != want {
+		t.Errorf("fileName(3) = %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,333 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efisig
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/internal/zero"
+)
+
+// authenticatedAttributes are the attributes of PK, KEK, db and dbx.
+const authenticatedAttributes = efivar.NonVolatile | efivar.BootserviceAccess | efivar.RuntimeAccess | efivar.TimeBasedAuthenticatedWriteAccess
+
+type KeyAlgorithm int
+
+const (
+	RSA2048 KeyAlgorithm = iota
+	RSA4096
+	ECDSAP256
+	ECDSAP384
+)
+
+func (a KeyAlgorithm) generate() (crypto.Signer, error) {
+	switch a {
+	case RSA2048:
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case RSA4096:
+		return rsa.GenerateKey(rand.Reader, 4096)
+	case ECDSAP256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case ECDSAP384:
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	}
+	return nil, fmt.Errorf("efisig: unknown key algorithm %d", a)
+}
+
+type KeyOptions struct {
+	// Algorithm is used for all three keys. Note that a lot of firmware only accepts RSA2048.
+	Algorithm KeyAlgorithm
+
+	// Organization is used as the subject organization, and to name the keys.
+	Organization string
+
+	// Owner is the signature owner GUID recorded when enrolling the keys.
+	// A random GUID is used if it is not set.
+	Owner uuid.UUID
+
+	// Validity defaults to 20 years.
+	Validity time.Duration
+}
+
+// Key is one level of a Secure Boot key hierarchy.
+type Key struct {
+	// Name is the variable the key is enrolled into: "PK", "KEK" or "db".
+	Name        string
+	Owner       uuid.UUID
+	Certificate *x509.Certificate
+	Signer      crypto.Signer
+}
+
+// KeyHierarchy is a complete set of keys for taking ownership of Secure Boot.
+type KeyHierarchy struct {
+	PK, KEK, DB *Key
+}
+
+func newKey(name string, opts *KeyOptions, usage x509.KeyUsage, extUsage []x509.ExtKeyUsage, isCA bool) (*Key, error) {
+	signer, err := opts.Algorithm.generate()
+	if err != nil {
+		return nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			Organization: []string{opts.Organization},
+			CommonName:   fmt.Sprintf("%s %s", opts.Organization, name),
+		},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(opts.Validity),
+		KeyUsage:              usage,
+		ExtKeyUsage:           extUsage,
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, signer.Public(), signer)
+	if err != nil {
+		return nil, fmt.Errorf("efisig: creating %v certificate: %v", name, err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{Name: name, Owner: opts.Owner, Certificate: cert, Signer: signer}, nil
+}
+
+// GenerateKeyHierarchy creates self-signed PK, KEK and db keys.
+func GenerateKeyHierarchy(opts *KeyOptions) (*KeyHierarchy, error) {
+	o := *opts
+	if o.Owner == uuid.Nil {
+		o.Owner = uuid.New()
+	}
+	if o.Validity == 0 {
+		o.Validity = 20 * 365 * 24 * time.Hour
+	}
+
+	var h KeyHierarchy
+	var err error
+	if h.PK, err = newKey("PK", &o, x509.KeyUsageDigitalSignature|x509.KeyUsageCertSign, nil, true); err != nil {
+		return nil, err
+	}
+	if h.KEK, err = newKey("KEK", &o, x509.KeyUsageDigitalSignature|x509.KeyUsageCertSign, nil, true); err != nil {
+		return nil, err
+	}
+	if h.DB, err = newKey("db", &o, x509.KeyUsageDigitalSignature, []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning}, false); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// Database returns a signature database holding just this key's certificate.
+func (k *Key) Database() Database {
+	return Database{{
+		Type:       CertX509UUID,
+		Signatures: []SignatureData{{Owner: k.Owner, Data: k.Certificate.Raw}},
+	}}
+}
+
+func (k *Key) CertificatePEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: k.Certificate.Raw})
+}
+
+// PrivateKeyPEM encodes k's private key. Callers should overwrite the result
+// once they have written it out.
+func (k *Key) PrivateKeyPEM() ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(k.Signer)
+	if err != nil {
+		return nil, err
+	}
+	defer zero.Bytes(der)
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// Destroy overwrites k's private key, if it is an RSA or ECDSA key held in
+// memory, and removes it from k, which can then no longer sign.
+func (k *Key) Destroy() {
+	if k == nil {
+		return
+	}
+	zero.PrivateKey(k.Signer)
+	k.Signer = nil
+}
+
+// Destroy destroys each of h's keys.
+func (h *KeyHierarchy) Destroy() {
+	h.PK.Destroy()
+	h.KEK.Destroy()
+	h.DB.Destroy()
+}
+
+// Sign produces an authenticated write of data to vn, signed by k.
+func (k *Key) Sign(vn efivar.VariableName, timestamp time.Time, data []byte) (*AuthenticatedData, error) {
+	return SignVariable(vn, authenticatedAttributes, timestamp, data, k.Certificate, k.Signer)
+}
+
+// SignedPayload returns the serialised authenticated write of data to vn, signed by k.
+// It allows a Key to be used as an efiboot.VariableSigner.
+func (k *Key) SignedPayload(vn efivar.VariableName, attrs efivar.Attributes, data []byte) ([]byte, error) {
+	a, err := SignVariable(vn, attrs, time.Now(), data, k.Certificate, k.Signer)
+	if err != nil {
+		return nil, err
+	}
+	return a.Bytes(), nil
+}
+
+func (h *KeyHierarchy) variable(k *Key) efivar.VariableName {
+	switch k {
+	case h.PK:
+		return PKName
+	case h.KEK:
+		return KEKName
+	}
+	return DBName
+}
+
+// AuthPayloads returns ready-to-enroll payloads for each key, keyed by name:
+// PK is self-signed, KEK is signed by PK and db is signed by KEK.
+func (h *KeyHierarchy) AuthPayloads(timestamp time.Time) (map[string][]byte, error) {
+	out := make(map[string][]byte)
+	for _, s := range []struct{ key, signer *Key }{
+		{h.PK, h.PK},
+		{h.KEK, h.PK},
+		{h.DB, h.KEK},
+	} {
+		a, err := s.signer.Sign(h.variable(s.key), timestamp, s.key.Database().Bytes())
+		if err != nil {
+			return nil, err
+		}
+		out[s.key.Name] = a.Bytes()
+	}
+	return out, nil
+}
+
+// ownerFile is the name of the file in which WriteFiles records the signature owner.
+const ownerFile = "GUID"
+
+// WriteFiles writes NAME.pem, NAME.key and NAME.auth for each key into dir,
+// and the signature owner GUID into a file named GUID.
+func (h *KeyHierarchy) WriteFiles(dir string) error {
+	auths, err := h.AuthPayloads(time.Now())
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, ownerFile), []byte(h.PK.Owner.String()+"\n"), 0644); err != nil {
+		return err
+	}
+	for _, k := range []*Key{h.PK, h.KEK, h.DB} {
+		keyPEM, err := k.PrivateKeyPEM()
+		if err != nil {
+			return err
+		}
+		for ext, data := range map[string][]byte{
+			".pem":  k.CertificatePEM(),
+			".key":  keyPEM,
+			".auth": auths[k.Name],
+		} {
+			mode := 0644
+			if ext == ".key" {
+				mode = 0600
+			}
+			if err := ioutil.WriteFile(filepath.Join(dir, k.Name+ext), data, os.FileMode(mode)); err != nil {
+				zero.Bytes(keyPEM)
+				return err
+			}
+		}
+		zero.Bytes(keyPEM)
+	}
+	return nil
+}
+
+func loadKey(dir, name string, owner uuid.UUID) (*Key, error) {
+	certPEM, err := ioutil.ReadFile(filepath.Join(dir, name+".pem"))
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(certPEM)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("efisig: %v.pem does not contain a PEM certificate", name)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("efisig: parsing %v.pem: %v", name, err)
+	}
+
+	keyPEM, err := ioutil.ReadFile(filepath.Join(dir, name+".key"))
+	if err != nil {
+		return nil, err
+	}
+	defer zero.Bytes(keyPEM)
+	block, _ = pem.Decode(keyPEM)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("efisig: %v.key does not contain a PEM private key", name)
+	}
+	defer zero.Bytes(block.Bytes)
+	priv, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("efisig: parsing %v.key: %v", name, err)
+	}
+	signer, ok := priv.(crypto.Signer)
+	if !ok {
+		zero.PrivateKey(priv)
+		return nil, fmt.Errorf("efisig: %v.key holds a %T, which cannot sign", name, priv)
+	}
+	return &Key{Name: name, Owner: owner, Certificate: cert, Signer: signer}, nil
+}
+
+// LoadKeyHierarchy reads a key hierarchy saved by WriteFiles.
+func LoadKeyHierarchy(dir string) (*KeyHierarchy, error) {
+	ownerText, err := ioutil.ReadFile(filepath.Join(dir, ownerFile))
+	if err != nil {
+		return nil, err
+	}
+	owner, err := uuid.Parse(strings.TrimSpace(string(ownerText)))
+	if err != nil {
+		return nil, fmt.Errorf("efisig: parsing %v: %v", ownerFile, err)
+	}
+
+	var h KeyHierarchy
+	for _, k := range []struct {
+		name string
+		key  **Key
+	}{
+		{"PK", &h.PK},
+		{"KEK", &h.KEK},
+		{"db", &h.DB},
+	} {
+		if *k.key, err = loadKey(dir, k.name, owner); err != nil {
+			return nil, err
+		}
+	}
+	return &h, nil
+}
@@ -0,0 +1,140 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efisig
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestKeyHierarchyAuthPayloads(t *testing.T) {
+	for _, alg := range []KeyAlgorithm{RSA2048, ECDSAP256} {
+		h, err := GenerateKeyHierarchy(&KeyOptions{Algorithm: alg, Organization: "Test"})
+		if err != nil {
+			t.Fatalf("GenerateKeyHierarchy(%v): %v", alg, err)
+		}
+		if h.DB.Certificate.ExtKeyUsage[0] != x509.ExtKeyUsageCodeSigning {
+			t.Errorf("db certificate is missing the code signing EKU")
+		}
+
+		ts := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+		auths, err := h.AuthPayloads(ts)
+		if err != nil {
+			t.Fatalf("AuthPayloads: %v", err)
+		}
+
+		for _, s := range []struct{ key, signer *Key }{{h.PK, h.PK}, {h.KEK, h.PK}, {h.DB, h.KEK}} {
+			a, err := ParseAuthenticatedData(auths[s.key.Name])
+			if err != nil {
+				t.Fatalf("%v: ParseAuthenticatedData: %v", s.key.Name, err)
+			}
+			if a.Timestamp != EFITimeFromTime(ts) {
+				t.Errorf("%v: Timestamp = %v; want %v", s.key.Name, a.Timestamp, EFITimeFromTime(ts))
+			}
+
+			var sd signedData
+			if _, err := asn1.Unmarshal(a.CertData, &sd); err != nil {
+				t.Fatalf("%v: unmarshalling SignedData: %v", s.key.Name, err)
+			}
+			content := signedContent(h.variable(s.key), authenticatedAttributes, a.Timestamp, a.Payload)
+			sigAlg := x509.SHA256WithRSA
+			if alg == ECDSAP256 {
+				sigAlg = x509.ECDSAWithSHA256
+			}
+			if err := s.signer.Certificate.CheckSignature(sigAlg, content, sd.SignerInfos[0].EncryptedDigest); err != nil {
+				t.Errorf("%v: signature does not verify against %v: %v", s.key.Name, s.signer.Name, err)
+			}
+
+			db, err := ParseDatabase(a.Payload)
+			if err != nil {
+				t.Fatalf("%v: ParseDatabase: %v", s.key.Name, err)
+			}
+			if !db.Contains(CertX509UUID, s.key.Certificate.Raw) {
+				t.Errorf("%v: payload does not contain the key's certificate", s.key.Name)
+			}
+		}
+	}
+}
+
+func TestSignVariableUnsupportedKey(t *testing.T) {
+	h, err := GenerateKeyHierarchy(&KeyOptions{Algorithm: ECDSAP256})
+	if err != nil {
+		t.Fatalf("GenerateKeyHierarchy: %v", err)
+	}
+	if _, err := SignVariable(DBName, authenticatedAttributes, time.Now(), nil, h.DB.Certificate, fakeSigner{}); err == nil {
+		t.Errorf("SignVariable with unsupported key returned no error")
+	}
+}
+
+type fakeSigner struct{}
+
+func (fakeSigner) Public() crypto.PublicKey { return "not a key" }
+func (fakeSigner) Sign(_ io.Reader, _ []byte, _ crypto.SignerOpts) ([]byte, error) {
+	return nil, nil
+}
+
+func TestLoadKeyHierarchy(t *testing.T) {
+	h, err := GenerateKeyHierarchy(&KeyOptions{Algorithm: ECDSAP256, Organization: "Test"})
+	if err != nil {
+		t.Fatalf("GenerateKeyHierarchy: %v", err)
+	}
+	dir, err := ioutil.TempDir("", "efisig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := h.WriteFiles(dir); err != nil {
+		t.Fatalf("WriteFiles: %v", err)
+	}
+	got, err := LoadKeyHierarchy(dir)
+	if err != nil {
+		t.Fatalf("LoadKeyHierarchy: %v", err)
+	}
+	for _, k := range []struct{ got, want *Key }{{got.PK, h.PK}, {got.KEK, h.KEK}, {got.DB, h.DB}} {
+		if k.got.Name != k.want.Name || k.got.Owner != k.want.Owner || !k.got.Certificate.Equal(k.want.Certificate) {
+			t.Errorf("loaded %v = {%v, %v, %v}; want {%v, %v, %v}", k.want.Name, k.got.Name, k.got.Owner, k.got.Certificate.Subject, k.want.Name, k.want.Owner, k.want.Certificate.Subject)
+		}
+		if !reflect.DeepEqual(k.got.Signer.Public(), k.want.Signer.Public()) {
+			t.Errorf("loaded %v has the wrong private key", k.want.Name)
+		}
+	}
+}
+
+func TestKeyHierarchyDestroy(t *testing.T) {
+	h, err := GenerateKeyHierarchy(&KeyOptions{Algorithm: ECDSAP256, Organization: "Test"})
+	if err != nil {
+		t.Fatalf("GenerateKeyHierarchy: %v", err)
+	}
+	priv := h.KEK.Signer.(*ecdsa.PrivateKey)
+	h.Destroy()
+	if priv.D.Sign() != 0 {
+		t.Errorf("KEK private key = %v after Destroy; want 0", priv.D)
+	}
+	if h.PK.Signer != nil {
+		t.Errorf("PK still has a Signer after Destroy")
+	}
+	if _, err := h.PK.PrivateKeyPEM(); err == nil {
+		t.Errorf("PrivateKeyPEM succeeded after Destroy")
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efisig
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// sameShape reports whether the signatures of a and b could share one list.
+func sameShape(a, b *SignatureList) bool {
+	return a.Type == b.Type && a.signatureSize() == b.signatureSize() && bytes.Equal(a.Header, b.Header)
+}
+
+// Merge combines databases into one. Lists with the same type, header and
+// signature size are coalesced, and duplicate signatures are dropped, keeping
+// the first occurrence and its owner.
+func Merge(dbs ...Database) Database {
+	var out Database
+	seen := make(map[string]bool)
+	for _, db := range dbs {
+		for _, sl := range db {
+			var dst *SignatureList
+			for _, o := range out {
+				if sameShape(o, sl) {
+					dst = o
+					break
+				}
+			}
+			if dst == nil {
+				dst = &SignatureList{Type: sl.Type, Header: sl.Header}
+				out = append(out, dst)
+			}
+			for _, s := range sl.Signatures {
+				key := sl.Type.String() + string(s.Data)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				dst.Signatures = append(dst.Signatures, s)
+			}
+		}
+	}
+
+	// Drop lists which ended up empty because all their signatures were duplicates.
+	n := 0
+	for _, sl := range out {
+		if len(sl.Signatures) > 0 {
+			out[n] = sl
+			n++
+		}
+	}
+	return out[:n]
+}
+
+// Dedup removes duplicate signatures across lists of the same type.
+func (d Database) Dedup() Database {
+	return Merge(d)
+}
+
+// Split breaks up lists whose encoding exceeds maxSize bytes into several
+// smaller lists, so that each can be written in a single variable update.
+func (d Database) Split(maxSize int) (Database, error) {
+	var out Database
+	for _, sl := range d {
+		overhead := signatureListHeaderSize + len(sl.Header)
+		perList := (maxSize - overhead) / sl.signatureSize()
+		if perList < 1 {
+			return nil, fmt.Errorf("efisig: a %v signature does not fit in %d bytes", sl.Type, maxSize)
+		}
+		sigs := sl.Signatures
+		for len(sigs) > perList {
+			out = append(out, &SignatureList{Type: sl.Type, Header: sl.Header, Signatures: sigs[:perList]})
+			sigs = sigs[perList:]
+		}
+		out = append(out, &SignatureList{Type: sl.Type, Header: sl.Header, Signatures: sigs})
+	}
+	return out, nil
+}
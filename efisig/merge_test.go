@@ -0,0 +1,69 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efisig
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func sha256Entry(owner uuid.UUID, b byte) SignatureData {
+	return SignatureData{Owner: owner, Data: bytes.Repeat([]byte{b}, 32)}
+}
+
+func TestMerge(t *testing.T) {
+	local := uuid.MustParse("84be9c3e-8a32-42c0-891c-4cd3b072becc")
+	vendor := Database{{Type: CertSHA256UUID, Signatures: []SignatureData{sha256Entry(testOwner, 1), sha256Entry(testOwner, 2)}}}
+	mine := Database{
+		{Type: CertSHA256UUID, Signatures: []SignatureData{sha256Entry(local, 2), sha256Entry(local, 3)}},
+		{Type: CertX509UUID, Signatures: []SignatureData{{Owner: local, Data: []byte("cert")}}},
+	}
+
+	got := Merge(vendor, mine)
+	want := Database{
+		{Type: CertSHA256UUID, Signatures: []SignatureData{sha256Entry(testOwner, 1), sha256Entry(testOwner, 2), sha256Entry(local, 3)}},
+		{Type: CertX509UUID, Signatures: []SignatureData{{Owner: local, Data: []byte("cert")}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Merge = %v; want %v", got, want)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	var sigs []SignatureData
+	for n := 0; n < 5; n++ {
+		sigs = append(sigs, sha256Entry(testOwner, byte(n)))
+	}
+	db := Database{{Type: CertSHA256UUID, Signatures: sigs}}
+
+	// Room for the list header and two 48 byte signatures.
+	got, err := db.Split(signatureListHeaderSize + 2*48 + 10)
+	if err != nil {
+		t.Fatalf("Split: %v", err)
+	}
+	if len(got) != 3 || got.Len() != 5 {
+		t.Fatalf("Split returned %d lists with %d signatures; want 3 lists with 5", len(got), got.Len())
+	}
+	if !reflect.DeepEqual(got.Dedup(), db) {
+		t.Errorf("Split(...).Dedup() = %v; want %v", got.Dedup(), db)
+	}
+
+	if _, err := db.Split(signatureListHeaderSize + 10); err == nil {
+		t.Errorf("Split into lists too small for a signature returned no error")
+	}
+}
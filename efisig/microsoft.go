@@ -0,0 +1,83 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efisig
+
+// MicrosoftCAs records which of Microsoft's Secure Boot CAs a db contains.
+type MicrosoftCAs struct {
+	// Windows is set if a CA which signs Windows boot loaders is present.
+	Windows bool
+
+	// ThirdParty is set if a CA which signs third-party loaders (such as
+	// shim) and option ROMs is present.
+	ThirdParty bool
+
+	// OptionROM is set if a CA which signs option ROMs, such as GPU and
+	// network card firmware, is present.
+	OptionROM bool
+}
+
+// CheckMicrosoftCAs reports which Microsoft CAs db contains.
+// This is useful before enrolling a custom db: many machines need the
+// third-party CA for their option ROMs to load, without which they may not
+// even be able to display the firmware setup.
+func CheckMicrosoftCAs(db Database) (*MicrosoftCAs, error) {
+	certs, err := db.Certificates()
+	if err != nil {
+		return nil, err
+	}
+	m := new(MicrosoftCAs)
+	for _, c := range certs {
+		ca, ok := IdentifyCertificate(c)
+		if !ok {
+			continue
+		}
+		switch ca {
+		case MicrosoftWindowsProductionPCA2011, MicrosoftWindowsUEFICA2023:
+			m.Windows = true
+		case MicrosoftUEFICA2011:
+			m.ThirdParty = true
+			m.OptionROM = true
+		case MicrosoftUEFICA2023:
+			m.ThirdParty = true
+		case MicrosoftOptionROMUEFICA2023:
+			m.OptionROM = true
+		}
+	}
+	return m, nil
+}
+
+// Warnings describes what will fail to boot with this set of CAs.
+func (m *MicrosoftCAs) Warnings() []string {
+	var out []string
+	if !m.Windows {
+		out = append(out, "db does not contain a Microsoft Windows CA: Windows will not boot")
+	}
+	if !m.ThirdParty {
+		out = append(out, "db does not contain the Microsoft UEFI CA: Microsoft-signed shim and other third-party loaders will not boot")
+	}
+	if !m.OptionROM {
+		out = append(out, "db does not contain a Microsoft option ROM CA: option ROMs (e.g. GPU or network firmware) may not load")
+	}
+	return out
+}
+
+// CurrentMicrosoftCAs reports which Microsoft CAs are enrolled in db.
+func CurrentMicrosoftCAs() (*MicrosoftCAs, error) {
+	db, err := ReadDatabase(DBName)
+	if err != nil {
+		return nil, err
+	}
+	return CheckMicrosoftCAs(db)
+}
@@ -0,0 +1,40 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efisig
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckMicrosoftCAs(t *testing.T) {
+	db := Database{{
+		Type: CertX509UUID,
+		Signatures: []SignatureData{
+			{Owner: testOwner, Data: mustCertificate(t, "Microsoft Windows Production PCA 2011").Raw},
+			{Owner: testOwner, Data: mustCertificate(t, "My Own db Key").Raw},
+		},
+	}}
+	got, err := CheckMicrosoftCAs(db)
+	if err != nil {
+		t.Fatalf("CheckMicrosoftCAs: %v", err)
+	}
+	if want := (&MicrosoftCAs{Windows: true}); !reflect.DeepEqual(got, want) {
+		t.Errorf("CheckMicrosoftCAs = %+v; want %+v", got, want)
+	}
+	if n := len(got.Warnings()); n != 2 {
+		t.Errorf("Warnings() returned %d warnings; want 2", n)
+	}
+}
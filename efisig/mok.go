@@ -0,0 +1,168 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efisig
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"unicode/utf16"
+
+	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/internal/zero"
+)
+
+var (
+	MOKListRTName    = efivar.VariableName{GUID: ShimLockUUID, Name: "MokListRT"}
+	MOKListXRTName   = efivar.VariableName{GUID: ShimLockUUID, Name: "MokListXRT"}
+	MOKSBStateRTName = efivar.VariableName{GUID: ShimLockUUID, Name: "MokSBStateRT"}
+
+	MOKNewName     = efivar.VariableName{GUID: ShimLockUUID, Name: "MokNew"}
+	MOKAuthName    = efivar.VariableName{GUID: ShimLockUUID, Name: "MokAuth"}
+	MOKDelName     = efivar.VariableName{GUID: ShimLockUUID, Name: "MokDel"}
+	MOKDelAuthName = efivar.VariableName{GUID: ShimLockUUID, Name: "MokDelAuth"}
+
+	ErrEmptyMOKPassword = errors.New("efisig: MOK request password must not be empty")
+)
+
+// MOKRequest is a pending change to the MOK list. Shim's MokManager asks for
+// it to be confirmed, with the password it was staged with, on the next boot.
+type MOKRequest struct {
+	// Name holds the certificates to add or remove; AuthName holds the password hash.
+	Name, AuthName efivar.VariableName
+}
+
+var (
+	MOKImportRequest = MOKRequest{MOKNewName, MOKAuthName}
+	MOKDeleteRequest = MOKRequest{MOKDelName, MOKDelAuthName}
+)
+
+// mokRequestAttributes are the attributes mokutil uses for MOK requests.
+const mokRequestAttributes = efivar.NonVolatile | efivar.BootserviceAccess | efivar.RuntimeAccess
+
+// maxMOKPasswordLength is the longest password MokManager will accept.
+const maxMOKPasswordLength = 256
+
+// MOKPasswordHash returns the content of the authentication variable for a
+// request: the SHA-256 of the request followed by the password in UCS-2.
+func MOKPasswordHash(request []byte, password string) []byte {
+	runes := []rune(password)
+	ucs2 := utf16.Encode(runes)
+	b := make([]byte, 2*len(ucs2))
+	for n, c := range ucs2 {
+		b[2*n], b[2*n+1] = byte(c), byte(c>>8)
+	}
+	h := sha256.New()
+	h.Write(request)
+	h.Write(b)
+	zero.Runes(runes)
+	zero.Uint16s(ucs2)
+	zero.Bytes(b)
+	return h.Sum(nil)
+}
+
+// Pending returns the certificates staged in r, or nil if there is no such request.
+func (r MOKRequest) Pending() (Database, error) {
+	db, err := ReadDatabase(r.Name)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return db, err
+}
+
+// Stage adds certs to r, protected by password. Certificates already staged
+// are kept, and the whole request is protected by the new password.
+func (r MOKRequest) Stage(certs []*x509.Certificate, password string) error {
+	if password == "" {
+		return ErrEmptyMOKPassword
+	}
+	if n := len(utf16.Encode([]rune(password))); n > maxMOKPasswordLength {
+		return fmt.Errorf("efisig: MOK request password is %d characters long; the maximum is %d", n, maxMOKPasswordLength)
+	}
+	db, err := r.Pending()
+	if err != nil {
+		return fmt.Errorf("efisig: reading %v: %v", r.Name.Name, err)
+	}
+	for _, cert := range certs {
+		if db.Contains(CertX509UUID, cert.Raw) {
+			continue
+		}
+		db = append(db, &SignatureList{
+			Type:       CertX509UUID,
+			Signatures: []SignatureData{{Owner: ShimLockUUID, Data: cert.Raw}},
+		})
+	}
+
+	data := db.Bytes()
+	for _, v := range []*efivar.Variable{
+		{VariableName: r.Name, Data: data, Attributes: mokRequestAttributes},
+		{VariableName: r.AuthName, Data: MOKPasswordHash(data, password), Attributes: mokRequestAttributes},
+	} {
+		if err := v.Set(0600); err != nil {
+			return fmt.Errorf("efisig: writing %v: %v", v.Name, err)
+		}
+	}
+	return nil
+}
+
+// Cancel withdraws r, if it is pending.
+func (r MOKRequest) Cancel() error {
+	for _, vn := range []efivar.VariableName{r.Name, r.AuthName} {
+		if err := vn.Delete(); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("efisig: deleting %v: %v", vn.Name, err)
+		}
+	}
+	return nil
+}
+
+// readMirroredDatabase reads a database that shim may have split across
+// vn, vn1, vn2, ... because it was too large for a single variable.
+func readMirroredDatabase(vn efivar.VariableName) (Database, error) {
+	db, err := ReadDatabase(vn)
+	if err != nil {
+		return nil, err
+	}
+	for n := 1; ; n++ {
+		part, err := ReadDatabase(efivar.VariableName{GUID: vn.GUID, Name: fmt.Sprintf("%s%d", vn.Name, n)})
+		switch {
+		case os.IsNotExist(err):
+			return db, nil
+		case err != nil:
+			return nil, err
+		}
+		db = append(db, part...)
+	}
+}
+
+// MOKList returns the Machine Owner Keys trusted by shim during this boot.
+func MOKList() (Database, error) { return readMirroredDatabase(MOKListRTName) }
+
+// MOKListX returns the Machine Owner Key revocations applied by shim during this boot.
+func MOKListX() (Database, error) { return readMirroredDatabase(MOKListXRTName) }
+
+// MOKValidationEnabled reports whether shim is verifying the images it loads.
+// Validation can be disabled through MokManager, in which case shim exposes MokSBStateRT.
+func MOKValidationEnabled() (bool, error) {
+	disabled, err := readBool(MOKSBStateRTName)
+	switch {
+	case os.IsNotExist(err):
+		return true, nil
+	case err != nil:
+		return false, err
+	}
+	return !disabled, nil
+}
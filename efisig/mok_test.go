@@ -0,0 +1,31 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efisig
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestMOKPasswordHash(t *testing.T) {
+	req := make([]byte, 16)
+	for n := range req {
+		req[n] = byte(n)
+	}
+	const want = "eaf46f8169f25902d4b71a916483c43a3a0f7491cafd09419097d75932c04121"
+	if got := hex.EncodeToString(MOKPasswordHash(req, "pässword")); got != want {
+		t.Errorf("MOKPasswordHash = %v; want %v", got, want)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efisig
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"unicode/utf16"
+
+	"github.com/lukegb/goefivar/efivar"
+)
+
+// TCG event types measured into PCR 7.
+const (
+	EventSeparator               = 0x00000004
+	EventEFIVariableDriverConfig = 0x80000001
+	EventEFIVariableAuthority    = 0x800000e0
+)
+
+// MeasuredEvent is an event which firmware extends into a PCR.
+type MeasuredEvent struct {
+	Type uint32
+	Data []byte
+}
+
+func (e MeasuredEvent) Digest(h crypto.Hash) []byte {
+	d := h.New()
+	d.Write(e.Data)
+	return d.Sum(nil)
+}
+
+// variableEvent encodes a UEFI_VARIABLE_DATA structure, as measured for variables.
+func variableEvent(typ uint32, vn efivar.VariableName, data []byte) MeasuredEvent {
+	name := utf16.Encode([]rune(vn.Name))
+	var buf bytes.Buffer
+	buf.Write(efivar.GUIDBytes(vn.GUID))
+	binary.Write(&buf, byteOrder, uint64(len(name)))
+	binary.Write(&buf, byteOrder, uint64(len(data)))
+	binary.Write(&buf, byteOrder, name)
+	buf.Write(data)
+	return MeasuredEvent{Type: typ, Data: buf.Bytes()}
+}
+
+// SecureBootConfig is the Secure Boot configuration measured into PCR 7.
+// The key databases hold raw variable contents, and are nil if the variable is absent.
+type SecureBootConfig struct {
+	SecureBoot bool
+	PK         []byte
+	KEK        []byte
+	DB         []byte
+	DBX        []byte
+}
+
+// CurrentSecureBootConfig reads the Secure Boot configuration of this machine,
+// which can then be modified to describe a proposed change.
+func CurrentSecureBootConfig() (*SecureBootConfig, error) {
+	c := new(SecureBootConfig)
+	var err error
+	if c.SecureBoot, err = readBool(SecureBootName); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("efisig: reading SecureBoot: %v", err)
+	}
+	for _, f := range []struct {
+		vn  efivar.VariableName
+		dst *[]byte
+	}{
+		{PKName, &c.PK},
+		{KEKName, &c.KEK},
+		{DBName, &c.DB},
+		{DBXName, &c.DBX},
+	} {
+		v, err := f.vn.Get()
+		switch {
+		case os.IsNotExist(err):
+			continue
+		case err != nil:
+			return nil, fmt.Errorf("efisig: reading %v: %v", f.vn.Name, err)
+		}
+		*f.dst = v.Data
+	}
+	return c, nil
+}
+
+// Events returns the events firmware measures into PCR 7 for this configuration,
+// up to and including the separator which ends the pre-OS configuration measurements.
+func (c *SecureBootConfig) Events() []MeasuredEvent {
+	sb := []byte{0}
+	if c.SecureBoot {
+		sb[0] = 1
+	}
+	return []MeasuredEvent{
+		variableEvent(EventEFIVariableDriverConfig, SecureBootName, sb),
+		variableEvent(EventEFIVariableDriverConfig, PKName, c.PK),
+		variableEvent(EventEFIVariableDriverConfig, KEKName, c.KEK),
+		variableEvent(EventEFIVariableDriverConfig, DBName, c.DB),
+		variableEvent(EventEFIVariableDriverConfig, DBXName, c.DBX),
+		{Type: EventSeparator, Data: make([]byte, 4)},
+	}
+}
+
+// Authority is a signature database entry which verified an image during boot.
+// Firmware measures each distinct authority into PCR 7 the first time it is used.
+type Authority struct {
+	Variable  efivar.VariableName
+	Signature SignatureData
+}
+
+func (a Authority) event() MeasuredEvent {
+	data := append(efivar.GUIDBytes(a.Signature.Owner), a.Signature.Data...)
+	return variableEvent(EventEFIVariableAuthority, a.Variable, data)
+}
+
+// PredictPCR7 computes the value of PCR 7 in the bank using h, for a boot with
+// configuration c in which images were verified by authorities, in order.
+// For a typical shim boot the authorities are the db entry that verified shim
+// followed by whatever shim itself measures, such as the MokList entry that verified the kernel.
+func PredictPCR7(h crypto.Hash, c *SecureBootConfig, authorities []Authority) []byte {
+	pcr := make([]byte, h.Size())
+	extend := func(e MeasuredEvent) {
+		d := h.New()
+		d.Write(pcr)
+		d.Write(e.Digest(h))
+		pcr = d.Sum(nil)
+	}
+	for _, e := range c.Events() {
+		extend(e)
+	}
+	seen := make(map[string]bool)
+	for _, a := range authorities {
+		e := a.event()
+		if seen[string(e.Data)] {
+			continue
+		}
+		seen[string(e.Data)] = true
+		extend(e)
+	}
+	return pcr
+}
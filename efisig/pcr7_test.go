@@ -0,0 +1,70 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efisig
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func TestVariableEvent(t *testing.T) {
+	e := variableEvent(EventEFIVariableDriverConfig, SecureBootName, []byte{1})
+	want, _ := hex.DecodeString("" +
+		"61dfe48bca93d211aa0d00e098032b8c" + // EFI_GLOBAL_VARIABLE
+		"0a00000000000000" + // UnicodeNameLength
+		"0100000000000000" + // VariableDataLength
+		"53006500630075007200650042006f006f007400" + // L"SecureBoot"
+		"01")
+	if !bytes.Equal(e.Data, want) {
+		t.Errorf("variableEvent(SecureBoot) = %x; want %x", e.Data, want)
+	}
+}
+
+func TestPredictPCR7(t *testing.T) {
+	c := &SecureBootConfig{SecureBoot: true, PK: []byte("pk"), KEK: []byte("kek"), DB: []byte("db")}
+
+	want := make([]byte, sha256.Size)
+	for _, e := range c.Events() {
+		d := sha256.Sum256(e.Data)
+		want = sha256Sum(want, d[:])
+	}
+	if got := PredictPCR7(crypto.SHA256, c, nil); !bytes.Equal(got, want) {
+		t.Errorf("PredictPCR7 = %x; want %x", got, want)
+	}
+
+	auth := Authority{Variable: DBName, Signature: SignatureData{Owner: testOwner, Data: []byte("cert")}}
+	withAuth := PredictPCR7(crypto.SHA256, c, []Authority{auth, auth})
+	d := sha256.Sum256(auth.event().Data)
+	if want := sha256Sum(want, d[:]); !bytes.Equal(withAuth, want) {
+		t.Errorf("PredictPCR7 with repeated authority = %x; want %x", withAuth, want)
+	}
+
+	changed := *c
+	changed.DBX = []byte("dbx")
+	if bytes.Equal(PredictPCR7(crypto.SHA256, &changed, nil), PredictPCR7(crypto.SHA256, c, nil)) {
+		t.Errorf("PredictPCR7 did not change when dbx changed")
+	}
+}
+
+func sha256Sum(parts ...[]byte) []byte {
+	h := sha256.New()
+	for _, p := range parts {
+		h.Write(p)
+	}
+	return h.Sum(nil)
+}
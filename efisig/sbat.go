@@ -0,0 +1,153 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efisig
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lukegb/goefivar/efivar"
+)
+
+var (
+	SBATLevelRTName = efivar.VariableName{GUID: ShimLockUUID, Name: "SbatLevelRT"}
+	SBATPolicyName  = efivar.VariableName{GUID: ShimLockUUID, Name: "SbatPolicy"}
+)
+
+// SBATPolicy selects which revocation level shim applies on the next boot.
+type SBATPolicy uint8
+
+const (
+	SBATPolicyLatest   SBATPolicy = 1
+	SBATPolicyPrevious SBATPolicy = 2
+	SBATPolicyReset    SBATPolicy = 3
+)
+
+func (p SBATPolicy) String() string {
+	switch p {
+	case SBATPolicyLatest:
+		return "latest"
+	case SBATPolicyPrevious:
+		return "previous"
+	case SBATPolicyReset:
+		return "reset"
+	}
+	return fmt.Sprintf("SBATPolicy(%d)", uint8(p))
+}
+
+// SBATGeneration is a single component line of an SBAT revocation payload.
+// Binaries carrying an SBAT entry for Component with a lower generation are revoked.
+type SBATGeneration struct {
+	Component  string
+	Generation int
+}
+
+// SBATLevel is a parsed SBAT revocation payload, as stored in SbatLevelRT.
+type SBATLevel struct {
+	// Version is the SBAT format version from the leading "sbat" line.
+	Version int
+
+	// Datestamp identifies the revocation release, e.g. "2022111500".
+	Datestamp string
+
+	Generations []SBATGeneration
+}
+
+// Generation returns the minimum generation required for component.
+func (l *SBATLevel) Generation(component string) (int, bool) {
+	for _, g := range l.Generations {
+		if g.Component == component {
+			return g.Generation, true
+		}
+	}
+	return 0, false
+}
+
+func (l *SBATLevel) Bytes() []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "sbat,%d,%s\n", l.Version, l.Datestamp)
+	for _, g := range l.Generations {
+		fmt.Fprintf(&buf, "%s,%d\n", g.Component, g.Generation)
+	}
+	return buf.Bytes()
+}
+
+func ParseSBATLevel(data []byte) (*SBATLevel, error) {
+	data = bytes.TrimRight(data, "\x00")
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+
+	header := strings.Split(strings.TrimSpace(lines[0]), ",")
+	if len(header) < 2 || header[0] != "sbat" {
+		return nil, fmt.Errorf("efisig: SBAT level: missing sbat header line")
+	}
+	version, err := strconv.Atoi(header[1])
+	if err != nil {
+		return nil, fmt.Errorf("efisig: SBAT level: parsing version %q: %v", header[1], err)
+	}
+	l := &SBATLevel{Version: version}
+	if len(header) > 2 {
+		l.Datestamp = header[2]
+	}
+
+	for n, line := range lines[1:] {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, ",")
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("efisig: SBAT level: line %d: want component,generation; got %q", n+2, line)
+		}
+		gen, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("efisig: SBAT level: line %d: parsing generation %q: %v", n+2, fields[1], err)
+		}
+		l.Generations = append(l.Generations, SBATGeneration{fields[0], gen})
+	}
+	return l, nil
+}
+
+// CurrentSBATLevel returns the revocation level shim applied during this boot.
+func CurrentSBATLevel() (*SBATLevel, error) {
+	v, err := SBATLevelRTName.Get()
+	if err != nil {
+		return nil, err
+	}
+	return ParseSBATLevel(v.Data)
+}
+
+// CurrentSBATPolicy returns the pending SBAT policy, if one has been set.
+func CurrentSBATPolicy() (SBATPolicy, error) {
+	v, err := SBATPolicyName.Get()
+	if err != nil {
+		return 0, err
+	}
+	if len(v.Data) != 1 {
+		return 0, ErrVariableCorrupted
+	}
+	return SBATPolicy(v.Data[0]), nil
+}
+
+// SetSBATPolicy asks shim to apply policy on the next boot.
+func SetSBATPolicy(policy SBATPolicy) error {
+	v := &efivar.Variable{
+		VariableName: SBATPolicyName,
+		Data:         []byte{byte(policy)},
+		Attributes:   efivar.NonVolatile | efivar.BootserviceAccess | efivar.RuntimeAccess,
+	}
+	return v.Set(0644)
+}
@@ -0,0 +1,60 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efisig
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSBATLevel(t *testing.T) {
+	in := []byte("sbat,1,2022111500\nshim,2\ngrub,3\ngrub.debian,4\n\x00")
+	got, err := ParseSBATLevel(in)
+	if err != nil {
+		t.Fatalf("ParseSBATLevel: %v", err)
+	}
+	want := &SBATLevel{
+		Version:   1,
+		Datestamp: "2022111500",
+		Generations: []SBATGeneration{
+			{"shim", 2},
+			{"grub", 3},
+			{"grub.debian", 4},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseSBATLevel = %+v; want %+v", got, want)
+	}
+	if gen, ok := got.Generation("grub"); !ok || gen != 3 {
+		t.Errorf("Generation(%q) = %v, %v; want 3, true", "grub", gen, ok)
+	}
+	if string(got.Bytes()) != string(in[:len(in)-1]) {
+		t.Errorf("Bytes() = %q; want %q", got.Bytes(), in[:len(in)-1])
+	}
+}
+
+func TestParseSBATLevelInvalid(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"shim,1\n",
+		"sbat,x,2021030218\n",
+		"sbat,1,2021030218\nshim\n",
+		"sbat,1,2021030218\nshim,one\n",
+	} {
+		if _, err := ParseSBATLevel([]byte(in)); err == nil {
+			t.Errorf("ParseSBATLevel(%q) returned no error", in)
+		}
+	}
+}
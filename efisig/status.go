@@ -0,0 +1,133 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efisig
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/lukegb/goefivar/efivar"
+)
+
+// DatabaseStatus summarises the content of a signature database.
+type DatabaseStatus struct {
+	Entries int
+
+	// Issuers lists the distinct issuers of the X.509 certificates in the database.
+	Issuers []string
+}
+
+// Status is a summary of the Secure Boot configuration of this machine.
+type Status struct {
+	SecureBoot bool
+	SetupMode  bool
+
+	// PKSubject is the subject of the enrolled Platform Key, or empty if none is enrolled.
+	PKSubject string
+
+	KEK DatabaseStatus
+	DB  DatabaseStatus
+	DBX DatabaseStatus
+
+	// MOKValidation is false if shim has been told not to verify images.
+	MOKValidation bool
+	MOKEntries    int
+
+	// SBATLevel is nil unless shim published its revocation level.
+	SBATLevel *SBATLevel
+}
+
+func summarise(vn efivar.VariableName) (DatabaseStatus, error) {
+	var ds DatabaseStatus
+	db, err := ReadDatabase(vn)
+	switch {
+	case os.IsNotExist(err):
+		return ds, nil
+	case err != nil:
+		return ds, fmt.Errorf("efisig: reading %v: %v", vn.Name, err)
+	}
+	ds.Entries = db.Len()
+
+	certs, err := db.Certificates()
+	if err != nil {
+		return ds, err
+	}
+	seen := make(map[string]bool)
+	for _, c := range certs {
+		issuer := c.Issuer.String()
+		if !seen[issuer] {
+			seen[issuer] = true
+			ds.Issuers = append(ds.Issuers, issuer)
+		}
+	}
+	return ds, nil
+}
+
+// CurrentStatus collects the Secure Boot state, enrolled keys, MOK and SBAT state in one call.
+// Variables which are absent are reported as zero values rather than errors.
+func CurrentStatus() (*Status, error) {
+	s := new(Status)
+	var err error
+
+	if s.SecureBoot, err = readBool(SecureBootName); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("efisig: reading SecureBoot: %v", err)
+	}
+	if s.SetupMode, err = readBool(SetupModeName); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("efisig: reading SetupMode: %v", err)
+	}
+
+	pk, err := ReadDatabase(PKName)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, fmt.Errorf("efisig: reading PK: %v", err)
+	default:
+		certs, err := pk.Certificates()
+		if err != nil {
+			return nil, err
+		}
+		if len(certs) > 0 {
+			s.PKSubject = certs[0].Subject.String()
+		}
+	}
+
+	if s.KEK, err = summarise(KEKName); err != nil {
+		return nil, err
+	}
+	if s.DB, err = summarise(DBName); err != nil {
+		return nil, err
+	}
+	if s.DBX, err = summarise(DBXName); err != nil {
+		return nil, err
+	}
+
+	if s.MOKValidation, err = MOKValidationEnabled(); err != nil {
+		return nil, fmt.Errorf("efisig: reading MokSBStateRT: %v", err)
+	}
+	mok, err := MOKList()
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, fmt.Errorf("efisig: reading MokListRT: %v", err)
+	default:
+		s.MOKEntries = mok.Len()
+	}
+
+	s.SBATLevel, err = CurrentSBATLevel()
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("efisig: reading SbatLevelRT: %v", err)
+	}
+	return s, nil
+}
@@ -0,0 +1,38 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efisig
+
+import (
+	"testing"
+
+	"github.com/lukegb/goefivar/internal/testfixture"
+)
+
+func TestCurrentStatus(t *testing.T) {
+	defer testfixture.Use(t, "laptop")()
+	s, err := CurrentStatus()
+	if err != nil {
+		t.Fatalf("CurrentStatus: %v", err)
+	}
+	if !s.SecureBoot || s.SetupMode {
+		t.Errorf("SecureBoot, SetupMode = %v, %v; want true, false", s.SecureBoot, s.SetupMode)
+	}
+	if s.PKSubject != "" || s.KEK.Entries != 0 || s.DB.Entries != 0 || s.DBX.Entries != 0 {
+		t.Errorf("CurrentStatus reported keys which are not enrolled: %+v", s)
+	}
+	if !s.MOKValidation || s.MOKEntries != 0 || s.SBATLevel != nil {
+		t.Errorf("CurrentStatus reported shim state which is not present: %+v", s)
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efisig
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KnownCA is a well-known Secure Boot certificate authority.
+//
+// Certificates are matched by the common name of their subject (or issuer),
+// which is enough to tell whose keys a machine trusts but is not a substitute
+// for verifying signatures.
+type KnownCA struct {
+	Vendor string
+
+	// CommonName is matched as a prefix, so that "Red Hat Secure Boot CA"
+	// also matches its numbered successors.
+	CommonName string
+}
+
+func (ca KnownCA) String() string { return fmt.Sprintf("%s (%s)", ca.CommonName, ca.Vendor) }
+
+func (ca KnownCA) matches(n pkix.Name) bool {
+	return n.CommonName != "" && strings.HasPrefix(n.CommonName, ca.CommonName)
+}
+
+var (
+	MicrosoftWindowsProductionPCA2011 = KnownCA{"Microsoft", "Microsoft Windows Production PCA 2011"}
+	MicrosoftWindowsUEFICA2023        = KnownCA{"Microsoft", "Windows UEFI CA 2023"}
+	MicrosoftUEFICA2011               = KnownCA{"Microsoft", "Microsoft Corporation UEFI CA 2011"}
+	MicrosoftUEFICA2023               = KnownCA{"Microsoft", "Microsoft UEFI CA 2023"}
+	MicrosoftOptionROMUEFICA2023      = KnownCA{"Microsoft", "Microsoft Option ROM UEFI CA 2023"}
+	MicrosoftKEKCA2011                = KnownCA{"Microsoft", "Microsoft Corporation KEK CA 2011"}
+	MicrosoftKEKCA2023                = KnownCA{"Microsoft", "Microsoft Corporation KEK 2K CA 2023"}
+	CanonicalMasterCA                 = KnownCA{"Canonical", "Canonical Ltd. Master Certificate Authority"}
+	RedHatSecureBootCA                = KnownCA{"Red Hat", "Red Hat Secure Boot CA"}
+	DebianSecureBootCA                = KnownCA{"Debian", "Debian Secure Boot CA"}
+	SUSESecureBootCA                  = KnownCA{"SUSE", "SUSE Linux Enterprise Secure Boot CA"}
+	OpenSUSESecureBootCA              = KnownCA{"SUSE", "openSUSE Secure Boot CA"}
+
+	KnownCAs = []KnownCA{
+		MicrosoftWindowsProductionPCA2011,
+		MicrosoftWindowsUEFICA2023,
+		MicrosoftUEFICA2011,
+		MicrosoftUEFICA2023,
+		MicrosoftOptionROMUEFICA2023,
+		MicrosoftKEKCA2011,
+		MicrosoftKEKCA2023,
+		CanonicalMasterCA,
+		RedHatSecureBootCA,
+		DebianSecureBootCA,
+		SUSESecureBootCA,
+		OpenSUSESecureBootCA,
+	}
+)
+
+// IdentifyCertificate returns the well-known CA that cert is, if any.
+func IdentifyCertificate(cert *x509.Certificate) (KnownCA, bool) {
+	for _, ca := range KnownCAs {
+		if ca.matches(cert.Subject) {
+			return ca, true
+		}
+	}
+	return KnownCA{}, false
+}
+
+// identifyChain returns the well-known CAs which appear in, or issued, the certificates of chain.
+func identifyChain(chain []*x509.Certificate) []KnownCA {
+	var out []KnownCA
+	seen := make(map[KnownCA]bool)
+	for _, c := range chain {
+		for _, ca := range KnownCAs {
+			if !seen[ca] && (ca.matches(c.Subject) || ca.matches(c.Issuer)) {
+				seen[ca] = true
+				out = append(out, ca)
+			}
+		}
+	}
+	return out
+}
+
+// TrustedCAs returns the well-known CAs enrolled in db or the MOK list.
+func TrustedCAs() ([]KnownCA, error) {
+	var certs []*x509.Certificate
+	for _, read := range []func() (Database, error){
+		func() (Database, error) { return ReadDatabase(DBName) },
+		MOKList,
+	} {
+		db, err := read()
+		switch {
+		case os.IsNotExist(err):
+			continue
+		case err != nil:
+			return nil, err
+		}
+		c, err := db.Certificates()
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, c...)
+	}
+
+	var out []KnownCA
+	seen := make(map[KnownCA]bool)
+	for _, c := range certs {
+		if ca, ok := IdentifyCertificate(c); ok && !seen[ca] {
+			seen[ca] = true
+			out = append(out, ca)
+		}
+	}
+	return out, nil
+}
+
+// ImageCAs returns the well-known CAs in the signing chains of the PE image at path.
+// Signatures which do not verify are ignored.
+func ImageCAs(path string) ([]KnownCA, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	img, err := parsePEImage(f, fi.Size())
+	if err != nil {
+		return nil, err
+	}
+	sigs, err := img.signatures(f)
+	if err != nil {
+		return nil, err
+	}
+	var chain []*x509.Certificate
+	for _, der := range sigs {
+		sig, err := parseAuthenticode(der)
+		if err != nil {
+			continue
+		}
+		signer, err := sig.signerCertificate()
+		if err != nil {
+			continue
+		}
+		chain = append(chain, sig.chain(signer)...)
+	}
+	return identifyChain(chain), nil
+}
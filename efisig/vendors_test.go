@@ -0,0 +1,62 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efisig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIdentifyCertificate(t *testing.T) {
+	for cn, want := range map[string]KnownCA{
+		"Microsoft Corporation UEFI CA 2011": MicrosoftUEFICA2011,
+		"Red Hat Secure Boot CA 5":           RedHatSecureBootCA,
+		"Debian Secure Boot CA":              DebianSecureBootCA,
+	} {
+		got, ok := IdentifyCertificate(mustCertificate(t, cn))
+		if !ok || got != want {
+			t.Errorf("IdentifyCertificate(CN=%q) = %v, %v; want %v, true", cn, got, ok, want)
+		}
+	}
+	if got, ok := IdentifyCertificate(mustCertificate(t, "My Own Key")); ok {
+		t.Errorf("IdentifyCertificate(CN=%q) = %v, true; want false", "My Own Key", got)
+	}
+}
+
+func TestImageCAs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "efisig")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca := newTestCA(t, "Canonical Ltd. Master Certificate Authority", nil)
+	signer := newTestCA(t, "Canonical Ltd. Secure Boot Signing", ca)
+	path := filepath.Join(dir, "grubx64.efi")
+	if err := ioutil.WriteFile(path, signPE(t, buildPE([]byte("grub"), nil), signer), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	got, err := ImageCAs(path)
+	if err != nil {
+		t.Fatalf("ImageCAs: %v", err)
+	}
+	if want := []KnownCA{CanonicalMasterCA}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ImageCAs = %v; want %v", got, want)
+	}
+}
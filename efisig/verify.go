@@ -0,0 +1,435 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efisig
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/google/uuid"
+)
+
+const winCertTypePKCSSignedData = 0x0002
+
+var (
+	ErrNotSigned = errors.New("efisig: image has no Authenticode signature")
+
+	oidSignedData        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidSPCIndirectData   = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 2, 1, 4}
+	oidAttrMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidSHA1              = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidSHA384            = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512            = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+	oidSHA256WithRSA     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+	oidECDSAWithSHA384   = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 3}
+	oidECDSAWithSHA512   = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 4}
+	oidECPublicKey       = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+
+	digestAlgorithmsByOID = map[string]crypto.Hash{
+		oidSHA1.String():   crypto.SHA1,
+		oidSHA256.String(): crypto.SHA256,
+		oidSHA384.String(): crypto.SHA384,
+		oidSHA512.String(): crypto.SHA512,
+	}
+)
+
+type digestInfo struct {
+	DigestAlgorithm pkix.AlgorithmIdentifier
+	Digest          []byte
+}
+
+type spcIndirectDataContent struct {
+	Data          asn1.RawValue
+	MessageDigest digestInfo
+}
+
+type attribute struct {
+	Type  asn1.ObjectIdentifier
+	Value asn1.RawValue `asn1:"set"`
+}
+
+// authenticodeSignature is a parsed Authenticode signature embedded in a PE image.
+type authenticodeSignature struct {
+	hash         crypto.Hash
+	imageDigest  []byte
+	content      []byte
+	certificates []*x509.Certificate
+	signer       signerInfo
+}
+
+func parseAuthenticode(der []byte) (*authenticodeSignature, error) {
+	var ci contentInfo
+	if _, err := asn1.Unmarshal(der, &ci); err != nil {
+		return nil, fmt.Errorf("efisig: parsing Authenticode ContentInfo: %v", err)
+	}
+	if !ci.ContentType.Equal(oidSignedData) {
+		return nil, fmt.Errorf("efisig: Authenticode signature is not SignedData")
+	}
+	var sd signedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return nil, fmt.Errorf("efisig: parsing Authenticode SignedData: %v", err)
+	}
+	if !sd.ContentInfo.ContentType.Equal(oidSPCIndirectData) || len(sd.SignerInfos) != 1 {
+		return nil, fmt.Errorf("efisig: Authenticode SignedData is malformed")
+	}
+	var idc spcIndirectDataContent
+	if _, err := asn1.Unmarshal(sd.ContentInfo.Content.Bytes, &idc); err != nil {
+		return nil, fmt.Errorf("efisig: parsing SpcIndirectDataContent: %v", err)
+	}
+	h, ok := digestAlgorithmsByOID[idc.MessageDigest.DigestAlgorithm.Algorithm.String()]
+	if !ok {
+		return nil, fmt.Errorf("efisig: unsupported Authenticode digest algorithm %v", idc.MessageDigest.DigestAlgorithm.Algorithm)
+	}
+	certs, err := x509.ParseCertificates(sd.Certificates.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("efisig: parsing Authenticode certificates: %v", err)
+	}
+
+	// The signed content is the value of the SpcIndirectDataContent, without its tag and length.
+	var inner asn1.RawValue
+	if _, err := asn1.Unmarshal(sd.ContentInfo.Content.Bytes, &inner); err != nil {
+		return nil, err
+	}
+	return &authenticodeSignature{
+		hash:         h,
+		imageDigest:  idc.MessageDigest.Digest,
+		content:      inner.Bytes,
+		certificates: certs,
+		signer:       sd.SignerInfos[0],
+	}, nil
+}
+
+func signatureAlgorithm(si *signerInfo, h crypto.Hash) x509.SignatureAlgorithm {
+	alg := si.DigestEncryptionAlgorithm.Algorithm
+	switch {
+	case alg.Equal(oidRSAEncryption), alg.Equal(oidSHA256WithRSA):
+		return map[crypto.Hash]x509.SignatureAlgorithm{
+			crypto.SHA1:   x509.SHA1WithRSA,
+			crypto.SHA256: x509.SHA256WithRSA,
+			crypto.SHA384: x509.SHA384WithRSA,
+			crypto.SHA512: x509.SHA512WithRSA,
+		}[h]
+	case alg.Equal(oidECPublicKey), alg.Equal(oidECDSAWithSHA2), alg.Equal(oidECDSAWithSHA384), alg.Equal(oidECDSAWithSHA512):
+		return map[crypto.Hash]x509.SignatureAlgorithm{
+			crypto.SHA256: x509.ECDSAWithSHA256,
+			crypto.SHA384: x509.ECDSAWithSHA384,
+			crypto.SHA512: x509.ECDSAWithSHA512,
+		}[h]
+	}
+	return x509.UnknownSignatureAlgorithm
+}
+
+// signerCertificate checks the SignerInfo and returns the certificate which produced it.
+func (s *authenticodeSignature) signerCertificate() (*x509.Certificate, error) {
+	si := &s.signer
+	var signer *x509.Certificate
+	for _, c := range s.certificates {
+		if bytes.Equal(c.RawIssuer, si.IssuerAndSerialNumber.Issuer.FullBytes) && c.SerialNumber.Cmp(si.IssuerAndSerialNumber.SerialNumber) == 0 {
+			signer = c
+			break
+		}
+	}
+	if signer == nil {
+		return nil, fmt.Errorf("efisig: Authenticode signer certificate not found")
+	}
+
+	h, ok := digestAlgorithmsByOID[si.DigestAlgorithm.Algorithm.String()]
+	if !ok {
+		return nil, fmt.Errorf("efisig: unsupported signer digest algorithm %v", si.DigestAlgorithm.Algorithm)
+	}
+	if len(si.AuthenticatedAttributes.FullBytes) == 0 {
+		return nil, fmt.Errorf("efisig: Authenticode signature has no authenticated attributes")
+	}
+
+	// The authenticated attributes must carry the digest of the signed content.
+	var messageDigest []byte
+	for rest := si.AuthenticatedAttributes.Bytes; len(rest) > 0; {
+		var a attribute
+		var err error
+		if rest, err = asn1.Unmarshal(rest, &a); err != nil {
+			return nil, fmt.Errorf("efisig: parsing authenticated attributes: %v", err)
+		}
+		if a.Type.Equal(oidAttrMessageDigest) {
+			if _, err := asn1.Unmarshal(a.Value.Bytes, &messageDigest); err != nil {
+				return nil, fmt.Errorf("efisig: parsing messageDigest attribute: %v", err)
+			}
+		}
+	}
+	d := h.New()
+	d.Write(s.content)
+	if !bytes.Equal(d.Sum(nil), messageDigest) {
+		return nil, fmt.Errorf("efisig: Authenticode content digest mismatch")
+	}
+
+	// The signature covers the attributes encoded as a SET OF rather than with their implicit tag.
+	signed := append([]byte(nil), si.AuthenticatedAttributes.FullBytes...)
+	signed[0] = 0x31
+	if err := signer.CheckSignature(signatureAlgorithm(si, h), signed, si.EncryptedDigest); err != nil {
+		return nil, fmt.Errorf("efisig: Authenticode signature does not verify: %v", err)
+	}
+	return signer, nil
+}
+
+// chain returns the certificates from signer up towards its root, as far as
+// they can be found among the embedded certificates.
+func (s *authenticodeSignature) chain(signer *x509.Certificate) []*x509.Certificate {
+	chain := []*x509.Certificate{signer}
+	for cur := signer; len(chain) <= len(s.certificates); {
+		if bytes.Equal(cur.RawIssuer, cur.RawSubject) {
+			break
+		}
+		var parent *x509.Certificate
+		for _, c := range s.certificates {
+			if bytes.Equal(c.RawSubject, cur.RawIssuer) && c.CheckSignature(cur.SignatureAlgorithm, cur.RawTBSCertificate, cur.Signature) == nil {
+				parent = c
+				break
+			}
+		}
+		if parent == nil {
+			break
+		}
+		chain = append(chain, parent)
+		cur = parent
+	}
+	return chain
+}
+
+// trustedBy returns the certificate from trusted which anchors chain, if any.
+// As in firmware, any certificate in db acts as a trust anchor, and validity periods are ignored.
+func trustedBy(chain []*x509.Certificate, trusted []*x509.Certificate) *x509.Certificate {
+	for _, c := range chain {
+		for _, t := range trusted {
+			if c.Equal(t) {
+				return t
+			}
+			if bytes.Equal(c.RawIssuer, t.RawSubject) && t.CheckSignature(c.SignatureAlgorithm, c.RawTBSCertificate, c.Signature) == nil {
+				return t
+			}
+		}
+	}
+	return nil
+}
+
+// tbsHashTypes maps the certificate hash signature types to the digest they contain.
+var tbsHashTypes = map[uuid.UUID]crypto.Hash{
+	CertX509SHA256UUID: crypto.SHA256,
+	CertX509SHA384UUID: crypto.SHA384,
+	CertX509SHA512UUID: crypto.SHA512,
+}
+
+// revokedBy reports whether any certificate of chain is listed in dbx.
+func revokedBy(chain []*x509.Certificate, dbx Database) bool {
+	for _, c := range chain {
+		if dbx.Contains(CertX509UUID, c.Raw) {
+			return true
+		}
+		for _, sl := range dbx {
+			h, ok := tbsHashTypes[sl.Type]
+			if !ok {
+				continue
+			}
+			d := h.New()
+			d.Write(c.RawTBSCertificate)
+			digest := d.Sum(nil)
+			// EFI_CERT_X509_SHA* entries are followed by a revocation time, so compare prefixes.
+			for _, sig := range sl.Signatures {
+				if bytes.HasPrefix(sig.Data, digest) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// digestIn reports whether the image's digest appears in d, for any hash type d holds.
+func (img *peImage) digestIn(r io.ReaderAt, d Database) (bool, crypto.Hash, error) {
+	seen := make(map[crypto.Hash]bool)
+	for _, sl := range d {
+		h, ok := hashTypes[sl.Type]
+		if !ok || seen[h] {
+			continue
+		}
+		seen[h] = true
+		digest, err := img.digest(r, h)
+		if err != nil {
+			return false, 0, err
+		}
+		if d.Contains(sl.Type, digest) {
+			return true, h, nil
+		}
+	}
+	return false, 0, nil
+}
+
+// Verification is the outcome of checking an image against db and dbx.
+type Verification struct {
+	// Allowed is true if firmware enforcing Secure Boot would run the image.
+	Allowed bool
+
+	// Reason explains the outcome.
+	Reason string
+
+	// Signers lists the certificates which produced valid signatures on the image.
+	Signers []*x509.Certificate
+
+	// TrustedBy is the db certificate which the accepted signature chains to, if any.
+	TrustedBy *x509.Certificate
+}
+
+// VerifyImage decides whether firmware would execute the PE image in r given db and dbx.
+// It follows the firmware's logic: the image is refused if its digest, or any certificate
+// in a signing chain, is in dbx; otherwise it is accepted if its digest is in db or if a
+// signature chains to a certificate in db.
+func VerifyImage(r io.ReaderAt, size int64, db, dbx Database) (*Verification, error) {
+	img, err := parsePEImage(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	if revoked, h, err := img.digestIn(r, dbx); err != nil {
+		return nil, err
+	} else if revoked {
+		return &Verification{Reason: fmt.Sprintf("image %v digest is revoked in dbx", h)}, nil
+	}
+
+	trusted, err := db.Certificates()
+	if err != nil {
+		return nil, err
+	}
+
+	v := new(Verification)
+	sigs, err := img.signatures(r)
+	if err != nil {
+		return nil, err
+	}
+	var sigErr error
+	for _, der := range sigs {
+		sig, err := parseAuthenticode(der)
+		if err != nil {
+			sigErr = err
+			continue
+		}
+		digest, err := img.digest(r, sig.hash)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(digest, sig.imageDigest) {
+			sigErr = fmt.Errorf("efisig: Authenticode image digest mismatch")
+			continue
+		}
+		signer, err := sig.signerCertificate()
+		if err != nil {
+			sigErr = err
+			continue
+		}
+		v.Signers = append(v.Signers, signer)
+		chain := sig.chain(signer)
+		if revokedBy(chain, dbx) {
+			return &Verification{Reason: fmt.Sprintf("signing certificate chain of %q is revoked in dbx", signer.Subject), Signers: v.Signers}, nil
+		}
+		if v.TrustedBy == nil {
+			v.TrustedBy = trustedBy(chain, trusted)
+		}
+	}
+
+	if v.TrustedBy != nil {
+		v.Allowed = true
+		v.Reason = fmt.Sprintf("signature chains to db certificate %q", v.TrustedBy.Subject)
+		return v, nil
+	}
+	if allowed, h, err := img.digestIn(r, db); err != nil {
+		return nil, err
+	} else if allowed {
+		v.Allowed = true
+		v.Reason = fmt.Sprintf("image %v digest is in db", h)
+		return v, nil
+	}
+
+	switch {
+	case len(sigs) == 0:
+		v.Reason = ErrNotSigned.Error()
+	case len(v.Signers) == 0:
+		v.Reason = sigErr.Error()
+	default:
+		v.Reason = "no signature chains to a certificate in db"
+	}
+	return v, nil
+}
+
+// CheckRevoked reports whether dbx alone would prevent the PE image in r from
+// running, either by its digest or by revoking a certificate it was signed with.
+func CheckRevoked(r io.ReaderAt, size int64, dbx Database) (revoked bool, reason string, err error) {
+	img, err := parsePEImage(r, size)
+	if err != nil {
+		return false, "", err
+	}
+	if revoked, h, err := img.digestIn(r, dbx); err != nil || revoked {
+		return revoked, fmt.Sprintf("image %v digest is revoked in dbx", h), err
+	}
+	sigs, err := img.signatures(r)
+	if err != nil {
+		return false, "", err
+	}
+	for _, der := range sigs {
+		sig, err := parseAuthenticode(der)
+		if err != nil {
+			continue
+		}
+		signer, err := sig.signerCertificate()
+		if err != nil {
+			continue
+		}
+		if revokedBy(sig.chain(signer), dbx) {
+			return true, fmt.Sprintf("signing certificate chain of %q is revoked in dbx", signer.Subject), nil
+		}
+	}
+	return false, "", nil
+}
+
+// CheckRevokedFile is like CheckRevoked for the image stored at path.
+func CheckRevokedFile(path string, dbx Database) (revoked bool, reason string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, "", err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return false, "", err
+	}
+	return CheckRevoked(f, fi.Size(), dbx)
+}
+
+// VerifyFile is like VerifyImage for the image stored at path.
+func VerifyFile(path string, db, dbx Database) (*Verification, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return VerifyImage(f, fi.Size(), db, dbx)
+}
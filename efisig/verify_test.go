@@ -0,0 +1,217 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efisig
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"io"
+	"math/big"
+	"testing"
+	"time"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *rsa.PrivateKey
+}
+
+func newTestCA(t *testing.T, cn string, parent *testCA) *testCA {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil,
+	}
+	issuer, issuerKey := tmpl, key
+	if parent != nil {
+		issuer, issuerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, issuer, &key.PublicKey, issuerKey)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate: %v", err)
+	}
+	return &testCA{cert, key}
+}
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	der, err := asn1.Marshal(v)
+	if err != nil {
+		t.Fatalf("asn1.Marshal: %v", err)
+	}
+	return der
+}
+
+// explicit wraps der in an explicit [0] tag; RawValues ignore their field's tag when marshalled.
+func explicit(der []byte) asn1.RawValue {
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: der}
+}
+
+// signPE appends an Authenticode signature by signer to the unsigned image img.
+func signPE(t *testing.T, img []byte, signer *testCA) []byte {
+	t.Helper()
+	digest, err := ImageDigest(bytes.NewReader(img), int64(len(img)), crypto.SHA256)
+	if err != nil {
+		t.Fatalf("ImageDigest: %v", err)
+	}
+	sha256Alg := pkix.AlgorithmIdentifier{Algorithm: oidSHA256, Parameters: asn1.NullRawValue}
+
+	content := mustMarshal(t, spcIndirectDataContent{
+		Data:          asn1.RawValue{FullBytes: mustMarshal(t, []asn1.ObjectIdentifier{{1, 3, 6, 1, 4, 1, 311, 2, 1, 15}})},
+		MessageDigest: digestInfo{DigestAlgorithm: sha256Alg, Digest: digest},
+	})
+	var inner asn1.RawValue
+	if _, err := asn1.Unmarshal(content, &inner); err != nil {
+		t.Fatalf("asn1.Unmarshal: %v", err)
+	}
+	contentDigest := sha256.Sum256(inner.Bytes)
+
+	attr := mustMarshal(t, attribute{
+		Type:  oidAttrMessageDigest,
+		Value: asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: mustMarshal(t, contentDigest[:])},
+	})
+	attrsSigned := mustMarshal(t, asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: attr})
+	attrsDigest := sha256.Sum256(attrsSigned)
+	sig, err := signer.key.Sign(rand.Reader, attrsDigest[:], crypto.SHA256)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	sd := mustMarshal(t, signedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{sha256Alg},
+		ContentInfo:      contentInfo{ContentType: oidSPCIndirectData, Content: explicit(content)},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: signer.cert.Raw},
+		SignerInfos: []signerInfo{{
+			Version: 1,
+			IssuerAndSerialNumber: issuerAndSerialNumber{
+				Issuer:       asn1.RawValue{FullBytes: signer.cert.RawIssuer},
+				SerialNumber: signer.cert.SerialNumber,
+			},
+			DigestAlgorithm:           sha256Alg,
+			AuthenticatedAttributes:   asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: attr},
+			DigestEncryptionAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidRSAEncryption, Parameters: asn1.NullRawValue},
+			EncryptedDigest:           sig,
+		}},
+	})
+	der := mustMarshal(t, contentInfo{ContentType: oidSignedData, Content: explicit(sd)})
+
+	winCert := make([]byte, winCertificateSize, (winCertificateSize+len(der)+7)&^7)
+	binary.LittleEndian.PutUint32(winCert[0:], uint32(winCertificateSize+len(der)))
+	binary.LittleEndian.PutUint16(winCert[4:], winCertRevision)
+	binary.LittleEndian.PutUint16(winCert[6:], winCertTypePKCSSignedData)
+	winCert = append(winCert, der...)
+	winCert = winCert[:cap(winCert)]
+
+	signed := append(append([]byte(nil), img...), winCert...)
+	dir := signed[testPEOptOff+112+4*8:]
+	binary.LittleEndian.PutUint32(dir[0:], uint32(len(img)))
+	binary.LittleEndian.PutUint32(dir[4:], uint32(len(winCert)))
+	return signed
+}
+
+func TestVerifyImage(t *testing.T) {
+	ca := newTestCA(t, "Test UEFI CA", nil)
+	leaf := newTestCA(t, "Test Signer", ca)
+	other := newTestCA(t, "Other UEFI CA", nil)
+
+	unsigned := buildPE([]byte("a loader"), nil)
+	signed := signPE(t, unsigned, leaf)
+	digest, err := ImageDigest(bytes.NewReader(unsigned), int64(len(unsigned)), crypto.SHA256)
+	if err != nil {
+		t.Fatalf("ImageDigest: %v", err)
+	}
+	tampered := append([]byte(nil), signed...)
+	tampered[testPEHeaderSize] ^= 0xff
+
+	db := Database{{Type: CertX509UUID, Signatures: []SignatureData{{Owner: testOwner, Data: ca.cert.Raw}}}}
+	otherDB := Database{{Type: CertX509UUID, Signatures: []SignatureData{{Owner: testOwner, Data: other.cert.Raw}}}}
+	hashDB := Database{{Type: CertSHA256UUID, Signatures: []SignatureData{{Owner: testOwner, Data: digest}}}}
+	certDBX := Database{{Type: CertX509UUID, Signatures: []SignatureData{{Owner: testOwner, Data: leaf.cert.Raw}}}}
+	tbsDigest := sha256.Sum256(leaf.cert.RawTBSCertificate)
+	tbsDBX := Database{{Type: CertX509SHA256UUID, Signatures: []SignatureData{{Owner: testOwner, Data: append(tbsDigest[:], make([]byte, 16)...)}}}}
+
+	for _, tc := range []struct {
+		name    string
+		img     []byte
+		db, dbx Database
+		want    bool
+	}{
+		{"signed by db CA", signed, db, nil, true},
+		{"signed by other CA", signed, otherDB, nil, false},
+		{"unsigned", unsigned, db, nil, false},
+		{"unsigned with digest in db", unsigned, hashDB, nil, true},
+		{"signed with digest in dbx", signed, db, hashDB, false},
+		{"signer in dbx", signed, db, certDBX, false},
+		{"signer TBS digest in dbx", signed, db, tbsDBX, false},
+		{"tampered", tampered, db, nil, false},
+	} {
+		v, err := VerifyImage(bytes.NewReader(tc.img), int64(len(tc.img)), tc.db, tc.dbx)
+		if err != nil {
+			t.Errorf("%s: VerifyImage: %v", tc.name, err)
+			continue
+		}
+		if v.Allowed != tc.want {
+			t.Errorf("%s: Allowed = %v (%s); want %v", tc.name, v.Allowed, v.Reason, tc.want)
+		}
+	}
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.ReaderAt
+	n int64
+}
+
+func (c *countingReader) ReadAt(p []byte, off int64) (int, error) {
+	n, err := c.r.ReadAt(p, off)
+	c.n += int64(n)
+	return n, err
+}
+
+func TestCheckRevokedDigestsOncePerHash(t *testing.T) {
+	img := buildPE([]byte("a loader"), nil)
+	list := &SignatureList{Type: CertSHA256UUID, Signatures: []SignatureData{{Owner: testOwner, Data: make([]byte, 32)}}}
+
+	read := func(dbx Database) int64 {
+		r := &countingReader{r: bytes.NewReader(img)}
+		if _, _, err := CheckRevoked(r, int64(len(img)), dbx); err != nil {
+			t.Fatalf("CheckRevoked: %v", err)
+		}
+		return r.n
+	}
+	if one, three := read(Database{list}), read(Database{list, list, list}); one != three {
+		t.Errorf("CheckRevoked read %d bytes for three SHA-256 lists; want %d, as for one", three, one)
+	}
+}
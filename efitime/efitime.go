@@ -0,0 +1,197 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package efitime encodes and decodes EFI_TIME, the timestamp format used
+// throughout UEFI: in authenticated variables, capsule results and the
+// real-time clock services.
+package efitime
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Size is the encoded size of an EFI_TIME.
+const Size = 16
+
+// UnspecifiedTimeZone, as TimeZone, means the time is in local time.
+const UnspecifiedTimeZone = 0x07ff
+
+// Daylight flags.
+const (
+	// AdjustDaylight means the time should be adjusted for daylight saving.
+	AdjustDaylight = 0x01
+	// InDaylight means daylight saving is in effect, putting local time an
+	// hour ahead of what TimeZone alone implies.
+	InDaylight = 0x02
+)
+
+var (
+	ErrTruncated = errors.New("efitime: EFI_TIME is truncated")
+
+	byteOrder = binary.LittleEndian
+)
+
+// Time is an EFI_TIME.
+type Time struct {
+	Year       uint16
+	Month      uint8
+	Day        uint8
+	Hour       uint8
+	Minute     uint8
+	Second     uint8
+	Nanosecond uint32
+	// TimeZone is the offset of the time from UTC, in minutes, or
+	// UnspecifiedTimeZone. As of UEFI 2.7 errata A, local time is UTC plus
+	// TimeZone; earlier revisions had the opposite sign.
+	TimeZone int16
+	Daylight uint8
+}
+
+// Parse decodes the EFI_TIME at the start of b.
+func Parse(b []byte) (Time, error) {
+	if len(b) < Size {
+		return Time{}, ErrTruncated
+	}
+	return Time{
+		Year:       byteOrder.Uint16(b[0:]),
+		Month:      b[2],
+		Day:        b[3],
+		Hour:       b[4],
+		Minute:     b[5],
+		Second:     b[6],
+		Nanosecond: byteOrder.Uint32(b[8:]),
+		TimeZone:   int16(byteOrder.Uint16(b[12:])),
+		Daylight:   b[14],
+	}, nil
+}
+
+func (t Time) Bytes() []byte {
+	b := make([]byte, Size)
+	byteOrder.PutUint16(b[0:], t.Year)
+	b[2] = t.Month
+	b[3] = t.Day
+	b[4] = t.Hour
+	b[5] = t.Minute
+	b[6] = t.Second
+	byteOrder.PutUint32(b[8:], t.Nanosecond)
+	byteOrder.PutUint16(b[12:], uint16(t.TimeZone))
+	b[14] = t.Daylight
+	return b
+}
+
+// FromTime converts t to a Time, recording t's offset from UTC in TimeZone.
+func FromTime(t time.Time) Time {
+	_, offset := t.Zone()
+	return Time{
+		Year:       uint16(t.Year()),
+		Month:      uint8(t.Month()),
+		Day:        uint8(t.Day()),
+		Hour:       uint8(t.Hour()),
+		Minute:     uint8(t.Minute()),
+		Second:     uint8(t.Second()),
+		Nanosecond: uint32(t.Nanosecond()),
+		TimeZone:   int16(offset / 60),
+	}
+}
+
+// Location returns the time zone t is in: a fixed offset from UTC, or
+// time.Local if the time zone is unspecified.
+func (t Time) Location() *time.Location {
+	if t.TimeZone == UnspecifiedTimeZone {
+		return time.Local
+	}
+	offset := int(t.TimeZone)
+	if t.Daylight&InDaylight != 0 {
+		offset += 60
+	}
+	if offset == 0 {
+		return time.UTC
+	}
+	return time.FixedZone("", offset*60)
+}
+
+// Time converts t to a time.Time.
+func (t Time) Time() time.Time {
+	return time.Date(int(t.Year), time.Month(t.Month), int(t.Day), int(t.Hour), int(t.Minute), int(t.Second), int(t.Nanosecond), t.Location())
+}
+
+// Valid checks that each field is within the range UEFI allows.
+func (t Time) Valid() error {
+	if t.Year < 1900 || t.Year > 9999 || t.Month < 1 || t.Month > 12 || t.Day < 1 || t.Day > 31 ||
+		t.Hour > 23 || t.Minute > 59 || t.Second > 59 || t.Nanosecond > 999999999 {
+		return fmt.Errorf("efitime: %v is not a valid time", t)
+	}
+	if t.TimeZone != UnspecifiedTimeZone && (t.TimeZone < -1440 || t.TimeZone > 1440) {
+		return fmt.Errorf("efitime: %v has out of range TimeZone %d", t, t.TimeZone)
+	}
+	return nil
+}
+
+// ValidForAuthentication checks the constraints UEFI places on the timestamp of
+// an authentication descriptor: the nanosecond, time zone and daylight fields must be zero.
+func (t Time) ValidForAuthentication() error {
+	if t.Nanosecond != 0 || t.TimeZone != 0 || t.Daylight != 0 {
+		return fmt.Errorf("efitime: authentication timestamp %v has non-zero Nanosecond, TimeZone or Daylight", t)
+	}
+	if t.Month < 1 || t.Month > 12 || t.Day < 1 || t.Day > 31 || t.Hour > 23 || t.Minute > 59 || t.Second > 59 {
+		return fmt.Errorf("efitime: authentication timestamp %v is not a valid time", t)
+	}
+	return nil
+}
+
+// String formats t in RFC 3339 form. A time in an unspecified time zone has
+// no offset.
+func (t Time) String() string {
+	s := fmt.Sprintf("%04d-%02d-%02dT%02d:%02d:%02d", t.Year, t.Month, t.Day, t.Hour, t.Minute, t.Second)
+	if t.Nanosecond != 0 {
+		s += fmt.Sprintf(".%09d", t.Nanosecond)
+	}
+	if t.TimeZone == UnspecifiedTimeZone {
+		return s
+	}
+	_, offset := t.Time().Zone()
+	if offset == 0 {
+		return s + "Z"
+	}
+	sign := '+'
+	if offset < 0 {
+		sign, offset = '-', -offset
+	}
+	return s + fmt.Sprintf("%c%02d:%02d", sign, offset/3600, offset/60%60)
+}
+
+// Compare returns -1, 0 or +1 as t is before, equal to or after u, comparing
+// the fields in the same way as firmware does: without regard to time zone.
+func (t Time) Compare(u Time) int {
+	for _, f := range [][2]uint32{
+		{uint32(t.Year), uint32(u.Year)},
+		{uint32(t.Month), uint32(u.Month)},
+		{uint32(t.Day), uint32(u.Day)},
+		{uint32(t.Hour), uint32(u.Hour)},
+		{uint32(t.Minute), uint32(u.Minute)},
+		{uint32(t.Second), uint32(u.Second)},
+		{t.Nanosecond, u.Nanosecond},
+	} {
+		switch {
+		case f[0] < f[1]:
+			return -1
+		case f[0] > f[1]:
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,92 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efitime
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRoundtrip(t *testing.T) {
+	want := Time{Year: 2010, Month: 3, Day: 6, Hour: 19, Minute: 17, Second: 21, Nanosecond: 5, TimeZone: -60, Daylight: AdjustDaylight}
+	got, err := Parse(want.Bytes())
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if got != want {
+		t.Errorf("Parse(%v.Bytes()) = %+v; want %+v", want, got, want)
+	}
+	if _, err := Parse(want.Bytes()[:Size-1]); err != ErrTruncated {
+		t.Errorf("Parse of %d bytes = %v; want ErrTruncated", Size-1, err)
+	}
+}
+
+func TestTimeZones(t *testing.T) {
+	for _, tc := range []struct {
+		in   Time
+		want time.Time
+		str  string
+	}{
+		{
+			Time{Year: 2024, Month: 1, Day: 2, Hour: 3, Minute: 4, Second: 5},
+			time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			"2024-01-02T03:04:05Z",
+		},
+		{
+			Time{Year: 2024, Month: 1, Day: 2, Hour: 3, Minute: 4, Second: 5, TimeZone: -300},
+			time.Date(2024, 1, 2, 8, 4, 5, 0, time.UTC),
+			"2024-01-02T03:04:05-05:00",
+		},
+		{
+			Time{Year: 2024, Month: 7, Day: 2, Hour: 3, Minute: 4, Second: 5, TimeZone: 60, Daylight: AdjustDaylight | InDaylight},
+			time.Date(2024, 7, 2, 1, 4, 5, 0, time.UTC),
+			"2024-07-02T03:04:05+02:00",
+		},
+		{
+			Time{Year: 2024, Month: 1, Day: 2, Hour: 3, Minute: 4, Second: 5, Nanosecond: 6, TimeZone: 330},
+			time.Date(2024, 1, 1, 21, 34, 5, 6, time.UTC),
+			"2024-01-02T03:04:05.000000006+05:30",
+		},
+	} {
+		if got := tc.in.Time(); !got.Equal(tc.want) {
+			t.Errorf("%+v.Time() = %v; want %v", tc.in, got, tc.want)
+		}
+		if got := tc.in.String(); got != tc.str {
+			t.Errorf("%+v.String() = %q; want %q", tc.in, got, tc.str)
+		}
+		if err := tc.in.Valid(); err != nil {
+			t.Errorf("%+v.Valid() = %v", tc.in, err)
+		}
+	}
+
+	local := Time{Year: 2024, Month: 1, Day: 2, TimeZone: UnspecifiedTimeZone}
+	if loc := local.Location(); loc != time.Local {
+		t.Errorf("Location() with unspecified time zone = %v; want Local", loc)
+	}
+	if got, want := local.String(), "2024-01-02T00:00:00"; got != want {
+		t.Errorf("String() = %q; want %q", got, want)
+	}
+}
+
+func TestFromTime(t *testing.T) {
+	tt := time.Date(2010, 3, 6, 19, 17, 21, 0, time.FixedZone("", -90*60))
+	got := FromTime(tt)
+	if got.TimeZone != -90 {
+		t.Errorf("FromTime(%v).TimeZone = %d; want -90", tt, got.TimeZone)
+	}
+	if !got.Time().Equal(tt) {
+		t.Errorf("FromTime(%v).Time() = %v", tt, got.Time())
+	}
+}
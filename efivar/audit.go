@@ -0,0 +1,103 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import (
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// AuditRecord describes one successful change to a variable.
+type AuditRecord struct {
+	Time time.Time
+	// Op is "set", "append" or "delete".
+	Op         string
+	Name       VariableName
+	Attributes Attributes
+	// Before and After are the SHA-256 of the variable's data before and
+	// after the change. Each is nil if the variable did not exist, or
+	// could not be read.
+	Before, After []byte
+	// Tag identifies the program or component making the change, as given
+	// to UseAuditor.
+	Tag string
+}
+
+// An Auditor is told of every change made to a variable through this
+// package, once it has succeeded. Audit must be safe for concurrent use.
+type Auditor interface {
+	Audit(r *AuditRecord)
+}
+
+var (
+	auditMu  sync.RWMutex
+	auditor  Auditor
+	auditTag string
+)
+
+// UseAuditor sends a record of every successful Set and Delete to a, tagged
+// with tag, or with the program's name if tag is empty. UseAuditor(nil, "")
+// stops auditing. While an Auditor is installed, each change also reads the
+// variable before and after it, to hash its content.
+func UseAuditor(a Auditor, tag string) {
+	if tag == "" {
+		tag = filepath.Base(os.Args[0])
+	}
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	auditor, auditTag = a, tag
+}
+
+func currentAuditor() (Auditor, string) {
+	auditMu.RLock()
+	defer auditMu.RUnlock()
+	return auditor, auditTag
+}
+
+// contentHash returns the SHA-256 of vn's data, or nil if it cannot be read.
+func contentHash(vn VariableName) []byte {
+	v, err := vn.Get()
+	if err != nil {
+		return nil
+	}
+	sum := sha256.Sum256(v.Data)
+	return sum[:]
+}
+
+// audited runs change, the operation op on vn, and reports it to the
+// Auditor if there is one and change succeeds.
+func audited(op string, vn VariableName, attrs Attributes, change func() error) error {
+	a, tag := currentAuditor()
+	if a == nil {
+		return change()
+	}
+	before := contentHash(vn)
+	if err := change(); err != nil {
+		return err
+	}
+	a.Audit(&AuditRecord{
+		Time:       time.Now(),
+		Op:         op,
+		Name:       vn,
+		Attributes: attrs,
+		Before:     before,
+		After:      contentHash(vn),
+		Tag:        tag,
+	})
+	return nil
+}
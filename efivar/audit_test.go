@@ -0,0 +1,89 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"sync"
+	"testing"
+)
+
+// recordingAuditor keeps the records it is given.
+type recordingAuditor struct {
+	mu      sync.Mutex
+	records []AuditRecord
+}
+
+func (a *recordingAuditor) Audit(r *AuditRecord) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.records = append(a.records, *r)
+}
+
+func TestAuditor(t *testing.T) {
+	defer useMemStore()()
+	a := &recordingAuditor{}
+	UseAuditor(a, "test")
+	defer UseAuditor(nil, "")
+
+	vn := VariableName{Name: "GoefivarAudit", GUID: bootCurrent.GUID}
+	hash := func(b string) []byte {
+		sum := sha256.Sum256([]byte(b))
+		return sum[:]
+	}
+	v := &Variable{VariableName: vn, Data: []byte("one"), Attributes: NonVolatile | BootserviceAccess}
+	if err := v.Set(0644); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	v.Data = []byte("two")
+	if err := v.Set(0644); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	v.Attributes |= AppendWrite
+	if err := v.Set(0644); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := vn.Delete(); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	// A failed change is not audited.
+	if err := vn.Delete(); err == nil {
+		t.Fatal("Delete of missing variable succeeded")
+	}
+
+	want := []struct {
+		op            string
+		before, after []byte
+	}{
+		{"set", nil, hash("one")},
+		{"set", hash("one"), hash("two")},
+		// memStore replaces rather than appends.
+		{"append", hash("two"), hash("two")},
+		{"delete", hash("two"), nil},
+	}
+	if len(a.records) != len(want) {
+		t.Fatalf("got %d records, want %d: %+v", len(a.records), len(want), a.records)
+	}
+	for i, w := range want {
+		r := a.records[i]
+		if r.Op != w.op || r.Name != vn || r.Tag != "test" || r.Time.IsZero() {
+			t.Errorf("record %d = %+v, want op %q for %v tagged test", i, r, w.op, vn)
+		}
+		if !bytes.Equal(r.Before, w.before) || !bytes.Equal(r.After, w.after) {
+			t.Errorf("record %d hashes = %x, %x; want %x, %x", i, r.Before, r.After, w.before, w.after)
+		}
+	}
+}
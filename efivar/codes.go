@@ -0,0 +1,45 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import v2 "github.com/lukegb/goefivar/v2/efivar"
+
+// Code classifies an error, for programs which map failures onto their own
+// reporting, such as RPC status codes or exit codes. The names returned by
+// String are stable.
+type Code = v2.Code
+
+// The Codes, as described in version 2.
+const (
+	Unknown      = v2.Unknown
+	NotFound     = v2.NotFound
+	Permission   = v2.Permission
+	NVRAMFull    = v2.NVRAMFull
+	Corrupt      = v2.Corrupt
+	Unsupported  = v2.Unsupported
+	AuthRequired = v2.AuthRequired
+)
+
+// Coder is implemented by errors which know their own Code.
+type Coder = v2.Coder
+
+// NewError returns an error with the message msg which CodeOf classifies
+// as c. It is for packages' sentinel errors.
+func NewError(c Code, msg string) error { return v2.NewError(c, msg) }
+
+// CodeOf classifies err. It looks through *os.PathError, *os.SyscallError,
+// and any error with an Unwrap method, for an error implementing Coder or a
+// system error it recognises.
+func CodeOf(err error) Code { return v2.CodeOf(err) }
@@ -0,0 +1,91 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import (
+	"context"
+	"os"
+
+	v2 "github.com/lukegb/goefivar/v2/efivar"
+)
+
+// ops is this package's variable operations as a version 2 Backend, so that
+// the Context methods can be interrupted as version 2's System is. Set uses
+// mode.
+type ops struct{ mode os.FileMode }
+
+func (ops) Get(ctx context.Context, vn v2.VariableName) (*v2.Variable, error) {
+	v, err := VariableName(vn).Get()
+	if err != nil {
+		return nil, err
+	}
+	return v.toV2(), nil
+}
+
+func (ops) Exists(ctx context.Context, vn v2.VariableName) (bool, error) {
+	return VariableName(vn).Exists()
+}
+
+func (o ops) Set(ctx context.Context, v *v2.Variable) error {
+	return fromV2(v).Set(o.mode)
+}
+
+func (ops) Delete(ctx context.Context, vn v2.VariableName) error {
+	return VariableName(vn).Delete()
+}
+
+func (ops) Variables(ctx context.Context) ([]v2.VariableName, error) {
+	vns, err := Variables()
+	if err != nil {
+		return nil, err
+	}
+	return toV2Names(vns), nil
+}
+
+// ExistsContext is Exists, giving up when ctx is done.
+func (vn VariableName) ExistsContext(ctx context.Context) (bool, error) {
+	return v2.Interruptible(ops{}).(v2.Exister).Exists(ctx, v2.VariableName(vn))
+}
+
+// GetContext is Get, giving up when ctx is done, as when reading from buggy
+// firmware hangs.
+func (vn VariableName) GetContext(ctx context.Context) (*Variable, error) {
+	v, err := v2.Interruptible(ops{}).Get(ctx, v2.VariableName(vn))
+	if err != nil {
+		return nil, err
+	}
+	return fromV2(v), nil
+}
+
+// DeleteContext is Delete, giving up when ctx is done. The variable may
+// still be deleted afterwards.
+func (vn VariableName) DeleteContext(ctx context.Context) error {
+	return v2.Interruptible(ops{}).Delete(ctx, v2.VariableName(vn))
+}
+
+// SetContext is Set, giving up when ctx is done. The write may still be
+// made afterwards.
+func (v *Variable) SetContext(ctx context.Context, mode os.FileMode) error {
+	return v2.Interruptible(ops{mode}).Set(ctx, v.toV2())
+}
+
+// VariablesContext is Variables, giving up when ctx is done.
+func VariablesContext(ctx context.Context) ([]VariableName, error) {
+	vns, err := v2.Interruptible(ops{}).Variables(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return fromV2Names(vns), nil
+}
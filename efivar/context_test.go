@@ -0,0 +1,97 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// hangingBackend is a memStore whose Get waits until release is closed.
+type hangingBackend struct {
+	memStore
+	release chan struct{}
+}
+
+func (b *hangingBackend) Get(vn VariableName) (*Variable, error) {
+	<-b.release
+	return b.memStore.Get(vn)
+}
+
+func TestContext(t *testing.T) {
+	defer useMemStore()()
+	ctx := context.Background()
+	if v, err := bootCurrent.GetContext(ctx); err != nil || v.Data[0] != 0x0c {
+		t.Errorf("GetContext = %+v, %v; want BootCurrent", v, err)
+	}
+	if ok, err := bootCurrent.ExistsContext(ctx); !ok || err != nil {
+		t.Errorf("ExistsContext = %v, %v; want true, nil", ok, err)
+	}
+	if vns, err := VariablesContext(ctx); err != nil || len(vns) != 1 {
+		t.Errorf("VariablesContext = %v, %v; want [BootCurrent]", vns, err)
+	}
+	v := &Variable{VariableName: VariableName{GUID: GlobalUUID, Name: "Timeout"}, Data: []byte{5, 0}}
+	if err := v.SetContext(ctx, 0644); err != nil {
+		t.Errorf("SetContext: %v", err)
+	}
+	if err := v.DeleteContext(ctx); err != nil {
+		t.Errorf("DeleteContext: %v", err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := v.SetContext(cancelled, 0644); err != context.Canceled {
+		t.Errorf("SetContext(cancelled) = %v, want %v", err, context.Canceled)
+	}
+	if ok, _ := v.Exists(); ok {
+		t.Error("SetContext(cancelled) wrote the variable")
+	}
+}
+
+func TestContextTimeout(t *testing.T) {
+	b := &hangingBackend{memStore: memStore{}, release: make(chan struct{})}
+	defer close(b.release)
+	UseStore(b)
+	defer UseStore(nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := bootCurrent.GetContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("GetContext on a hanging backend = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+// slowBackend is a memStore whose Get takes a while.
+type slowBackend struct{ memStore }
+
+func (b slowBackend) Get(vn VariableName) (*Variable, error) {
+	time.Sleep(20 * time.Millisecond)
+	return b.memStore.Get(vn)
+}
+
+// TestContextLateResult checks, under the race detector, that an operation
+// finishing after its context shares nothing with the caller.
+func TestContextLateResult(t *testing.T) {
+	UseStore(slowBackend{memStore{bootCurrent: {VariableName: bootCurrent, Data: []byte{1}}}})
+	defer UseStore(nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if v, err := bootCurrent.GetContext(ctx); v != nil || err != context.DeadlineExceeded {
+		t.Errorf("GetContext on a slow backend = %v, %v; want nil, %v", v, err, context.DeadlineExceeded)
+	}
+	time.Sleep(40 * time.Millisecond)
+}
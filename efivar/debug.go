@@ -0,0 +1,38 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import v2 "github.com/lukegb/goefivar/v2/efivar"
+
+// TraceEntry is one step of libefivar's record of how a call failed.
+type TraceEntry = v2.TraceEntry
+
+// TraceError is a failure of libefivar with the trace libefivar recorded,
+// which says where inside the library the call failed. It is returned for
+// failures which Code does not classify; those it does are returned as the
+// plain system error, so that os.IsNotExist and os.IsPermission work on
+// them, and their traces are sent to the log given to SetDebugLog.
+type TraceError = v2.TraceError
+
+// SetDebugLog sends debug messages up to level to log: libefivar's verbose
+// output, which also covers libefiboot, and this package's own messages
+// about efivarfs. Higher levels are more verbose, as with libefivar's
+// efi_set_verbose; level 0 or a nil log turns debugging off.
+//
+// libefivar does not say how verbose each line of its output is, so its
+// lines are all given to log at level 1, prefixed with "libefivar: ".
+func SetDebugLog(level int, log func(level int, msg string)) error {
+	return v2.SetDebugLog(level, log)
+}
@@ -0,0 +1,58 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package efivar reads and writes EFI variables through libefivar, or
+// through another Backend installed with UseBackend or UseStore.
+//
+// This is version 1 of the API, kept as a shim over version 2,
+// github.com/lukegb/goefivar/v2/efivar, in which every operation takes a
+// context. The running system's variables are reached through version 2's
+// System, and the two share read-only mode and the debug log.
+//
+// # Implementations
+//
+// Programs built without cgo, or with the purego build tag, use efivarfs
+// directly instead of libefivar, or on FreeBSD the ioctls of /dev/efi, and
+// format device paths with package efidp; ParseDevicePath is then
+// unsupported. Setting the environment variable GOEFIVAR_IMPL=purego makes
+// a program built with libefivar do the same. Implementation reports which
+// is in use.
+//
+// On Linux kernels without efivarfs mounted, the pure-Go implementation uses
+// the legacy interface in /sys/firmware/efi/vars, which holds at most 1024
+// bytes in each variable.
+//
+// EFIVARFS_PATH, if set, moves efivarfs from /sys/firmware/efi/efivars for
+// both implementations, for example into a chroot; Efivarfs returns a
+// Backend for efivarfs at any path.
+//
+// On macOS, which has no libefivar, variables are read with the nvram
+// command and cannot be changed. Variables outside the EFI namespace, such
+// as boot-args, have the GUID AppleNVRAMUUID.
+//
+// # Read-only mode
+//
+// SetReadOnly, the environment variable GOEFIVAR_READONLY=1 or the
+// goefivar_readonly build tag make every Set and Delete fail with
+// ErrReadOnly, for programs which must never change NVRAM. Once on,
+// read-only mode cannot be turned off.
+//
+// # Concurrency
+//
+// Everything in this package may be used from multiple goroutines at once.
+// libefivar itself keeps process-wide state without locking, so calls into
+// it are serialised; they are short, and variable access through efivarfs is
+// not fast enough for this to matter. Listings of variables through
+// libefivar share one iterator, so are made one at a time.
+package efivar
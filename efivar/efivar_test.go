@@ -18,8 +18,10 @@ import (
 	"fmt"
 	"os"
 	"sort"
+	"sync"
 	"testing"
 	"time"
+	"unsafe"
 
 	"github.com/google/uuid"
 )
@@ -36,41 +38,68 @@ var (
 	}
 )
 
-func TestUUIDToEFIRoundtrip(t *testing.T) {
-	u := uuid.MustParse("84be9c3e-8a32-42c0-891c-4cd3b072becc")
-	got := efiToUUID(uuidToEFI(u))
-	if got != u {
-		t.Errorf("efiToUUID(uuidToEFI(%q)) = %q; want %q", u, got, u)
+// memStore is a Store held in memory, so that these tests run without EFI
+// variable support or root. It cannot use package varstore, which imports
+// this one.
+type memStore map[VariableName]Variable
+
+func (m memStore) Get(vn VariableName) (*Variable, error) {
+	v, ok := m[vn]
+	if !ok {
+		return nil, NotExist("get", vn)
 	}
+	v.Data = append([]byte(nil), v.Data...)
+	return &v, nil
+}
 
+func (m memStore) Set(v *Variable) error {
+	m[v.VariableName] = *v
+	return nil
 }
 
-func TestUUIDToEFIString(t *testing.T) {
-	u := uuid.MustParse("84be9c3e-8a32-42c0-891c-4cd3b072becc")
-	got, err := efiGuidToStr(uuidToEFI(u))
-	if err != nil {
-		t.Fatalf("efiGuidToStr: %v", err)
-	}
-	if want := u.String(); got != want {
-		t.Errorf("efiGuidToStr(uuidToEFI(%q)) = %v; want %v", u, got, want)
+func (m memStore) Delete(vn VariableName) error {
+	if _, ok := m[vn]; !ok {
+		return NotExist("delete", vn)
 	}
+	delete(m, vn)
+	return nil
 }
 
-func TestEFIToUUID(t *testing.T) {
-	got := efiToUUID(globalUUID)
-	want, err := efiGuidToStr(globalUUID)
-	if err != nil {
-		t.Fatalf("efiGuidToStr: %v", err)
-	}
-	if got.String() != want {
-		t.Errorf("efiToUUID(globalUUID) = %v; want %v", got, want)
+func (m memStore) Variables() ([]VariableName, error) {
+	var vns []VariableName
+	for vn := range m {
+		vns = append(vns, vn)
 	}
+	return vns, nil
+}
+
+// useMemStore directs variable operations to a memStore holding BootCurrent
+// until the returned function is called.
+func useMemStore() func() {
+	UseStore(memStore{
+		bootCurrent: {
+			VariableName: bootCurrent,
+			Data:         []byte{0x0c, 0x00},
+			Attributes:   BootserviceAccess | RuntimeAccess,
+		},
+	})
+	return func() { UseStore(nil) }
 }
 
 func TestVariables(t *testing.T) {
+	defer useMemStore()()
+	testVariables(t)
+}
+
+// TestSystemVariables runs TestVariables against the running system.
+func TestSystemVariables(t *testing.T) {
 	if !Supported() {
 		t.Skip("efivar is not supported")
 	}
+	testVariables(t)
+}
+
+func testVariables(t *testing.T) {
 	vs, err := Variables()
 	if err != nil {
 		t.Errorf("Variables: %v", err)
@@ -95,9 +124,7 @@ func TestVariables(t *testing.T) {
 }
 
 func TestVariableExists(t *testing.T) {
-	if !Supported() {
-		t.Skip("efivar is not supported")
-	}
+	defer useMemStore()()
 	ok, err := bootCurrent.Exists()
 	if err != nil {
 		t.Errorf("bootCurrent.Exists: %v", err)
@@ -108,9 +135,7 @@ func TestVariableExists(t *testing.T) {
 }
 
 func TestVariableGet(t *testing.T) {
-	if !Supported() {
-		t.Skip("efivar is not supported")
-	}
+	defer useMemStore()()
 	v, err := bootCurrent.Get()
 	if err != nil {
 		t.Errorf("bootCurrent.Get: %v", err)
@@ -121,12 +146,22 @@ func TestVariableGet(t *testing.T) {
 }
 
 func TestVariableSetAndDelete(t *testing.T) {
+	defer useMemStore()()
+	testVariableSetAndDelete(t)
+}
+
+// TestSystemVariableSetAndDelete runs TestVariableSetAndDelete against the running system.
+func TestSystemVariableSetAndDelete(t *testing.T) {
 	if !Supported() {
 		t.Skip("efivar is not supported")
 	}
 	if os.Geteuid() != 0 {
 		t.Skip("this test only works *sigh* as root")
 	}
+	testVariableSetAndDelete(t)
+}
+
+func testVariableSetAndDelete(t *testing.T) {
 	testString := fmt.Sprintf("hello world %v", time.Now())
 	v := &Variable{
 		VariableName: testVariable,
@@ -158,3 +193,116 @@ func TestVariableSetAndDelete(t *testing.T) {
 		t.Fatalf("v.Exists() = %v; want false", ok)
 	}
 }
+
+// The benchmarks of Variables and Get measure libefivar on the running
+// system, as the fleet scanners which call them in bulk would.
+
+func BenchmarkVariables(b *testing.B) {
+	if !Supported() {
+		b.Skip("efivar is not supported")
+	}
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		if _, err := Variables(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGet(b *testing.B) {
+	if !Supported() {
+		b.Skip("efivar is not supported")
+	}
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		if _, err := bootCurrent.Get(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDevicePathToString(b *testing.B) {
+	// HD(1,GPT,41c147b6-e9bf-4c27-81c6-174026e79fd0,0x800,0x3a9800)/File(\vmlinuz-linux)
+	dp := []byte{
+		0x04, 0x01, 0x2a, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x98, 0x3a, 0x00, 0x00, 0x00, 0x00, 0x00, 0xb6, 0x47, 0xc1, 0x41, 0xbf, 0xe9, 0x27, 0x4c,
+		0x81, 0xc6, 0x17, 0x40, 0x26, 0xe7, 0x9f, 0xd0, 0x02, 0x02, 0x04, 0x04, 0x22, 0x00, 0x5c, 0x00,
+		0x76, 0x00, 0x6d, 0x00, 0x6c, 0x00, 0x69, 0x00, 0x6e, 0x00, 0x75, 0x00, 0x7a, 0x00, 0x2d, 0x00,
+		0x6c, 0x00, 0x69, 0x00, 0x6e, 0x00, 0x75, 0x00, 0x78, 0x00, 0x00, 0x00, 0x7f, 0xff, 0x04, 0x00,
+	}
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		if _, err := DevicePathToString(unsafe.Pointer(&dp[0]), len(dp)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// lockedStore makes a memStore safe for concurrent use.
+type lockedStore struct {
+	mu sync.Mutex
+	m  memStore
+}
+
+func (s *lockedStore) Get(vn VariableName) (*Variable, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.m.Get(vn)
+}
+
+func (s *lockedStore) Set(v *Variable) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.m.Set(v)
+}
+
+func (s *lockedStore) Delete(vn VariableName) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.m.Delete(vn)
+}
+
+func (s *lockedStore) Variables() ([]VariableName, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.m.Variables()
+}
+
+// TestConcurrentAccess is most useful with -race.
+func TestConcurrentAccess(t *testing.T) {
+	defer UseStore(nil)
+	dp := []byte{0x04, 0x04, 0x0a, 0x00, 'a', 0, 'b', 0, 0, 0, 0x7f, 0xff, 0x04, 0x00}
+	var wg sync.WaitGroup
+	for n := 0; n < 8; n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			s := &lockedStore{m: memStore{}}
+			for i := 0; i < 50; i++ {
+				switch n % 4 {
+				case 0:
+					// Switch between a store and the running system.
+					if i%2 == 0 {
+						UseStore(s)
+					} else {
+						UseStore(nil)
+					}
+				case 1:
+					v := &Variable{VariableName: testVariable, Data: []byte{byte(i)}, Attributes: NonVolatile | BootserviceAccess}
+					v.Set(0600)
+					testVariable.Get()
+					testVariable.Delete()
+				case 2:
+					bootCurrent.Exists()
+					Variables()
+				case 3:
+					if _, err := DevicePathToString(unsafe.Pointer(&dp[0]), len(dp)); err != nil {
+						t.Errorf("DevicePathToString: %v", err)
+						return
+					}
+				}
+			}
+		}(n)
+	}
+	wg.Wait()
+}
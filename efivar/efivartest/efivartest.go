@@ -0,0 +1,159 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package efivartest provides an in-memory efivar.Backend, so that code
+// using EFI variables can be tested on machines without EFI or root.
+//
+//	b := efivartest.New(&efivar.Variable{
+//		VariableName: efivar.VariableName{GUID: efivar.GlobalUUID, Name: "BootCurrent"},
+//		Data:         []byte{0x01, 0x00},
+//		Attributes:   efivar.BootserviceAccess | efivar.RuntimeAccess,
+//	})
+//	defer b.Use()()
+package efivartest
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/lukegb/goefivar/efivar"
+)
+
+// Backend holds variables in memory. It behaves as efivarfs does: writing
+// with AppendWrite appends to the existing data, and writing no data deletes
+// the variable. Authenticated writes are stored as given, without checking
+// or removing their authentication descriptor. A Backend is safe for
+// concurrent use.
+type Backend struct {
+	mu   sync.Mutex
+	vars map[efivar.VariableName]efivar.Variable
+	errs map[efivar.VariableName]error
+}
+
+// New returns a Backend holding copies of vs.
+func New(vs ...*efivar.Variable) *Backend {
+	b := &Backend{
+		vars: make(map[efivar.VariableName]efivar.Variable),
+		errs: make(map[efivar.VariableName]error),
+	}
+	b.Add(vs...)
+	return b
+}
+
+// Add stores copies of vs, replacing any variables of the same names.
+func (b *Backend) Add(vs ...*efivar.Variable) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, v := range vs {
+		b.vars[v.VariableName] = copyVariable(v)
+	}
+}
+
+// Fail makes every later operation on vn return err, until Fail(vn, nil).
+func (b *Backend) Fail(vn efivar.VariableName, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err == nil {
+		delete(b.errs, vn)
+		return
+	}
+	b.errs[vn] = err
+}
+
+// Use directs every variable operation in this process to b until the
+// returned function is called.
+func (b *Backend) Use() func() {
+	efivar.UseBackend(b)
+	return func() { efivar.UseBackend(nil) }
+}
+
+func copyVariable(v *efivar.Variable) efivar.Variable {
+	c := *v
+	c.Data = append([]byte(nil), v.Data...)
+	return c
+}
+
+func (b *Backend) Get(vn efivar.VariableName) (*efivar.Variable, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.errs[vn]; err != nil {
+		return nil, err
+	}
+	v, ok := b.vars[vn]
+	if !ok {
+		return nil, efivar.NotExist("get", vn)
+	}
+	c := copyVariable(&v)
+	return &c, nil
+}
+
+func (b *Backend) Exists(vn efivar.VariableName) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.errs[vn]; err != nil {
+		return false, err
+	}
+	_, ok := b.vars[vn]
+	return ok, nil
+}
+
+func (b *Backend) Set(v *efivar.Variable) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.errs[v.VariableName]; err != nil {
+		return err
+	}
+	n := copyVariable(v)
+	n.Attributes = n.Attributes.Without(efivar.AppendWrite)
+	if old, ok := b.vars[v.VariableName]; ok && v.Attributes.Has(efivar.AppendWrite) {
+		n.Data = append(old.Data, n.Data...)
+		n.Attributes = old.Attributes
+	}
+	if len(n.Data) == 0 {
+		delete(b.vars, v.VariableName)
+		return nil
+	}
+	b.vars[v.VariableName] = n
+	return nil
+}
+
+func (b *Backend) Delete(vn efivar.VariableName) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.errs[vn]; err != nil {
+		return err
+	}
+	if _, ok := b.vars[vn]; !ok {
+		return efivar.NotExist("delete", vn)
+	}
+	delete(b.vars, vn)
+	return nil
+}
+
+// Variables lists the variables in b, sorted by GUID and then name.
+func (b *Backend) Variables() ([]efivar.VariableName, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	vns := make([]efivar.VariableName, 0, len(b.vars))
+	for vn := range b.vars {
+		vns = append(vns, vn)
+	}
+	sort.Slice(vns, func(i, j int) bool {
+		if vns[i].GUID != vns[j].GUID {
+			return vns[i].GUID.String() < vns[j].GUID.String()
+		}
+		return vns[i].Name < vns[j].Name
+	})
+	return vns, nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivartest
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/lukegb/goefivar/efivar"
+)
+
+var bootCurrent = efivar.VariableName{GUID: efivar.GlobalUUID, Name: "BootCurrent"}
+
+func TestBackend(t *testing.T) {
+	b := New(&efivar.Variable{VariableName: bootCurrent, Data: []byte{1, 0}, Attributes: efivar.BootserviceAccess | efivar.RuntimeAccess})
+	defer b.Use()()
+
+	if !efivar.Supported() {
+		t.Error("Supported() = false while using a Backend")
+	}
+	v, err := bootCurrent.Get()
+	if err != nil || !bytes.Equal(v.Data, []byte{1, 0}) {
+		t.Fatalf("Get = %+v, %v; want the preloaded variable", v, err)
+	}
+	// Callers may not change the stored variable through what Get returns.
+	v.Data[0] = 9
+	if v, _ := bootCurrent.Get(); v.Data[0] != 1 {
+		t.Error("changing the result of Get changed the stored variable")
+	}
+
+	v.Data = []byte{2, 0}
+	v.Attributes = efivar.AppendWrite
+	if err := v.Set(0644); err != nil {
+		t.Fatalf("Set with AppendWrite: %v", err)
+	}
+	want := &efivar.Variable{VariableName: bootCurrent, Data: []byte{1, 0, 2, 0}, Attributes: efivar.BootserviceAccess | efivar.RuntimeAccess}
+	if got, err := bootCurrent.Get(); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("Get after append = %+v, %v; want %+v", got, err, want)
+	}
+
+	other := efivar.VariableName{GUID: efivar.GlobalUUID, Name: "BootNext"}
+	b.Add(&efivar.Variable{VariableName: other, Data: []byte{3, 0}})
+	if vns, err := efivar.Variables(); err != nil || !reflect.DeepEqual(vns, []efivar.VariableName{bootCurrent, other}) {
+		t.Errorf("Variables = %v, %v", vns, err)
+	}
+
+	// Writing no data deletes.
+	if err := (&efivar.Variable{VariableName: other}).Set(0644); err != nil {
+		t.Fatalf("Set with no data: %v", err)
+	}
+	if ok, err := other.Exists(); ok || err != nil {
+		t.Errorf("Exists after empty write = %v, %v; want false, nil", ok, err)
+	}
+	if err := other.Delete(); !os.IsNotExist(err) {
+		t.Errorf("Delete of missing variable = %v, want a not-exist error", err)
+	}
+}
+
+func TestBackendFail(t *testing.T) {
+	b := New(&efivar.Variable{VariableName: bootCurrent, Data: []byte{1, 0}})
+	errFull := errors.New("full")
+	b.Fail(bootCurrent, errFull)
+	if _, err := b.Get(bootCurrent); err != errFull {
+		t.Errorf("Get = %v, want %v", err, errFull)
+	}
+	if err := b.Set(&efivar.Variable{VariableName: bootCurrent, Data: []byte{2, 0}}); err != errFull {
+		t.Errorf("Set = %v, want %v", err, errFull)
+	}
+	b.Fail(bootCurrent, nil)
+	if v, err := b.Get(bootCurrent); err != nil || v.Data[0] != 1 {
+		t.Errorf("Get after Fail(nil) = %+v, %v; want the original variable", v, err)
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+
+	"github.com/google/uuid"
+)
+
+// Glob selects variables with shell patterns, in the syntax of path.Match.
+// A pattern matches a variable if it matches either its name, as "Boot*"
+// does, or its name and GUID as efivarfs shows them, NAME-GUID, as
+// "*-8be4df61-93ca-11d2-aa0d-00e098032b8c" and "Boot*-8be4df61-*" do. GUIDs
+// are written in lower case.
+//
+// Match has the type that vardump.Capture and varwatch.NewWatcher take, so a
+// Glob can select the variables to dump or to watch.
+type Glob struct {
+	patterns []string
+}
+
+// NewGlob returns a Glob matching any of patterns, or an error if one is
+// malformed. With no patterns, it matches nothing.
+func NewGlob(patterns ...string) (*Glob, error) {
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("efivar: bad pattern %q: %v", p, err)
+		}
+	}
+	return &Glob{patterns: append([]string(nil), patterns...)}, nil
+}
+
+// MustGlob is like NewGlob, but panics if a pattern is malformed.
+func MustGlob(patterns ...string) *Glob {
+	g, err := NewGlob(patterns...)
+	if err != nil {
+		panic(err)
+	}
+	return g
+}
+
+// Match reports whether vn matches any of g's patterns.
+func (g *Glob) Match(vn VariableName) bool {
+	full := vn.String()
+	for _, p := range g.patterns {
+		if ok, _ := path.Match(p, vn.Name); ok {
+			return true
+		}
+		if ok, _ := path.Match(p, full); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Variables lists the variables which match g.
+func (g *Glob) Variables() ([]VariableName, error) {
+	return find(g.Match, nil)
+}
+
+func (g *Glob) String() string {
+	return fmt.Sprint(g.patterns)
+}
+
+// Find lists the variables matching the shell pattern, as a Glob matches
+// them, such as "Boot*" or "dump-type0-*". If guids are given, only
+// variables of those vendors are listed.
+func Find(pattern string, guids ...uuid.UUID) ([]VariableName, error) {
+	g, err := NewGlob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return find(g.Match, guids)
+}
+
+// FindRegexp lists the variables whose names re matches. If guids are
+// given, only variables of those vendors are listed. re is matched against
+// the name alone; anchor it, as in "^Boot[0-9A-F]{4}$", to match whole
+// names.
+func FindRegexp(re *regexp.Regexp, guids ...uuid.UUID) ([]VariableName, error) {
+	return find(func(vn VariableName) bool {
+		return re.MatchString(vn.Name)
+	}, guids)
+}
+
+// find lists the variables of any of guids, or of any vendor if there are
+// none, which match reports true for.
+func find(match func(vn VariableName) bool, guids []uuid.UUID) ([]VariableName, error) {
+	var out []VariableName
+	err := WalkVariables(func(vn VariableName) error {
+		if !hasGUID(guids, vn.GUID) || !match(vn) {
+			return nil
+		}
+		out = append(out, vn)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func hasGUID(guids []uuid.UUID, guid uuid.UUID) bool {
+	if len(guids) == 0 {
+		return true
+	}
+	for _, g := range guids {
+		if g == guid {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestGlobMatch(t *testing.T) {
+	shim := uuid.MustParse("605dab50-e046-4300-abb6-3dd810dd8b23")
+	for _, tc := range []struct {
+		pattern string
+		vn      VariableName
+		want    bool
+	}{
+		{"Boot*", VariableName{GlobalUUID, "Boot0001"}, true},
+		{"Boot*", VariableName{shim, "BootFoo"}, true},
+		{"Boot*", VariableName{GlobalUUID, "Timeout"}, false},
+		{"Boot????", VariableName{GlobalUUID, "BootOrder"}, false},
+		{"Boot[0-9A-F][0-9A-F][0-9A-F][0-9A-F]", VariableName{GlobalUUID, "Boot000A"}, true},
+		{"*-8be4df61-93ca-11d2-aa0d-00e098032b8c", VariableName{GlobalUUID, "Timeout"}, true},
+		{"*-8be4df61-93ca-11d2-aa0d-00e098032b8c", VariableName{shim, "MokList"}, false},
+		{"*-8be4df61-*", VariableName{GlobalUUID, "Timeout"}, true},
+		{"Mok*-605dab50-*", VariableName{shim, "MokListRT"}, true},
+		{"Mok*-605dab50-*", VariableName{GlobalUUID, "MokListRT"}, false},
+		{"Timeout-8be4df61-93ca-11d2-aa0d-00e098032b8c", VariableName{GlobalUUID, "Timeout"}, true},
+		{"*", VariableName{shim, "SbatLevel"}, true},
+	} {
+		if got := MustGlob(tc.pattern).Match(tc.vn); got != tc.want {
+			t.Errorf("Glob(%q).Match(%v-%v) = %v; want %v", tc.pattern, tc.vn.Name, tc.vn.GUID, got, tc.want)
+		}
+	}
+}
+
+func TestNewGlob(t *testing.T) {
+	if _, err := NewGlob("Boot*", "[bad"); err == nil {
+		t.Errorf("NewGlob with a malformed pattern succeeded")
+	}
+	if g := MustGlob(); g.Match(bootCurrent) {
+		t.Errorf("empty Glob matched %v", bootCurrent)
+	}
+	if g := MustGlob("Timeout", "Boot*"); !g.Match(bootCurrent) {
+		t.Errorf("%v did not match %v", g, bootCurrent)
+	}
+}
+
+func TestGlobVariables(t *testing.T) {
+	defer useMemStore()()
+	if err := (&Variable{VariableName: testVariable, Data: []byte{1}}).Set(0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := MustGlob("*-8be4df61-*").Variables()
+	if err != nil || !reflect.DeepEqual(got, []VariableName{bootCurrent}) {
+		t.Errorf("Variables() = %v, %v; want %v", got, err, []VariableName{bootCurrent})
+	}
+}
+
+func TestFind(t *testing.T) {
+	other := VariableName{GUID: testVariable.GUID, Name: "Boot0001"}
+	UseStore(memStore{
+		bootCurrent: {VariableName: bootCurrent, Data: []byte{1, 0}},
+		other:       {VariableName: other, Data: []byte{1}},
+	})
+	defer UseStore(nil)
+
+	for _, tc := range []struct {
+		pattern string
+		guids   []uuid.UUID
+		want    int
+	}{
+		{"Boot*", nil, 2},
+		{"Boot*", []uuid.UUID{GlobalUUID}, 1},
+		{"Boot*", []uuid.UUID{GlobalUUID, testVariable.GUID}, 2},
+		{"Boot????", nil, 1},
+		{"Timeout", nil, 0},
+	} {
+		vns, err := Find(tc.pattern, tc.guids...)
+		if err != nil || len(vns) != tc.want {
+			t.Errorf("Find(%q, %v) = %v, %v; want %d variables", tc.pattern, tc.guids, vns, err, tc.want)
+		}
+	}
+	if _, err := Find("[bad"); err == nil {
+		t.Errorf("Find with a malformed pattern succeeded")
+	}
+
+	vns, err := FindRegexp(regexp.MustCompile(`^Boot[0-9A-F]{4}$`))
+	if err != nil || !reflect.DeepEqual(vns, []VariableName{other}) {
+		t.Errorf("FindRegexp = %v, %v; want %v", vns, err, []VariableName{other})
+	}
+	vns, err = FindRegexp(regexp.MustCompile(`^Boot`), testVariable.GUID)
+	if err != nil || !reflect.DeepEqual(vns, []VariableName{other}) {
+		t.Errorf("FindRegexp scoped to %v = %v, %v; want %v", testVariable.GUID, vns, err, []VariableName{other})
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import v2 "github.com/lukegb/goefivar/v2/efivar"
+
+// ReadOnlyEnv is the environment variable which, set to "1" when the
+// program starts, puts this package in read-only mode.
+const ReadOnlyEnv = v2.ReadOnlyEnv
+
+// ErrReadOnly is returned by every change made in read-only mode.
+var ErrReadOnly = v2.ErrReadOnly
+
+// isReadOnly is version 2's IsReadOnly, which tests replace since read-only
+// mode cannot be turned off.
+var isReadOnly = v2.IsReadOnly
+
+// SetReadOnly makes every later Set and Delete, through the running system,
+// the Backends returned by SystemBackend, Efivarfs and Libefivar, or an
+// installed Store, fail with ErrReadOnly. It cannot be undone, so a
+// monitoring or inventory tool can call it first thing and be sure it never
+// changes NVRAM. Building with the goefivar_readonly tag does the same from
+// the start. Version 2 shares the mode.
+func SetReadOnly() {
+	v2.SetReadOnly()
+}
+
+// ReadOnly reports whether this package is in read-only mode.
+func ReadOnly() bool {
+	return isReadOnly()
+}
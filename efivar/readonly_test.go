@@ -0,0 +1,48 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import (
+	"testing"
+
+	v2 "github.com/lukegb/goefivar/v2/efivar"
+)
+
+func TestReadOnly(t *testing.T) {
+	defer useMemStore()()
+	// SetReadOnly cannot be undone, so stand in for it.
+	isReadOnly = func() bool { return true }
+	defer func() { isReadOnly = v2.IsReadOnly }()
+
+	if !ReadOnly() {
+		t.Error("ReadOnly() = false after SetReadOnly")
+	}
+	v, err := bootCurrent.Get()
+	if err != nil {
+		t.Fatalf("Get in read-only mode: %v", err)
+	}
+	if err := v.Set(0644); err != ErrReadOnly {
+		t.Errorf("Set = %v, want %v", err, ErrReadOnly)
+	}
+	if err := bootCurrent.Delete(); err != ErrReadOnly {
+		t.Errorf("Delete = %v, want %v", err, ErrReadOnly)
+	}
+	if c := CodeOf(ErrReadOnly); c != Permission {
+		t.Errorf("CodeOf(ErrReadOnly) = %v, want %v", c, Permission)
+	}
+	if ok, _ := bootCurrent.Exists(); !ok {
+		t.Error("Delete in read-only mode removed the variable")
+	}
+}
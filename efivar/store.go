@@ -0,0 +1,158 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import (
+	"context"
+	"os"
+	"sync"
+
+	v2 "github.com/lukegb/goefivar/v2/efivar"
+)
+
+// Store holds a set of EFI variables other than the running system's, such
+// as a firmware image or a saved dump. A Store must be safe for concurrent
+// use if the program uses variables from more than one goroutine.
+type Store interface {
+	// Get returns the variable vn, or an error satisfying os.IsNotExist.
+	Get(vn VariableName) (*Variable, error)
+	Set(v *Variable) error
+	// Delete removes vn, returning an error satisfying os.IsNotExist if there is no such variable.
+	Delete(vn VariableName) error
+	Variables() ([]VariableName, error)
+}
+
+// A Backend holds a set of EFI variables: the running system's, through
+// libefivar or efivarfs, or a Store. Every variable operation in this package
+// goes through the Backend installed with UseBackend, or SystemBackend if
+// there is none. A Backend must be safe for concurrent use if the program
+// uses variables from more than one goroutine.
+type Backend interface {
+	Store
+	// Exists reports whether vn is set.
+	Exists(vn VariableName) (bool, error)
+}
+
+var (
+	backendMu sync.RWMutex
+	// backend, if set, is used in place of SystemBackend.
+	backend Backend
+)
+
+// UseBackend directs every variable operation in this process to b.
+// UseBackend(nil) restores SystemBackend. Operations already in progress
+// finish against the Backend they started with.
+func UseBackend(b Backend) {
+	backendMu.Lock()
+	defer backendMu.Unlock()
+	backend = b
+}
+
+func currentBackend() Backend {
+	backendMu.RLock()
+	defer backendMu.RUnlock()
+	if backend == nil {
+		return sys
+	}
+	return backend
+}
+
+// UseStore directs every variable operation in this process to s, rather
+// than to the running system. UseStore(nil) restores the default.
+func UseStore(s Store) {
+	if s == nil {
+		UseBackend(nil)
+		return
+	}
+	UseBackend(FromStore(s))
+}
+
+// FromStore returns s as a Backend, implementing Exists with Get if s does
+// not have it.
+func FromStore(s Store) Backend {
+	if b, ok := s.(Backend); ok {
+		return b
+	}
+	return storeBackend{s}
+}
+
+type storeBackend struct{ Store }
+
+func (s storeBackend) Exists(vn VariableName) (bool, error) {
+	_, err := s.Get(vn)
+	switch {
+	case err == nil:
+		return true, nil
+	case os.IsNotExist(err):
+		return false, nil
+	}
+	return false, err
+}
+
+// NotExist returns the error a Store returns for a missing variable.
+func NotExist(op string, vn VariableName) error {
+	return v2.NotExist(op, v2.VariableName(vn))
+}
+
+// BackendV2 adapts s, such as a varstore.Image, to a version 2 Backend. A
+// Backend returned by SystemBackend, Efivarfs or Libefivar gives its
+// version 2 System. Otherwise s's methods are called directly: ctx is
+// checked before each operation, but an operation which has started is not
+// interrupted, and changes are neither reported to the Auditor nor refused
+// in read-only mode. Use version 2's ReadOnly for the latter.
+func BackendV2(s Store) v2.Backend {
+	if b, ok := s.(systemBackend); ok {
+		return b.sys
+	}
+	return storeV2{s}
+}
+
+type storeV2 struct{ s Store }
+
+func (s storeV2) Get(ctx context.Context, vn v2.VariableName) (*v2.Variable, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	v, err := s.s.Get(VariableName(vn))
+	if err != nil {
+		return nil, err
+	}
+	return v.toV2(), nil
+}
+
+func (s storeV2) Set(ctx context.Context, v *v2.Variable) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.s.Set(fromV2(v))
+}
+
+func (s storeV2) Delete(ctx context.Context, vn v2.VariableName) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.s.Delete(VariableName(vn))
+}
+
+func (s storeV2) Variables(ctx context.Context) ([]v2.VariableName, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	vns, err := s.s.Variables()
+	if err != nil {
+		return nil, err
+	}
+	return toV2Names(vns), nil
+}
@@ -0,0 +1,118 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	v2 "github.com/lukegb/goefivar/v2/efivar"
+)
+
+// existsBackend is a memStore with its own Exists, counting its calls.
+type existsBackend struct {
+	memStore
+	calls int
+}
+
+func (b *existsBackend) Exists(vn VariableName) (bool, error) {
+	b.calls++
+	_, ok := b.memStore[vn]
+	return ok, nil
+}
+
+func TestUseBackend(t *testing.T) {
+	b := &existsBackend{memStore: memStore{}}
+	UseBackend(b)
+	defer UseBackend(nil)
+
+	if !Supported() {
+		t.Error("Supported() = false with a Backend installed")
+	}
+	v := &Variable{VariableName: bootCurrent, Data: []byte{1, 0}, Attributes: BootserviceAccess | RuntimeAccess}
+	if err := v.Set(0644); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if ok, err := bootCurrent.Exists(); !ok || err != nil {
+		t.Errorf("Exists = %v, %v; want true, nil", ok, err)
+	}
+	if b.calls != 1 {
+		t.Errorf("Backend's Exists called %d times, want 1", b.calls)
+	}
+	if FromStore(b) != Backend(b) {
+		t.Error("FromStore did not return a Backend unchanged")
+	}
+
+	// A Store without Exists gets one built on Get.
+	s := FromStore(memStore{})
+	if ok, err := s.Exists(bootCurrent); ok || err != nil {
+		t.Errorf("Exists on an empty Store = %v, %v; want false, nil", ok, err)
+	}
+}
+
+func TestSystemBackend(t *testing.T) {
+	if SystemBackend() != Backend(sys) {
+		t.Error("SystemBackend() is not the running system's implementation")
+	}
+}
+
+func TestEfivarfsPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "efivarfs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	UseBackend(Efivarfs(dir))
+	defer UseBackend(nil)
+
+	v := &Variable{VariableName: bootCurrent, Data: []byte{1, 0}, Attributes: BootserviceAccess | RuntimeAccess}
+	if err := v.Set(0600); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if fi, err := os.Stat(filepath.Join(dir, bootCurrent.String())); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("Set did not write to %v with mode 0600: %v, %v", dir, fi, err)
+	}
+}
+
+func TestBackendV2(t *testing.T) {
+	c := v2.New(BackendV2(memStore{}))
+	ctx := context.Background()
+	v := &v2.Variable{VariableName: v2.VariableName(bootCurrent), Data: []byte{1, 0}}
+	if err := c.Set(ctx, v); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if got, err := c.Get(ctx, v.VariableName); err != nil || !reflect.DeepEqual(got, v) {
+		t.Errorf("Get = %+v, %v; want %+v", got, err, v)
+	}
+	if err := c.Delete(ctx, v.VariableName); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := c.Get(ctx, v.VariableName); v2.CodeOf(err) != NotFound {
+		t.Errorf("Get after Delete = %v; want not exist", err)
+	}
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := c.Variables(cancelled); err == nil {
+		t.Error("Variables(cancelled context) succeeded")
+	}
+
+	if _, ok := BackendV2(SystemBackend()).(v2.System); !ok {
+		t.Error("BackendV2(SystemBackend()) is not a version 2 System")
+	}
+}
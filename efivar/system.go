@@ -0,0 +1,141 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import (
+	"context"
+	"os"
+
+	v2 "github.com/lukegb/goefivar/v2/efivar"
+)
+
+// Names of the implementations of the running system's variables, as
+// returned by Implementation.
+const (
+	ImplLibefivar = v2.ImplLibefivar
+	ImplPureGo    = v2.ImplPureGo
+)
+
+// ImplEnv is the environment variable which, set to ImplPureGo, makes a
+// program built with libefivar use the pure-Go implementation instead.
+const ImplEnv = v2.ImplEnv
+
+// DefaultMode is the permission given to the efivarfs files of variables
+// created through a system Backend's Set.
+const DefaultMode = v2.DefaultMode
+
+// EfivarfsPathEnv is the environment variable which, if set, gives the path
+// of efivarfs in place of /sys/firmware/efi/efivars. libefivar reads the
+// same variable.
+const EfivarfsPathEnv = v2.EfivarfsPathEnv
+
+// AppleNVRAMUUID is the GUID of macOS's own NVRAM variables, such as
+// boot-args, which the nvram command lists without a GUID.
+var AppleNVRAMUUID = v2.AppleNVRAMUUID
+
+// systemBackend is a version 2 System as a Backend.
+type systemBackend struct{ sys v2.System }
+
+func (s systemBackend) Exists(vn VariableName) (bool, error) {
+	return s.sys.Exists(context.Background(), v2.VariableName(vn))
+}
+
+func (s systemBackend) Get(vn VariableName) (*Variable, error) {
+	v, err := s.sys.Get(context.Background(), v2.VariableName(vn))
+	if err != nil {
+		return nil, err
+	}
+	return fromV2(v), nil
+}
+
+func (s systemBackend) Set(v *Variable) error {
+	return s.sys.Set(context.Background(), v.toV2())
+}
+
+// setMode is Set, giving a new variable's efivarfs file the permission
+// mode.
+func (s systemBackend) setMode(v *Variable, mode os.FileMode) error {
+	s.sys.Mode = mode
+	return s.Set(v)
+}
+
+func (s systemBackend) Delete(vn VariableName) error {
+	return s.sys.Delete(context.Background(), v2.VariableName(vn))
+}
+
+func (s systemBackend) Variables() ([]VariableName, error) {
+	vns, err := s.sys.Variables(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return fromV2Names(vns), nil
+}
+
+func (s systemBackend) WalkVariables(fn func(vn VariableName) error) error {
+	return s.sys.WalkVariables(context.Background(), func(vn v2.VariableName) error {
+		return fn(VariableName(vn))
+	})
+}
+
+// toV2Names converts a list of VariableNames to version 2's.
+func toV2Names(vns []VariableName) []v2.VariableName {
+	out := make([]v2.VariableName, len(vns))
+	for i, vn := range vns {
+		out[i] = v2.VariableName(vn)
+	}
+	return out
+}
+
+// fromV2Names converts a list of version 2 VariableNames.
+func fromV2Names(vns []v2.VariableName) []VariableName {
+	out := make([]VariableName, len(vns))
+	for i, vn := range vns {
+		out[i] = VariableName(vn)
+	}
+	return out
+}
+
+// sys is the running system's variables, through the implementation
+// version 2 chose when the program started.
+var sys = systemBackend{}
+
+// SystemBackend returns the Backend for the running system's variables which
+// this program uses by default: libefivar or efivarfs, as reported by
+// Implementation.
+func SystemBackend() Backend { return sys }
+
+// Efivarfs returns a Backend which reads and writes the efivarfs mounted at
+// path directly, whether or not the program was built with libefivar. The
+// path may be anywhere, such as in a chroot or a test directory; if it is
+// empty, Efivarfs uses EfivarfsPathEnv or /sys/firmware/efi/efivars.
+func Efivarfs(path string) Backend { return systemBackend{v2.Efivarfs(path)} }
+
+// Libefivar returns a Backend which goes through libefivar, whatever
+// ImplEnv says, or an Unsupported error if the program was built without
+// it.
+func Libefivar() (Backend, error) {
+	s, err := v2.Libefivar()
+	if err != nil {
+		return nil, err
+	}
+	return systemBackend{s}, nil
+}
+
+// Implementation returns ImplLibefivar or ImplPureGo, saying which
+// implementation this program uses for the running system's variables.
+// Package efiboot follows the same choice for load options.
+func Implementation() string {
+	return v2.Implementation()
+}
@@ -0,0 +1,76 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import v2 "github.com/lukegb/goefivar/v2/efivar"
+
+// ParseAttributes is the inverse of Attributes.String. It also accepts lower
+// case, numbers, and commas or | between the parts, so "nv,bs,rt" and
+// "0x7" give the same attributes as "NV+BS+RT".
+func ParseAttributes(s string) (Attributes, error) { return v2.ParseAttributes(s) }
+
+// String returns vn as NAME-GUID, the name of its efivarfs file, such as
+// BootOrder-8be4df61-93ca-11d2-aa0d-00e098032b8c. The GUID is in lower case.
+func (vn VariableName) String() string {
+	return v2.VariableName(vn).String()
+}
+
+// ParseVariableName parses NAME-GUID, as String returns it and as ls shows
+// efivarfs. The GUID may be in either case.
+func ParseVariableName(s string) (VariableName, error) {
+	vn, err := v2.ParseVariableName(s)
+	return VariableName(vn), err
+}
+
+// MarshalText encodes vn as NAME-GUID, the name of its efivarfs file.
+func (vn VariableName) MarshalText() ([]byte, error) {
+	return v2.VariableName(vn).MarshalText()
+}
+
+// UnmarshalText decodes NAME-GUID.
+func (vn *VariableName) UnmarshalText(text []byte) error {
+	return (*v2.VariableName)(vn).UnmarshalText(text)
+}
+
+// MarshalJSON encodes vn as an object with its GUID and name, such as
+// {"guid":"8be4df61-93ca-11d2-aa0d-00e098032b8c","name":"BootOrder"}. As a
+// map key, vn is still encoded as NAME-GUID.
+func (vn VariableName) MarshalJSON() ([]byte, error) {
+	return v2.VariableName(vn).MarshalJSON()
+}
+
+// UnmarshalJSON decodes what MarshalJSON encodes, or a NAME-GUID string.
+func (vn *VariableName) UnmarshalJSON(b []byte) error {
+	return (*v2.VariableName)(vn).UnmarshalJSON(b)
+}
+
+// MarshalJSON encodes v as an object with its GUID and name, as
+// VariableName.MarshalJSON does, its attributes as MarshalText names them,
+// and its data in base64. Without it, Variable would take the methods of its
+// VariableName and encode as the name alone.
+func (v Variable) MarshalJSON() ([]byte, error) {
+	return v.toV2().MarshalJSON()
+}
+
+// UnmarshalJSON decodes what MarshalJSON encodes. The attributes may also
+// be a number.
+func (v *Variable) UnmarshalJSON(b []byte) error {
+	var w v2.Variable
+	if err := w.UnmarshalJSON(b); err != nil {
+		return err
+	}
+	*v = *fromV2(&w)
+	return nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+	v2 "github.com/lukegb/goefivar/v2/efivar"
+)
+
+// StopWalk, returned by the function given to WalkVariables, ends the walk
+// early without error.
+var StopWalk = v2.StopWalk
+
+// A Walker is a Backend which can list its variables one at a time, without
+// first collecting them all. WalkVariables uses it if the Backend has it.
+type Walker interface {
+	// WalkVariables calls fn for each variable, stopping at the first
+	// error fn returns, which it returns unless it is StopWalk.
+	WalkVariables(fn func(vn VariableName) error) error
+}
+
+// WalkVariables calls fn for each variable, in no particular order,
+// stopping at the first error fn returns, which it returns unless it is
+// StopWalk. efivarfs is read as fn goes, so a program can stop early on a
+// system with thousands of variables; other Backends may list every
+// variable first.
+func WalkVariables(fn func(vn VariableName) error) error {
+	b := currentBackend()
+	if w, ok := b.(Walker); ok {
+		return w.WalkVariables(fn)
+	}
+	vns, err := b.Variables()
+	if err != nil {
+		return err
+	}
+	for _, vn := range vns {
+		if err := fn(vn); err == StopWalk {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// VariablesByGUID lists the variables of vendor guid whose names begin with
+// prefix, filtering as the variables are listed rather than afterwards.
+func VariablesByGUID(guid uuid.UUID, prefix string) ([]VariableName, error) {
+	var out []VariableName
+	err := WalkVariables(func(vn VariableName) error {
+		if vn.GUID == guid && strings.HasPrefix(vn.Name, prefix) {
+			out = append(out, vn)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
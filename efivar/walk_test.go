@@ -0,0 +1,88 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testWalk(t *testing.T, want int) {
+	t.Helper()
+	n := 0
+	if err := WalkVariables(func(vn VariableName) error { n++; return nil }); err != nil || n != want {
+		t.Errorf("WalkVariables visited %d variables, %v; want %d", n, err, want)
+	}
+	n = 0
+	if err := WalkVariables(func(vn VariableName) error { n++; return StopWalk }); err != nil || n != 1 {
+		t.Errorf("WalkVariables stopped after %d variables, %v; want 1, nil", n, err)
+	}
+	errBad := errors.New("bad")
+	if err := WalkVariables(func(vn VariableName) error { return errBad }); err != errBad {
+		t.Errorf("WalkVariables = %v, want the callback's error", err)
+	}
+}
+
+func TestWalkVariables(t *testing.T) {
+	dir, err := ioutil.TempDir("", "efivarfs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"Boot0000", "Boot0001", "BootOrder"} {
+		f := filepath.Join(dir, VariableName{GUID: GlobalUUID, Name: name}.String())
+		if err := ioutil.WriteFile(f, []byte{7, 0, 0, 0, 1}, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "not-a-variable"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	UseBackend(Efivarfs(dir))
+	defer UseBackend(nil)
+	testWalk(t, 3)
+}
+
+func TestWalkVariablesStore(t *testing.T) {
+	defer useMemStore()()
+	testWalk(t, 1)
+}
+
+func TestVariablesByGUID(t *testing.T) {
+	other := VariableName{GUID: testVariable.GUID, Name: "Boot0001"}
+	UseStore(memStore{
+		bootCurrent: {VariableName: bootCurrent, Data: []byte{1, 0}},
+		other:       {VariableName: other, Data: []byte{1}},
+	})
+	defer UseStore(nil)
+
+	for _, tc := range []struct {
+		prefix string
+		want   int
+	}{{"", 1}, {"Boot", 1}, {"BootCurrent", 1}, {"Timeout", 0}} {
+		vns, err := VariablesByGUID(GlobalUUID, tc.prefix)
+		if err != nil || len(vns) != tc.want {
+			t.Errorf("VariablesByGUID(GlobalUUID, %q) = %v, %v; want %d variables", tc.prefix, vns, err, tc.want)
+		}
+		for _, vn := range vns {
+			if vn != bootCurrent {
+				t.Errorf("VariablesByGUID(GlobalUUID, %q) returned %v", tc.prefix, vn)
+			}
+		}
+	}
+}
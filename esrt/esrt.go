@@ -0,0 +1,188 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package esrt reads the EFI System Resource Table, which lists the firmware
+// resources that can be updated with capsules.
+package esrt
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// esrtDir is where the kernel exposes the ESRT.
+var esrtDir = "/sys/firmware/efi/esrt"
+
+// Type is the kind of firmware resource an entry describes.
+type Type uint32
+
+const (
+	TypeUnknown        Type = 0
+	TypeSystemFirmware Type = 1
+	TypeDeviceFirmware Type = 2
+	TypeUEFIDriver     Type = 3
+)
+
+func (t Type) String() string {
+	switch t {
+	case TypeUnknown:
+		return "unknown"
+	case TypeSystemFirmware:
+		return "system firmware"
+	case TypeDeviceFirmware:
+		return "device firmware"
+	case TypeUEFIDriver:
+		return "UEFI driver"
+	}
+	return fmt.Sprintf("Type(%d)", uint32(t))
+}
+
+// Status is the result of the last attempted update of a resource.
+type Status uint32
+
+const (
+	StatusSuccess                      Status = 0
+	StatusErrorUnsuccessful            Status = 1
+	StatusErrorInsufficientResources   Status = 2
+	StatusErrorIncorrectVersion        Status = 3
+	StatusErrorInvalidFormat           Status = 4
+	StatusErrorAuthError               Status = 5
+	StatusErrorPowerEventAC            Status = 6
+	StatusErrorPowerEventBattery       Status = 7
+	StatusErrorUnsatisfiedDependencies Status = 8
+)
+
+var statusNames = map[Status]string{
+	StatusSuccess:                      "success",
+	StatusErrorUnsuccessful:            "unsuccessful",
+	StatusErrorInsufficientResources:   "insufficient resources",
+	StatusErrorIncorrectVersion:        "incorrect version",
+	StatusErrorInvalidFormat:           "invalid format",
+	StatusErrorAuthError:               "authentication error",
+	StatusErrorPowerEventAC:            "AC power required",
+	StatusErrorPowerEventBattery:       "insufficient battery",
+	StatusErrorUnsatisfiedDependencies: "unsatisfied dependencies",
+}
+
+func (s Status) String() string {
+	if n, ok := statusNames[s]; ok {
+		return n
+	}
+	return fmt.Sprintf("Status(%d)", uint32(s))
+}
+
+// Entry is a single updatable firmware resource.
+type Entry struct {
+	FirmwareClass          uuid.UUID
+	Type                   Type
+	Version                uint32
+	LowestSupportedVersion uint32
+	CapsuleFlags           uint32
+	LastAttemptVersion     uint32
+	LastAttemptStatus      Status
+}
+
+// Supported reports whether the kernel exposes an ESRT.
+func Supported() bool {
+	_, err := os.Stat(esrtDir)
+	return err == nil
+}
+
+func readString(dir, name string) (string, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+func readUint32(dir, name string) (uint32, error) {
+	s, err := readString(dir, name)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseUint(s, 0, 32)
+	if err != nil {
+		return 0, fmt.Errorf("esrt: parsing %v: %v", filepath.Join(dir, name), err)
+	}
+	return uint32(n), nil
+}
+
+func readEntry(dir string) (*Entry, error) {
+	class, err := readString(dir, "fw_class")
+	if err != nil {
+		return nil, err
+	}
+	e := &Entry{}
+	if e.FirmwareClass, err = uuid.Parse(class); err != nil {
+		return nil, fmt.Errorf("esrt: parsing %v: %v", filepath.Join(dir, "fw_class"), err)
+	}
+	var typ, status uint32
+	for _, f := range []struct {
+		name string
+		dst  *uint32
+	}{
+		{"fw_type", &typ},
+		{"fw_version", &e.Version},
+		{"lowest_supported_fw_version", &e.LowestSupportedVersion},
+		{"capsule_flags", &e.CapsuleFlags},
+		{"last_attempt_version", &e.LastAttemptVersion},
+		{"last_attempt_status", &status},
+	} {
+		if *f.dst, err = readUint32(dir, f.name); err != nil {
+			return nil, err
+		}
+	}
+	e.Type, e.LastAttemptStatus = Type(typ), Status(status)
+	return e, nil
+}
+
+// Entries returns the resources listed in the ESRT, in table order.
+func Entries() ([]*Entry, error) {
+	dir := filepath.Join(esrtDir, "entries")
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, fi := range fis {
+		if strings.HasPrefix(fi.Name(), "entry") {
+			names = append(names, fi.Name())
+		}
+	}
+	// entry10 sorts after entry9.
+	sort.Slice(names, func(i, j int) bool {
+		if len(names[i]) != len(names[j]) {
+			return len(names[i]) < len(names[j])
+		}
+		return names[i] < names[j]
+	})
+
+	out := make([]*Entry, 0, len(names))
+	for _, n := range names {
+		e, err := readEntry(filepath.Join(dir, n))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
@@ -0,0 +1,132 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package esrt
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func writeEntry(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content+"\n"), 0644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+}
+
+func TestEntries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "esrt")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(old string) { esrtDir = old }(esrtDir)
+	esrtDir = dir
+
+	system := uuid.MustParse("5b92f7de-3a43-4f5b-a4b4-ab3c7f0b4b8a")
+	device := uuid.MustParse("d3a2d1c8-f1a7-4e4a-9f76-0b5b1d43a9e1")
+	writeEntry(t, filepath.Join(dir, "entries", "entry10"), map[string]string{
+		"fw_class":                    device.String(),
+		"fw_type":                     "2",
+		"fw_version":                  "7",
+		"lowest_supported_fw_version": "0",
+		"capsule_flags":               "0x0",
+		"last_attempt_version":        "0",
+		"last_attempt_status":         "0",
+	})
+	writeEntry(t, filepath.Join(dir, "entries", "entry9"), map[string]string{
+		"fw_class":                    system.String(),
+		"fw_type":                     "1",
+		"fw_version":                  "65586",
+		"lowest_supported_fw_version": "65536",
+		"capsule_flags":               "0x8010",
+		"last_attempt_version":        "65586",
+		"last_attempt_status":         "5",
+	})
+
+	got, err := Entries()
+	if err != nil {
+		t.Fatalf("Entries: %v", err)
+	}
+	want := []*Entry{{
+		FirmwareClass:          system,
+		Type:                   TypeSystemFirmware,
+		Version:                65586,
+		LowestSupportedVersion: 65536,
+		CapsuleFlags:           0x8010,
+		LastAttemptVersion:     65586,
+		LastAttemptStatus:      StatusErrorAuthError,
+	}, {
+		FirmwareClass: device,
+		Type:          TypeDeviceFirmware,
+		Version:       7,
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Entries = %+v; want %+v", got, want)
+	}
+	if s := got[0].LastAttemptStatus.String(); s != "authentication error" {
+		t.Errorf("LastAttemptStatus.String() = %q", s)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	dir, err := ioutil.TempDir("", "esrt")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(old string) { nvmeDir = old }(nvmeDir)
+	nvmeDir = dir
+
+	writeEntry(t, filepath.Join(dir, "nvme0"), map[string]string{"model": "Samsung SSD 970 EVO Plus 1TB"})
+	writeEntry(t, filepath.Join(dir, "nvme0", "device"), map[string]string{
+		"vendor":           "0x144d",
+		"device":           "0xa808",
+		"subsystem_vendor": "0x144d",
+		"subsystem_device": "0xa801",
+	})
+
+	r, err := NewResolver()
+	if err != nil {
+		t.Fatalf("NewResolver: %v", err)
+	}
+	dbx := uuid.MustParse("5f3a3e1c-1b0c-4a3e-9b5d-3c0f7e9d1a2b")
+	r.Names[dbx] = "UEFI dbx"
+
+	for _, tc := range []struct {
+		e    Entry
+		want string
+	}{
+		{Entry{FirmwareClass: InstanceIDGUID(`NVME\VEN_144D&DEV_A808`), Type: TypeDeviceFirmware}, "Samsung SSD 970 EVO Plus 1TB"},
+		{Entry{FirmwareClass: InstanceIDGUID(`NVME\VEN_144D&DEV_A808&SUBSYS_144DA801`), Type: TypeDeviceFirmware}, "Samsung SSD 970 EVO Plus 1TB"},
+		{Entry{FirmwareClass: uuid.New(), Type: TypeSystemFirmware}, "System Firmware"},
+		{Entry{FirmwareClass: uuid.New(), Type: TypeDeviceFirmware}, "UEFI Device Firmware"},
+		{Entry{FirmwareClass: dbx, Type: TypeUnknown}, "UEFI dbx"},
+	} {
+		if got := r.Resolve(&tc.e).Name; got != tc.want {
+			t.Errorf("Resolve(%v) = %q; want %q", tc.e.FirmwareClass, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package esrt
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"unicode/utf16"
+
+	"github.com/google/uuid"
+)
+
+// nvmeDir lists the NVMe controllers known to the kernel.
+var nvmeDir = "/sys/class/nvme"
+
+// instanceIDNamespace is the namespace fwupd and Windows use when hashing
+// device instance IDs into GUIDs.
+var instanceIDNamespace = uuid.MustParse("70ffd812-4c7f-4c7d-0000-000000000000")
+
+// InstanceIDGUID returns the GUID for a device instance ID such as
+// NVME\VEN_144D&DEV_A808, as computed by fwupd and Windows.
+func InstanceIDGUID(id string) uuid.UUID {
+	var b []byte
+	for _, c := range utf16.Encode([]rune(id)) {
+		b = append(b, byte(c), byte(c>>8))
+	}
+	return uuid.NewSHA1(instanceIDNamespace, b)
+}
+
+// Device is the hardware or firmware component an ESRT entry updates.
+type Device struct {
+	Name string
+	// Path is the sysfs path of the device, if it was found.
+	Path string
+}
+
+// Resolver maps ESRT entries to the devices they describe.
+type Resolver struct {
+	// Names overrides the description of specific firmware classes.
+	Names map[uuid.UUID]string
+
+	devices map[uuid.UUID]Device
+}
+
+// NewResolver returns a Resolver which knows about the devices currently
+// attached to the system.
+func NewResolver() (*Resolver, error) {
+	r := &Resolver{Names: make(map[uuid.UUID]string), devices: make(map[uuid.UUID]Device)}
+	if err := r.scanNVMe(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func readUint16(dir, name string) (uint16, error) {
+	s, err := readString(dir, name)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseUint(s, 0, 16)
+	return uint16(n), err
+}
+
+func (r *Resolver) scanNVMe() error {
+	ctrls, err := filepath.Glob(filepath.Join(nvmeDir, "nvme*"))
+	if err != nil {
+		return err
+	}
+	for _, ctrl := range ctrls {
+		model, err := readString(ctrl, "model")
+		if err != nil {
+			continue
+		}
+		ids := make(map[string]uint16)
+		for _, f := range []string{"vendor", "device", "subsystem_vendor", "subsystem_device"} {
+			if n, err := readUint16(filepath.Join(ctrl, "device"), f); err == nil {
+				ids[f] = n
+			}
+		}
+		vendor, okV := ids["vendor"]
+		device, okD := ids["device"]
+		if !okV || !okD {
+			continue
+		}
+		dev := Device{Name: model, Path: ctrl}
+		r.devices[InstanceIDGUID(fmt.Sprintf(`NVME\VEN_%04X&DEV_%04X`, vendor, device))] = dev
+		if sv, ok := ids["subsystem_vendor"]; ok {
+			sd := ids["subsystem_device"]
+			r.devices[InstanceIDGUID(fmt.Sprintf(`NVME\VEN_%04X&DEV_%04X&SUBSYS_%04X%04X`, vendor, device, sv, sd))] = dev
+		}
+	}
+	return nil
+}
+
+// Resolve returns the device updated by e. Entries which can't be matched to
+// a particular device are named after their type, as fwupd does.
+func (r *Resolver) Resolve(e *Entry) Device {
+	if n, ok := r.Names[e.FirmwareClass]; ok {
+		return Device{Name: n}
+	}
+	if d, ok := r.devices[e.FirmwareClass]; ok {
+		return d
+	}
+	switch e.Type {
+	case TypeSystemFirmware:
+		return Device{Name: "System Firmware"}
+	case TypeDeviceFirmware:
+		return Device{Name: "UEFI Device Firmware"}
+	case TypeUEFIDriver:
+		return Device{Name: "UEFI Driver"}
+	}
+	return Device{Name: e.FirmwareClass.String()}
+}
@@ -0,0 +1,166 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fwupd reads the variables fwupd's UEFI capsule plugin uses to
+// track firmware updates scheduled for the next boot.
+package fwupd
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"github.com/google/uuid"
+	"github.com/lukegb/goefivar/efitime"
+	"github.com/lukegb/goefivar/efivar"
+)
+
+var (
+	ErrVariableCorrupted = efivar.NewError(efivar.Corrupt, "fwupd: variable content is not valid")
+
+	// VendorUUID is the vendor GUID of fwupd's variables.
+	VendorUUID = uuid.MustParse("0abba7dc-e516-4167-bbf5-4d9d1c739416")
+
+	byteOrder = binary.LittleEndian
+)
+
+// updateInfoHeaderSize is the size of the fixed part of an update info blob,
+// which is followed by the device path of the capsule file.
+const updateInfoHeaderSize = 4 + 16 + 4 + 8 + 16 + 4
+
+// Status is how far fwupd's EFI helper got with an update.
+type Status uint32
+
+const (
+	StatusUnknown Status = 0
+	// StatusAttemptUpdate means the update will be attempted on the next boot.
+	StatusAttemptUpdate Status = 1
+	// StatusAttempted means the firmware was asked to apply the update.
+	StatusAttempted Status = 2
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusUnknown:
+		return "unknown"
+	case StatusAttemptUpdate:
+		return "attempt-update"
+	case StatusAttempted:
+		return "attempted"
+	}
+	return fmt.Sprintf("Status(%d)", uint32(s))
+}
+
+// UpdateInfo is the content of a fwupd-GUID-INSTANCE variable.
+type UpdateInfo struct {
+	Name string
+
+	Version          uint32
+	FirmwareClass    uuid.UUID
+	CapsuleFlags     uint32
+	HardwareInstance uint64
+	TimeAttempted    efitime.Time
+	Status           Status
+
+	// DevicePath is the location of the capsule file on the ESP.
+	DevicePath []byte
+}
+
+func ParseUpdateInfo(name string, b []byte) (*UpdateInfo, error) {
+	if len(b) < updateInfoHeaderSize {
+		return nil, ErrVariableCorrupted
+	}
+	ts, err := efitime.Parse(b[32:48])
+	if err != nil {
+		return nil, ErrVariableCorrupted
+	}
+	return &UpdateInfo{
+		Name:             name,
+		Version:          byteOrder.Uint32(b[0:4]),
+		FirmwareClass:    efivar.GUIDFromBytes(b[4:20]),
+		CapsuleFlags:     byteOrder.Uint32(b[20:24]),
+		HardwareInstance: byteOrder.Uint64(b[24:32]),
+		TimeAttempted:    ts,
+		Status:           Status(byteOrder.Uint32(b[48:52])),
+		DevicePath:       append([]byte(nil), b[updateInfoHeaderSize:]...),
+	}, nil
+}
+
+// Pending reports whether the update will be attempted on the next boot.
+func (u *UpdateInfo) Pending() bool { return u.Status == StatusAttemptUpdate }
+
+// DevicePathString formats DevicePath in the usual textual form.
+func (u *UpdateInfo) DevicePathString() (string, error) {
+	if len(u.DevicePath) == 0 {
+		return "", nil
+	}
+	return efivar.DevicePathToString(unsafe.Pointer(&u.DevicePath[0]), len(u.DevicePath))
+}
+
+// isUpdateInfoName reports whether name looks like fwupd-GUID-INSTANCE.
+func isUpdateInfoName(name string) bool {
+	if !strings.HasPrefix(name, "fwupd-") {
+		return false
+	}
+	rest := name[len("fwupd-"):]
+	if len(rest) < 38 || rest[36] != '-' {
+		return false
+	}
+	if _, err := uuid.Parse(rest[:36]); err != nil {
+		return false
+	}
+	_, err := strconv.ParseUint(rest[37:], 10, 64)
+	return err == nil
+}
+
+// UpdateInfos returns every update fwupd has recorded.
+func UpdateInfos() ([]*UpdateInfo, error) {
+	vns, err := efivar.Variables()
+	if err != nil {
+		return nil, err
+	}
+	var out []*UpdateInfo
+	for _, vn := range vns {
+		if vn.GUID != VendorUUID || !isUpdateInfoName(vn.Name) {
+			continue
+		}
+		v, err := vn.Get()
+		if err != nil {
+			return nil, err
+		}
+		u, err := ParseUpdateInfo(vn.Name, v.Data)
+		if err != nil {
+			return nil, fmt.Errorf("fwupd: %v: %v", vn.Name, err)
+		}
+		out = append(out, u)
+	}
+	return out, nil
+}
+
+// PendingUpdates returns the updates fwupd has scheduled for the next boot.
+func PendingUpdates() ([]*UpdateInfo, error) {
+	all, err := UpdateInfos()
+	if err != nil {
+		return nil, err
+	}
+	var out []*UpdateInfo
+	for _, u := range all {
+		if u.Pending() {
+			out = append(out, u)
+		}
+	}
+	return out, nil
+}
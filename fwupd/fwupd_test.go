@@ -0,0 +1,75 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fwupd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/lukegb/goefivar/efitime"
+	"github.com/lukegb/goefivar/efivar"
+)
+
+func TestParseUpdateInfo(t *testing.T) {
+	class := uuid.MustParse("ddc0ee61-e7f0-4e7d-acc5-c070a398838e")
+	ts := efitime.Time{Year: 2024, Month: 3, Day: 14, Hour: 9, Minute: 26, Second: 53}
+	dp := []byte{0x04, 0x04, 0x08, 0x00, 'a', 0, 0, 0, 0x7f, 0xff, 0x04, 0x00}
+
+	var b bytes.Buffer
+	b.Write([]byte{7, 0, 0, 0})
+	b.Write(efivar.GUIDBytes(class))
+	b.Write([]byte{0x00, 0x00, 0x05, 0x00})
+	b.Write([]byte{1, 0, 0, 0, 0, 0, 0, 0})
+	b.Write(ts.Bytes())
+	b.Write([]byte{1, 0, 0, 0})
+	b.Write(dp)
+
+	name := "fwupd-" + class.String() + "-1"
+	u, err := ParseUpdateInfo(name, b.Bytes())
+	if err != nil {
+		t.Fatalf("ParseUpdateInfo: %v", err)
+	}
+	if u.Version != 7 || u.FirmwareClass != class || u.CapsuleFlags != 0x50000 || u.HardwareInstance != 1 {
+		t.Errorf("ParseUpdateInfo = %+v", u)
+	}
+	if u.TimeAttempted != ts {
+		t.Errorf("TimeAttempted = %v; want %v", u.TimeAttempted, ts)
+	}
+	if !u.Pending() {
+		t.Errorf("Pending = false for status %v", u.Status)
+	}
+	if !bytes.Equal(u.DevicePath, dp) {
+		t.Errorf("DevicePath = %x; want %x", u.DevicePath, dp)
+	}
+
+	if _, err := ParseUpdateInfo(name, b.Bytes()[:updateInfoHeaderSize-1]); err != ErrVariableCorrupted {
+		t.Errorf("ParseUpdateInfo(truncated) = %v; want ErrVariableCorrupted", err)
+	}
+}
+
+func TestIsUpdateInfoName(t *testing.T) {
+	for name, want := range map[string]bool{
+		"fwupd-ddc0ee61-e7f0-4e7d-acc5-c070a398838e-0":  true,
+		"fwupd-ddc0ee61-e7f0-4e7d-acc5-c070a398838e-12": true,
+		"fwupd-ddc0ee61-e7f0-4e7d-acc5-c070a398838e":    false,
+		"FWUPDATE_VERBOSE": false,
+		"fwupd-debug":      false,
+	} {
+		if got := isUpdateInfoName(name); got != want {
+			t.Errorf("isUpdateInfoName(%q) = %v; want %v", name, got, want)
+		}
+	}
+}
@@ -2,4 +2,14 @@ module github.com/lukegb/goefivar
 
 go 1.12
 
-require github.com/google/uuid v1.1.1
+require (
+	github.com/google/uuid v1.1.1
+	github.com/lukegb/goefivar/v2 v2.0.0-00010101000000-000000000000
+)
+
+// Package efivar is a shim over version 2 of itself, in the v2 module of
+// this repository, which in turn requires this module.
+replace (
+	github.com/lukegb/goefivar v0.0.0-00010101000000-000000000000 => ./
+	github.com/lukegb/goefivar/v2 => ./v2
+)
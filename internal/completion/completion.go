@@ -0,0 +1,273 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package completion generates bash, zsh and fish completion scripts for the
+// bundled commands.
+//
+// A command calls Register before flag.Parse and Run after it. Running the
+// command with -completion SHELL then prints a script to source, such as
+//
+//	source <(goefibootmgr -completion bash)
+//
+// The scripts complete flag names, and complete boot option numbers and
+// variable names by asking the command itself, so they reflect the running
+// system.
+package completion
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/lukegb/goefivar/efiboot"
+	"github.com/lukegb/goefivar/efivar"
+)
+
+// Kind is what a flag value or positional argument holds.
+type Kind string
+
+const (
+	None Kind = ""
+	// Text is anything, and isn't completed.
+	Text Kind = "text"
+	// Files are paths.
+	Files Kind = "files"
+	// BootNumbers are boot option numbers such as 0001.
+	BootNumbers Kind = "boot"
+	// BootVariables are boot option variable names such as Boot0001.
+	BootVariables Kind = "bootvars"
+	// Variables are variable names: bare for global variables, and
+	// NAME-GUID for others.
+	Variables Kind = "vars"
+)
+
+var (
+	shell    = flag.String("completion", "", "Print a completion script for this shell (bash, zsh or fish) and exit")
+	complete = flag.String("complete", "", "Print completion candidates of this kind and exit")
+
+	args  Kind
+	flags = map[string]Kind{"completion": Text}
+)
+
+// Register records what the command's positional arguments are. Importing
+// this package adds the completion flags to the command line.
+func Register(positional Kind) {
+	args = positional
+}
+
+// Flag records what the value of the named flag is.
+func Flag(name string, k Kind) {
+	flags[name] = k
+}
+
+// Run handles the completion flags, exiting if one was given.
+func Run() {
+	switch {
+	case *shell != "":
+		s, err := Script(*shell, filepath.Base(os.Args[0]), flag.CommandLine, args, flags)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Print(s)
+		os.Exit(0)
+	case *complete != "":
+		Candidates(os.Stdout, Kind(*complete))
+		os.Exit(0)
+	}
+}
+
+// Candidates writes the completion candidates of kind k to w, one per line.
+// Nothing is written if they can't be read.
+func Candidates(w io.Writer, k Kind) {
+	if !efivar.Supported() {
+		return
+	}
+	switch k {
+	case BootNumbers, BootVariables:
+		bos, err := efiboot.BootOptions()
+		if err != nil {
+			return
+		}
+		for _, bo := range bos {
+			if k == BootNumbers {
+				fmt.Fprintln(w, strings.TrimPrefix(bo.Variable.Name, "Boot"))
+			} else {
+				fmt.Fprintln(w, bo.Variable.Name)
+			}
+		}
+	case Variables:
+		vns, err := efivar.Variables()
+		if err != nil {
+			return
+		}
+		var names []string
+		for _, vn := range vns {
+			if vn.GUID == efivar.GlobalUUID {
+				names = append(names, vn.Name)
+			} else {
+				names = append(names, vn.String())
+			}
+		}
+		sort.Strings(names)
+		for _, n := range names {
+			fmt.Fprintln(w, n)
+		}
+	}
+}
+
+type flagInfo struct {
+	name  string
+	usage string
+	// value is what the flag takes, or None for boolean flags.
+	value Kind
+}
+
+func flagsOf(fs *flag.FlagSet, kinds map[string]Kind) []flagInfo {
+	var out []flagInfo
+	fs.VisitAll(func(f *flag.Flag) {
+		if f.Name == "complete" {
+			return
+		}
+		fi := flagInfo{name: f.Name, usage: f.Usage, value: kinds[f.Name]}
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); !ok || !b.IsBoolFlag() {
+			if fi.value == None {
+				fi.value = Files
+			}
+		}
+		out = append(out, fi)
+	})
+	return out
+}
+
+// Script returns the completion script for the command called name, with
+// flags fs and positional arguments of kind positional. kinds says what the
+// values of non-boolean flags are; the default is Files.
+func Script(shell, name string, fs *flag.FlagSet, positional Kind, kinds map[string]Kind) (string, error) {
+	fis := flagsOf(fs, kinds)
+	switch shell {
+	case "bash":
+		return bashScript(name, fis, positional), nil
+	case "zsh":
+		return zshScript(name, fis, positional), nil
+	case "fish":
+		return fishScript(name, fis, positional), nil
+	}
+	return "", fmt.Errorf("completion: unknown shell %q; want bash, zsh or fish", shell)
+}
+
+func funcName(name string) string {
+	return "_goefivar_" + strings.NewReplacer("-", "_", ".", "_").Replace(name)
+}
+
+func bashWords(name string, k Kind) string {
+	switch k {
+	case Files:
+		return `$(compgen -f -- "$cur")`
+	case None, Text:
+		return ""
+	}
+	return fmt.Sprintf(`$(compgen -W "$(%s -complete %s 2>/dev/null)" -- "$cur")`, name, k)
+}
+
+func bashScript(name string, fis []flagInfo, positional Kind) string {
+	var b strings.Builder
+	var names []string
+	for _, fi := range fis {
+		names = append(names, "-"+fi.name)
+	}
+	fmt.Fprintf(&b, "%s() {\n", funcName(name))
+	fmt.Fprintf(&b, "\tlocal cur=${COMP_WORDS[COMP_CWORD]} prev=${COMP_WORDS[COMP_CWORD-1]}\n")
+	fmt.Fprintf(&b, "\tcase $prev in\n")
+	for _, fi := range fis {
+		if fi.value != None {
+			fmt.Fprintf(&b, "\t-%s|--%s) COMPREPLY=(%s); return;;\n", fi.name, fi.name, bashWords(name, fi.value))
+		}
+	}
+	fmt.Fprintf(&b, "\tesac\n")
+	fmt.Fprintf(&b, "\tif [[ $cur == -* ]]; then\n")
+	fmt.Fprintf(&b, "\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(names, " "))
+	fmt.Fprintf(&b, "\t\treturn\n\tfi\n")
+	fmt.Fprintf(&b, "\tCOMPREPLY=(%s)\n", bashWords(name, positional))
+	fmt.Fprintf(&b, "}\ncomplete -F %s %s\n", funcName(name), name)
+	return b.String()
+}
+
+func zshWords(name string, k Kind) string {
+	switch k {
+	case Files:
+		return "_files"
+	case None, Text:
+		return ":"
+	}
+	return fmt.Sprintf(`compadd -- ${(f)"$(%s -complete %s 2>/dev/null)"}`, name, k)
+}
+
+func zshScript(name string, fis []flagInfo, positional Kind) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "#compdef %s\n\n%s() {\n", name, funcName(name))
+	fmt.Fprintf(&b, "\tcase $words[CURRENT-1] in\n")
+	for _, fi := range fis {
+		if fi.value != None {
+			fmt.Fprintf(&b, "\t-%s|--%s) %s; return;;\n", fi.name, fi.name, zshWords(name, fi.value))
+		}
+	}
+	fmt.Fprintf(&b, "\tesac\n")
+	fmt.Fprintf(&b, "\tif [[ $PREFIX == -* ]]; then\n\t\tlocal -a opts\n\t\topts=(\n")
+	for _, fi := range fis {
+		fmt.Fprintf(&b, "\t\t\t%s\n", zshQuote("-"+fi.name+":"+strings.Replace(fi.usage, ":", `\:`, -1)))
+	}
+	fmt.Fprintf(&b, "\t\t)\n\t\t_describe option opts\n\t\treturn\n\tfi\n")
+	fmt.Fprintf(&b, "\t%s\n}\n\ncompdef %s %s\n", zshWords(name, positional), funcName(name), name)
+	return b.String()
+}
+
+func zshQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+func fishArgs(name string, k Kind) string {
+	switch k {
+	case Files:
+		return "-r -F"
+	case None:
+		return "-f"
+	case Text:
+		return "-x"
+	}
+	return fmt.Sprintf("-x -a '(%s -complete %s 2>/dev/null)'", name, k)
+}
+
+func fishScript(name string, fis []flagInfo, positional Kind) string {
+	var b strings.Builder
+	for _, fi := range fis {
+		args := ""
+		if fi.value != None {
+			args = " " + fishArgs(name, fi.value)
+		}
+		fmt.Fprintf(&b, "complete -c %s -o %s%s -d %s\n", name, fi.name, args, zshQuote(fi.usage))
+	}
+	switch positional {
+	case None, Text:
+		fmt.Fprintf(&b, "complete -c %s -f\n", name)
+	case Files:
+	default:
+		fmt.Fprintf(&b, "complete -c %s -f -a '(%s -complete %s 2>/dev/null)'\n", name, name, positional)
+	}
+	return b.String()
+}
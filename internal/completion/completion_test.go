@@ -0,0 +1,74 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package completion
+
+import (
+	"flag"
+	"strings"
+	"testing"
+)
+
+func testFlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("goefibootmgr", flag.ContinueOnError)
+	fs.Bool("v", false, "Be verbose")
+	fs.String("b", "", "Boot option number")
+	fs.String("l", "", "Path of the loader")
+	fs.String("complete", "", "hidden")
+	return fs
+}
+
+func TestScript(t *testing.T) {
+	kinds := map[string]Kind{"b": BootNumbers}
+	for _, tc := range []struct {
+		shell string
+		want  []string
+	}{
+		{"bash", []string{
+			"complete -F _goefivar_goefibootmgr goefibootmgr",
+			`-b|--b) COMPREPLY=($(compgen -W "$(goefibootmgr -complete boot 2>/dev/null)" -- "$cur")); return;;`,
+			`-l|--l) COMPREPLY=($(compgen -f -- "$cur")); return;;`,
+			`compgen -W "-b -l -v"`,
+		}},
+		{"zsh", []string{
+			"#compdef goefibootmgr",
+			`-b|--b) compadd -- ${(f)"$(goefibootmgr -complete boot 2>/dev/null)"}; return;;`,
+			`'-v:Be verbose'`,
+			"compdef _goefivar_goefibootmgr goefibootmgr",
+		}},
+		{"fish", []string{
+			"complete -c goefibootmgr -o b -x -a '(goefibootmgr -complete boot 2>/dev/null)' -d 'Boot option number'",
+			"complete -c goefibootmgr -o v -d 'Be verbose'",
+			"complete -c goefibootmgr -f\n",
+		}},
+	} {
+		got, err := Script(tc.shell, "goefibootmgr", testFlagSet(), None, kinds)
+		if err != nil {
+			t.Errorf("Script(%v): %v", tc.shell, err)
+			continue
+		}
+		for _, want := range tc.want {
+			if !strings.Contains(got, want) {
+				t.Errorf("Script(%v) does not contain %q:\n%s", tc.shell, want, got)
+			}
+		}
+		if strings.Contains(got, "complete -complete") || strings.Contains(got, "-complete:") || strings.Contains(got, "-o complete") {
+			t.Errorf("Script(%v) offers the hidden -complete flag:\n%s", tc.shell, got)
+		}
+	}
+
+	if _, err := Script("tcsh", "goefibootmgr", testFlagSet(), None, nil); err == nil {
+		t.Errorf("Script(tcsh) succeeded")
+	}
+}
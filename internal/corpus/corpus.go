@@ -0,0 +1,109 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package corpus reads the collection of load options captured from real
+// firmware which is kept under testdata/loadopts, so that the tests of both
+// the pure-Go and the libefiboot parsers can check it.
+//
+// Each option is a NAME.hex file holding the content of a Boot#### variable,
+// without its attributes, as hex. Lines starting with # say where it came
+// from. NAME.golden holds the expected decoding as "key: value" lines; the
+// loadopt tests rewrite it when run with -update. See
+// testdata/loadopts/README.md for how to add to the corpus.
+package corpus
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// LoadOption is one entry in the corpus.
+type LoadOption struct {
+	Name string
+	Data []byte
+	// Golden is the content of the .golden file, or empty if there is none yet.
+	Golden string
+}
+
+// Field returns the value of key in the golden file.
+func (lo LoadOption) Field(key string) (string, bool) {
+	for _, l := range strings.Split(lo.Golden, "\n") {
+		if strings.HasPrefix(l, key+": ") {
+			return l[len(key)+2:], true
+		}
+	}
+	return "", false
+}
+
+// dir returns the directory holding the load options.
+func dir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "testdata", "loadopts")
+}
+
+// LoadOptions reads every load option in the corpus, sorted by name.
+func LoadOptions() ([]LoadOption, error) {
+	paths, err := filepath.Glob(filepath.Join(dir(), "*.hex"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	var los []LoadOption
+	for _, p := range paths {
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSuffix(filepath.Base(p), ".hex")
+		data, err := decodeHex(b)
+		if err != nil {
+			return nil, fmt.Errorf("corpus: %v: %v", name, err)
+		}
+		golden, err := ioutil.ReadFile(filepath.Join(dir(), name+".golden"))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		los = append(los, LoadOption{Name: name, Data: data, Golden: string(golden)})
+	}
+	return los, nil
+}
+
+// WriteGolden replaces the golden file of the load option called name.
+func WriteGolden(name, golden string) error {
+	return ioutil.WriteFile(filepath.Join(dir(), name+".golden"), []byte(golden), 0644)
+}
+
+func decodeHex(b []byte) ([]byte, error) {
+	var h bytes.Buffer
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		l := strings.TrimSpace(s.Text())
+		if strings.HasPrefix(l, "#") {
+			continue
+		}
+		h.WriteString(l)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(h.String())
+}
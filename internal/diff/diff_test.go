@@ -0,0 +1,31 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	got := Lines([]string{"a", "b", "c", "d"}, []string{"a", "c", "e", "d"})
+	want := []string{" a", "-b", " c", "+e", " d"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Lines = %q; want %q", got, want)
+	}
+	if got := Lines(nil, []string{"x"}); !reflect.DeepEqual(got, []string{"+x"}) {
+		t.Errorf("Lines(nil, [x]) = %q", got)
+	}
+}
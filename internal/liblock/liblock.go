@@ -0,0 +1,31 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package liblock serialises calls into libefivar and libefiboot. The
+// libraries keep process-wide state with no locking of their own: the error
+// table behind efi_error_set, the directory iterator behind
+// efi_get_next_variable_name, and the buffer efi_loadopt_desc returns.
+package liblock
+
+import "sync"
+
+var mu sync.Mutex
+
+// Lock must be held while calling into libefivar or libefiboot, and until
+// any result pointing into the library's own buffers has been copied. It is
+// not reentrant.
+func Lock() { mu.Lock() }
+
+// Unlock releases Lock.
+func Unlock() { mu.Unlock() }
@@ -0,0 +1,80 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package mounts finds partitions and the filesystems mounted from them, for
+// the packages which locate files on the EFI System Partition.
+package mounts
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// File lists the mounted filesystems, in fstab format.
+	File = "/proc/self/mounts"
+	// PartUUIDDir is maintained by udev, with a symlink to each partition
+	// named after its lowercase GPT partition GUID.
+	PartUUIDDir = "/dev/disk/by-partuuid"
+)
+
+// A Mount is an entry of File.
+type Mount struct {
+	// Source is the mounted device, or a name such as "proc" for
+	// filesystems without one.
+	Source string
+	// Dir is the mountpoint.
+	Dir string
+	// Type is the filesystem type, such as "vfat".
+	Type string
+}
+
+// Read returns the entries of File, in order.
+func Read() ([]Mount, error) {
+	f, err := os.Open(File)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var out []Mount
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		m := Mount{Source: unescape(fields[0]), Dir: unescape(fields[1])}
+		if len(fields) > 2 {
+			m.Type = fields[2]
+		}
+		out = append(out, m)
+	}
+	return out, s.Err()
+}
+
+// unescape undoes the octal escaping of whitespace in mounts entries.
+func unescape(s string) string {
+	return strings.NewReplacer(`\040`, " ", `\011`, "\t", `\012`, "\n", `\134`, `\`).Replace(s)
+}
+
+// Partition returns the device of the GPT partition u, with symlinks
+// resolved. The error satisfies os.IsNotExist if there is no such partition.
+func Partition(u uuid.UUID) (string, error) {
+	return filepath.EvalSymlinks(filepath.Join(PartUUIDDir, u.String()))
+}
@@ -0,0 +1,50 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mounts
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRead(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mounts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(old string) { File = old }(File)
+	File = filepath.Join(dir, "mounts")
+	content := "proc /proc proc rw 0 0\n/dev/sda1 /boot\\040efi vfat rw 0 0\n\n/dev/sdb1 /mnt/a\\134b\n"
+	if err := ioutil.WriteFile(File, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Read()
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	want := []Mount{
+		{Source: "proc", Dir: "/proc", Type: "proc"},
+		{Source: "/dev/sda1", Dir: "/boot efi", Type: "vfat"},
+		{Source: "/dev/sdb1", Dir: `/mnt/a\b`},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Read() = %+v; want %+v", got, want)
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package output gives the bundled commands a common -format flag, so that
+// every report can be printed as text for people, or as JSON or YAML for
+// programs.
+//
+// The JSON and YAML forms of a report are produced from the same value, and
+// its json struct tags are its schema: YAML output has the same keys, in the
+// same order, as JSON output.
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Format is an output format.
+type Format string
+
+const (
+	Text Format = "text"
+	JSON Format = "json"
+	YAML Format = "yaml"
+)
+
+var format = flag.String("format", string(Text), "Output format: text, json or yaml")
+
+// Selected returns the format chosen with -format.
+func Selected() (Format, error) {
+	switch f := Format(*format); f {
+	case Text, JSON, YAML:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown -format %q; want text, json or yaml", *format)
+}
+
+// Set overrides -format, for commands which accept older flags such as -json.
+func Set(f Format) {
+	*format = string(f)
+}
+
+// Write prints v in the selected format. text prints the text form.
+func Write(w io.Writer, v interface{}, text func(io.Writer) error) error {
+	f, err := Selected()
+	if err != nil {
+		return err
+	}
+	switch f {
+	case JSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case YAML:
+		b, err := MarshalYAML(v)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	}
+	return text(w)
+}
+
+// WriteRecord prints v, one of a stream of records, in the selected format:
+// in JSON as a line of its own, and in YAML as a document of its own.
+func WriteRecord(w io.Writer, v interface{}, text func(io.Writer) error) error {
+	f, err := Selected()
+	if err != nil {
+		return err
+	}
+	switch f {
+	case JSON:
+		return json.NewEncoder(w).Encode(v)
+	case YAML:
+		b, err := MarshalYAML(v)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "---\n%s", b)
+		return err
+	}
+	return text(w)
+}
+
+// node is a parsed JSON value, with the order of object keys preserved.
+type node struct {
+	// kind is '{' for objects, '[' for arrays, and 0 for scalars.
+	kind   byte
+	keys   []string
+	values []*node
+	// scalar is the value of a scalar: a string, json.Number, bool or nil.
+	scalar interface{}
+}
+
+func parse(dec *json.Decoder) (*node, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		n := &node{kind: '{'}
+		for dec.More() {
+			k, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			v, err := parse(dec)
+			if err != nil {
+				return nil, err
+			}
+			n.keys = append(n.keys, k.(string))
+			n.values = append(n.values, v)
+		}
+		_, err := dec.Token()
+		return n, err
+	case json.Delim('['):
+		n := &node{kind: '['}
+		for dec.More() {
+			v, err := parse(dec)
+			if err != nil {
+				return nil, err
+			}
+			n.values = append(n.values, v)
+		}
+		_, err := dec.Token()
+		return n, err
+	}
+	return &node{scalar: tok}, nil
+}
+
+// MarshalYAML returns the YAML form of v, which has the same structure as
+// its JSON form.
+func MarshalYAML(v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	n, err := parse(dec)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if n.kind == 0 || len(n.values) == 0 {
+		buf.WriteString(scalar(n))
+		buf.WriteByte('\n')
+	} else {
+		emit(&buf, n, "")
+	}
+	return buf.Bytes(), nil
+}
+
+var plainString = regexp.MustCompile(`^[A-Za-z_/\\][A-Za-z0-9_./\\=+-]*$`)
+
+// scalar formats a scalar or an empty collection.
+func scalar(n *node) string {
+	switch n.kind {
+	case '{':
+		return "{}"
+	case '[':
+		return "[]"
+	}
+	switch s := n.scalar.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return fmt.Sprint(s)
+	case json.Number:
+		return s.String()
+	case string:
+		switch strings.ToLower(s) {
+		case "true", "false", "yes", "no", "on", "off", "null", "y", "n":
+		default:
+			if plainString.MatchString(s) {
+				return s
+			}
+		}
+		// A JSON string is a valid YAML double-quoted scalar.
+		b, _ := json.Marshal(s)
+		return string(b)
+	}
+	return fmt.Sprint(n.scalar)
+}
+
+func isCollection(n *node) bool {
+	return n.kind != 0 && len(n.values) > 0
+}
+
+// emit writes the non-empty collection n, with each line prefixed by indent.
+func emit(buf *bytes.Buffer, n *node, indent string) {
+	for i, v := range n.values {
+		if n.kind == '{' {
+			buf.WriteString(indent + scalar(&node{scalar: n.keys[i]}) + ":")
+			if isCollection(v) {
+				buf.WriteByte('\n')
+				emit(buf, v, indent+"  ")
+			} else {
+				buf.WriteString(" " + scalar(v) + "\n")
+			}
+			continue
+		}
+
+		if !isCollection(v) {
+			buf.WriteString(indent + "- " + scalar(v) + "\n")
+			continue
+		}
+		// Nested collections start on the same line as their "- ".
+		var child bytes.Buffer
+		emit(&child, v, indent+"  ")
+		buf.WriteString(indent + "- ")
+		buf.Write(child.Bytes()[len(indent)+2:])
+	}
+}
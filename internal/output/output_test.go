@@ -0,0 +1,87 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package output
+
+import (
+	"testing"
+)
+
+func TestMarshalYAML(t *testing.T) {
+	type option struct {
+		Number      string   `json:"number"`
+		Description string   `json:"description"`
+		Active      bool     `json:"active"`
+		Tags        []string `json:"tags"`
+	}
+	v := struct {
+		BootCurrent *string           `json:"boot_current"`
+		Timeout     int               `json:"timeout"`
+		Order       []string          `json:"boot_order"`
+		Options     []option          `json:"options"`
+		Empty       map[string]string `json:"empty"`
+		Nested      [][]int           `json:"nested"`
+	}{
+		Timeout: 5,
+		Order:   []string{"0001", "0000"},
+		Options: []option{
+			{"0000", "Windows Boot Manager", true, []string{"yes"}},
+			{"0001", "Linux: rescue", false, nil},
+		},
+		Empty:  map[string]string{},
+		Nested: [][]int{{1, 2}, {}},
+	}
+	got, err := MarshalYAML(v)
+	if err != nil {
+		t.Fatalf("MarshalYAML: %v", err)
+	}
+	want := `boot_current: null
+timeout: 5
+boot_order:
+  - "0001"
+  - "0000"
+options:
+  - number: "0000"
+    description: "Windows Boot Manager"
+    active: true
+    tags:
+      - "yes"
+  - number: "0001"
+    description: "Linux: rescue"
+    active: false
+    tags: null
+empty: {}
+nested:
+  - - 1
+    - 2
+  - []
+`
+	if string(got) != want {
+		t.Errorf("MarshalYAML =\n%s\nwant\n%s", got, want)
+	}
+
+	for _, tc := range []struct {
+		v    interface{}
+		want string
+	}{
+		{"plain", "plain\n"},
+		{[]int{}, "[]\n"},
+		{`C:\EFI "x"`, `"C:\\EFI \"x\""` + "\n"},
+	} {
+		got, err := MarshalYAML(tc.v)
+		if err != nil || string(got) != tc.want {
+			t.Errorf("MarshalYAML(%#v) = %q, %v; want %q", tc.v, got, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package testfixture lets tests run against checked-in sets of EFI
+// variables, so that they need neither an EFI machine nor root.
+//
+// Each fixture is a directory under testdata in the layout written by
+// vardump.WriteDir. The "laptop" fixture holds an Arch Linux entry booted by
+// systemd-boot (Boot000C), Windows Boot Manager (Boot0000), BootOrder,
+// BootCurrent, Timeout, the Secure Boot state, and the loader variables.
+package testfixture
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/varstore"
+)
+
+// dir returns the directory holding the fixture called name.
+func dir(name string) string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "testdata", name)
+}
+
+// Use directs every variable operation to a copy of the fixture called
+// name, so that tests may change it freely. Call the returned function,
+// usually with defer, to remove the copy and return to the running system.
+func Use(t testing.TB, name string) func() {
+	t.Helper()
+	tmp, err := ioutil.TempDir("", "testfixture")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fis, err := ioutil.ReadDir(dir(name))
+	if err != nil {
+		os.RemoveAll(tmp)
+		t.Fatalf("testfixture: %v", err)
+	}
+	for _, fi := range fis {
+		b, err := ioutil.ReadFile(filepath.Join(dir(name), fi.Name()))
+		if err == nil {
+			err = ioutil.WriteFile(filepath.Join(tmp, fi.Name()), b, 0600)
+		}
+		if err != nil {
+			os.RemoveAll(tmp)
+			t.Fatalf("testfixture: %v", err)
+		}
+	}
+	efivar.UseStore(&varstore.Dir{Path: tmp})
+	return func() {
+		efivar.UseStore(nil)
+		os.RemoveAll(tmp)
+	}
+}
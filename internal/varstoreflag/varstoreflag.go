@@ -0,0 +1,46 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package varstoreflag gives the bundled commands a common -varstore flag,
+// which makes them operate on a firmware image or a dump directory rather
+// than on the running system.
+package varstoreflag
+
+import (
+	"flag"
+
+	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/internal/completion"
+	"github.com/lukegb/goefivar/varstore"
+)
+
+var path = flag.String("varstore", "", "Operate on the variables in this OVMF_VARS.fd-style firmware image or efivardump directory, rather than the running system's")
+
+func init() {
+	completion.Flag("varstore", completion.Files)
+}
+
+// Apply opens the store named by -varstore, if any, and directs all variable
+// operations to it. It must be called after flag.Parse.
+func Apply() error {
+	if *path == "" {
+		return nil
+	}
+	s, err := varstore.Open(*path)
+	if err != nil {
+		return err
+	}
+	efivar.UseStore(s)
+	return nil
+}
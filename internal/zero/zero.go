@@ -0,0 +1,91 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package zero overwrites secrets in memory once they are no longer needed.
+// Go may already have copied them, when a slice grew or a string was
+// converted, so this narrows the time in which a core dump or a swapped-out
+// page exposes them rather than closing it.
+package zero
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"math/big"
+	"runtime"
+)
+
+// Bytes overwrites b with zeros.
+func Bytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+	runtime.KeepAlive(b)
+}
+
+// Uint16s overwrites s with zeros.
+func Uint16s(s []uint16) {
+	for i := range s {
+		s[i] = 0
+	}
+	runtime.KeepAlive(s)
+}
+
+// Runes overwrites s with zeros.
+func Runes(s []rune) {
+	for i := range s {
+		s[i] = 0
+	}
+	runtime.KeepAlive(s)
+}
+
+// Int overwrites the digits of n and sets it to zero.
+func Int(n *big.Int) {
+	if n == nil {
+		return
+	}
+	w := n.Bits()
+	for i := range w {
+		w[i] = 0
+	}
+	runtime.KeepAlive(w)
+	n.SetInt64(0)
+}
+
+// PrivateKey overwrites the private values of k and reports whether it
+// knows how: k must be an *rsa.PrivateKey or an *ecdsa.PrivateKey. Copies
+// the crypto packages keep internally, such as the values newer releases of
+// Go precompute for RSA, are out of reach.
+func PrivateKey(k crypto.PrivateKey) bool {
+	switch k := k.(type) {
+	case *rsa.PrivateKey:
+		Int(k.D)
+		for _, p := range k.Primes {
+			Int(p)
+		}
+		Int(k.Precomputed.Dp)
+		Int(k.Precomputed.Dq)
+		Int(k.Precomputed.Qinv)
+		for _, v := range k.Precomputed.CRTValues {
+			Int(v.Exp)
+			Int(v.Coeff)
+			Int(v.R)
+		}
+		return true
+	case *ecdsa.PrivateKey:
+		Int(k.D)
+		return true
+	}
+	return false
+}
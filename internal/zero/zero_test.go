@@ -0,0 +1,69 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zero
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"math/big"
+	"testing"
+)
+
+func TestBytes(t *testing.T) {
+	b := []byte("secret")
+	Bytes(b)
+	for i, c := range b {
+		if c != 0 {
+			t.Fatalf("b[%d] = %#x after Bytes", i, c)
+		}
+	}
+}
+
+func TestInt(t *testing.T) {
+	n, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	w := n.Bits()
+	Int(n)
+	if n.Sign() != 0 {
+		t.Errorf("n = %v after Int; want 0", n)
+	}
+	for i, d := range w[:cap(w)] {
+		if d != 0 {
+			t.Errorf("digit %d = %#x after Int", i, d)
+		}
+	}
+	Int(nil)
+}
+
+func TestPrivateKey(t *testing.T) {
+	rk, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ek, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !PrivateKey(rk) || rk.D.Sign() != 0 || rk.Primes[0].Sign() != 0 || rk.Precomputed.Dp.Sign() != 0 {
+		t.Errorf("RSA key not cleared: D = %v, P = %v", rk.D, rk.Primes[0])
+	}
+	if !PrivateKey(ek) || ek.D.Sign() != 0 {
+		t.Errorf("ECDSA key not cleared: D = %v", ek.D)
+	}
+	if PrivateKey("not a key") {
+		t.Errorf("PrivateKey(string) = true")
+	}
+}
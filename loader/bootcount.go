@@ -0,0 +1,126 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loader
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/lukegb/goefivar/efiboot"
+	"github.com/lukegb/goefivar/efivar"
+)
+
+var LoaderBootCountPathName = efivar.VariableName{GUID: VendorUUID, Name: "LoaderBootCountPath"}
+
+// bootCounter matches the "+LEFT[-DONE]" counter at the end of a boot entry
+// file name, before the extension.
+var bootCounter = regexp.MustCompile(`^(.*)\+(\d+)(?:-(\d+))?$`)
+
+// BootCount is the boot counting state of the booted entry. See
+// https://systemd.io/AUTOMATIC_BOOT_ASSESSMENT/.
+type BootCount struct {
+	// Path is the path of the entry file on the ESP, with forward slashes.
+	Path string
+
+	// TriesLeft is the number of boots left before the loader considers the
+	// entry bad, and TriesDone the number already attempted. Both include
+	// the current boot.
+	TriesLeft int
+	TriesDone int
+}
+
+// ParseBootCountPath parses the counter from the path of a boot entry.
+func ParseBootCountPath(p string) (*BootCount, error) {
+	p = strings.Replace(p, `\`, "/", -1)
+	ext := path.Ext(p)
+	m := bootCounter.FindStringSubmatch(strings.TrimSuffix(p, ext))
+	if m == nil {
+		return nil, ErrVariableCorrupted
+	}
+	bc := &BootCount{Path: p}
+	var err error
+	if bc.TriesLeft, err = strconv.Atoi(m[2]); err != nil {
+		return nil, ErrVariableCorrupted
+	}
+	if m[3] != "" {
+		if bc.TriesDone, err = strconv.Atoi(m[3]); err != nil {
+			return nil, ErrVariableCorrupted
+		}
+	}
+	return bc, nil
+}
+
+// CurrentBootCount returns the boot counting state of the booted entry, or
+// nil if the entry isn't subject to boot counting.
+func CurrentBootCount() (*BootCount, error) {
+	s, err := readString(LoaderBootCountPathName)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return ParseBootCountPath(s)
+}
+
+// InTriesWindow reports whether the entry has yet to be marked good: a failure
+// to reach the point of blessing this boot counts against it.
+func (bc *BootCount) InTriesWindow() bool { return bc != nil }
+
+// LastTry reports whether the loader will consider the entry bad if this
+// boot isn't marked good.
+func (bc *BootCount) LastTry() bool { return bc != nil && bc.TriesLeft == 0 }
+
+func (bc *BootCount) renamed(counter string) string {
+	ext := path.Ext(bc.Path)
+	m := bootCounter.FindStringSubmatch(strings.TrimSuffix(bc.Path, ext))
+	return m[1] + counter + ext
+}
+
+// GoodPath is the path of the entry once marked good: without a counter.
+func (bc *BootCount) GoodPath() string { return bc.renamed("") }
+
+// BadPath is the path of the entry once marked bad: with no tries left.
+func (bc *BootCount) BadPath() string {
+	return bc.renamed("+0-" + strconv.Itoa(bc.TriesDone+bc.TriesLeft))
+}
+
+func (bc *BootCount) rename(esp, to string) error {
+	return os.Rename(filepath.Join(esp, filepath.FromSlash(bc.Path)), filepath.Join(esp, filepath.FromSlash(to)))
+}
+
+// MarkGood marks the entry as good on the ESP mounted at esp, ending boot
+// counting for it, as systemd-bless-boot does.
+func (bc *BootCount) MarkGood(esp string) error { return bc.rename(esp, bc.GoodPath()) }
+
+// MarkBad marks the entry as bad on the ESP mounted at esp, so the loader
+// will prefer other entries.
+func (bc *BootCount) MarkBad(esp string) error { return bc.rename(esp, bc.BadPath()) }
+
+// FallBack marks the booted entry bad and arranges for the firmware to start
+// bootNum on the next boot. Setting BootNext alone bypasses the loader's own
+// fallback, so orchestrators should use this rather than efiboot.SetBootNext
+// while the entry is in its tries window.
+func (bc *BootCount) FallBack(esp string, bootNum uint16) error {
+	if bc.InTriesWindow() {
+		if err := bc.MarkBad(esp); err != nil {
+			return err
+		}
+	}
+	return efiboot.SetBootNext(bootNum)
+}
@@ -0,0 +1,84 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loader
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/internal/mounts"
+)
+
+var LoaderDevicePartUUIDName = efivar.VariableName{GUID: VendorUUID, Name: "LoaderDevicePartUUID"}
+
+// DevicePartUUID returns the GPT partition GUID of the partition the loader
+// was loaded from.
+func DevicePartUUID() (uuid.UUID, error) {
+	s, err := readString(LoaderDevicePartUUIDName)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	// systemd-boot writes the GUID in upper case.
+	u, err := uuid.Parse(strings.ToLower(strings.TrimSpace(s)))
+	if err != nil {
+		return uuid.Nil, ErrVariableCorrupted
+	}
+	return u, nil
+}
+
+// ESP describes the EFI System Partition the loader was loaded from.
+type ESP struct {
+	PartUUID uuid.UUID
+	// Device is the block device of the partition, e.g. /dev/nvme0n1p1.
+	Device string
+	// Mountpoint is where it's mounted, or empty if it isn't.
+	Mountpoint string
+}
+
+// LoaderESP finds the ESP the loader was started from, using
+// LoaderDevicePartUUID. This is more reliable than looking for mounted FAT
+// filesystems when a system has several.
+func LoaderESP() (*ESP, error) {
+	u, err := DevicePartUUID()
+	if err != nil {
+		return nil, err
+	}
+	return resolveESP(u)
+}
+
+func resolveESP(u uuid.UUID) (*ESP, error) {
+	dev, err := mounts.Partition(u)
+	if err != nil {
+		return nil, err
+	}
+	esp := &ESP{PartUUID: u, Device: dev}
+
+	ms, err := mounts.Read()
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range ms {
+		if !strings.HasPrefix(m.Source, "/") {
+			continue
+		}
+		if src, err := filepath.EvalSymlinks(m.Source); err == nil && src == dev {
+			esp.Mountpoint = m.Dir
+			break
+		}
+	}
+	return esp, nil
+}
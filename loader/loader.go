@@ -0,0 +1,153 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package loader provides access to the variables systemd-boot and other
+// loaders implementing the Boot Loader Interface use to communicate with
+// the operating system.
+package loader
+
+import (
+	"unicode/utf16"
+
+	"github.com/google/uuid"
+	"github.com/lukegb/goefivar/efivar"
+)
+
+var (
+	ErrVariableCorrupted = efivar.NewError(efivar.Corrupt, "loader: variable content is not valid")
+
+	// VendorUUID is the vendor GUID of the Boot Loader Interface variables.
+	VendorUUID = uuid.MustParse("4a67b082-0a4c-41cf-b6c7-440b29bb8c4f")
+
+	LoaderInfoName          = efivar.VariableName{GUID: VendorUUID, Name: "LoaderInfo"}
+	LoaderFirmwareInfoName  = efivar.VariableName{GUID: VendorUUID, Name: "LoaderFirmwareInfo"}
+	LoaderFirmwareTypeName  = efivar.VariableName{GUID: VendorUUID, Name: "LoaderFirmwareType"}
+	LoaderEntriesName       = efivar.VariableName{GUID: VendorUUID, Name: "LoaderEntries"}
+	LoaderEntrySelectedName = efivar.VariableName{GUID: VendorUUID, Name: "LoaderEntrySelected"}
+	LoaderEntryDefaultName  = efivar.VariableName{GUID: VendorUUID, Name: "LoaderEntryDefault"}
+	LoaderEntryOneShotName  = efivar.VariableName{GUID: VendorUUID, Name: "LoaderEntryOneShot"}
+)
+
+// loaderAttributes are the attributes of the variables the OS may set.
+const loaderAttributes = efivar.NonVolatile | efivar.BootserviceAccess | efivar.RuntimeAccess
+
+func decodeUCS2(b []byte) ([]uint16, error) {
+	if len(b)%2 != 0 {
+		return nil, ErrVariableCorrupted
+	}
+	u := make([]uint16, len(b)/2)
+	for n := range u {
+		u[n] = uint16(b[2*n]) | uint16(b[2*n+1])<<8
+	}
+	return u, nil
+}
+
+// decodeString decodes a NUL-terminated UCS-2 string. The terminator is
+// optional, as not every loader writes it.
+func decodeString(b []byte) (string, error) {
+	u, err := decodeUCS2(b)
+	if err != nil {
+		return "", err
+	}
+	for n, c := range u {
+		if c == 0 {
+			u = u[:n]
+			break
+		}
+	}
+	return string(utf16.Decode(u)), nil
+}
+
+// decodeStrings decodes a sequence of NUL-terminated UCS-2 strings.
+func decodeStrings(b []byte) ([]string, error) {
+	u, err := decodeUCS2(b)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	start := 0
+	for n, c := range u {
+		if c == 0 {
+			out = append(out, string(utf16.Decode(u[start:n])))
+			start = n + 1
+		}
+	}
+	if start < len(u) {
+		out = append(out, string(utf16.Decode(u[start:])))
+	}
+	return out, nil
+}
+
+// encodeString encodes s as a NUL-terminated UCS-2 string.
+func encodeString(s string) []byte {
+	u := utf16.Encode([]rune(s + "\x00"))
+	b := make([]byte, 2*len(u))
+	for n, c := range u {
+		b[2*n], b[2*n+1] = byte(c), byte(c>>8)
+	}
+	return b
+}
+
+func readString(vn efivar.VariableName) (string, error) {
+	v, err := vn.Get()
+	if err != nil {
+		return "", err
+	}
+	return decodeString(v.Data)
+}
+
+func writeString(vn efivar.VariableName, s string) error {
+	if s == "" {
+		return vn.Delete()
+	}
+	v := &efivar.Variable{VariableName: vn, Data: encodeString(s), Attributes: loaderAttributes}
+	return v.Set(0644)
+}
+
+// Info returns the name and version of the loader, e.g. "systemd-boot 254".
+func Info() (string, error) { return readString(LoaderInfoName) }
+
+// FirmwareInfo returns the firmware vendor and revision, as seen by the loader.
+func FirmwareInfo() (string, error) { return readString(LoaderFirmwareInfoName) }
+
+// FirmwareType returns the type of firmware the loader ran on, e.g. "UEFI 2.70".
+func FirmwareType() (string, error) { return readString(LoaderFirmwareTypeName) }
+
+// Entries returns the identifiers of the boot entries the loader offered.
+func Entries() ([]string, error) {
+	v, err := LoaderEntriesName.Get()
+	if err != nil {
+		return nil, err
+	}
+	return decodeStrings(v.Data)
+}
+
+// EntrySelected returns the identifier of the entry that was booted.
+func EntrySelected() (string, error) { return readString(LoaderEntrySelectedName) }
+
+// EntryDefault returns the identifier of the entry the loader boots by
+// default, if set from the OS.
+func EntryDefault() (string, error) { return readString(LoaderEntryDefaultName) }
+
+// SetEntryDefault sets the default boot entry. An empty id removes the
+// setting, returning to the loader's configuration.
+func SetEntryDefault(id string) error { return writeString(LoaderEntryDefaultName, id) }
+
+// EntryOneShot returns the identifier of the entry selected for the next
+// boot only, if any.
+func EntryOneShot() (string, error) { return readString(LoaderEntryOneShotName) }
+
+// SetEntryOneShot selects the entry to boot on the next boot only. An empty
+// id cancels a previous request.
+func SetEntryOneShot(id string) error { return writeString(LoaderEntryOneShotName, id) }
@@ -0,0 +1,187 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loader
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/lukegb/goefivar/internal/mounts"
+	"github.com/lukegb/goefivar/internal/testfixture"
+)
+
+func TestDecodeString(t *testing.T) {
+	for _, tc := range []struct {
+		in   []byte
+		want string
+	}{
+		{encodeString("systemd-boot 254"), "systemd-boot 254"},
+		{[]byte{'a', 0, 'b', 0}, "ab"},
+		{[]byte{'a', 0, 0, 0, 'b', 0}, "a"},
+		{nil, ""},
+	} {
+		got, err := decodeString(tc.in)
+		if err != nil {
+			t.Errorf("decodeString(%x): %v", tc.in, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("decodeString(%x) = %q; want %q", tc.in, got, tc.want)
+		}
+	}
+	if _, err := decodeString([]byte{'a'}); err != ErrVariableCorrupted {
+		t.Errorf("decodeString(odd length) = %v; want ErrVariableCorrupted", err)
+	}
+}
+
+func TestDecodeStrings(t *testing.T) {
+	var b []byte
+	for _, s := range []string{"arch.conf", "arch-fallback.conf", "auto-efi-shell"} {
+		b = append(b, encodeString(s)...)
+	}
+	got, err := decodeStrings(b)
+	if err != nil {
+		t.Fatalf("decodeStrings: %v", err)
+	}
+	want := []string{"arch.conf", "arch-fallback.conf", "auto-efi-shell"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decodeStrings = %q; want %q", got, want)
+	}
+}
+
+func TestResolveESP(t *testing.T) {
+	dir, err := ioutil.TempDir("", "loader")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(p, m string) { mounts.PartUUIDDir, mounts.File = p, m }(mounts.PartUUIDDir, mounts.File)
+	mounts.PartUUIDDir = filepath.Join(dir, "by-partuuid")
+	mounts.File = filepath.Join(dir, "mounts")
+
+	dev := filepath.Join(dir, "nvme0n1p1")
+	if err := ioutil.WriteFile(dev, nil, 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	u := uuid.MustParse("8d6e3a5c-1b2f-4c6e-9f3d-2a1b0c9d8e7f")
+	if err := os.MkdirAll(mounts.PartUUIDDir, 0755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	if err := os.Symlink(dev, filepath.Join(mounts.PartUUIDDir, u.String())); err != nil {
+		t.Fatalf("Symlink: %v", err)
+	}
+	content := "/dev/root / ext4 rw 0 0\n" + dev + ` /boot\040efi vfat rw 0 0` + "\n"
+	if err := ioutil.WriteFile(mounts.File, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	got, err := resolveESP(u)
+	if err != nil {
+		t.Fatalf("resolveESP: %v", err)
+	}
+	want := &ESP{PartUUID: u, Device: dev, Mountpoint: "/boot efi"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("resolveESP = %+v; want %+v", got, want)
+	}
+}
+
+func TestParseTimeout(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want Timeout
+	}{
+		{"0", 0},
+		{"10", 10},
+		{"menu-force", TimeoutMenuForce},
+		{"menu-hidden", TimeoutMenuHidden},
+		{"menu-disabled", TimeoutMenuDisabled},
+	} {
+		got, err := ParseTimeout(tc.in)
+		if err != nil {
+			t.Errorf("ParseTimeout(%q): %v", tc.in, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("ParseTimeout(%q) = %v; want %v", tc.in, got, tc.want)
+		}
+		if got.String() != tc.in {
+			t.Errorf("%v.String() = %q; want %q", got, got.String(), tc.in)
+		}
+	}
+	for _, bad := range []string{"", "-1", "forever", "1.5"} {
+		if _, err := ParseTimeout(bad); err == nil {
+			t.Errorf("ParseTimeout(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestParseBootCountPath(t *testing.T) {
+	for _, tc := range []struct {
+		in        string
+		want      BootCount
+		good, bad string
+	}{
+		{`\loader\entries\linux-6.1+3-1.conf`, BootCount{"/loader/entries/linux-6.1+3-1.conf", 3, 1}, "/loader/entries/linux-6.1.conf", "/loader/entries/linux-6.1+0-4.conf"},
+		{"/EFI/Linux/linux+2.efi", BootCount{"/EFI/Linux/linux+2.efi", 2, 0}, "/EFI/Linux/linux.efi", "/EFI/Linux/linux+0-2.efi"},
+	} {
+		got, err := ParseBootCountPath(tc.in)
+		if err != nil {
+			t.Errorf("ParseBootCountPath(%q): %v", tc.in, err)
+			continue
+		}
+		if *got != tc.want {
+			t.Errorf("ParseBootCountPath(%q) = %+v; want %+v", tc.in, *got, tc.want)
+		}
+		if g := got.GoodPath(); g != tc.good {
+			t.Errorf("GoodPath = %q; want %q", g, tc.good)
+		}
+		if b := got.BadPath(); b != tc.bad {
+			t.Errorf("BadPath = %q; want %q", b, tc.bad)
+		}
+	}
+	if _, err := ParseBootCountPath("/loader/entries/linux.conf"); err != ErrVariableCorrupted {
+		t.Errorf("ParseBootCountPath(no counter) = %v; want ErrVariableCorrupted", err)
+	}
+}
+
+func TestFixture(t *testing.T) {
+	defer testfixture.Use(t, "laptop")()
+	if got, err := Info(); err != nil || got != "systemd-boot 254" {
+		t.Errorf("Info() = %q, %v; want %q", got, err, "systemd-boot 254")
+	}
+	if got, err := EntrySelected(); err != nil || got != "arch.conf" {
+		t.Errorf("EntrySelected() = %q, %v; want %q", got, err, "arch.conf")
+	}
+	entries, err := Entries()
+	if err != nil {
+		t.Fatalf("Entries: %v", err)
+	}
+	if want := []string{"arch.conf", "arch-fallback.conf"}; !reflect.DeepEqual(entries, want) {
+		t.Errorf("Entries() = %q; want %q", entries, want)
+	}
+	if _, err := EntryOneShot(); !os.IsNotExist(err) {
+		t.Errorf("EntryOneShot() before SetEntryOneShot = %v; want a not-exist error", err)
+	}
+	if err := SetEntryOneShot("arch-fallback.conf"); err != nil {
+		t.Fatalf("SetEntryOneShot: %v", err)
+	}
+	if got, err := EntryOneShot(); err != nil || got != "arch-fallback.conf" {
+		t.Errorf("EntryOneShot() = %q, %v; want %q", got, err, "arch-fallback.conf")
+	}
+}
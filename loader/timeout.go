@@ -0,0 +1,109 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loader
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/lukegb/goefivar/efivar"
+)
+
+var (
+	LoaderConfigTimeoutName        = efivar.VariableName{GUID: VendorUUID, Name: "LoaderConfigTimeout"}
+	LoaderConfigTimeoutOneShotName = efivar.VariableName{GUID: VendorUUID, Name: "LoaderConfigTimeoutOneShot"}
+)
+
+// Timeout is how long the loader shows its menu, in seconds, or one of the
+// special menu modes.
+type Timeout int
+
+const (
+	// TimeoutMenuForce shows the menu until an entry is chosen.
+	TimeoutMenuForce Timeout = -1
+	// TimeoutMenuHidden boots the default entry immediately, unless a key
+	// is pressed.
+	TimeoutMenuHidden Timeout = -2
+	// TimeoutMenuDisabled boots the default entry without checking for
+	// key presses.
+	TimeoutMenuDisabled Timeout = -3
+)
+
+var timeoutModes = map[Timeout]string{
+	TimeoutMenuForce:    "menu-force",
+	TimeoutMenuHidden:   "menu-hidden",
+	TimeoutMenuDisabled: "menu-disabled",
+}
+
+func (t Timeout) String() string {
+	if s, ok := timeoutModes[t]; ok {
+		return s
+	}
+	return strconv.Itoa(int(t))
+}
+
+// ParseTimeout parses a timeout in the encoding used by the loader variables
+// and loader.conf: a number of seconds or a menu mode.
+func ParseTimeout(s string) (Timeout, error) {
+	for t, name := range timeoutModes {
+		if s == name {
+			return t, nil
+		}
+	}
+	n, err := strconv.ParseUint(s, 10, 31)
+	if err != nil {
+		return 0, fmt.Errorf("loader: invalid timeout %q", s)
+	}
+	return Timeout(n), nil
+}
+
+func readTimeout(vn efivar.VariableName) (Timeout, error) {
+	s, err := readString(vn)
+	if err != nil {
+		return 0, err
+	}
+	t, err := ParseTimeout(s)
+	if err != nil {
+		return 0, ErrVariableCorrupted
+	}
+	return t, nil
+}
+
+func writeTimeout(vn efivar.VariableName, t Timeout) error {
+	if _, ok := timeoutModes[t]; !ok && t < 0 {
+		return fmt.Errorf("loader: invalid timeout %d", int(t))
+	}
+	return writeString(vn, t.String())
+}
+
+// ConfigTimeout returns the menu timeout set from the OS, which overrides
+// loader.conf.
+func ConfigTimeout() (Timeout, error) { return readTimeout(LoaderConfigTimeoutName) }
+
+// SetConfigTimeout sets the menu timeout for every boot.
+func SetConfigTimeout(t Timeout) error { return writeTimeout(LoaderConfigTimeoutName, t) }
+
+// ClearConfigTimeout removes the timeout set from the OS, returning to the
+// one in loader.conf.
+func ClearConfigTimeout() error { return LoaderConfigTimeoutName.Delete() }
+
+// ConfigTimeoutOneShot returns the menu timeout requested for the next boot.
+func ConfigTimeoutOneShot() (Timeout, error) { return readTimeout(LoaderConfigTimeoutOneShotName) }
+
+// SetConfigTimeoutOneShot sets the menu timeout for the next boot only.
+func SetConfigTimeoutOneShot(t Timeout) error { return writeTimeout(LoaderConfigTimeoutOneShotName, t) }
+
+// ClearConfigTimeoutOneShot cancels a one-shot timeout.
+func ClearConfigTimeoutOneShot() error { return LoaderConfigTimeoutOneShotName.Delete() }
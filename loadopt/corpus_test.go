@@ -0,0 +1,74 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loadopt
+
+import (
+	"bytes"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/lukegb/goefivar/internal/corpus"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of the load option corpus")
+
+// describe formats lo as the golden files of the corpus record it.
+func describe(lo *LoadOption) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "attributes: %#x\n", lo.Attributes)
+	fmt.Fprintf(&b, "description: %s\n", lo.Description)
+	fmt.Fprintf(&b, "file_path: %s\n", lo.FilePath)
+	if p := lo.FilePath.FilePath(); p != "" {
+		fmt.Fprintf(&b, "path_name: %s\n", p)
+	}
+	if len(lo.OptionalData) > 0 {
+		fmt.Fprintf(&b, "optional_data: %s\n", hex.EncodeToString(lo.OptionalData))
+		if s, ok := lo.OptionalDataUCS2(); ok && s != "" && strings.IndexFunc(s, func(r rune) bool { return !unicode.IsPrint(r) }) < 0 {
+			fmt.Fprintf(&b, "optional_data_ucs2: %s\n", s)
+		}
+	}
+	return b.String()
+}
+
+func TestCorpus(t *testing.T) {
+	los, err := corpus.LoadOptions()
+	if err != nil {
+		t.Fatalf("corpus.LoadOptions: %v", err)
+	}
+	for _, c := range los {
+		lo, err := Parse(c.Data)
+		if err != nil {
+			t.Errorf("%v: Parse: %v", c.Name, err)
+			continue
+		}
+		got := describe(lo)
+		if *update {
+			if err := corpus.WriteGolden(c.Name, got); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if got != c.Golden {
+			t.Errorf("%v: got:\n%vwant:\n%v", c.Name, got, c.Golden)
+		}
+		if b := lo.Bytes(); !bytes.Equal(b, c.Data) {
+			t.Errorf("%v: Bytes() = %x; want %x", c.Name, b, c.Data)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package loadopt
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/lukegb/goefivar/internal/corpus"
+)
+
+func FuzzParse(f *testing.F) {
+	f.Add(archBootOptBytes)
+	los, err := corpus.LoadOptions()
+	if err != nil {
+		f.Fatalf("corpus.LoadOptions: %v", err)
+	}
+	for _, lo := range los {
+		f.Add(lo.Data)
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		lo, err := Parse(b)
+		if err != nil {
+			return
+		}
+		_ = describe(lo)
+		got, err := Parse(lo.Bytes())
+		if err != nil {
+			t.Fatalf("Parse(Parse(%x).Bytes()): %v", b, err)
+		}
+		if got.Attributes != lo.Attributes || got.Description != lo.Description || !bytes.Equal(got.FilePathList, lo.FilePathList) || !bytes.Equal(got.OptionalData, lo.OptionalData) {
+			t.Errorf("Parse(Parse(%x).Bytes()) = %+v; want %+v", b, got, lo)
+		}
+	})
+}
@@ -0,0 +1,128 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package loadopt parses and builds EFI_LOAD_OPTION structures, the content
+// of Boot####, Driver#### and similar variables, in pure Go.
+//
+// Unlike efiboot it does not need libefiboot, so it can decode load options
+// captured on another machine.
+package loadopt
+
+import (
+	"encoding/binary"
+	"errors"
+	"unicode/utf16"
+
+	"github.com/lukegb/goefivar/efidp"
+)
+
+var ErrInvalid = errors.New("loadopt: load option is not valid")
+
+var byteOrder = binary.LittleEndian
+
+// Attributes of a load option.
+const (
+	Active         uint32 = 0x00000001
+	ForceReconnect uint32 = 0x00000002
+	Hidden         uint32 = 0x00000008
+	CategoryApp    uint32 = 0x00000100
+)
+
+// LoadOption is a decoded EFI_LOAD_OPTION.
+type LoadOption struct {
+	Attributes  uint32
+	Description string
+
+	// FilePath is the first device path of the option's file path list,
+	// which locates the image to load.
+	FilePath efidp.DevicePath
+	// FilePathList is the file path list as stored, including any device
+	// paths after the first.
+	FilePathList []byte
+
+	OptionalData []byte
+}
+
+// Parse decodes b.
+func Parse(b []byte) (*LoadOption, error) {
+	if len(b) < 6 {
+		return nil, ErrInvalid
+	}
+	lo := &LoadOption{Attributes: byteOrder.Uint32(b[0:4])}
+	fplLen := int(byteOrder.Uint16(b[4:6]))
+	b = b[6:]
+
+	var desc []uint16
+	for {
+		if len(b) < 2 {
+			return nil, ErrInvalid
+		}
+		c := byteOrder.Uint16(b)
+		b = b[2:]
+		if c == 0 {
+			break
+		}
+		desc = append(desc, c)
+	}
+	lo.Description = string(utf16.Decode(desc))
+
+	if fplLen > len(b) {
+		return nil, ErrInvalid
+	}
+	lo.FilePathList = append([]byte(nil), b[:fplLen]...)
+	dp, err := efidp.Parse(lo.FilePathList)
+	if err != nil {
+		return nil, err
+	}
+	lo.FilePath = dp
+	lo.OptionalData = append([]byte(nil), b[fplLen:]...)
+	return lo, nil
+}
+
+// Bytes encodes lo. The file path list is FilePathList if it is set, and
+// FilePath otherwise.
+func (lo *LoadOption) Bytes() []byte {
+	fpl := lo.FilePathList
+	if fpl == nil {
+		fpl = lo.FilePath.Bytes()
+	}
+	desc := utf16.Encode([]rune(lo.Description + "\x00"))
+
+	b := make([]byte, 6, 6+2*len(desc)+len(fpl)+len(lo.OptionalData))
+	byteOrder.PutUint32(b[0:4], lo.Attributes)
+	byteOrder.PutUint16(b[4:6], uint16(len(fpl)))
+	for _, c := range desc {
+		b = append(b, byte(c), byte(c>>8))
+	}
+	b = append(b, fpl...)
+	return append(b, lo.OptionalData...)
+}
+
+// OptionalDataUCS2 interprets the optional data as a UCS-2 string, as Linux
+// EFI stubs and most loaders expect their command line. It reports false if
+// the data has an odd length. A trailing NUL is dropped.
+func (lo *LoadOption) OptionalDataUCS2() (string, bool) {
+	d := lo.OptionalData
+	if len(d)%2 != 0 {
+		return "", false
+	}
+	d16 := make([]uint16, len(d)/2)
+	for n := range d16 {
+		d16[n] = byteOrder.Uint16(d[2*n:])
+	}
+	if len(d16) > 0 && d16[len(d16)-1] == 0 {
+		d16 = d16[:len(d16)-1]
+	}
+	return string(utf16.Decode(d16)), true
+}
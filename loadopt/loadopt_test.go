@@ -0,0 +1,81 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loadopt
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// Generated with:
+// xxd -seek 0x04 -ps /sys/firmware/efi/efivars/Boot000C-8be4df61-93ca-11d2-aa0d-00e098032b8c
+var archBootOptBytes = mustDecodeString(`
+010000005000410072006300680020004c0069006e007500780000000401
+2a0001000000000001000000000000983a0000000000b647c141bfe9274c
+81c6174026e79fd00202040422005c0076006d006c0069006e0075007a00
+2d006c0069006e007500780000007fff040072006f006f0074003d004c00
+4100420045004c003d004c0049004e0052004f004f005400200072007700
+200069006e0069007400720064003d005c0069006e00740065006c002d00
+750063006f00640065002e0069006d006700200069006e00690074007200
+64003d005c0069006e0069007400720061006d00660073002d006c006900
+6e00750078002e0069006d00670020006e00760069006400690061002d00
+640072006d002e006d006f00640065007300650074003d003100
+`)
+
+func mustDecodeString(s string) []byte {
+	bs, err := hex.DecodeString(strings.Replace(s, "\n", "", -1))
+	if err != nil {
+		panic(err)
+	}
+	return bs
+}
+
+func TestParse(t *testing.T) {
+	lo, err := Parse(archBootOptBytes)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if lo.Attributes != Active {
+		t.Errorf("Attributes = %#x; want %#x", lo.Attributes, Active)
+	}
+	if lo.Description != "Arch Linux" {
+		t.Errorf("Description = %q; want %q", lo.Description, "Arch Linux")
+	}
+	if got, want := lo.FilePath.FilePath(), `\vmlinuz-linux`; got != want {
+		t.Errorf("FilePath.FilePath() = %q; want %q", got, want)
+	}
+	wantData := `root=LABEL=LINROOT rw initrd=\intel-ucode.img initrd=\initramfs-linux.img nvidia-drm.modeset=1`
+	if got, ok := lo.OptionalDataUCS2(); !ok || got != wantData {
+		t.Errorf("OptionalDataUCS2() = %q, %v; want %q", got, ok, wantData)
+	}
+	if got := lo.Bytes(); !bytes.Equal(got, archBootOptBytes) {
+		t.Errorf("Bytes() = %x; want %x", got, archBootOptBytes)
+	}
+
+	lo.FilePathList = nil
+	if got := lo.Bytes(); !bytes.Equal(got, archBootOptBytes) {
+		t.Errorf("Bytes() from FilePath = %x; want %x", got, archBootOptBytes)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for n := 0; n < 0x4a; n += 7 {
+		if _, err := Parse(archBootOptBytes[:n]); err == nil {
+			t.Errorf("Parse of the first %d bytes succeeded", n)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package mswindows detects Windows state which matters to tools sharing a
+// machine with it.
+package mswindows
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+	"github.com/lukegb/goefivar/efivar"
+)
+
+var (
+	// VendorUUID is Microsoft's vendor GUID for its EFI variables.
+	VendorUUID = uuid.MustParse("77fa9abd-0359-4d32-bd60-28f4e78f784b")
+
+	// HibernateLocationName is set by Windows while a hibernation image is
+	// waiting to be resumed. Its content is not documented.
+	HibernateLocationName = efivar.VariableName{GUID: VendorUUID, Name: "HibernateLocation"}
+)
+
+// HiberfilState is the state of a Windows hibernation file.
+type HiberfilState int
+
+const (
+	// HiberfilAbsent means there is no hibernation file.
+	HiberfilAbsent HiberfilState = iota
+	// HiberfilInactive means the file holds no image waiting to be resumed.
+	HiberfilInactive
+	// HiberfilHibernated means Windows is hibernated, or was shut down with
+	// fast startup enabled, and will resume from the file.
+	HiberfilHibernated
+)
+
+func (s HiberfilState) String() string {
+	switch s {
+	case HiberfilAbsent:
+		return "absent"
+	case HiberfilInactive:
+		return "inactive"
+	case HiberfilHibernated:
+		return "hibernated"
+	}
+	return "unknown"
+}
+
+// ReadHiberfilState inspects the signature at the start of a hiberfil.sys.
+// As in ntfs-3g, a "hibr" signature, in either case, marks an image still to
+// be resumed; "wake" or zeroes mark one already used.
+func ReadHiberfilState(path string) (HiberfilState, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return HiberfilAbsent, nil
+	} else if err != nil {
+		return HiberfilAbsent, err
+	}
+	defer f.Close()
+
+	sig := make([]byte, 4)
+	if _, err := io.ReadFull(f, sig); err == io.EOF || err == io.ErrUnexpectedEOF {
+		return HiberfilInactive, nil
+	} else if err != nil {
+		return HiberfilAbsent, err
+	}
+	if bytes.EqualFold(sig, []byte("hibr")) {
+		return HiberfilHibernated, nil
+	}
+	return HiberfilInactive, nil
+}
+
+// Hibernation is what's known about whether Windows is hibernated.
+type Hibernation struct {
+	// VariableSet is true if HibernateLocation exists.
+	VariableSet bool
+	// Hiberfiles maps each hiberfil.sys found to its state.
+	Hiberfiles map[string]HiberfilState
+}
+
+// Hibernated reports whether any evidence says Windows is hibernated. It is
+// then unsafe to mount its volumes read-write or to change the boot order
+// away from Windows Boot Manager.
+func (h *Hibernation) Hibernated() bool {
+	if h.VariableSet {
+		return true
+	}
+	for _, s := range h.Hiberfiles {
+		if s == HiberfilHibernated {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckHibernation checks the EFI variables and the hiberfil.sys at the root
+// of each of the given mounted NTFS volumes.
+func CheckHibernation(ntfsMountpoints ...string) (*Hibernation, error) {
+	h := &Hibernation{Hiberfiles: make(map[string]HiberfilState)}
+	if efivar.Supported() {
+		ok, err := HibernateLocationName.Exists()
+		if err != nil {
+			return nil, err
+		}
+		h.VariableSet = ok
+	}
+	for _, mnt := range ntfsMountpoints {
+		p := filepath.Join(mnt, "hiberfil.sys")
+		s, err := ReadHiberfilState(p)
+		if err != nil {
+			return nil, err
+		}
+		if s != HiberfilAbsent {
+			h.Hiberfiles[p] = s
+		}
+	}
+	return h, nil
+}
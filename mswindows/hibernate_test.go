@@ -0,0 +1,58 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mswindows
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadHiberfilState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mswindows")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, tc := range []struct {
+		content []byte
+		want    HiberfilState
+	}{
+		{nil, HiberfilAbsent},
+		{[]byte("HIBR\x00\x00\x00\x00"), HiberfilHibernated},
+		{[]byte("hibr\x00\x00\x00\x00"), HiberfilHibernated},
+		{[]byte("wake\x00\x00\x00\x00"), HiberfilInactive},
+		{make([]byte, 8), HiberfilInactive},
+		{[]byte("hi"), HiberfilInactive},
+	} {
+		p := filepath.Join(dir, "hiberfil.sys")
+		os.Remove(p)
+		if tc.content != nil {
+			if err := ioutil.WriteFile(p, tc.content, 0644); err != nil {
+				t.Fatalf("WriteFile: %v", err)
+			}
+		}
+		got, err := ReadHiberfilState(p)
+		if err != nil {
+			t.Errorf("ReadHiberfilState(%q): %v", tc.content, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("ReadHiberfilState(%q) = %v; want %v", tc.content, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package systab
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/google/uuid"
+	"github.com/lukegb/goefivar/efivar"
+)
+
+var ErrTableCorrupted = errors.New("systab: configuration table is not valid")
+
+var (
+	// ConformanceProfilesTableUUID identifies the EFI_CONFORMANCE_PROFILES_TABLE
+	// in the configuration table, introduced in UEFI 2.10.
+	ConformanceProfilesTableUUID = uuid.MustParse("36122546-f7e7-4c8f-bd9b-eb8525b50c0b")
+
+	// ProfileUEFISpec is declared by platforms implementing the full UEFI
+	// specification. Its absence implies it, for tables without profiles.
+	ProfileUEFISpec = uuid.MustParse("523c91af-a195-4382-818d-295fe4006465")
+	// ProfileEBBR21 is declared by platforms conforming to EBBR 2.1.
+	ProfileEBBR21 = uuid.MustParse("cce33c35-74ac-4087-bce7-8b29b02eeb27")
+)
+
+var profileNames = map[uuid.UUID]string{
+	ProfileUEFISpec: "UEFI specification",
+	ProfileEBBR21:   "EBBR 2.1",
+}
+
+// ProfileName returns a description of a well-known conformance profile.
+func ProfileName(u uuid.UUID) string {
+	if n, ok := profileNames[u]; ok {
+		return n
+	}
+	return u.String()
+}
+
+const (
+	conformanceProfilesVersion    = 1
+	conformanceProfilesHeaderSize = 2 + 2
+)
+
+// ParseConformanceProfiles parses an EFI_CONFORMANCE_PROFILES_TABLE, returning
+// the profiles it declares.
+func ParseConformanceProfiles(b []byte) ([]uuid.UUID, error) {
+	if len(b) < conformanceProfilesHeaderSize || binary.LittleEndian.Uint16(b[0:2]) != conformanceProfilesVersion {
+		return nil, ErrTableCorrupted
+	}
+	n := int(binary.LittleEndian.Uint16(b[2:4]))
+	if len(b) < conformanceProfilesHeaderSize+16*n {
+		return nil, ErrTableCorrupted
+	}
+	out := make([]uuid.UUID, n)
+	for i := range out {
+		out[i] = efivar.GUIDFromBytes(b[conformanceProfilesHeaderSize+16*i:])
+	}
+	return out, nil
+}
+
+// memFile gives access to physical memory.
+var memFile = "/dev/mem"
+
+// ReadConformanceProfilesAt reads the conformance profiles table at physical
+// address addr. The kernel does not list this table in config_table, so the
+// address must come from elsewhere, such as a firmware log. Reading physical
+// memory requires root and a kernel permitting access to the region.
+func ReadConformanceProfilesAt(addr uint64) ([]uuid.UUID, error) {
+	f, err := os.Open(memFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	hdr := make([]byte, conformanceProfilesHeaderSize)
+	if _, err := f.ReadAt(hdr, int64(addr)); err != nil {
+		return nil, err
+	}
+	n := int(binary.LittleEndian.Uint16(hdr[2:4]))
+	b := make([]byte, conformanceProfilesHeaderSize+16*n)
+	if _, err := f.ReadAt(b, int64(addr)); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return ParseConformanceProfiles(b)
+}
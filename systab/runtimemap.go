@@ -0,0 +1,180 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package systab
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MemoryType is an EFI_MEMORY_TYPE.
+type MemoryType uint32
+
+const (
+	ReservedMemoryType      MemoryType = 0
+	LoaderCode              MemoryType = 1
+	LoaderData              MemoryType = 2
+	BootServicesCode        MemoryType = 3
+	BootServicesData        MemoryType = 4
+	RuntimeServicesCode     MemoryType = 5
+	RuntimeServicesData     MemoryType = 6
+	ConventionalMemory      MemoryType = 7
+	UnusableMemory          MemoryType = 8
+	ACPIReclaimMemory       MemoryType = 9
+	ACPIMemoryNVS           MemoryType = 10
+	MemoryMappedIO          MemoryType = 11
+	MemoryMappedIOPortSpace MemoryType = 12
+	PalCode                 MemoryType = 13
+	PersistentMemory        MemoryType = 14
+	UnacceptedMemoryType    MemoryType = 15
+)
+
+var memoryTypeNames = []string{
+	"Reserved", "LoaderCode", "LoaderData", "BootServicesCode",
+	"BootServicesData", "RuntimeServicesCode", "RuntimeServicesData",
+	"ConventionalMemory", "UnusableMemory", "ACPIReclaimMemory",
+	"ACPIMemoryNVS", "MemoryMappedIO", "MemoryMappedIOPortSpace", "PalCode",
+	"PersistentMemory", "UnacceptedMemory",
+}
+
+func (t MemoryType) String() string {
+	if int(t) < len(memoryTypeNames) {
+		return memoryTypeNames[t]
+	}
+	return fmt.Sprintf("MemoryType(%#x)", uint32(t))
+}
+
+// MemoryAttribute is a bitmask of EFI_MEMORY_* attributes.
+type MemoryAttribute uint64
+
+const (
+	MemoryUC           MemoryAttribute = 0x1
+	MemoryWC           MemoryAttribute = 0x2
+	MemoryWT           MemoryAttribute = 0x4
+	MemoryWB           MemoryAttribute = 0x8
+	MemoryUCE          MemoryAttribute = 0x10
+	MemoryWP           MemoryAttribute = 0x1000
+	MemoryRP           MemoryAttribute = 0x2000
+	MemoryXP           MemoryAttribute = 0x4000
+	MemoryNV           MemoryAttribute = 0x8000
+	MemoryMoreReliable MemoryAttribute = 0x10000
+	MemoryRO           MemoryAttribute = 0x20000
+	MemorySP           MemoryAttribute = 0x40000
+	MemoryCPUCrypto    MemoryAttribute = 0x80000
+	MemoryRuntime      MemoryAttribute = 0x8000000000000000
+)
+
+var memoryAttributeNames = []struct {
+	a    MemoryAttribute
+	name string
+}{
+	{MemoryRuntime, "RUNTIME"}, {MemoryUC, "UC"}, {MemoryWC, "WC"},
+	{MemoryWT, "WT"}, {MemoryWB, "WB"}, {MemoryUCE, "UCE"}, {MemoryWP, "WP"},
+	{MemoryRP, "RP"}, {MemoryXP, "XP"}, {MemoryNV, "NV"},
+	{MemoryMoreReliable, "MORE_RELIABLE"}, {MemoryRO, "RO"}, {MemorySP, "SP"},
+	{MemoryCPUCrypto, "CPU_CRYPTO"},
+}
+
+func (a MemoryAttribute) String() string {
+	var parts []string
+	for _, n := range memoryAttributeNames {
+		if a&n.a != 0 {
+			parts = append(parts, n.name)
+			a &^= n.a
+		}
+	}
+	if a != 0 || len(parts) == 0 {
+		parts = append(parts, fmt.Sprintf("%#x", uint64(a)))
+	}
+	return strings.Join(parts, "|")
+}
+
+// pageSize is the size of an EFI page, in which NumPages is counted.
+const pageSize = 4096
+
+// MemoryRange is an entry in the runtime memory map: a region the firmware's
+// runtime services use, and where the kernel mapped it.
+type MemoryRange struct {
+	Type      MemoryType
+	PhysAddr  uint64
+	VirtAddr  uint64
+	NumPages  uint64
+	Attribute MemoryAttribute
+}
+
+// Size returns the length of the range in bytes.
+func (r *MemoryRange) Size() uint64 { return r.NumPages * pageSize }
+
+func (r *MemoryRange) String() string {
+	return fmt.Sprintf("%v [%#016x-%#016x] -> %#016x %v", r.Type, r.PhysAddr, r.PhysAddr+r.Size()-1, r.VirtAddr, r.Attribute)
+}
+
+func readHex(dir, name string) (uint64, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseUint(strings.TrimSpace(string(b)), 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("systab: parsing %v: %v", filepath.Join(dir, name), err)
+	}
+	return n, nil
+}
+
+// RuntimeMap returns the runtime memory map the kernel passed to
+// SetVirtualAddressMap. It is only exposed by kernels built with
+// CONFIG_EFI_RUNTIME_MAP.
+func RuntimeMap() ([]*MemoryRange, error) {
+	dir := filepath.Join(efiDir, "runtime-map")
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var idx []int
+	for _, fi := range fis {
+		if n, err := strconv.Atoi(fi.Name()); err == nil {
+			idx = append(idx, n)
+		}
+	}
+	sort.Ints(idx)
+
+	out := make([]*MemoryRange, 0, len(idx))
+	for _, n := range idx {
+		d := filepath.Join(dir, strconv.Itoa(n))
+		r := &MemoryRange{}
+		var typ, attr uint64
+		for _, f := range []struct {
+			name string
+			dst  *uint64
+		}{
+			{"type", &typ},
+			{"phys_addr", &r.PhysAddr},
+			{"virt_addr", &r.VirtAddr},
+			{"num_pages", &r.NumPages},
+			{"attribute", &attr},
+		} {
+			if *f.dst, err = readHex(d, f.name); err != nil {
+				return nil, err
+			}
+		}
+		r.Type, r.Attribute = MemoryType(typ), MemoryAttribute(attr)
+		out = append(out, r)
+	}
+	return out, nil
+}
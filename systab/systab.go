@@ -0,0 +1,143 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package systab reports what the kernel exposes about the EFI system table:
+// the firmware's word size, the runtime services table and the installed
+// configuration tables.
+package systab
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// efiDir is where the kernel exposes EFI information.
+var efiDir = "/sys/firmware/efi"
+
+// ConfigTable is an entry in the EFI configuration table, named by the
+// kernel (for example ACPI20 or SMBIOS3).
+type ConfigTable struct {
+	Name    string
+	Address uint64
+}
+
+// Info describes the EFI system table.
+type Info struct {
+	// PlatformSize is 32 or 64: the word size of the firmware, which can
+	// differ from the kernel's. Zero if the kernel doesn't report it.
+	PlatformSize int
+
+	// Runtime is the physical address of the runtime services table. The
+	// kernel does not expose the table's revision.
+	Runtime uint64
+
+	ConfigTables []ConfigTable
+}
+
+// Is32Bit reports whether the firmware is 32-bit. A 64-bit kernel can only
+// boot from 32-bit firmware through a mixed mode thunk, and bootloaders for
+// such machines must be built for ia32.
+func (i *Info) Is32Bit() bool { return i.PlatformSize == 32 }
+
+// Table returns the address of the named configuration table.
+func (i *Info) Table(name string) (uint64, bool) {
+	for _, t := range i.ConfigTables {
+		if t.Name == name {
+			return t.Address, true
+		}
+	}
+	return 0, false
+}
+
+func readFile(name string) ([]byte, error) {
+	b, err := ioutil.ReadFile(filepath.Join(efiDir, name))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return b, err
+}
+
+func parseAddress(s string) (uint64, error) {
+	return strconv.ParseUint(strings.TrimSpace(s), 0, 64)
+}
+
+// parseTables parses the NAME=0xADDRESS lines of config_table and systab.
+func parseTables(b []byte) ([]ConfigTable, error) {
+	var out []ConfigTable
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" {
+			continue
+		}
+		eq := strings.IndexByte(line, '=')
+		if eq < 0 {
+			return nil, fmt.Errorf("systab: malformed config table line %q", line)
+		}
+		addr, err := parseAddress(line[eq+1:])
+		if err != nil {
+			return nil, fmt.Errorf("systab: malformed config table line %q: %v", line, err)
+		}
+		out = append(out, ConfigTable{Name: line[:eq], Address: addr})
+	}
+	return out, s.Err()
+}
+
+// Read returns the system table information exposed by the running kernel.
+func Read() (*Info, error) {
+	if _, err := os.Stat(efiDir); err != nil {
+		return nil, err
+	}
+	info := &Info{}
+
+	b, err := readFile("fw_platform_size")
+	if err != nil {
+		return nil, err
+	}
+	if b != nil {
+		if info.PlatformSize, err = strconv.Atoi(strings.TrimSpace(string(b))); err != nil {
+			return nil, fmt.Errorf("systab: parsing fw_platform_size: %v", err)
+		}
+	}
+
+	if b, err = readFile("runtime"); err != nil {
+		return nil, err
+	}
+	if b != nil {
+		if info.Runtime, err = parseAddress(string(b)); err != nil {
+			return nil, fmt.Errorf("systab: parsing runtime: %v", err)
+		}
+	}
+
+	// config_table is only readable by root; systab is the older, deprecated
+	// location of the same list.
+	for _, name := range []string{"config_table", "systab"} {
+		if b, err = readFile(name); err != nil {
+			return nil, err
+		}
+		if b != nil {
+			if info.ConfigTables, err = parseTables(b); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	return info, nil
+}
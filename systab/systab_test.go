@@ -0,0 +1,126 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package systab
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/lukegb/goefivar/efivar"
+)
+
+func TestRead(t *testing.T) {
+	dir, err := ioutil.TempDir("", "systab")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(old string) { efiDir = old }(efiDir)
+	efiDir = dir
+
+	for name, content := range map[string]string{
+		"fw_platform_size": "32\n",
+		"runtime":          "0x7fbee018\n",
+		"systab":           "ACPI20=0x7fb7e014\nACPI=0x7fb7e000\nSMBIOS=0x7f9ce000\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+
+	got, err := Read()
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	want := &Info{
+		PlatformSize: 32,
+		Runtime:      0x7fbee018,
+		ConfigTables: []ConfigTable{{"ACPI20", 0x7fb7e014}, {"ACPI", 0x7fb7e000}, {"SMBIOS", 0x7f9ce000}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Read = %+v; want %+v", got, want)
+	}
+	if !got.Is32Bit() {
+		t.Error("Is32Bit = false")
+	}
+	if a, ok := got.Table("SMBIOS"); !ok || a != 0x7f9ce000 {
+		t.Errorf("Table(SMBIOS) = %#x, %v", a, ok)
+	}
+}
+
+func TestRuntimeMap(t *testing.T) {
+	dir, err := ioutil.TempDir("", "systab")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(old string) { efiDir = old }(efiDir)
+	efiDir = dir
+
+	for n, files := range []map[string]string{{
+		"type": "0x5", "phys_addr": "0x7fb00000", "virt_addr": "0xfffffffeff800000", "num_pages": "0x10", "attribute": "0x800000000000000f",
+	}, {
+		"type": "0xb", "phys_addr": "0xffc00000", "virt_addr": "0xfffffffeff400000", "num_pages": "0x400", "attribute": "0x8000000000000001",
+	}} {
+		d := filepath.Join(dir, "runtime-map", strconv.Itoa(n))
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatalf("MkdirAll: %v", err)
+		}
+		for name, content := range files {
+			if err := ioutil.WriteFile(filepath.Join(d, name), []byte(content+"\n"), 0644); err != nil {
+				t.Fatalf("WriteFile: %v", err)
+			}
+		}
+	}
+
+	got, err := RuntimeMap()
+	if err != nil {
+		t.Fatalf("RuntimeMap: %v", err)
+	}
+	want := []*MemoryRange{
+		{RuntimeServicesCode, 0x7fb00000, 0xfffffffeff800000, 0x10, MemoryRuntime | MemoryUC | MemoryWC | MemoryWT | MemoryWB},
+		{MemoryMappedIO, 0xffc00000, 0xfffffffeff400000, 0x400, MemoryRuntime | MemoryUC},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RuntimeMap = %v; want %v", got, want)
+	}
+	if s := got[1].Attribute.String(); s != "RUNTIME|UC" {
+		t.Errorf("Attribute.String() = %q", s)
+	}
+}
+
+func TestParseConformanceProfiles(t *testing.T) {
+	b := []byte{1, 0, 2, 0}
+	b = append(b, efivar.GUIDBytes(ProfileUEFISpec)...)
+	b = append(b, efivar.GUIDBytes(ProfileEBBR21)...)
+	got, err := ParseConformanceProfiles(b)
+	if err != nil {
+		t.Fatalf("ParseConformanceProfiles: %v", err)
+	}
+	if want := []uuid.UUID{ProfileUEFISpec, ProfileEBBR21}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseConformanceProfiles = %v; want %v", got, want)
+	}
+	if n := ProfileName(got[1]); n != "EBBR 2.1" {
+		t.Errorf("ProfileName = %q", n)
+	}
+	if _, err := ParseConformanceProfiles(b[:len(b)-1]); err != ErrTableCorrupted {
+		t.Errorf("ParseConformanceProfiles(truncated) = %v; want ErrTableCorrupted", err)
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Error records a failed operation on a variable.
+type Error struct {
+	// Op is "get", "set", "delete" or "list".
+	Op string
+	// Name is the variable, or the zero VariableName for "list".
+	Name VariableName
+	Err  error
+}
+
+func (e *Error) Error() string {
+	if e.Name == (VariableName{}) {
+		return fmt.Sprintf("efivar: %s: %v", e.Op, e.Err)
+	}
+	return fmt.Sprintf("efivar: %s %s: %v", e.Op, e.Name.String(), e.Err)
+}
+
+// Unwrap returns the cause of e.
+func (e *Error) Unwrap() error { return e.Err }
+
+// Code classifies the cause of e.
+func (e *Error) Code() Code { return CodeOf(e.Err) }
+
+// A Backend holds a set of variables. Backends need not wrap their errors in
+// *Error; Client does that. A Backend's methods may be called concurrently.
+type Backend interface {
+	// Get returns the variable vn, or an error satisfying errors.Is(err, os.ErrNotExist).
+	Get(ctx context.Context, vn VariableName) (*Variable, error)
+	// Set writes v, appending to it if v has the AppendWrite attribute.
+	Set(ctx context.Context, v *Variable) error
+	// Delete removes vn, returning an error satisfying errors.Is(err, os.ErrNotExist) if there is no such variable.
+	Delete(ctx context.Context, vn VariableName) error
+	Variables(ctx context.Context) ([]VariableName, error)
+}
+
+// An Exister is a Backend which can tell whether a variable is set more
+// cheaply than by getting it. Client.Exists uses it if the Backend has it.
+type Exister interface {
+	Exists(ctx context.Context, vn VariableName) (bool, error)
+}
+
+// NotExist returns the error a Backend returns for a missing variable.
+func NotExist(op string, vn VariableName) error {
+	return &os.PathError{Op: op, Path: vn.String(), Err: os.ErrNotExist}
+}
+
+// A Client performs variable operations through a Backend.
+type Client struct {
+	Backend Backend
+}
+
+// New returns a Client using b.
+func New(b Backend) *Client { return &Client{Backend: b} }
+
+// Default uses the running system's variables.
+var Default = New(System{})
+
+// Get returns the variable vn.
+func (c *Client) Get(ctx context.Context, vn VariableName) (*Variable, error) {
+	v, err := c.Backend.Get(ctx, vn)
+	if err != nil {
+		return nil, &Error{Op: "get", Name: vn, Err: err}
+	}
+	return v, nil
+}
+
+// Exists reports whether vn is set.
+func (c *Client) Exists(ctx context.Context, vn VariableName) (bool, error) {
+	ok, err := exists(ctx, c.Backend, vn)
+	if err != nil {
+		return false, &Error{Op: "get", Name: vn, Err: err}
+	}
+	return ok, nil
+}
+
+// exists reports whether vn is set in b, using Get if b is not an Exister.
+func exists(ctx context.Context, b Backend, vn VariableName) (bool, error) {
+	if e, ok := b.(Exister); ok {
+		return e.Exists(ctx, vn)
+	}
+	_, err := b.Get(ctx, vn)
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, os.ErrNotExist):
+		return false, nil
+	}
+	return false, err
+}
+
+// Set writes v, appending to it if v has the AppendWrite attribute.
+func (c *Client) Set(ctx context.Context, v *Variable) error {
+	if err := c.Backend.Set(ctx, v); err != nil {
+		return &Error{Op: "set", Name: v.VariableName, Err: err}
+	}
+	return nil
+}
+
+// Delete removes vn.
+func (c *Client) Delete(ctx context.Context, vn VariableName) error {
+	if err := c.Backend.Delete(ctx, vn); err != nil {
+		return &Error{Op: "delete", Name: vn, Err: err}
+	}
+	return nil
+}
+
+// Variables lists every variable.
+func (c *Client) Variables(ctx context.Context) ([]VariableName, error) {
+	vns, err := c.Backend.Variables(ctx)
+	if err != nil {
+		return nil, &Error{Op: "list", Err: err}
+	}
+	return vns, nil
+}
+
+// WalkVariables calls fn for each variable, in no particular order,
+// stopping at the first error fn returns, which it returns unless it is
+// StopWalk. A Walker, such as System on efivarfs, is read as fn goes, so a
+// program can stop early on a system with thousands of variables; other
+// Backends list every variable first.
+func (c *Client) WalkVariables(ctx context.Context, fn func(vn VariableName) error) error {
+	// fn's own errors are returned as they are.
+	var fnErr error
+	walk := func(vn VariableName) error {
+		fnErr = fn(vn)
+		return fnErr
+	}
+	var err error
+	if w, ok := c.Backend.(Walker); ok {
+		err = w.WalkVariables(ctx, walk)
+	} else {
+		err = walkList(ctx, c.Backend, walk)
+	}
+	if err != nil && err != fnErr {
+		return &Error{Op: "list", Err: err}
+	}
+	return err
+}
+
+func Get(ctx context.Context, vn VariableName) (*Variable, error) { return Default.Get(ctx, vn) }
+func Exists(ctx context.Context, vn VariableName) (bool, error)   { return Default.Exists(ctx, vn) }
+func Set(ctx context.Context, v *Variable) error                  { return Default.Set(ctx, v) }
+func Delete(ctx context.Context, vn VariableName) error           { return Default.Delete(ctx, vn) }
+func Variables(ctx context.Context) ([]VariableName, error)       { return Default.Variables(ctx) }
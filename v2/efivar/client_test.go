@@ -0,0 +1,214 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memBackend is a Backend held in memory, so that these tests run without
+// EFI variable support or root.
+type memBackend struct {
+	mu sync.Mutex
+	m  map[VariableName]Variable
+}
+
+func newMemBackend() *memBackend {
+	return &memBackend{m: make(map[VariableName]Variable)}
+}
+
+func (b *memBackend) Get(ctx context.Context, vn VariableName) (*Variable, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	v, ok := b.m[vn]
+	if !ok {
+		return nil, NotExist("get", vn)
+	}
+	v.Data = append([]byte(nil), v.Data...)
+	return &v, nil
+}
+
+func (b *memBackend) Set(ctx context.Context, v *Variable) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.m[v.VariableName] = *v
+	return nil
+}
+
+func (b *memBackend) Delete(ctx context.Context, vn VariableName) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.m[vn]; !ok {
+		return NotExist("delete", vn)
+	}
+	delete(b.m, vn)
+	return nil
+}
+
+func (b *memBackend) Variables(ctx context.Context) ([]VariableName, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var vns []VariableName
+	for vn := range b.m {
+		vns = append(vns, vn)
+	}
+	return vns, nil
+}
+
+func TestClient(t *testing.T) {
+	c := New(newMemBackend())
+	ctx := context.Background()
+
+	v := &Variable{
+		VariableName: VariableName{GUID: GlobalUUID, Name: "BootNext"},
+		Data:         []byte{1, 0},
+		Attributes:   NonVolatile | BootserviceAccess | RuntimeAccess,
+	}
+	if err := c.Set(ctx, v); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	got, err := c.Get(ctx, v.VariableName)
+	if err != nil || !reflect.DeepEqual(got, v) {
+		t.Errorf("Get = %+v, %v; want %+v", got, err, v)
+	}
+	if err := c.Delete(ctx, v.VariableName); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if ok, err := c.Exists(ctx, v.VariableName); ok || err != nil {
+		t.Errorf("Exists after Delete = %v, %v; want false, nil", ok, err)
+	}
+
+	_, err = c.Get(ctx, v.VariableName)
+	var e *Error
+	if !errors.As(err, &e) {
+		t.Fatalf("Get of a deleted variable = %v; want an *Error", err)
+	}
+	if e.Op != "get" || e.Name != v.VariableName || !errors.Is(err, os.ErrNotExist) || CodeOf(err) != NotFound {
+		t.Errorf("Get of a deleted variable = %#v; want a not-exist error for get %v", e, v.Name)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := c.Variables(cancelled); !errors.Is(err, context.Canceled) {
+		t.Errorf("Variables(cancelled context) = %v; want context.Canceled", err)
+	}
+}
+
+func TestErrorString(t *testing.T) {
+	vn := VariableName{GUID: GlobalUUID, Name: "BootNext"}
+	for _, tc := range []struct {
+		err  *Error
+		want string
+	}{
+		{&Error{Op: "get", Name: vn, Err: os.ErrNotExist}, "efivar: get BootNext-8be4df61-93ca-11d2-aa0d-00e098032b8c: file does not exist"},
+		{&Error{Op: "list", Err: os.ErrPermission}, "efivar: list: permission denied"},
+	} {
+		if got := tc.err.Error(); got != tc.want {
+			t.Errorf("Error() = %q, want %q", got, tc.want)
+		}
+	}
+}
+
+func TestClientWalkVariables(t *testing.T) {
+	b := newMemBackend()
+	ctx := context.Background()
+	for _, name := range []string{"Boot0000", "Boot0001", "BootOrder"} {
+		b.Set(ctx, &Variable{VariableName: VariableName{GUID: GlobalUUID, Name: name}})
+	}
+	c := New(b)
+	n := 0
+	if err := c.WalkVariables(ctx, func(vn VariableName) error { n++; return nil }); err != nil || n != 3 {
+		t.Errorf("WalkVariables visited %d variables, %v; want 3", n, err)
+	}
+	n = 0
+	if err := c.WalkVariables(ctx, func(vn VariableName) error { n++; return StopWalk }); err != nil || n != 1 {
+		t.Errorf("WalkVariables stopped after %d variables, %v; want 1, nil", n, err)
+	}
+	errBad := errors.New("bad")
+	if err := c.WalkVariables(ctx, func(vn VariableName) error { return errBad }); err != errBad {
+		t.Errorf("WalkVariables = %v, want the callback's error", err)
+	}
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	var e *Error
+	if err := c.WalkVariables(cancelled, func(vn VariableName) error { return nil }); !errors.As(err, &e) || e.Err != context.Canceled {
+		t.Errorf("WalkVariables(cancelled context) = %v; want an *Error for context.Canceled", err)
+	}
+}
+
+// slowBackend is a memBackend whose Get takes a while.
+type slowBackend struct{ *memBackend }
+
+func (b slowBackend) Get(ctx context.Context, vn VariableName) (*Variable, error) {
+	time.Sleep(20 * time.Millisecond)
+	return b.memBackend.Get(ctx, vn)
+}
+
+// TestInterruptible checks, under the race detector, that Interruptible
+// returns when ctx is done and that the operation left running shares
+// nothing with the caller.
+func TestInterruptible(t *testing.T) {
+	b := Interruptible(slowBackend{newMemBackend()})
+	vn := VariableName{GUID: GlobalUUID, Name: "BootNext"}
+	if err := b.Set(context.Background(), &Variable{VariableName: vn, Data: []byte{1, 0}}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if ok, err := b.(Exister).Exists(context.Background(), vn); !ok || err != nil {
+		t.Errorf("Exists = %v, %v; want true, nil", ok, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if v, err := b.Get(ctx, vn); v != nil || err != context.DeadlineExceeded {
+		t.Errorf("Get from a slow backend = %v, %v; want nil, %v", v, err, context.DeadlineExceeded)
+	}
+	time.Sleep(40 * time.Millisecond)
+}
+
+func TestReadOnly(t *testing.T) {
+	b := newMemBackend()
+	ctx := context.Background()
+	v := &Variable{
+		VariableName: VariableName{GUID: GlobalUUID, Name: "BootNext"},
+		Data:         []byte{1, 0},
+		Attributes:   NonVolatile | BootserviceAccess | RuntimeAccess,
+	}
+	if err := b.Set(ctx, v); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	c := New(ReadOnly(b))
+	if _, err := c.Get(ctx, v.VariableName); err != nil {
+		t.Errorf("Get: %v", err)
+	}
+	if err := c.Set(ctx, v); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Set = %v; want ErrReadOnly", err)
+	}
+	if err := c.Delete(ctx, v.VariableName); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Delete = %v; want ErrReadOnly", err)
+	}
+	if ok, err := c.Exists(ctx, v.VariableName); !ok || err != nil {
+		t.Errorf("Exists after refused Delete = %v, %v; want true, nil", ok, err)
+	}
+}
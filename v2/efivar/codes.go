@@ -0,0 +1,132 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import (
+	"os"
+	"syscall"
+)
+
+// Code classifies an error, for programs which map failures onto their own
+// reporting, such as RPC status codes or exit codes. The names returned by
+// String are stable.
+type Code int
+
+const (
+	// Unknown is the Code of nil and of errors which fit no other class.
+	Unknown Code = iota
+	// NotFound means the variable does not exist.
+	NotFound
+	// Permission means the caller may not read or change the variable, or
+	// the variable is protected by the kernel or firmware.
+	Permission
+	// NVRAMFull means the firmware has no space for the write.
+	NVRAMFull
+	// Corrupt means the variable's content is not valid for its type.
+	Corrupt
+	// Unsupported means the system or firmware lacks the feature needed.
+	Unsupported
+	// AuthRequired means the write needs a valid authentication descriptor,
+	// as writes to the Secure Boot variables do outside Setup Mode.
+	AuthRequired
+)
+
+var codeNames = []string{
+	Unknown:      "UNKNOWN",
+	NotFound:     "NOT_FOUND",
+	Permission:   "PERMISSION",
+	NVRAMFull:    "NVRAM_FULL",
+	Corrupt:      "CORRUPT",
+	Unsupported:  "UNSUPPORTED",
+	AuthRequired: "AUTH_REQUIRED",
+}
+
+func (c Code) String() string {
+	if c < 0 || int(c) >= len(codeNames) {
+		return codeNames[Unknown]
+	}
+	return codeNames[c]
+}
+
+// MarshalText encodes c as its name, such as NOT_FOUND.
+func (c Code) MarshalText() ([]byte, error) { return []byte(c.String()), nil }
+
+// Coder is implemented by errors which know their own Code.
+type Coder interface {
+	Code() Code
+}
+
+type codedError struct {
+	code Code
+	msg  string
+}
+
+func (e *codedError) Error() string { return e.msg }
+func (e *codedError) Code() Code    { return e.code }
+
+// NewError returns an error with the message msg which CodeOf classifies
+// as c. It is for packages' sentinel errors.
+func NewError(c Code, msg string) error {
+	return &codedError{c, msg}
+}
+
+// CodeOf classifies err. It looks through *os.PathError, *os.SyscallError,
+// and any error with an Unwrap method, for an error implementing Coder or a
+// system error it recognises.
+func CodeOf(err error) Code {
+	for err != nil {
+		if c, ok := err.(Coder); ok {
+			return c.Code()
+		}
+		switch e := err.(type) {
+		case syscall.Errno:
+			return errnoCode(e)
+		case *os.PathError:
+			err = e.Err
+			continue
+		case *os.SyscallError:
+			err = e.Err
+			continue
+		}
+		switch err {
+		case os.ErrNotExist:
+			return NotFound
+		case os.ErrPermission:
+			return Permission
+		}
+		u, ok := err.(interface{ Unwrap() error })
+		if !ok {
+			break
+		}
+		err = u.Unwrap()
+	}
+	return Unknown
+}
+
+// errnoCode classifies the errors efivarfs returns, which are the kernel's
+// translations of the firmware's EFI_STATUS.
+func errnoCode(e syscall.Errno) Code {
+	switch e {
+	case syscall.ENOENT:
+		return NotFound
+	case syscall.EPERM, syscall.EACCES, syscall.EROFS:
+		return Permission
+	case syscall.ENOSPC:
+		return NVRAMFull
+	case syscall.ENOSYS, syscall.EOPNOTSUPP:
+		return Unsupported
+	}
+	return Unknown
+}
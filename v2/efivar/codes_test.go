@@ -0,0 +1,66 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+)
+
+type wrapped struct{ err error }
+
+func (w wrapped) Error() string { return "wrapped: " + w.err.Error() }
+func (w wrapped) Unwrap() error { return w.err }
+
+func TestCodeOf(t *testing.T) {
+	corrupt := NewError(Corrupt, "test: corrupt")
+	for _, tc := range []struct {
+		err  error
+		want Code
+	}{
+		{nil, Unknown},
+		{fmt.Errorf("something else"), Unknown},
+		{syscall.ENOENT, NotFound},
+		{NotExist("get", testVariable), NotFound},
+		{&Error{Op: "set", Name: testVariable, Err: syscall.ENOSPC}, NVRAMFull},
+		{&os.PathError{Op: "open", Path: "/sys/firmware/efi/efivars/x", Err: syscall.EACCES}, Permission},
+		{syscall.EPERM, Permission},
+		{os.ErrPermission, Permission},
+		{syscall.ENOSPC, NVRAMFull},
+		{syscall.EOPNOTSUPP, Unsupported},
+		{corrupt, Corrupt},
+		{wrapped{corrupt}, Corrupt},
+		{wrapped{&os.SyscallError{Syscall: "write", Err: syscall.ENOSPC}}, NVRAMFull},
+	} {
+		if got := CodeOf(tc.err); got != tc.want {
+			t.Errorf("CodeOf(%v) = %v; want %v", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestCodeString(t *testing.T) {
+	for c, want := range map[Code]string{
+		Unknown:      "UNKNOWN",
+		NotFound:     "NOT_FOUND",
+		AuthRequired: "AUTH_REQUIRED",
+		Code(100):    "UNKNOWN",
+	} {
+		if got := c.String(); got != want {
+			t.Errorf("Code(%d).String() = %q; want %q", c, got, want)
+		}
+	}
+}
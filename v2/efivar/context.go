@@ -0,0 +1,88 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import "context"
+
+// withContext runs f, returning ctx.Err() if ctx is done first. Neither
+// efivarfs nor libefivar can be interrupted, so f is left to finish in the
+// background; a change may still be made after the operation returns. f's
+// results come back over a channel, so a late f shares nothing with the
+// caller. A context which is never done, such as context.Background(), has
+// f run directly.
+func withContext(ctx context.Context, f func() (interface{}, error)) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if ctx.Done() == nil {
+		return f()
+	}
+	type result struct {
+		v   interface{}
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		v, err := f()
+		done <- result{v, err}
+	}()
+	select {
+	case r := <-done:
+		return r.v, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Interruptible returns a Backend which returns ctx.Err() as soon as ctx is
+// done, for a Backend which may hang without heeding ctx. As System does,
+// it leaves the operation to finish in the background, so a change may
+// still be made after Set or Delete returns. It is an Exister, using b's
+// Exists if b is one.
+func Interruptible(b Backend) Backend { return interruptible{b} }
+
+type interruptible struct{ b Backend }
+
+func (i interruptible) Get(ctx context.Context, vn VariableName) (*Variable, error) {
+	r, err := withContext(ctx, func() (interface{}, error) { return i.b.Get(ctx, vn) })
+	if err != nil {
+		return nil, err
+	}
+	return r.(*Variable), nil
+}
+
+func (i interruptible) Exists(ctx context.Context, vn VariableName) (bool, error) {
+	r, err := withContext(ctx, func() (interface{}, error) { return exists(ctx, i.b, vn) })
+	ok, _ := r.(bool)
+	return ok, err
+}
+
+func (i interruptible) Set(ctx context.Context, v *Variable) error {
+	_, err := withContext(ctx, func() (interface{}, error) { return nil, i.b.Set(ctx, v) })
+	return err
+}
+
+func (i interruptible) Delete(ctx context.Context, vn VariableName) error {
+	_, err := withContext(ctx, func() (interface{}, error) { return nil, i.b.Delete(ctx, vn) })
+	return err
+}
+
+func (i interruptible) Variables(ctx context.Context) ([]VariableName, error) {
+	r, err := withContext(ctx, func() (interface{}, error) { return i.b.Variables(ctx) })
+	if err != nil {
+		return nil, err
+	}
+	return r.([]VariableName), nil
+}
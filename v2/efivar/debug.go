@@ -0,0 +1,54 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import (
+	"fmt"
+	"sync"
+)
+
+var (
+	debugMu    sync.RWMutex
+	debugLevel int
+	debugLog   func(level int, msg string)
+)
+
+// SetDebugLog sends debug messages up to level to log: libefivar's verbose
+// output, which also covers libefiboot, and this package's own messages
+// about efivarfs. Higher levels are more verbose, as with libefivar's
+// efi_set_verbose; level 0 or a nil log turns debugging off.
+//
+// libefivar does not say how verbose each line of its output is, so its
+// lines are all given to log at level 1, prefixed with "libefivar: ".
+func SetDebugLog(level int, log func(level int, msg string)) error {
+	if log == nil || level < 0 {
+		level = 0
+	}
+	debugMu.Lock()
+	debugLevel, debugLog = level, log
+	debugMu.Unlock()
+	return sys.setVerbose(level)
+}
+
+// debugf sends a message to the log given to SetDebugLog, if level is
+// enabled.
+func debugf(level int, format string, args ...interface{}) {
+	debugMu.RLock()
+	log, enabled := debugLog, level <= debugLevel
+	debugMu.RUnlock()
+	if log != nil && enabled {
+		log(level, fmt.Sprintf(format, args...))
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDebugLog(t *testing.T) {
+	var got []string
+	if err := SetDebugLog(2, func(level int, msg string) { got = append(got, msg) }); err != nil {
+		t.Fatalf("SetDebugLog: %v", err)
+	}
+	debugf(1, "one %d", 1)
+	debugf(2, "two")
+	debugf(3, "three")
+	if err := SetDebugLog(0, nil); err != nil {
+		t.Fatalf("SetDebugLog(0, nil): %v", err)
+	}
+	debugf(1, "after")
+	if want := []string{"one 1", "two"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("logged %q, want %q", got, want)
+	}
+}
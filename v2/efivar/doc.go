@@ -0,0 +1,60 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package efivar is version 2 of the efivar API. Every operation takes a
+// context and goes through a Backend: System for the running system's
+// variables, or any other implementation. Client wraps every error a
+// Backend returns in an *Error, so that errors.Is(err, os.ErrNotExist),
+// errors.As and CodeOf work on it.
+//
+// Version 1, github.com/lukegb/goefivar/efivar, is a shim over this
+// package, so the two may be used in one program while it moves over.
+//
+// # Implementations
+//
+// System goes through libefivar, unless the program was built without cgo
+// or with the purego build tag, in which case it uses efivarfs directly,
+// or on FreeBSD the ioctls of /dev/efi, and formats device paths with
+// package efidp; ParseDevicePath is then unsupported. Setting the
+// environment variable GOEFIVAR_IMPL=purego makes a program built with
+// libefivar do the same. Implementation reports which is in use.
+//
+// On Linux kernels without efivarfs mounted, the pure-Go implementation uses
+// the legacy interface in /sys/firmware/efi/vars, which holds at most 1024
+// bytes in each variable.
+//
+// EFIVARFS_PATH, if set, moves efivarfs from /sys/firmware/efi/efivars for
+// both implementations, for example into a chroot; Efivarfs returns a
+// System for efivarfs at any path.
+//
+// On macOS, which has no libefivar, variables are read with the nvram
+// command and cannot be changed. Variables outside the EFI namespace, such
+// as boot-args, have the GUID AppleNVRAMUUID.
+//
+// # Read-only mode
+//
+// SetReadOnly, the environment variable GOEFIVAR_READONLY=1 or the
+// goefivar_readonly build tag make every change through System, here or
+// through version 1, fail with ErrReadOnly, for programs which must never
+// change NVRAM. Once on, read-only mode cannot be turned off. ReadOnly
+// refuses changes through a single Backend.
+//
+// # Concurrency
+//
+// Everything in this package may be used from multiple goroutines at once.
+// libefivar itself keeps process-wide state without locking, so calls into
+// it are serialised; they are short, and variable access through efivarfs is
+// not fast enough for this to matter. Listings of variables through
+// libefivar share one iterator, so are made one at a time.
+package efivar
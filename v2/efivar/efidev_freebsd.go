@@ -0,0 +1,195 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import (
+	"os"
+	"syscall"
+	"unicode/utf16"
+	"unsafe"
+)
+
+// efiDevPath is FreeBSD's EFI runtime services device, from efidev(4).
+const efiDevPath = "/dev/efi"
+
+// efiVarIoc is struct efi_var_ioc from <sys/efiio.h>. Its vendor field is a
+// struct uuid, whose fields are in host byte order, which on the
+// little-endian machines with EFI is the layout of GUIDBytes.
+type efiVarIoc struct {
+	name     *uint16
+	namesize uintptr
+	vendor   [16]byte
+	attrib   uint32
+	data     *byte
+	datasize uintptr
+}
+
+// The ioctls of efidev(4), _IOWR('E', n, struct efi_var_ioc).
+const (
+	iocInOut      = 0xc0000000
+	efiVarIocLen  = unsafe.Sizeof(efiVarIoc{})
+	efiIocVarGet  = iocInOut | efiVarIocLen<<16 | 'E'<<8 | 4
+	efiIocVarNext = iocInOut | efiVarIocLen<<16 | 'E'<<8 | 5
+	efiIocVarSet  = iocInOut | efiVarIocLen<<16 | 'E'<<8 | 7
+)
+
+// efidev is the system reached through FreeBSD's /dev/efi, which passes
+// each request to the firmware's runtime services.
+type efidev struct {
+	goSystem
+	path string
+}
+
+func newEfidev() *efidev {
+	return &efidev{path: efiDevPath}
+}
+
+// defaultGoSystem returns the pure-Go system for this platform.
+func defaultGoSystem() system {
+	return newEfidev()
+}
+
+func (d *efidev) supported() bool {
+	f, err := os.Open(d.path)
+	if err != nil {
+		return false
+	}
+	f.Close()
+	return true
+}
+
+// ioctl opens the device and makes request with ioc, reporting failure as
+// an *os.PathError for op on vn.
+func (d *efidev) ioctl(op string, vn VariableName, request uintptr, ioc *efiVarIoc) error {
+	f, err := os.OpenFile(d.path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, _, e := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), request, uintptr(unsafe.Pointer(ioc))); e != 0 {
+		return &os.PathError{Op: op, Path: vn.String(), Err: e}
+	}
+	return nil
+}
+
+// ucs2Name returns vn's name as NUL-terminated UCS-2.
+func ucs2Name(vn VariableName) []uint16 {
+	return append(utf16.Encode([]rune(vn.Name)), 0)
+}
+
+func (d *efidev) Get(vn VariableName) (*Variable, error) {
+	name := ucs2Name(vn)
+	ioc := efiVarIoc{name: &name[0], namesize: uintptr(2 * len(name))}
+	copy(ioc.vendor[:], GUIDBytes(vn.GUID))
+	// With no buffer, efidev returns the size of the data.
+	if err := d.ioctl("get", vn, efiIocVarGet, &ioc); err != nil {
+		return nil, err
+	}
+	data := make([]byte, ioc.datasize)
+	if len(data) > 0 {
+		ioc.data = &data[0]
+		if err := d.ioctl("get", vn, efiIocVarGet, &ioc); err != nil {
+			return nil, err
+		}
+	}
+	return &Variable{
+		VariableName: vn,
+		Attributes:   Attributes(ioc.attrib),
+		Data:         data[:ioc.datasize],
+	}, nil
+}
+
+func (d *efidev) Exists(vn VariableName) (bool, error) {
+	_, err := d.Get(vn)
+	switch {
+	case err == nil:
+		return true, nil
+	case os.IsNotExist(err):
+		return false, nil
+	}
+	return false, err
+}
+
+func (d *efidev) Set(v *Variable) error { return d.setMode(v, DefaultMode) }
+
+// setMode ignores mode: efidev has no files to give it to.
+func (d *efidev) setMode(v *Variable, mode os.FileMode) error {
+	if IsReadOnly() {
+		return ErrReadOnly
+	}
+	name := ucs2Name(v.VariableName)
+	ioc := efiVarIoc{
+		name:     &name[0],
+		namesize: uintptr(2 * len(name)),
+		attrib:   uint32(v.Attributes),
+		datasize: uintptr(len(v.Data)),
+	}
+	copy(ioc.vendor[:], GUIDBytes(v.GUID))
+	if len(v.Data) > 0 {
+		ioc.data = &v.Data[0]
+	}
+	return d.ioctl("set", v.VariableName, efiIocVarSet, &ioc)
+}
+
+// Delete writes no data, which deletes the variable as SetVariable does, or
+// fails with ENOENT if there is no such variable.
+func (d *efidev) Delete(vn VariableName) error {
+	if IsReadOnly() {
+		return ErrReadOnly
+	}
+	name := ucs2Name(vn)
+	ioc := efiVarIoc{name: &name[0], namesize: uintptr(2 * len(name))}
+	copy(ioc.vendor[:], GUIDBytes(vn.GUID))
+	return d.ioctl("delete", vn, efiIocVarSet, &ioc)
+}
+
+// Variables walks GetNextVariableName. efidev returns the size needed, and
+// no name, when the buffer is too small.
+func (d *efidev) Variables() ([]VariableName, error) {
+	f, err := os.OpenFile(d.path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var out []VariableName
+	buf := make([]uint16, 64)
+	var ioc efiVarIoc
+	for {
+		ioc.name = &buf[0]
+		ioc.namesize = uintptr(2 * len(buf))
+		_, _, e := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), efiIocVarNext, uintptr(unsafe.Pointer(&ioc)))
+		switch {
+		case e == syscall.ENOENT:
+			return out, nil
+		case e != 0:
+			return nil, &os.SyscallError{Syscall: "ioctl EFIIOC_VAR_NEXT", Err: e}
+		case ioc.name == nil:
+			// The name did not fit: grow the buffer, keeping the
+			// previous name to continue from.
+			n := make([]uint16, ioc.namesize/2)
+			copy(n, buf)
+			buf = n
+			continue
+		}
+		n := 0
+		for n < len(buf) && buf[n] != 0 {
+			n++
+		}
+		out = append(out, VariableName{
+			GUID: GUIDFromBytes(ioc.vendor[:]),
+			Name: string(utf16.Decode(buf[:n])),
+		})
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import (
+	"encoding/binary"
+	"errors"
+	"unsafe"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrSomethingWentWrong = errors.New("efivar: something went wrong")
+
+	uuidByteOrder = binary.BigEndian
+	byteOrder     = endianness()
+
+	GlobalUUID = uuid.MustParse("8be4df61-93ca-11d2-aa0d-00e098032b8c")
+)
+
+type Attributes uint32
+
+const (
+	NonVolatile                       Attributes = 0x00000001
+	BootserviceAccess                            = 0x00000002
+	RuntimeAccess                                = 0x00000004
+	HardwareErrorRecord                          = 0x00000008
+	AuthenticatedWriteAccess                     = 0x00000010
+	TimeBasedAuthenticatedWriteAccess            = 0x00000020
+	AppendWrite                                  = 0x00000040
+)
+
+// Has reports whether a has every bit of b.
+func (a Attributes) Has(b Attributes) bool {
+	return a&b == b
+}
+
+// With returns a with the bits of b set.
+func (a Attributes) With(b Attributes) Attributes {
+	return a | b
+}
+
+// Without returns a with the bits of b cleared.
+func (a Attributes) Without(b Attributes) Attributes {
+	return a &^ b
+}
+
+// endianness returns the byte order of this machine, in which efivarfs
+// presents variable attributes.
+func endianness() binary.ByteOrder {
+	x := uint16(1)
+	if *(*byte)(unsafe.Pointer(&x)) == 1 {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}
+
+// GUIDFromBytes decodes a GUID stored in the mixed-endian layout used by EFI
+// structures, where the first three fields are little-endian.
+func GUIDFromBytes(b []byte) uuid.UUID {
+	var u uuid.UUID
+	copy(u[:], b[:16])
+	u[0], u[1], u[2], u[3] = u[3], u[2], u[1], u[0]
+	u[4], u[5] = u[5], u[4]
+	u[6], u[7] = u[7], u[6]
+	return u
+}
+
+// GUIDBytes encodes u in the mixed-endian layout used by EFI structures.
+func GUIDBytes(u uuid.UUID) []byte {
+	b := make([]byte, 16)
+	copy(b, u[:])
+	b[0], b[1], b[2], b[3] = b[3], b[2], b[1], b[0]
+	b[4], b[5] = b[5], b[4]
+	b[6], b[7] = b[7], b[6]
+	return b
+}
+
+// VariableName names an EFI variable.
+type VariableName struct {
+	// GUID is the UUID of the vendor providing this variable.
+	// The value stored in GlobalUUID is used for variables defined in the UEFI specification.
+	GUID uuid.UUID
+
+	// Name is the string name of this variable.
+	// It is namespaced by GUID.
+	Name string
+}
+
+// Variable is an EFI variable with its content.
+type Variable struct {
+	VariableName
+
+	Data       []byte
+	Attributes Attributes
+}
@@ -0,0 +1,52 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+var testVariable = VariableName{
+	GUID: uuid.MustParse("74552304-ce9f-4e52-89a0-f6c6fa47deac"),
+	Name: "LukegbEFIvarTest",
+}
+
+func TestAttributesHelpers(t *testing.T) {
+	a := Attributes(NonVolatile | BootserviceAccess)
+	if !a.Has(NonVolatile) || !a.Has(NonVolatile|BootserviceAccess) || a.Has(NonVolatile|RuntimeAccess) {
+		t.Errorf("Has on %v gave the wrong answer", a)
+	}
+	if got := a.With(RuntimeAccess); got != NonVolatile|BootserviceAccess|RuntimeAccess {
+		t.Errorf("%v.With(RT) = %v", a, got)
+	}
+	if got := a.Without(BootserviceAccess | AppendWrite); got != NonVolatile {
+		t.Errorf("%v.Without(BS+AW) = %v", a, got)
+	}
+}
+
+func TestGUIDBytesRoundtrip(t *testing.T) {
+	u := uuid.MustParse("a5c059a1-94e4-4aa7-87b5-ab155c2bf072")
+	b := GUIDBytes(u)
+	want := []byte{0xa1, 0x59, 0xc0, 0xa5, 0xe4, 0x94, 0xa7, 0x4a, 0x87, 0xb5, 0xab, 0x15, 0x5c, 0x2b, 0xf0, 0x72}
+	if !bytes.Equal(b, want) {
+		t.Errorf("GUIDBytes(%v) = %x; want %x", u, b, want)
+	}
+	if got := GUIDFromBytes(b); got != u {
+		t.Errorf("GUIDFromBytes(%x) = %v; want %v", b, got, u)
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/lukegb/goefivar/efidp"
+	"github.com/lukegb/goefivar/internal/zero"
+)
+
+// efivarfsPath is where Linux mounts efivarfs.
+const efivarfsPath = "/sys/firmware/efi/efivars"
+
+// EfivarfsPathEnv is the environment variable which, if set, gives the path
+// of efivarfs in place of /sys/firmware/efi/efivars. libefivar reads the
+// same variable.
+const EfivarfsPathEnv = "EFIVARFS_PATH"
+
+// efivarfs is the system reached by reading and writing the files of
+// efivarfs directly, as libefivar itself does on Linux. Each file holds the
+// variable's attributes, in host byte order, followed by its data.
+type efivarfs struct {
+	goSystem
+	path string
+}
+
+// newEfivarfs returns the efivarfs at path, or if path is empty at the path
+// given by EfivarfsPathEnv or the usual mount point.
+func newEfivarfs(path string) *efivarfs {
+	if path == "" {
+		path = os.Getenv(EfivarfsPathEnv)
+	}
+	if path == "" {
+		path = efivarfsPath
+	}
+	return &efivarfs{path: path}
+}
+
+func (fs *efivarfs) file(vn VariableName) string {
+	return filepath.Join(fs.path, vn.String())
+}
+
+func (fs *efivarfs) supported() bool {
+	fi, err := os.Stat(fs.path)
+	return err == nil && fi.IsDir()
+}
+
+func (fs *efivarfs) Exists(vn VariableName) (bool, error) {
+	_, err := os.Stat(fs.file(vn))
+	switch {
+	case err == nil:
+		return true, nil
+	case os.IsNotExist(err):
+		return false, nil
+	}
+	return false, err
+}
+
+func (fs *efivarfs) Get(vn VariableName) (*Variable, error) {
+	b, err := os.ReadFile(fs.file(vn))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < 4 {
+		return nil, fmt.Errorf("efivar: %v has no attributes", fs.file(vn))
+	}
+	return &Variable{
+		VariableName: vn,
+		Attributes:   Attributes(byteOrder.Uint32(b)),
+		Data:         b[4:],
+	}, nil
+}
+
+func (fs *efivarfs) Set(v *Variable) error { return fs.setMode(v, DefaultMode) }
+
+func (fs *efivarfs) setMode(v *Variable, mode os.FileMode) error {
+	if IsReadOnly() {
+		return ErrReadOnly
+	}
+	path := fs.file(v.VariableName)
+	flags := os.O_WRONLY | os.O_CREATE
+	switch {
+	case v.Attributes.Has(AppendWrite):
+		flags |= os.O_APPEND
+	case !isEfivarfs(fs.path):
+		// efivarfs replaces the whole variable with each write, but an
+		// ordinary file would keep the tail of longer old content.
+		flags |= os.O_TRUNC
+	}
+	setMutable(path)
+	f, err := os.OpenFile(path, flags, mode)
+	if err != nil {
+		return err
+	}
+	// efivarfs takes the attributes and the data in a single write.
+	b := make([]byte, 4+len(v.Data))
+	byteOrder.PutUint32(b, uint32(v.Attributes))
+	copy(b[4:], v.Data)
+	_, err = f.Write(b)
+	zero.Bytes(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		debugf(1, "efivarfs: writing %s with attributes %s: %v", path, v.Attributes, err)
+	}
+	return err
+}
+
+func (fs *efivarfs) Delete(vn VariableName) error {
+	if IsReadOnly() {
+		return ErrReadOnly
+	}
+	path := fs.file(vn)
+	setMutable(path)
+	err := os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		debugf(1, "efivarfs: removing %s: %v", path, err)
+	}
+	return err
+}
+
+func (fs *efivarfs) Variables() ([]VariableName, error) {
+	return collect(fs.WalkVariables)
+}
+
+func (fs *efivarfs) WalkVariables(fn func(vn VariableName) error) error {
+	return walkDir(fs.path, false, fn)
+}
+
+// goSystem provides the methods shared by the pure-Go systems.
+type goSystem struct{}
+
+func (goSystem) name() string { return ImplPureGo }
+
+func (goSystem) formatDevicePath(b []byte) (string, error) {
+	dp, err := efidp.Parse(b)
+	if err != nil {
+		return "", fmt.Errorf("efivar: formatting device path as string failed: %v", err)
+	}
+	return dp.String(), nil
+}
+
+func (goSystem) parseDevicePath(s string) ([]byte, error) {
+	return nil, NewError(Unsupported, fmt.Sprintf("efivar: parsing device path %q needs libefivar", s))
+}
+
+func (goSystem) setVerbose(level int) error { return nil }
@@ -0,0 +1,99 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestEfivarfs(t *testing.T) {
+	dir := t.TempDir()
+	fs := &efivarfs{path: dir}
+	if !fs.supported() {
+		t.Errorf("supported() = false for %v", dir)
+	}
+
+	v := &Variable{
+		VariableName: testVariable,
+		Attributes:   NonVolatile | BootserviceAccess | RuntimeAccess,
+		Data:         []byte("hello"),
+	}
+	if err := fs.setMode(v, 0644); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "LukegbEFIvarTest-74552304-ce9f-4e52-89a0-f6c6fa47deac"))
+	if want := []byte("\x07\x00\x00\x00hello"); err != nil || !bytes.Equal(b, want) {
+		t.Errorf("file = %q, %v; want %q", b, err, want)
+	}
+	if ok, err := fs.Exists(testVariable); !ok || err != nil {
+		t.Errorf("exists = %v, %v; want true", ok, err)
+	}
+	got, err := fs.Get(testVariable)
+	if err != nil || !reflect.DeepEqual(got, v) {
+		t.Errorf("get = %+v, %v; want %+v", got, err, v)
+	}
+
+	short := &Variable{VariableName: testVariable, Attributes: v.Attributes, Data: []byte("hi")}
+	if err := fs.setMode(short, 0644); err != nil {
+		t.Fatalf("set with shorter data: %v", err)
+	}
+	if got, err := fs.Get(testVariable); err != nil || string(got.Data) != "hi" {
+		t.Errorf("get after shorter set = %q, %v; want %q", got.Data, err, "hi")
+	}
+
+	app := &Variable{VariableName: testVariable, Attributes: v.Attributes | AppendWrite, Data: []byte(" world")}
+	if err := fs.setMode(app, 0644); err != nil {
+		t.Fatalf("set with AppendWrite: %v", err)
+	}
+	// A plain directory keeps the second attributes, where efivarfs would
+	// consume them.
+	if got, err := fs.Get(testVariable); err != nil || !bytes.HasSuffix(got.Data, []byte("hi\x47\x00\x00\x00 world")) {
+		t.Errorf("get after append = %q, %v", got.Data, err)
+	}
+
+	os.WriteFile(filepath.Join(dir, "not-a-variable"), nil, 0644)
+	os.Mkdir(filepath.Join(dir, "Dir-74552304-ce9f-4e52-89a0-f6c6fa47deac"), 0755)
+	if vns, err := fs.Variables(); err != nil || !reflect.DeepEqual(vns, []VariableName{testVariable}) {
+		t.Errorf("variables = %v, %v; want just %v", vns, err, testVariable)
+	}
+
+	if err := fs.Delete(testVariable); err != nil {
+		t.Errorf("delete: %v", err)
+	}
+	if _, err := fs.Get(testVariable); CodeOf(err) != NotFound {
+		t.Errorf("get after delete = %v; want NotFound", err)
+	}
+	if err := fs.Delete(testVariable); !os.IsNotExist(err) {
+		t.Errorf("second delete = %v; want not exist", err)
+	}
+}
+
+func TestEfivarfsDevicePath(t *testing.T) {
+	fs := &efivarfs{}
+	dp := []byte{0x02, 0x01, 0x0c, 0x00, 0xd0, 0x41, 0x03, 0x0a, 0x00, 0x00, 0x00, 0x00, 0x7f, 0xff, 0x04, 0x00}
+	if got, err := fs.formatDevicePath(dp); err != nil || got != "PciRoot(0x0)" {
+		t.Errorf("formatDevicePath = %q, %v; want PciRoot(0x0)", got, err)
+	}
+	if _, err := fs.formatDevicePath(dp[:6]); err == nil {
+		t.Errorf("formatDevicePath of a truncated path succeeded")
+	}
+	if _, err := fs.parseDevicePath("PciRoot(0x0)"); CodeOf(err) != Unsupported {
+		t.Errorf("parseDevicePath = %v; want Unsupported", err)
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import (
+	"os"
+	"syscall"
+)
+
+// efivarfsMagic is EFIVARFS_MAGIC, the f_type of a mounted efivarfs.
+const efivarfsMagic = 0xde5e81e4
+
+// defaultGoSystem returns efivarfs if it is mounted, or EfivarfsPathEnv
+// names it, and otherwise the legacy sysfs interface if the kernel has it.
+func defaultGoSystem() system {
+	fs := newEfivarfs("")
+	if os.Getenv(EfivarfsPathEnv) != "" {
+		return fs
+	}
+	if isEfivarfs(fs.path) {
+		return fs
+	}
+	if s := newSysfsVars(); s.supported() {
+		return s
+	}
+	return fs
+}
+
+// isEfivarfs reports whether path is on a mounted efivarfs.
+func isEfivarfs(path string) bool {
+	var st syscall.Statfs_t
+	return syscall.Statfs(path, &st) == nil && uint32(st.Type) == efivarfsMagic
+}
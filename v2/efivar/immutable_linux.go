@@ -0,0 +1,49 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// FS_IOC_GETFLAGS and FS_IOC_SETFLAGS are _IOR('f', 1, long) and
+// _IOW('f', 2, long) in the ioctl encoding of x86, ARM and RISC-V.
+const (
+	longSize        = unsafe.Sizeof(uintptr(0))
+	fsIocGetflags   = 2<<30 | longSize<<16 | 'f'<<8 | 1
+	fsIocSetflags   = 1<<30 | longSize<<16 | 'f'<<8 | 2
+	fsImmutableFlag = 0x00000010
+)
+
+// setMutable clears the immutable flag which the kernel sets on most files
+// in efivarfs, to guard against accidental deletion; libefivar does the same
+// before writing or deleting a variable. Errors are ignored: the file may
+// not exist yet, and the write or delete which follows reports any problem.
+func setMutable(path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	var flags int32
+	if _, _, e := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), fsIocGetflags, uintptr(unsafe.Pointer(&flags))); e != 0 || flags&fsImmutableFlag == 0 {
+		return
+	}
+	debugf(2, "efivarfs: clearing the immutable flag on %s", path)
+	flags &^= fsImmutableFlag
+	syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), fsIocSetflags, uintptr(unsafe.Pointer(&flags)))
+}
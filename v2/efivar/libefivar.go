@@ -0,0 +1,378 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build cgo && !purego && !darwin
+// +build cgo,!purego,!darwin
+
+package efivar
+
+// #cgo pkg-config: efivar
+// #include <efivar.h>
+// #include <stdio.h>
+// #include <stdlib.h>
+// #include <string.h>
+//
+// static FILE *goefivar_stderr(void) { return stderr; }
+import "C"
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"github.com/google/uuid"
+	"github.com/lukegb/goefivar/internal/liblock"
+)
+
+func defaultSystem() system {
+	if os.Getenv(ImplEnv) == ImplPureGo {
+		return defaultGoSystem()
+	}
+	return libefivar{}
+}
+
+// Libefivar returns a System which goes through libefivar, whatever ImplEnv
+// says.
+func Libefivar() (System, error) { return System{sys: libefivar{}}, nil }
+
+var globalUUID = C.EFI_GLOBAL_GUID
+
+// libefivar is the system reached through libefivar.
+type libefivar struct{}
+
+func (libefivar) name() string { return ImplLibefivar }
+
+func uuidToEFI(u uuid.UUID) C.efi_guid_t {
+	ret := C.efi_guid_t{
+		a: C.uint32_t(uuidByteOrder.Uint32(u[0:4])),
+		b: C.uint16_t(uuidByteOrder.Uint16(u[4:6])),
+		c: C.uint16_t(uuidByteOrder.Uint16(u[6:8])),
+		d: C.uint16_t(byteOrder.Uint16(u[8:10])),
+	}
+	for n, b := range u[10:16] {
+		ret.e[n] = C.uint8_t(b)
+	}
+	return ret
+}
+
+func efiGuidToStr(g C.efi_guid_t) (string, error) {
+	var p *C.char
+	liblock.Lock()
+	C.efi_error_clear()
+	ok := C.efi_guid_to_str(&g, &p)
+	trace := errorTrace(ok < 0)
+	liblock.Unlock()
+	if ok < 0 {
+		return "", withTrace(ErrSomethingWentWrong, trace)
+	}
+	defer C.free(unsafe.Pointer(p))
+	return C.GoString(p), nil
+}
+
+func efiToUUID(g C.efi_guid_t) uuid.UUID {
+	var ret uuid.UUID
+	uuidByteOrder.PutUint32(ret[0:4], uint32(g.a))
+	uuidByteOrder.PutUint16(ret[4:6], uint16(g.b))
+	uuidByteOrder.PutUint16(ret[6:8], uint16(g.c))
+	byteOrder.PutUint16(ret[8:10], uint16(g.d))
+	for n, b := range g.e {
+		ret[10+n] = byte(b)
+	}
+	return ret
+}
+
+func (libefivar) supported() bool {
+	liblock.Lock()
+	defer liblock.Unlock()
+	return C.efi_variables_supported() == 1
+}
+
+// nameAndGuid converts vn for passing to libefivar. The name is held in Go
+// memory rather than allocated with C.CString, which saves a malloc and free
+// on every call.
+func (vn VariableName) nameAndGuid() (*C.char, C.efi_guid_t) {
+	name := make([]byte, len(vn.Name)+1)
+	copy(name, vn.Name)
+	return (*C.char)(unsafe.Pointer(&name[0])), uuidToEFI(vn.GUID)
+}
+
+// bytesPtr returns a pointer to the start of b, which must not contain Go
+// pointers, for passing to C. It returns nil if b is empty.
+func bytesPtr(b []byte) *C.uint8_t {
+	if len(b) == 0 {
+		return nil
+	}
+	return (*C.uint8_t)(unsafe.Pointer(&b[0]))
+}
+
+func (libefivar) Exists(vn VariableName) (bool, error) {
+	name, guid := vn.nameAndGuid()
+	liblock.Lock()
+	C.efi_error_clear()
+	rc, err := C.efi_get_variable_exists(guid, name)
+	trace := errorTrace(rc != 0)
+	liblock.Unlock()
+	switch {
+	case rc == 0:
+		return true, nil
+	case os.IsNotExist(err):
+		return false, nil
+	}
+	return false, withTrace(err, trace)
+}
+
+func (libefivar) Get(vn VariableName) (*Variable, error) {
+	v := &Variable{
+		VariableName: vn,
+	}
+	name, guid := vn.nameAndGuid()
+	var data *C.uint8_t
+	var dataSize C.size_t
+	var attributes C.uint32_t
+	liblock.Lock()
+	C.efi_error_clear()
+	rc, err := C.efi_get_variable(guid, name, &data, &dataSize, &attributes)
+	trace := errorTrace(rc < 0)
+	liblock.Unlock()
+	if rc < 0 {
+		return nil, withTrace(err, trace)
+	}
+	defer func() {
+		// Variables such as MokAuth hold secrets: clear libefivar's
+		// copy rather than leave it in the C heap.
+		C.memset(unsafe.Pointer(data), 0, dataSize)
+		C.free(unsafe.Pointer(data))
+	}()
+	v.Data = C.GoBytes(unsafe.Pointer(data), C.int(dataSize))
+	v.Attributes = Attributes(attributes)
+	return v, nil
+}
+
+func (libefivar) Delete(vn VariableName) error {
+	if IsReadOnly() {
+		return ErrReadOnly
+	}
+	name, guid := vn.nameAndGuid()
+	liblock.Lock()
+	C.efi_error_clear()
+	rc, err := C.efi_del_variable(guid, name)
+	trace := errorTrace(rc < 0)
+	liblock.Unlock()
+	if rc < 0 {
+		return withTrace(err, trace)
+	}
+	return nil
+}
+
+func (l libefivar) Set(v *Variable) error { return l.setMode(v, DefaultMode) }
+
+func (libefivar) setMode(v *Variable, mode os.FileMode) error {
+	if IsReadOnly() {
+		return ErrReadOnly
+	}
+	name, guid := v.nameAndGuid()
+	dataSize := C.size_t(len(v.Data))
+	liblock.Lock()
+	C.efi_error_clear()
+	rc, err := C.efi_set_variable(guid, name, bytesPtr(v.Data), dataSize, C.uint32_t(v.Attributes), C.mode_t(mode))
+	trace := errorTrace(rc < 0)
+	liblock.Unlock()
+	if rc < 0 {
+		return withTrace(err, trace)
+	}
+	return nil
+}
+
+// listMu is held for the whole of a listing. efi_get_next_variable_name
+// keeps one iterator for the process, so two listings at once would each
+// see only part of the variables.
+var listMu sync.Mutex
+
+func (libefivar) Variables() ([]VariableName, error) {
+	listMu.Lock()
+	defer listMu.Unlock()
+	var guid *C.efi_guid_t
+	var name *C.char
+	var errno C.int
+	var out []VariableName
+	var trace []TraceEntry
+	next := func() C.int {
+		// guid and name point into libefivar's own buffers.
+		liblock.Lock()
+		defer liblock.Unlock()
+		C.efi_error_clear()
+		rc := C.efi_get_next_variable_name(&guid, &name, &errno)
+		if rc > 0 {
+			out = append(out, VariableName{GUID: efiToUUID(*guid), Name: C.GoString(name)})
+		}
+		trace = errorTrace(rc < 0)
+		return rc
+	}
+	rc := next()
+	for rc > 0 {
+		rc = next()
+	}
+	if rc < 0 {
+		return nil, withTrace(syscall.Errno(errno), trace)
+	}
+	return out, nil
+}
+
+// formatBuffers holds buffers for formatDevicePath, which formats most
+// device paths in a single call into one of them rather than first asking
+// libefivar for the length.
+var formatBuffers = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 512)
+		return &b
+	},
+}
+
+func (libefivar) formatDevicePath(b []byte) (string, error) {
+	bp := formatBuffers.Get().(*[]byte)
+	defer formatBuffers.Put(bp)
+
+	dp, dpSz := (C.const_efidp)(unsafe.Pointer(&b[0])), C.ssize_t(len(b))
+
+	liblock.Lock()
+	defer liblock.Unlock()
+	C.efi_error_clear()
+
+	buf := *bp
+	sz := C.efidp_format_device_path((*C.char)(unsafe.Pointer(&buf[0])), C.size_t(len(buf)), dp, dpSz)
+	if sz <= 0 || int(sz) > len(buf) {
+		// Too long for the buffer, or this libefivar will not format
+		// into a short one: find the length, and try again.
+		sz = C.efidp_format_device_path(nil, 0, dp, dpSz)
+		if sz <= 0 {
+			return "", wrapTrace(fmt.Errorf("efivar: getting device path string length failed"), errorTrace(true))
+		}
+		buf = make([]byte, sz)
+		if rc := C.efidp_format_device_path((*C.char)(unsafe.Pointer(&buf[0])), C.size_t(sz), dp, dpSz); rc < 0 {
+			return "", wrapTrace(fmt.Errorf("efivar: formatting device path as string failed"), errorTrace(true))
+		}
+		if cap(buf) > cap(*bp) {
+			*bp = buf
+		}
+	}
+	return string(buf[:sz-1]), nil
+}
+
+func (libefivar) parseDevicePath(s string) ([]byte, error) {
+	cs := C.CString(s)
+	defer C.free(unsafe.Pointer(cs))
+
+	liblock.Lock()
+	defer liblock.Unlock()
+	C.efi_error_clear()
+
+	sz := C.efidp_parse_device_path(cs, nil, 0)
+	if sz <= 0 {
+		return nil, wrapTrace(fmt.Errorf("efivar: parsing device path %q failed", s), errorTrace(true))
+	}
+
+	buf := C.malloc(C.size_t(sz))
+	defer C.free(buf)
+	if rc := C.efidp_parse_device_path(cs, (C.efidp)(buf), C.size_t(sz)); rc < 0 {
+		return nil, wrapTrace(fmt.Errorf("efivar: parsing device path %q failed", s), errorTrace(true))
+	}
+	return C.GoBytes(buf, C.int(sz)), nil
+}
+
+// errorTrace returns the trace libefivar recorded of the call just made, if
+// it failed, and clears it. The caller must hold liblock.
+func errorTrace(failed bool) []TraceEntry {
+	if !failed {
+		return nil
+	}
+	var trace []TraceEntry
+	for n := C.uint(0); ; n++ {
+		var file, function, message *C.char
+		var line, errno C.int
+		if C.efi_error_get(n, &file, &function, &line, &message, &errno) <= 0 {
+			break
+		}
+		trace = append(trace, TraceEntry{
+			File:     C.GoString(file),
+			Function: C.GoString(function),
+			Line:     int(line),
+			Message:  C.GoString(message),
+			Errno:    syscall.Errno(errno),
+		})
+	}
+	C.efi_error_clear()
+	return trace
+}
+
+// logFile is the write end of a pipe which libefivar writes its verbose
+// output to while debugging is on, and logFd its descriptor. readVerbose
+// reads the other end.
+var (
+	logMu   sync.Mutex
+	logFile *C.FILE
+	logFd   int
+)
+
+func (libefivar) setVerbose(level int) error {
+	logMu.Lock()
+	defer logMu.Unlock()
+	if level > 0 && logFile == nil {
+		var p [2]int
+		if err := syscall.Pipe(p[:]); err != nil {
+			return err
+		}
+		syscall.CloseOnExec(p[0])
+		syscall.CloseOnExec(p[1])
+		mode := C.CString("w")
+		f, err := C.fdopen(C.int(p[1]), mode)
+		C.free(unsafe.Pointer(mode))
+		if f == nil {
+			syscall.Close(p[0])
+			syscall.Close(p[1])
+			return err
+		}
+		C.setvbuf(f, nil, C._IOLBF, 0)
+		logFile, logFd = f, p[1]
+		go readVerbose(os.NewFile(uintptr(p[0]), "libefivar log"))
+	}
+
+	liblock.Lock()
+	if level > 0 {
+		C.efi_set_verbose(C.int(level), logFile)
+	} else {
+		// libefivar keeps its previous file when given NULL, so point it
+		// back at stderr before closing the pipe.
+		C.efi_set_verbose(0, C.goefivar_stderr())
+	}
+	liblock.Unlock()
+
+	if level == 0 && logFile != nil {
+		C.fclose(logFile)
+		logFile = nil
+	}
+	return nil
+}
+
+// readVerbose passes the lines libefivar writes to r to debugf, until the
+// write end is closed.
+func readVerbose(r *os.File) {
+	defer r.Close()
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		debugf(1, "libefivar: %s", s.Text())
+	}
+}
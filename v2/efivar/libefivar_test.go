@@ -0,0 +1,142 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build cgo && !purego && !darwin
+// +build cgo,!purego,!darwin
+
+package efivar
+
+import (
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestUUIDToEFIRoundtrip(t *testing.T) {
+	u := uuid.MustParse("84be9c3e-8a32-42c0-891c-4cd3b072becc")
+	got := efiToUUID(uuidToEFI(u))
+	if got != u {
+		t.Errorf("efiToUUID(uuidToEFI(%q)) = %q; want %q", u, got, u)
+	}
+
+}
+
+func TestUUIDToEFIString(t *testing.T) {
+	u := uuid.MustParse("84be9c3e-8a32-42c0-891c-4cd3b072becc")
+	got, err := efiGuidToStr(uuidToEFI(u))
+	if err != nil {
+		t.Fatalf("efiGuidToStr: %v", err)
+	}
+	if want := u.String(); got != want {
+		t.Errorf("efiGuidToStr(uuidToEFI(%q)) = %v; want %v", u, got, want)
+	}
+}
+
+func TestEFIToUUID(t *testing.T) {
+	got := efiToUUID(globalUUID)
+	want, err := efiGuidToStr(globalUUID)
+	if err != nil {
+		t.Fatalf("efiGuidToStr: %v", err)
+	}
+	if got.String() != want {
+		t.Errorf("efiToUUID(globalUUID) = %v; want %v", got, want)
+	}
+}
+
+func TestGlobalUUID(t *testing.T) {
+	if got := efiToUUID(globalUUID); got != GlobalUUID {
+		t.Errorf("EFI_GLOBAL_GUID = %v; GlobalUUID = %v", got, GlobalUUID)
+	}
+}
+
+func TestVerboseBridge(t *testing.T) {
+	if Implementation() != ImplLibefivar {
+		t.Skip("not using libefivar")
+	}
+	lines := make(chan string, 1)
+	if err := SetDebugLog(1, func(level int, msg string) { lines <- msg }); err != nil {
+		t.Fatalf("SetDebugLog: %v", err)
+	}
+	defer SetDebugLog(0, nil)
+
+	// Write as libefivar would.
+	logMu.Lock()
+	_, err := syscall.Write(logFd, []byte("set_variable failed\n"))
+	logMu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-lines:
+		if want := "libefivar: set_variable failed"; got != want {
+			t.Errorf("logged %q, want %q", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("nothing logged")
+	}
+}
+
+func TestErrorTrace(t *testing.T) {
+	if Implementation() != ImplLibefivar {
+		t.Skip("not using libefivar")
+	}
+	var logged []string
+	SetDebugLog(1, func(level int, msg string) { logged = append(logged, msg) })
+	defer SetDebugLog(0, nil)
+
+	// The test library fails to set this variable with EIO, and a trace.
+	v := &Variable{VariableName: VariableName{GUID: GlobalUUID, Name: "GoefivarTrace"}, Data: []byte{1}}
+	err := libefivar{}.Set(v)
+	te, ok := err.(*TraceError)
+	if !ok {
+		t.Fatalf("Set = %#v, want a *TraceError", err)
+	}
+	if te.Unwrap() != syscall.EIO || len(te.Trace) != 2 {
+		t.Errorf("Set = %#v, want EIO with two trace entries", te)
+	}
+	want := TraceEntry{File: "vars.c", Function: "vars_set_variable", Line: 42, Message: "writing variable failed", Errno: syscall.EIO}
+	if te.Trace[0] != want {
+		t.Errorf("first trace entry = %+v, want %+v", te.Trace[0], want)
+	}
+	if len(logged) != 2 {
+		t.Errorf("logged %q, want the two trace entries", logged)
+	}
+}
+
+// TestConcurrentVariables checks that listings made at once each see every
+// variable, though libefivar has one iterator for them all.
+func TestConcurrentVariables(t *testing.T) {
+	want, err := libefivar{}.Variables()
+	if err != nil {
+		t.Fatalf("Variables: %v", err)
+	}
+	var wg sync.WaitGroup
+	for n := 0; n < 8; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				got, err := libefivar{}.Variables()
+				if err != nil || len(got) != len(want) {
+					t.Errorf("Variables = %d variables, %v; want %d", len(got), err, len(want))
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
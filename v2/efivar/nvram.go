@@ -0,0 +1,186 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// nvramToolPath is macOS's command for reading and writing NVRAM through
+// IOKit's IODeviceTree:/options entry.
+const nvramToolPath = "/usr/sbin/nvram"
+
+// AppleNVRAMUUID is the GUID of macOS's own NVRAM variables, such as
+// boot-args, which the nvram command lists without a GUID.
+var AppleNVRAMUUID = uuid.MustParse("7c436110-ab2a-4bbb-a880-fe41995c9f82")
+
+// nvramAttributes are reported for every macOS variable, since IOKit does
+// not say what a variable's attributes are.
+const nvramAttributes = NonVolatile | BootserviceAccess | RuntimeAccess
+
+// nvram is the system reached through macOS's nvram command, which reads
+// NVRAM through IOKit. It can only read: the nvram command cannot set a
+// variable's attributes.
+type nvram struct {
+	goSystem
+	// list returns the XML property list printed by nvram -x -p.
+	list func() ([]byte, error)
+}
+
+func newNVRAM() *nvram {
+	return &nvram{list: func() ([]byte, error) {
+		return exec.Command(nvramToolPath, "-x", "-p").Output()
+	}}
+}
+
+// supported reports whether nvram works on an Intel Mac. Macs with Apple
+// silicon boot with iBoot rather than EFI, so have no EFI variables, though
+// nvram still lists their NVRAM.
+func (n *nvram) supported() bool {
+	if runtime.GOOS == "darwin" && runtime.GOARCH == "arm64" {
+		return false
+	}
+	_, err := n.list()
+	return err == nil
+}
+
+func (n *nvram) all() (map[VariableName][]byte, error) {
+	b, err := n.list()
+	if err != nil {
+		return nil, err
+	}
+	return parseNVRAMPlist(b)
+}
+
+func (n *nvram) Exists(vn VariableName) (bool, error) {
+	vs, err := n.all()
+	if err != nil {
+		return false, err
+	}
+	_, ok := vs[vn]
+	return ok, nil
+}
+
+func (n *nvram) Get(vn VariableName) (*Variable, error) {
+	vs, err := n.all()
+	if err != nil {
+		return nil, err
+	}
+	data, ok := vs[vn]
+	if !ok {
+		return nil, NotExist("get", vn)
+	}
+	return &Variable{VariableName: vn, Data: data, Attributes: nvramAttributes}, nil
+}
+
+func (n *nvram) Variables() ([]VariableName, error) {
+	vs, err := n.all()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]VariableName, 0, len(vs))
+	for vn := range vs {
+		out = append(out, vn)
+	}
+	return out, nil
+}
+
+func (n *nvram) Set(v *Variable) error { return n.setMode(v, DefaultMode) }
+
+func (n *nvram) setMode(v *Variable, mode os.FileMode) error {
+	return NewError(Unsupported, fmt.Sprintf("efivar: setting %s needs libefivar or efivarfs", v.VariableName))
+}
+
+func (n *nvram) Delete(vn VariableName) error {
+	return NewError(Unsupported, fmt.Sprintf("efivar: deleting %s needs libefivar or efivarfs", vn))
+}
+
+// parseNVRAMKey splits a key printed by nvram, GUID:NAME for variables
+// outside AppleNVRAMUUID and NAME for those within it.
+func parseNVRAMKey(key string) VariableName {
+	if len(key) > 37 && key[36] == ':' {
+		if u, err := uuid.Parse(key[:36]); err == nil {
+			return VariableName{GUID: u, Name: key[37:]}
+		}
+	}
+	return VariableName{GUID: AppleNVRAMUUID, Name: key}
+}
+
+// parseNVRAMPlist decodes the dictionary printed by nvram -x -p. Values are
+// usually data, but nvram prints some as strings, numbers or booleans;
+// these are given as their text.
+func parseNVRAMPlist(b []byte) (map[VariableName][]byte, error) {
+	d := xml.NewDecoder(bytes.NewReader(b))
+	vs := make(map[VariableName][]byte)
+	var (
+		depth int
+		key   *string
+	)
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, fmt.Errorf("efivar: reading nvram output: %v", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			// The variables are the children of the top-level dict,
+			// at depth 3 inside plist and dict.
+			if depth != 3 {
+				continue
+			}
+			var text string
+			if err := d.DecodeElement(&text, &t); err != nil {
+				return nil, fmt.Errorf("efivar: reading nvram output: %v", err)
+			}
+			depth--
+			if t.Name.Local == "key" {
+				key = &text
+				continue
+			}
+			if key == nil {
+				return nil, fmt.Errorf("efivar: reading nvram output: %s without a key", t.Name.Local)
+			}
+			var data []byte
+			switch t.Name.Local {
+			case "data":
+				data, err = base64.StdEncoding.DecodeString(strings.Join(strings.Fields(text), ""))
+				if err != nil {
+					return nil, fmt.Errorf("efivar: reading nvram output for %s: %v", *key, err)
+				}
+			case "true", "false":
+				data = []byte(t.Name.Local)
+			default:
+				data = []byte(text)
+			}
+			vs[parseNVRAMKey(*key)] = data
+			key = nil
+		case xml.EndElement:
+			depth--
+			if depth == 0 {
+				return vs, nil
+			}
+		}
+	}
+}
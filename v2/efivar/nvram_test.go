@@ -0,0 +1,80 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// nvramOutput is trimmed from nvram -x -p on an Intel Mac.
+const nvramOutput = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>8BE4DF61-93CA-11D2-AA0D-00E098032B8C:BootOrder</key>
+	<data>
+	gAA=
+	</data>
+	<key>8BE4DF61-93CA-11D2-AA0D-00E098032B8C:BootCurrent</key>
+	<data>gAA=</data>
+	<key>boot-args</key>
+	<string>-v</string>
+	<key>csr-active-config</key>
+	<data>dwAAAA==</data>
+</dict>
+</plist>
+`
+
+func TestNVRAM(t *testing.T) {
+	n := &nvram{list: func() ([]byte, error) { return []byte(nvramOutput), nil }}
+	bootOrder := VariableName{GUID: GlobalUUID, Name: "BootOrder"}
+	bootArgs := VariableName{GUID: AppleNVRAMUUID, Name: "boot-args"}
+
+	v, err := n.Get(bootOrder)
+	if want := (&Variable{VariableName: bootOrder, Data: []byte{0x80, 0}, Attributes: nvramAttributes}); err != nil || !reflect.DeepEqual(v, want) {
+		t.Errorf("Get(BootOrder) = %+v, %v; want %+v", v, err, want)
+	}
+	if v, err := n.Get(bootArgs); err != nil || string(v.Data) != "-v" {
+		t.Errorf("Get(boot-args) = %+v, %v; want -v", v, err)
+	}
+	if _, err := n.Get(VariableName{GUID: GlobalUUID, Name: "BootNext"}); CodeOf(err) != NotFound {
+		t.Errorf("Get(BootNext) = %v, want NotFound", err)
+	}
+
+	vns, err := n.Variables()
+	if err != nil {
+		t.Fatalf("Variables: %v", err)
+	}
+	var names []string
+	for _, vn := range vns {
+		names = append(names, vn.Name)
+	}
+	sort.Strings(names)
+	if want := []string{"BootCurrent", "BootOrder", "boot-args", "csr-active-config"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Variables = %v, want %v", names, want)
+	}
+
+	if err := n.Set(v); CodeOf(err) != Unsupported {
+		t.Errorf("Set = %v, want Unsupported", err)
+	}
+
+	n.list = func() ([]byte, error) { return nil, errors.New("no nvram") }
+	if n.supported() {
+		t.Error("supported() = true when nvram fails")
+	}
+}
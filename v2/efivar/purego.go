@@ -0,0 +1,29 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !cgo || purego || darwin
+// +build !cgo purego darwin
+
+package efivar
+
+func defaultSystem() system {
+	return defaultGoSystem()
+}
+
+// Libefivar returns a System which goes through libefivar, whatever ImplEnv
+// says. This program was built without it, so Libefivar fails with an
+// Unsupported error.
+func Libefivar() (System, error) {
+	return System{}, NewError(Unsupported, "efivar: built without libefivar")
+}
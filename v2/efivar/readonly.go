@@ -0,0 +1,62 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import (
+	"context"
+	"os"
+	"sync/atomic"
+)
+
+// ReadOnlyEnv is the environment variable which, set to "1" when the
+// program starts, puts this package in read-only mode.
+const ReadOnlyEnv = "GOEFIVAR_READONLY"
+
+// ErrReadOnly is the cause of every change refused in read-only mode or by a
+// ReadOnly Backend.
+var ErrReadOnly = NewError(Permission, "efivar: read-only mode")
+
+// readOnly is 1 in read-only mode.
+var readOnly int32
+
+func init() {
+	if buildReadOnly || os.Getenv(ReadOnlyEnv) == "1" {
+		SetReadOnly()
+	}
+}
+
+// SetReadOnly makes every later Set and Delete through System, or through
+// version 1 of this package, fail with ErrReadOnly. It cannot be undone, so
+// a monitoring or inventory tool can call it first thing and be sure it
+// never changes NVRAM. Building with the goefivar_readonly tag does the
+// same from the start.
+func SetReadOnly() {
+	atomic.StoreInt32(&readOnly, 1)
+}
+
+// IsReadOnly reports whether this package is in read-only mode.
+func IsReadOnly() bool {
+	return atomic.LoadInt32(&readOnly) != 0
+}
+
+// ReadOnly returns a Backend which reads from b and refuses every change
+// with ErrReadOnly, without calling b. It does not affect other Backends or
+// read-only mode.
+func ReadOnly(b Backend) Backend { return readOnlyBackend{b} }
+
+type readOnlyBackend struct{ Backend }
+
+func (readOnlyBackend) Set(ctx context.Context, v *Variable) error        { return ErrReadOnly }
+func (readOnlyBackend) Delete(ctx context.Context, vn VariableName) error { return ErrReadOnly }
@@ -0,0 +1,56 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import (
+	"context"
+	"os"
+	"sync/atomic"
+	"testing"
+)
+
+func TestReadOnlyMode(t *testing.T) {
+	dir := t.TempDir()
+	SetReadOnly()
+	defer atomic.StoreInt32(&readOnly, 0)
+	if !IsReadOnly() {
+		t.Error("IsReadOnly() = false after SetReadOnly")
+	}
+	if c := CodeOf(ErrReadOnly); c != Permission {
+		t.Errorf("CodeOf(ErrReadOnly) = %v, want %v", c, Permission)
+	}
+
+	systems := map[string]System{
+		"System":   {},
+		"Efivarfs": Efivarfs(dir),
+	}
+	if s, err := Libefivar(); err == nil {
+		systems["Libefivar"] = s
+	}
+	ctx := context.Background()
+	vn := VariableName{GUID: GlobalUUID, Name: "BootNext"}
+	v := &Variable{VariableName: vn, Data: []byte{1, 0}, Attributes: BootserviceAccess | RuntimeAccess}
+	for name, s := range systems {
+		if err := s.Set(ctx, v); err != ErrReadOnly {
+			t.Errorf("%s: Set = %v, want %v", name, err, ErrReadOnly)
+		}
+		if err := s.Delete(ctx, vn); err != ErrReadOnly {
+			t.Errorf("%s: Delete = %v, want %v", name, err, ErrReadOnly)
+		}
+	}
+	if des, err := os.ReadDir(dir); err != nil || len(des) != 0 {
+		t.Errorf("Efivarfs wrote %d files in read-only mode, %v", len(des), err)
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"unicode/utf16"
+	"unsafe"
+)
+
+// sysfsVarsPath is where kernels before efivarfs, and those built without
+// it, present variables.
+const sysfsVarsPath = "/sys/firmware/efi/vars"
+
+// Sizes in struct efi_variable, which the kernel packs.
+const (
+	sysfsNameSize = 1024
+	sysfsDataSize = 1024
+	sysfsLongSize = int(unsafe.Sizeof(uintptr(0)))
+	// sysfsVarSize is sizeof(struct efi_variable): the name, the GUID,
+	// DataSize, the data, Status and Attributes.
+	sysfsVarSize = sysfsNameSize + 16 + sysfsLongSize + sysfsDataSize + sysfsLongSize + 4
+)
+
+// sysfsVars is the system reached through the legacy sysfs interface. Each
+// variable is a directory, NAME-GUID, whose raw_var file holds a struct
+// efi_variable; writing one to new_var creates a variable, and to del_var
+// deletes one. It holds at most 1024 bytes of data in each variable.
+type sysfsVars struct {
+	goSystem
+	path string
+}
+
+func newSysfsVars() *sysfsVars {
+	return &sysfsVars{path: sysfsVarsPath}
+}
+
+func (s *sysfsVars) dir(vn VariableName) string {
+	return filepath.Join(s.path, vn.String())
+}
+
+func (s *sysfsVars) supported() bool {
+	_, err := os.Stat(filepath.Join(s.path, "new_var"))
+	return err == nil
+}
+
+func (s *sysfsVars) Exists(vn VariableName) (bool, error) {
+	_, err := os.Stat(s.dir(vn))
+	switch {
+	case err == nil:
+		return true, nil
+	case os.IsNotExist(err):
+		return false, nil
+	}
+	return false, err
+}
+
+func (s *sysfsVars) Get(vn VariableName) (*Variable, error) {
+	b, err := os.ReadFile(filepath.Join(s.dir(vn), "raw_var"))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != sysfsVarSize {
+		return nil, fmt.Errorf("efivar: raw_var of %s is %d bytes, want %d", vn, len(b), sysfsVarSize)
+	}
+	off := sysfsNameSize + 16
+	size := int(sysfsLong(b[off:]))
+	off += sysfsLongSize
+	if size > sysfsDataSize {
+		return nil, fmt.Errorf("efivar: raw_var of %s claims %d bytes of data", vn, size)
+	}
+	data := append([]byte(nil), b[off:off+size]...)
+	off += sysfsDataSize + sysfsLongSize
+	return &Variable{
+		VariableName: vn,
+		Data:         data,
+		Attributes:   Attributes(byteOrder.Uint32(b[off:])),
+	}, nil
+}
+
+func sysfsLong(b []byte) uint64 {
+	if sysfsLongSize == 4 {
+		return uint64(byteOrder.Uint32(b))
+	}
+	return byteOrder.Uint64(b)
+}
+
+func putSysfsLong(b []byte, v uint64) {
+	if sysfsLongSize == 4 {
+		byteOrder.PutUint32(b, uint32(v))
+		return
+	}
+	byteOrder.PutUint64(b, v)
+}
+
+// encodeSysfsVar returns v as a struct efi_variable.
+func encodeSysfsVar(v *Variable) ([]byte, error) {
+	name := append(utf16.Encode([]rune(v.Name)), 0)
+	if 2*len(name) > sysfsNameSize {
+		return nil, fmt.Errorf("efivar: name of %s is too long for sysfs", v.VariableName)
+	}
+	if len(v.Data) > sysfsDataSize {
+		return nil, NewError(NVRAMFull, fmt.Sprintf("efivar: %s has %d bytes of data; sysfs holds at most %d", v.VariableName, len(v.Data), sysfsDataSize))
+	}
+	b := make([]byte, sysfsVarSize)
+	for i, c := range name {
+		byteOrder.PutUint16(b[2*i:], c)
+	}
+	off := sysfsNameSize
+	copy(b[off:], GUIDBytes(v.GUID))
+	off += 16
+	putSysfsLong(b[off:], uint64(len(v.Data)))
+	off += sysfsLongSize
+	copy(b[off:], v.Data)
+	off += sysfsDataSize + sysfsLongSize
+	byteOrder.PutUint32(b[off:], uint32(v.Attributes))
+	return b, nil
+}
+
+func (s *sysfsVars) write(file string, v *Variable) error {
+	b, err := encodeSysfsVar(v)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(file, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (s *sysfsVars) Set(v *Variable) error { return s.setMode(v, DefaultMode) }
+
+// setMode ignores mode: the kernel decides the permissions of sysfs files.
+// The interface cannot append, so an AppendWrite is made by rewriting the
+// variable, as libefivar does.
+func (s *sysfsVars) setMode(v *Variable, mode os.FileMode) error {
+	if IsReadOnly() {
+		return ErrReadOnly
+	}
+	old, err := s.Get(v.VariableName)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if v.Attributes.Has(AppendWrite) && old != nil {
+		v = &Variable{
+			VariableName: v.VariableName,
+			Data:         append(old.Data, v.Data...),
+			Attributes:   v.Attributes.Without(AppendWrite),
+		}
+	}
+	if old != nil {
+		return s.write(filepath.Join(s.dir(v.VariableName), "raw_var"), v)
+	}
+	return s.write(filepath.Join(s.path, "new_var"), v)
+}
+
+func (s *sysfsVars) Delete(vn VariableName) error {
+	if IsReadOnly() {
+		return ErrReadOnly
+	}
+	v, err := s.Get(vn)
+	if err != nil {
+		return err
+	}
+	return s.write(filepath.Join(s.path, "del_var"), v)
+}
+
+func (s *sysfsVars) Variables() ([]VariableName, error) {
+	return collect(s.WalkVariables)
+}
+
+func (s *sysfsVars) WalkVariables(fn func(vn VariableName) error) error {
+	return walkDir(s.path, true, fn)
+}
@@ -0,0 +1,100 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSysfsVars(t *testing.T) {
+	dir := t.TempDir()
+	s := &sysfsVars{path: dir}
+	if s.supported() {
+		t.Error("supported() = true without new_var")
+	}
+	for _, f := range []string{"new_var", "del_var"} {
+		if err := os.WriteFile(filepath.Join(dir, f), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !s.supported() {
+		t.Error("supported() = false with new_var")
+	}
+
+	// Present a variable as the kernel would.
+	v := &Variable{
+		VariableName: testVariable,
+		Attributes:   NonVolatile | BootserviceAccess | RuntimeAccess,
+		Data:         []byte("hello"),
+	}
+	raw, err := encodeSysfsVar(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(raw) != sysfsVarSize {
+		t.Fatalf("encoded %d bytes, want %d", len(raw), sysfsVarSize)
+	}
+	if err := os.Mkdir(s.dir(testVariable), 0700); err != nil {
+		t.Fatal(err)
+	}
+	rawVar := filepath.Join(s.dir(testVariable), "raw_var")
+	if err := os.WriteFile(rawVar, raw, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := s.Get(testVariable); err != nil || !reflect.DeepEqual(got, v) {
+		t.Errorf("Get = %+v, %v; want %+v", got, err, v)
+	}
+	if ok, err := s.Exists(testVariable); !ok || err != nil {
+		t.Errorf("Exists = %v, %v; want true, nil", ok, err)
+	}
+	if vns, err := s.Variables(); err != nil || !reflect.DeepEqual(vns, []VariableName{testVariable}) {
+		t.Errorf("Variables = %v, %v; want [%v]", vns, err, testVariable)
+	}
+
+	// Appending rewrites raw_var with the whole of the data.
+	if err := s.Set(&Variable{VariableName: testVariable, Attributes: v.Attributes | AppendWrite, Data: []byte(" world")}); err != nil {
+		t.Fatalf("Set with AppendWrite: %v", err)
+	}
+	if got, err := s.Get(testVariable); err != nil || string(got.Data) != "hello world" || got.Attributes != v.Attributes {
+		t.Errorf("Get after append = %+v, %v; want hello world", got, err)
+	}
+
+	if err := s.Delete(testVariable); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	del, _ := os.ReadFile(filepath.Join(dir, "del_var"))
+	if want, _ := os.ReadFile(rawVar); !bytes.Equal(del, want) {
+		t.Error("Delete did not write the variable to del_var")
+	}
+
+	other := &Variable{VariableName: VariableName{GUID: testVariable.GUID, Name: "Other"}, Data: []byte{1}}
+	if err := s.Set(other); err != nil {
+		t.Fatalf("Set of a new variable: %v", err)
+	}
+	want, _ := encodeSysfsVar(other)
+	if got, _ := os.ReadFile(filepath.Join(dir, "new_var")); !bytes.Equal(got, want) {
+		t.Error("Set of a new variable did not write it to new_var")
+	}
+
+	big := &Variable{VariableName: testVariable, Data: make([]byte, sysfsDataSize+1)}
+	if err := s.Set(big); CodeOf(err) != NVRAMFull {
+		t.Errorf("Set of %d bytes = %v, want NVRAMFull", len(big.Data), err)
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// Names of the implementations of the running system's variables, as
+// returned by Implementation.
+const (
+	// ImplLibefivar goes through libefivar and libefiboot, and formats
+	// device paths exactly as efibootmgr does. It needs cgo.
+	ImplLibefivar = "libefivar"
+	// ImplPureGo reads and writes efivarfs directly, or /dev/efi on
+	// FreeBSD, or on macOS reads NVRAM with the nvram command, and
+	// handles device paths and load options with packages efidp and
+	// loadopt. It needs no C libraries, but ParseDevicePath is unsupported.
+	ImplPureGo = "purego"
+)
+
+// ImplEnv is the environment variable which, set to ImplPureGo, makes a
+// program built with libefivar use the pure-Go implementation instead.
+const ImplEnv = "GOEFIVAR_IMPL"
+
+// DefaultMode is the permission given to the efivarfs files of variables
+// created through System when its Mode is zero.
+const DefaultMode os.FileMode = 0644
+
+// system is an implementation of the running system's variables, which also
+// formats device paths. Its methods are not interruptible; System calls
+// them through withContext.
+type system interface {
+	Exists(vn VariableName) (bool, error)
+	// Get returns the variable vn, or an error satisfying os.IsNotExist.
+	Get(vn VariableName) (*Variable, error)
+	// Set is setMode with DefaultMode.
+	Set(v *Variable) error
+	Delete(vn VariableName) error
+	Variables() ([]VariableName, error)
+	name() string
+	supported() bool
+	// setMode is Set, giving a new variable's efivarfs file the
+	// permission mode.
+	setMode(v *Variable, mode os.FileMode) error
+	formatDevicePath(dp []byte) (string, error)
+	parseDevicePath(s string) ([]byte, error)
+	// setVerbose sets how much debug output the implementation's C
+	// libraries write to debugf, if it has any.
+	setVerbose(level int) error
+}
+
+// sys is chosen once, when the program starts: libefivar if the program was
+// built with cgo and without the purego tag, unless ImplEnv asks otherwise.
+var sys = defaultSystem()
+
+// System is the Backend of the running system's variables, through the
+// implementation reported by Implementation, or of the efivarfs or
+// libefivar given to Efivarfs or Libefivar. Neither efivarfs nor libefivar
+// can be interrupted, so when ctx is done before an operation finishes
+// System returns ctx.Err() and leaves the operation to complete in the
+// background; a change may still be made after Set or Delete returns.
+//
+// In read-only mode, System refuses every change with ErrReadOnly.
+type System struct {
+	// Mode is the permission given to the efivarfs files of new
+	// variables. If it is zero, DefaultMode is used.
+	Mode os.FileMode
+
+	// sys is the implementation, or nil for the running system's.
+	sys system
+}
+
+// Efivarfs returns a System which reads and writes the efivarfs mounted at
+// path directly, whether or not the program was built with libefivar. The
+// path may be anywhere, such as in a chroot or a test directory; if it is
+// empty, Efivarfs uses EfivarfsPathEnv or /sys/firmware/efi/efivars.
+func Efivarfs(path string) System { return System{sys: newEfivarfs(path)} }
+
+func (s System) system() system {
+	if s.sys == nil {
+		return sys
+	}
+	return s.sys
+}
+
+// Supported reports whether s's variables can be used on this system.
+func (s System) Supported() bool {
+	return s.system().supported()
+}
+
+// Get returns the variable vn.
+func (s System) Get(ctx context.Context, vn VariableName) (*Variable, error) {
+	r, err := withContext(ctx, func() (interface{}, error) { return s.system().Get(vn) })
+	if err != nil {
+		return nil, err
+	}
+	return r.(*Variable), nil
+}
+
+// Exists reports whether vn is set.
+func (s System) Exists(ctx context.Context, vn VariableName) (bool, error) {
+	r, err := withContext(ctx, func() (interface{}, error) { return s.system().Exists(vn) })
+	ok, _ := r.(bool)
+	return ok, err
+}
+
+// Set writes v, creating any efivarfs file with s.Mode.
+func (s System) Set(ctx context.Context, v *Variable) error {
+	mode := s.Mode
+	if mode == 0 {
+		mode = DefaultMode
+	}
+	_, err := withContext(ctx, func() (interface{}, error) { return nil, s.system().setMode(v, mode) })
+	return err
+}
+
+// Delete removes vn.
+func (s System) Delete(ctx context.Context, vn VariableName) error {
+	_, err := withContext(ctx, func() (interface{}, error) { return nil, s.system().Delete(vn) })
+	return err
+}
+
+// Variables lists every variable.
+func (s System) Variables(ctx context.Context) ([]VariableName, error) {
+	r, err := withContext(ctx, func() (interface{}, error) { return s.system().Variables() })
+	if err != nil {
+		return nil, err
+	}
+	return r.([]VariableName), nil
+}
+
+// WalkVariables calls fn for each variable, as Client.WalkVariables does.
+// efivarfs is read as fn goes, and ctx is checked before each call of fn;
+// the other implementations list every variable first.
+func (s System) WalkVariables(ctx context.Context, fn func(vn VariableName) error) error {
+	w, ok := s.system().(interface {
+		WalkVariables(fn func(vn VariableName) error) error
+	})
+	if !ok {
+		return walkList(ctx, s, fn)
+	}
+	return w.WalkVariables(func(vn VariableName) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return fn(vn)
+	})
+}
+
+// Implementation returns ImplLibefivar or ImplPureGo, saying which
+// implementation this program uses for the running system's variables.
+// Package efiboot follows the same choice for load options.
+func Implementation() string {
+	return sys.name()
+}
+
+// FormatDevicePath formats the binary device path dp in the text form used
+// by the UEFI specification and efibootmgr.
+func FormatDevicePath(dp []byte) (string, error) {
+	if len(dp) == 0 {
+		return "", fmt.Errorf("efivar: empty device path")
+	}
+	return sys.formatDevicePath(dp)
+}
+
+// ParseDevicePath converts the textual form of a device path, as returned by
+// FormatDevicePath, back to its binary form.
+func ParseDevicePath(s string) ([]byte, error) {
+	return sys.parseDevicePath(s)
+}
@@ -0,0 +1,112 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSystem(t *testing.T) {
+	dir := t.TempDir()
+	s := Efivarfs(dir)
+	s.Mode = 0600
+	if !s.Supported() {
+		t.Errorf("Supported() = false for %v", dir)
+	}
+	ctx := context.Background()
+
+	v := &Variable{VariableName: testVariable, Data: []byte("hello"), Attributes: NonVolatile | BootserviceAccess}
+	if err := s.Set(ctx, v); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if fi, err := os.Stat(filepath.Join(dir, testVariable.String())); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("Set wrote %v, %v; want mode 0600", fi, err)
+	}
+	if got, err := s.Get(ctx, testVariable); err != nil || string(got.Data) != "hello" {
+		t.Errorf("Get = %+v, %v; want hello", got, err)
+	}
+	if ok, err := s.Exists(ctx, testVariable); !ok || err != nil {
+		t.Errorf("Exists = %v, %v; want true, nil", ok, err)
+	}
+	n := 0
+	if err := s.WalkVariables(ctx, func(vn VariableName) error { n++; return nil }); err != nil || n != 1 {
+		t.Errorf("WalkVariables visited %d variables, %v; want 1", n, err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := s.Delete(cancelled, testVariable); err != context.Canceled {
+		t.Errorf("Delete(cancelled context) = %v, want %v", err, context.Canceled)
+	}
+	if err := s.WalkVariables(cancelled, func(vn VariableName) error { return nil }); err != context.Canceled {
+		t.Errorf("WalkVariables(cancelled context) = %v, want %v", err, context.Canceled)
+	}
+
+	if err := s.Delete(ctx, testVariable); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := New(s).Get(ctx, testVariable); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Get after Delete = %v; want not exist", err)
+	}
+}
+
+// TestSystemVariables reads the running system's variables.
+func TestSystemVariables(t *testing.T) {
+	if !(System{}).Supported() {
+		t.Skip("efivar is not supported")
+	}
+	vns, err := Variables(context.Background())
+	if err != nil {
+		t.Fatalf("Variables: %v", err)
+	}
+	bootCurrent := VariableName{GUID: GlobalUUID, Name: "BootCurrent"}
+	for _, vn := range vns {
+		if vn == bootCurrent {
+			return
+		}
+	}
+	t.Errorf("Variables = %v; want BootCurrent among them", vns)
+}
+
+func TestEfivarfsPath(t *testing.T) {
+	dir := t.TempDir()
+	if old, ok := os.LookupEnv(EfivarfsPathEnv); ok {
+		defer os.Setenv(EfivarfsPathEnv, old)
+	} else {
+		defer os.Unsetenv(EfivarfsPathEnv)
+	}
+	os.Unsetenv(EfivarfsPathEnv)
+	if got := newEfivarfs("").path; got != efivarfsPath {
+		t.Errorf("efivarfs path = %q, want %q", got, efivarfsPath)
+	}
+	os.Setenv(EfivarfsPathEnv, dir)
+	if got := newEfivarfs("").path; got != dir {
+		t.Errorf("efivarfs path with %s=%s is %q", EfivarfsPathEnv, dir, got)
+	}
+}
+
+func TestFormatDevicePath(t *testing.T) {
+	dp := []byte{0x04, 0x04, 0x0a, 0x00, 'a', 0, 'b', 0, 0, 0, 0x7f, 0xff, 0x04, 0x00}
+	if got, err := FormatDevicePath(dp); err != nil || got == "" {
+		t.Errorf("FormatDevicePath = %q, %v; want File(ab)", got, err)
+	}
+	if _, err := FormatDevicePath(nil); err == nil {
+		t.Error("FormatDevicePath of an empty path succeeded")
+	}
+}
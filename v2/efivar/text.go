@@ -0,0 +1,206 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+var attributeNames = []struct {
+	a    Attributes
+	name string
+}{
+	{NonVolatile, "NV"}, {BootserviceAccess, "BS"}, {RuntimeAccess, "RT"},
+	{HardwareErrorRecord, "HR"}, {AuthenticatedWriteAccess, "AT"},
+	{TimeBasedAuthenticatedWriteAccess, "TAT"}, {AppendWrite, "AW"},
+}
+
+// String names the bits of a joined with +, such as NV+BS+RT. Unnamed bits
+// are given in hex, and no attributes as 0.
+func (a Attributes) String() string {
+	var parts []string
+	for _, n := range attributeNames {
+		if a&n.a != 0 {
+			parts = append(parts, n.name)
+			a &^= n.a
+		}
+	}
+	if a != 0 {
+		parts = append(parts, fmt.Sprintf("%#x", uint32(a)))
+	}
+	if len(parts) == 0 {
+		return "0"
+	}
+	return strings.Join(parts, "+")
+}
+
+// ParseAttributes is the inverse of Attributes.String. It also accepts lower
+// case, numbers, and commas or | between the parts, so "nv,bs,rt" and
+// "0x7" give the same attributes as "NV+BS+RT".
+func ParseAttributes(s string) (Attributes, error) {
+	var a Attributes
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r == '+' || r == ',' || r == '|' })
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if n, err := strconv.ParseUint(part, 0, 32); err == nil {
+			a |= Attributes(n)
+			continue
+		}
+		found := false
+		for _, n := range attributeNames {
+			if strings.EqualFold(part, n.name) {
+				a |= n.a
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("efivar: unknown attribute %q", part)
+		}
+	}
+	return a, nil
+}
+
+// MarshalText encodes a as its attribute names, such as NV+BS+RT.
+func (a Attributes) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText decodes attribute names joined with + or commas, in either
+// case, or a number.
+func (a *Attributes) UnmarshalText(text []byte) error {
+	v, err := ParseAttributes(string(text))
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+// String returns vn as NAME-GUID, the name of its efivarfs file, such as
+// BootOrder-8be4df61-93ca-11d2-aa0d-00e098032b8c. The GUID is in lower case.
+func (vn VariableName) String() string {
+	return vn.Name + "-" + vn.GUID.String()
+}
+
+// ParseVariableName parses NAME-GUID, as String returns it and as ls shows
+// efivarfs. The GUID may be in either case.
+func ParseVariableName(s string) (VariableName, error) {
+	if len(s) < 38 || s[len(s)-37] != '-' {
+		return VariableName{}, fmt.Errorf("efivar: %q is not NAME-GUID", s)
+	}
+	u, err := uuid.Parse(s[len(s)-36:])
+	if err != nil {
+		return VariableName{}, fmt.Errorf("efivar: %q is not NAME-GUID: %v", s, err)
+	}
+	return VariableName{GUID: u, Name: s[:len(s)-37]}, nil
+}
+
+// MarshalText encodes vn as NAME-GUID, the name of its efivarfs file.
+func (vn VariableName) MarshalText() ([]byte, error) {
+	return []byte(vn.String()), nil
+}
+
+// UnmarshalText decodes NAME-GUID.
+func (vn *VariableName) UnmarshalText(text []byte) error {
+	v, err := ParseVariableName(string(text))
+	if err != nil {
+		return err
+	}
+	*vn = v
+	return nil
+}
+
+// UnmarshalJSON decodes attribute names as UnmarshalText does, or a JSON
+// number, as vardump and goefivard write attributes.
+func (a *Attributes) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		return a.UnmarshalText([]byte(s))
+	}
+	var n uint32
+	if err := json.Unmarshal(b, &n); err != nil {
+		return fmt.Errorf("efivar: attributes %s are neither a string nor a number", b)
+	}
+	*a = Attributes(n)
+	return nil
+}
+
+// nameFields is the JSON form of a VariableName.
+type nameFields struct {
+	GUID uuid.UUID `json:"guid"`
+	Name string    `json:"name"`
+}
+
+// MarshalJSON encodes vn as an object with its GUID and name, such as
+// {"guid":"8be4df61-93ca-11d2-aa0d-00e098032b8c","name":"BootOrder"}. As a
+// map key, vn is still encoded as NAME-GUID.
+func (vn VariableName) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nameFields{vn.GUID, vn.Name})
+}
+
+// UnmarshalJSON decodes what MarshalJSON encodes, or a NAME-GUID string.
+func (vn *VariableName) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		return vn.UnmarshalText([]byte(s))
+	}
+	var f nameFields
+	if err := json.Unmarshal(b, &f); err != nil {
+		return err
+	}
+	*vn = VariableName{f.GUID, f.Name}
+	return nil
+}
+
+// variableFields is the JSON form of a Variable. Without MarshalJSON and
+// UnmarshalJSON, Variable would take the methods of its VariableName and
+// encode as the name alone.
+type variableFields struct {
+	GUID       uuid.UUID  `json:"guid"`
+	Name       string     `json:"name"`
+	Attributes Attributes `json:"attributes"`
+	// Data is base64-encoded.
+	Data []byte `json:"data"`
+}
+
+// MarshalJSON encodes v as an object with its GUID and name, as
+// VariableName.MarshalJSON does, its attributes as MarshalText names them,
+// and its data in base64.
+func (v Variable) MarshalJSON() ([]byte, error) {
+	return json.Marshal(variableFields{v.GUID, v.Name, v.Attributes, v.Data})
+}
+
+// UnmarshalJSON decodes what MarshalJSON encodes. The attributes may also
+// be a number.
+func (v *Variable) UnmarshalJSON(b []byte) error {
+	var f variableFields
+	if err := json.Unmarshal(b, &f); err != nil {
+		return err
+	}
+	*v = Variable{VariableName{f.GUID, f.Name}, f.Data, f.Attributes}
+	return nil
+}
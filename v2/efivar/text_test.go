@@ -0,0 +1,186 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestAttributesText(t *testing.T) {
+	for _, tc := range []struct {
+		a    Attributes
+		text string
+	}{
+		{0, "0"},
+		{NonVolatile | BootserviceAccess | RuntimeAccess, "NV+BS+RT"},
+		{NonVolatile | BootserviceAccess | RuntimeAccess | TimeBasedAuthenticatedWriteAccess, "NV+BS+RT+TAT"},
+		{AppendWrite | 0x100, "AW+0x100"},
+	} {
+		b, err := tc.a.MarshalText()
+		if err != nil || string(b) != tc.text {
+			t.Errorf("%#x.MarshalText() = %q, %v; want %q", uint32(tc.a), b, err, tc.text)
+		}
+		var a Attributes
+		if err := a.UnmarshalText([]byte(tc.text)); err != nil || a != tc.a {
+			t.Errorf("UnmarshalText(%q) = %#x, %v; want %#x", tc.text, uint32(a), err, uint32(tc.a))
+		}
+	}
+
+	var a Attributes
+	if err := a.UnmarshalText([]byte("nv, bs,0x4")); err != nil || a != NonVolatile|BootserviceAccess|RuntimeAccess {
+		t.Errorf("UnmarshalText(nv, bs,0x4) = %#x, %v", uint32(a), err)
+	}
+	if err := a.UnmarshalText([]byte("NV+XX")); err == nil {
+		t.Error("UnmarshalText(NV+XX) succeeded")
+	}
+}
+
+func TestParseAttributes(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want Attributes
+	}{
+		{"nv,bs,rt", NonVolatile | BootserviceAccess | RuntimeAccess},
+		{" BS , rt,", BootserviceAccess | RuntimeAccess},
+		{"NV|BS|RT|TAT", 0x27},
+		{"0x7", 0x7},
+		{"", 0},
+	} {
+		got, err := ParseAttributes(tc.in)
+		if err != nil || got != tc.want {
+			t.Errorf("ParseAttributes(%q) = %#x, %v; want %#x", tc.in, uint32(got), err, uint32(tc.want))
+		}
+	}
+	if _, err := ParseAttributes("nv,volatile"); err == nil {
+		t.Errorf("ParseAttributes of an unknown attribute succeeded")
+	}
+	if got := fmt.Sprint(NonVolatile | RuntimeAccess); got != "NV+RT" {
+		t.Errorf("fmt.Sprint(NV|RT) = %q", got)
+	}
+}
+
+func TestVariableNameText(t *testing.T) {
+	vn := VariableName{GUID: GlobalUUID, Name: "Boot-0001"}
+	const text = "Boot-0001-8be4df61-93ca-11d2-aa0d-00e098032b8c"
+	b, err := vn.MarshalText()
+	if err != nil || string(b) != text {
+		t.Errorf("MarshalText() = %q, %v; want %q", b, err, text)
+	}
+	var got VariableName
+	if err := got.UnmarshalText([]byte(text)); err != nil || got != vn {
+		t.Errorf("UnmarshalText(%q) = %v, %v; want %v", text, got, err, vn)
+	}
+	for _, bad := range []string{"", "Boot0001", "Boot0001-8be4df61-93ca-11d2-aa0d-00e098032bXX"} {
+		if err := got.UnmarshalText([]byte(bad)); err == nil {
+			t.Errorf("UnmarshalText(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestParseVariableName(t *testing.T) {
+	vn := VariableName{GUID: GlobalUUID, Name: "BootOrder"}
+	if got, err := ParseVariableName(vn.String()); err != nil || got != vn {
+		t.Errorf("ParseVariableName(%q) = %v, %v; want %v", vn.String(), got, err, vn)
+	}
+	const upper = "BootOrder-8BE4DF61-93CA-11D2-AA0D-00E098032B8C"
+	if got, err := ParseVariableName(upper); err != nil || got != vn {
+		t.Errorf("ParseVariableName(%q) = %v, %v; want %v", upper, got, err, vn)
+	}
+	if got := fmt.Sprint(vn); got != "BootOrder-8be4df61-93ca-11d2-aa0d-00e098032b8c" {
+		t.Errorf("fmt.Sprint(%#v) = %q", vn, got)
+	}
+}
+
+func TestTextInJSON(t *testing.T) {
+	type config struct {
+		Attributes Attributes
+		Names      map[VariableName]bool
+	}
+	c := config{
+		Attributes: NonVolatile | BootserviceAccess,
+		Names:      map[VariableName]bool{{GUID: GlobalUUID, Name: "BootNext"}: true},
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `{"Attributes":"NV+BS","Names":{"BootNext-8be4df61-93ca-11d2-aa0d-00e098032b8c":true}}`
+	if string(b) != want {
+		t.Errorf("json.Marshal = %s, want %s", b, want)
+	}
+	var got config
+	if err := json.Unmarshal(b, &got); err != nil || !reflect.DeepEqual(got, c) {
+		t.Errorf("json.Unmarshal = %+v, %v; want %+v", got, err, c)
+	}
+
+	// A Variable keeps its data, rather than encoding as its name.
+	v := Variable{VariableName{GlobalUUID, "BootNext"}, []byte{1, 0}, NonVolatile}
+	b, err = json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gotV Variable
+	if err := json.Unmarshal(b, &gotV); err != nil || !reflect.DeepEqual(gotV, v) {
+		t.Errorf("Variable JSON %s decodes to %+v, %v; want %+v", b, gotV, err, v)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	v := Variable{VariableName{GlobalUUID, "BootNext"}, []byte{1, 0}, NonVolatile | BootserviceAccess | RuntimeAccess}
+	const wantV = `{"guid":"8be4df61-93ca-11d2-aa0d-00e098032b8c","name":"BootNext","attributes":"NV+BS+RT","data":"AQA="}`
+	if b, err := json.Marshal(v); err != nil || string(b) != wantV {
+		t.Errorf("json.Marshal(Variable) = %s, %v; want %s", b, err, wantV)
+	}
+	const wantVN = `{"guid":"8be4df61-93ca-11d2-aa0d-00e098032b8c","name":"BootNext"}`
+	if b, err := json.Marshal(v.VariableName); err != nil || string(b) != wantVN {
+		t.Errorf("json.Marshal(VariableName) = %s, %v; want %s", b, err, wantVN)
+	}
+
+	for _, in := range []string{
+		wantVN,
+		`"BootNext-8be4df61-93ca-11d2-aa0d-00e098032b8c"`,
+	} {
+		var vn VariableName
+		if err := json.Unmarshal([]byte(in), &vn); err != nil || vn != v.VariableName {
+			t.Errorf("json.Unmarshal(%s) = %v, %v; want %v", in, vn, err, v.VariableName)
+		}
+	}
+	for _, in := range []string{
+		wantV,
+		`{"guid":"8be4df61-93ca-11d2-aa0d-00e098032b8c","name":"BootNext","attributes":7,"data":"AQA="}`,
+	} {
+		var got Variable
+		if err := json.Unmarshal([]byte(in), &got); err != nil || !reflect.DeepEqual(got, v) {
+			t.Errorf("json.Unmarshal(%s) = %+v, %v; want %+v", in, got, err, v)
+		}
+	}
+	for _, in := range []string{
+		`{"guid":"not-a-guid","name":"BootNext"}`,
+		`{"name":"BootNext","attributes":"NV+XX"}`,
+		`{"name":"BootNext","attributes":true}`,
+	} {
+		var got Variable
+		if err := json.Unmarshal([]byte(in), &got); err == nil {
+			t.Errorf("json.Unmarshal(%s) succeeded", in)
+		}
+	}
+	var vn VariableName
+	if err := json.Unmarshal([]byte(`"BootNext"`), &vn); err == nil {
+		t.Errorf("json.Unmarshal of a name without a GUID succeeded")
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+)
+
+// TraceEntry is one step of libefivar's record of how a call failed.
+type TraceEntry struct {
+	File     string
+	Function string
+	Line     int
+	Message  string
+	Errno    syscall.Errno
+}
+
+func (e TraceEntry) String() string {
+	s := fmt.Sprintf("%s:%d %s(): %s", e.File, e.Line, e.Function, e.Message)
+	if e.Errno != 0 {
+		s += ": " + e.Errno.Error()
+	}
+	return s
+}
+
+// TraceError is a failure of libefivar with the trace libefivar recorded,
+// which says where inside the library the call failed. It is returned for
+// failures which Code does not classify; those it does are returned as the
+// plain system error, so that os.IsNotExist and os.IsPermission work on
+// them, and their traces are sent to the log given to SetDebugLog.
+type TraceError struct {
+	Err   error
+	Trace []TraceEntry
+}
+
+func (e *TraceError) Error() string {
+	parts := make([]string, len(e.Trace))
+	for i, t := range e.Trace {
+		parts[i] = t.String()
+	}
+	return fmt.Sprintf("%v (libefivar: %s)", e.Err, strings.Join(parts, "; "))
+}
+
+// Unwrap returns the error the call returned.
+func (e *TraceError) Unwrap() error { return e.Err }
+
+// Code classifies the error the call returned.
+func (e *TraceError) Code() Code { return CodeOf(e.Err) }
+
+// withTrace logs trace, from a failed call which returned err, and returns
+// wrapTrace(err, trace). It must not be called holding liblock, since the
+// log may use this package.
+func withTrace(err error, trace []TraceEntry) error {
+	for _, t := range trace {
+		debugf(1, "libefivar: %v", t)
+	}
+	return wrapTrace(err, trace)
+}
+
+// wrapTrace wraps err in a *TraceError holding trace, unless trace is empty
+// or CodeOf can classify err.
+func wrapTrace(err error, trace []TraceEntry) error {
+	if len(trace) == 0 || CodeOf(err) != Unknown {
+		return err
+	}
+	return &TraceError{Err: err, Trace: trace}
+}
@@ -0,0 +1,43 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import (
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestWrapTrace(t *testing.T) {
+	trace := []TraceEntry{{File: "vars.c", Function: "vars_get_variable", Line: 10, Message: "read failed", Errno: syscall.EIO}}
+
+	err := wrapTrace(syscall.EIO, trace)
+	te, ok := err.(*TraceError)
+	if !ok {
+		t.Fatalf("wrapTrace(EIO) = %#v, want a *TraceError", err)
+	}
+	const want = "input/output error (libefivar: vars.c:10 vars_get_variable(): read failed: input/output error)"
+	if te.Error() != want {
+		t.Errorf("Error() = %q, want %q", te.Error(), want)
+	}
+
+	// Classified errors stay as they are, for os.IsNotExist and friends.
+	if err := wrapTrace(syscall.ENOENT, trace); !os.IsNotExist(err) {
+		t.Errorf("wrapTrace(ENOENT) = %#v, want ENOENT", err)
+	}
+	if err := wrapTrace(syscall.EIO, nil); err != syscall.EIO {
+		t.Errorf("wrapTrace(EIO, nil) = %#v, want EIO", err)
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efivar
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+)
+
+// StopWalk, returned by the function given to WalkVariables, ends the walk
+// early without error.
+var StopWalk = errors.New("efivar: stop walk")
+
+// A Walker is a Backend which can list its variables one at a time, without
+// first collecting them all. Client.WalkVariables uses it if the Backend
+// has it.
+type Walker interface {
+	// WalkVariables calls fn for each variable, stopping at the first
+	// error fn returns, which it returns unless it is StopWalk.
+	WalkVariables(ctx context.Context, fn func(vn VariableName) error) error
+}
+
+// walkList calls fn for each variable b lists, as WalkVariables does.
+func walkList(ctx context.Context, b Backend, fn func(vn VariableName) error) error {
+	vns, err := b.Variables(ctx)
+	if err != nil {
+		return err
+	}
+	for _, vn := range vns {
+		if err := fn(vn); err == StopWalk {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkDir calls fn for each entry of dir named NAME-GUID which is a
+// directory if dirs is true and a regular file otherwise, as WalkVariables
+// does, reading dir a little at a time.
+func walkDir(dir string, dirs bool, fn func(vn VariableName) error) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	for {
+		des, err := f.ReadDir(128)
+		for _, de := range des {
+			if de.IsDir() != dirs || !dirs && !de.Type().IsRegular() {
+				continue
+			}
+			vn, perr := ParseVariableName(de.Name())
+			if perr != nil {
+				continue
+			}
+			if err := fn(vn); err == StopWalk {
+				return nil
+			} else if err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// collect returns every variable walk passes to its function.
+func collect(walk func(fn func(vn VariableName) error) error) ([]VariableName, error) {
+	var out []VariableName
+	err := walk(func(vn VariableName) error {
+		out = append(out, vn)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,16 @@
+module github.com/lukegb/goefivar/v2
+
+go 1.16
+
+require (
+	github.com/google/uuid v1.1.1
+	github.com/lukegb/goefivar v0.0.0-00010101000000-000000000000
+)
+
+// The two modules share a repository and require each other: version 1 of
+// package efivar is a shim over version 2, which uses efidp and the
+// internal packages of version 1.
+replace (
+	github.com/lukegb/goefivar => ../
+	github.com/lukegb/goefivar/v2 v2.0.0-00010101000000-000000000000 => ./
+)
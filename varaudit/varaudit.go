@@ -0,0 +1,201 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package varaudit keeps a tamper-evident log of changes to EFI variables.
+//
+// A Log is an efivar.Auditor which appends one JSON object per line to a
+// file. Each line holds an HMAC-SHA256 of the line before it, keyed with a
+// key the caller keeps away from the log, so editing, removing or reordering
+// lines breaks the chain, which Verify detects, and only someone with the
+// key can forge a new one.
+//
+// Without a key the chain is a plain SHA-256, which shows accidental damage
+// and careless edits but not tampering: anyone who can write the file can
+// recompute every hash. Either way, truncating the end of the log, or
+// replacing the whole log, cannot be detected from the log alone. To detect
+// those, record Head somewhere the log's writer cannot change, such as
+// journald or a remote log, and compare it with the head Verify returns.
+//
+//	l, err := varaudit.Open("/var/log/efivar-audit.jsonl", key)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer l.Close()
+//	efivar.UseAuditor(l, "")
+package varaudit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lukegb/goefivar/efivar"
+)
+
+// ErrTampered is returned by Verify when a line does not follow from the
+// one before it.
+var ErrTampered = efivar.NewError(efivar.Corrupt, "varaudit: log has been altered")
+
+// Entry is one line of the log.
+type Entry struct {
+	Time       time.Time `json:"time"`
+	Op         string    `json:"op"`
+	GUID       uuid.UUID `json:"guid"`
+	Name       string    `json:"name"`
+	Attributes uint32    `json:"attributes"`
+	// Before and After are hex SHA-256 hashes, empty if the variable did
+	// not exist.
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+	Tag    string `json:"tag,omitempty"`
+	// Prev is the hex HMAC-SHA256, or SHA-256 without a key, of the
+	// previous line, without its newline, or empty on the first line.
+	Prev string `json:"prev,omitempty"`
+}
+
+// A Log appends audit records to a file. It is safe for concurrent use.
+type Log struct {
+	mu   sync.Mutex
+	f    *os.File
+	key  []byte
+	prev string
+	err  error
+}
+
+// Open opens the log at path for appending, creating it if need be, chaining
+// its lines with key. A nil or empty key chains them with SHA-256, which does
+// not stop deliberate tampering. Open does not verify the existing content;
+// use Verify for that.
+func Open(path string, key []byte) (*Log, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) == 0 {
+		key = nil
+	} else {
+		key = append([]byte(nil), key...)
+	}
+	l := &Log{f: f, key: key}
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if line = bytes.TrimSuffix(line, []byte("\n")); len(line) > 0 {
+			l.prev = lineHash(key, line)
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// lineHash returns the hash of line which the next line holds.
+func lineHash(key, line []byte) string {
+	if len(key) == 0 {
+		sum := sha256.Sum256(line)
+		return hex.EncodeToString(sum[:])
+	}
+	h := hmac.New(sha256.New, key)
+	h.Write(line)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Audit implements efivar.Auditor. Audit cannot fail the change it records,
+// so a failure to write is kept and returned by Err and Close, and stops
+// further writes.
+func (l *Log) Audit(r *efivar.AuditRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err != nil {
+		return
+	}
+	line, err := json.Marshal(&Entry{
+		Time:       r.Time.UTC(),
+		Op:         r.Op,
+		GUID:       r.Name.GUID,
+		Name:       r.Name.Name,
+		Attributes: uint32(r.Attributes),
+		Before:     hex.EncodeToString(r.Before),
+		After:      hex.EncodeToString(r.After),
+		Tag:        r.Tag,
+		Prev:       l.prev,
+	})
+	if err != nil {
+		l.err = err
+		return
+	}
+	if _, err := l.f.Write(append(line, '\n')); err != nil {
+		l.err = err
+		return
+	}
+	if err := l.f.Sync(); err != nil {
+		l.err = err
+		return
+	}
+	l.prev = lineHash(l.key, line)
+}
+
+// Head returns the hash of the last line of the log, which the next line
+// will hold, or "" if the log is empty. Recorded elsewhere, it lets Verify's
+// caller detect a log which has been truncated or replaced.
+func (l *Log) Head() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.prev
+}
+
+// Err returns the first error writing the log, if any.
+func (l *Log) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.err
+}
+
+// Close closes the log file, returning the first error writing it, if any.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.f.Close(); l.err == nil {
+		l.err = err
+	}
+	return l.err
+}
+
+// Verify reads a log written with key and checks its chain of hashes. It
+// returns the entries read, which on error are those before the first bad
+// line, and the head of the log, as Log.Head would give it.
+func Verify(r io.Reader, key []byte) (entries []Entry, head string, err error) {
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		var e Entry
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil || !hmac.Equal([]byte(e.Prev), []byte(head)) {
+			return entries, head, ErrTampered
+		}
+		entries = append(entries, e)
+		head = lineHash(key, s.Bytes())
+	}
+	return entries, head, s.Err()
+}
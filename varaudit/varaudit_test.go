@@ -0,0 +1,135 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package varaudit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/varstore"
+)
+
+var testName = efivar.VariableName{GUID: uuid.MustParse("74552304-ce9f-4e52-89a0-f6c6fa47deac"), Name: "Audited"}
+
+func TestLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "varaudit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	efivar.UseStore(&varstore.Dir{Path: dir})
+	defer efivar.UseStore(nil)
+	path := filepath.Join(dir, "audit.jsonl")
+
+	// Write through two Logs, to check that a reopened Log continues the
+	// chain.
+	key := []byte("secret")
+	var head string
+	for _, data := range []string{"one", "two"} {
+		l, err := Open(path, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		efivar.UseAuditor(l, "test")
+		v := &efivar.Variable{VariableName: testName, Data: []byte(data), Attributes: efivar.NonVolatile | efivar.BootserviceAccess}
+		if err := v.Set(0644); err != nil {
+			t.Fatalf("Set: %v", err)
+		}
+		efivar.UseAuditor(nil, "")
+		head = l.Head()
+		if err := l.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+	}
+
+	f, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	es, gotHead, err := Verify(bytes.NewReader(f), key)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if gotHead != head || head == "" {
+		t.Errorf("Verify gave head %q; the Log had %q", gotHead, head)
+	}
+	if len(es) != 2 {
+		t.Fatalf("Verify returned %d entries, want 2", len(es))
+	}
+	two := sha256.Sum256([]byte("two"))
+	if e := es[1]; e.Op != "set" || e.Name != testName.Name || e.GUID != testName.GUID || e.Tag != "test" || e.After != hex.EncodeToString(two[:]) || e.Before != es[0].After {
+		t.Errorf("second entry = %+v", e)
+	}
+
+	tampered := bytes.Replace(f, []byte(`"tag":"test"`), []byte(`"tag":"evil"`), 1)
+	if es, _, err := Verify(bytes.NewReader(tampered), key); err != ErrTampered || len(es) != 1 {
+		t.Errorf("Verify of edited log = %d entries, %v; want 1, %v", len(es), err, ErrTampered)
+	}
+	lines := bytes.SplitAfter(f, []byte("\n"))
+	if _, _, err := Verify(bytes.NewReader(lines[1]), key); err != ErrTampered {
+		t.Errorf("Verify of log missing its first line = %v, want %v", err, ErrTampered)
+	}
+	if _, _, err := Verify(bytes.NewReader(f), []byte("guess")); err != ErrTampered {
+		t.Errorf("Verify with the wrong key = %v, want %v", err, ErrTampered)
+	}
+	// Truncation leaves a valid chain, but not the head the Log had.
+	if _, h, err := Verify(bytes.NewReader(lines[0]), key); err != nil || h == head {
+		t.Errorf("Verify of truncated log = head %q, %v; want a different head than %q", h, err, head)
+	}
+}
+
+func TestLogEmptyKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "varaudit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	efivar.UseStore(&varstore.Dir{Path: dir})
+	defer efivar.UseStore(nil)
+	path := filepath.Join(dir, "audit.jsonl")
+
+	for _, data := range []string{"one", "two"} {
+		l, err := Open(path, []byte{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		efivar.UseAuditor(l, "test")
+		v := &efivar.Variable{VariableName: testName, Data: []byte(data), Attributes: efivar.NonVolatile | efivar.BootserviceAccess}
+		if err := v.Set(0644); err != nil {
+			t.Fatalf("Set: %v", err)
+		}
+		efivar.UseAuditor(nil, "")
+		if err := l.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+	}
+
+	f, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range [][]byte{nil, {}} {
+		if es, _, err := Verify(bytes.NewReader(f), key); err != nil || len(es) != 2 {
+			t.Errorf("Verify with key %#v = %d entries, %v; want 2, nil", key, len(es), err)
+		}
+	}
+}
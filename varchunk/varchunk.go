@@ -0,0 +1,255 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package varchunk stores blobs, such as certificates or logs, which are
+// larger than firmware accepts in a single variable or a single write.
+//
+// By default Write splits the data across numbered variables, NAME.0000,
+// NAME.0001 and so on, and writes NAME itself last as a manifest giving the
+// number of chunks, the total length and a SHA-256 of the data. Read
+// follows the manifest and checks the result. With Options.Append, Write
+// instead writes one variable in several appending writes, which is enough
+// when firmware limits the size of a write but not of a variable.
+//
+// Data which fits in one chunk is written to NAME unchanged, so Read can be
+// used on any variable.
+package varchunk
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"os"
+
+	"github.com/lukegb/goefivar/efivar"
+)
+
+// DefaultChunkSize is small enough for the per-variable limits of OVMF and
+// of the PC firmware we know of, which count the variable's header and
+// name as well as its data.
+const DefaultChunkSize = 4096
+
+// ErrCorrupted is returned by Read when a manifest and its chunks disagree,
+// which happens if a Write was interrupted.
+var ErrCorrupted = efivar.NewError(efivar.Corrupt, "varchunk: chunks do not match their manifest")
+
+// manifestMagic starts every manifest.
+var manifestMagic = []byte("GOEFICHK")
+
+// manifestSize is the size of a manifest: the magic, the number of chunks
+// and the total length as little-endian uint32s, and the SHA-256.
+const manifestSize = 8 + 4 + 4 + sha256.Size
+
+type manifest struct {
+	count  uint32
+	length uint32
+	sum    [sha256.Size]byte
+}
+
+func (m *manifest) bytes() []byte {
+	b := make([]byte, manifestSize)
+	copy(b, manifestMagic)
+	binary.LittleEndian.PutUint32(b[8:], m.count)
+	binary.LittleEndian.PutUint32(b[12:], m.length)
+	copy(b[16:], m.sum[:])
+	return b
+}
+
+// parseManifest returns nil if b is not a manifest.
+func parseManifest(b []byte) *manifest {
+	if len(b) != manifestSize || !bytes.HasPrefix(b, manifestMagic) {
+		return nil
+	}
+	m := &manifest{
+		count:  binary.LittleEndian.Uint32(b[8:]),
+		length: binary.LittleEndian.Uint32(b[12:]),
+	}
+	copy(m.sum[:], b[16:])
+	return m
+}
+
+// ChunkName returns the name of chunk n of vn.
+func ChunkName(vn efivar.VariableName, n int) efivar.VariableName {
+	return efivar.VariableName{GUID: vn.GUID, Name: fmt.Sprintf("%s.%04X", vn.Name, n)}
+}
+
+// Options controls how Write splits data. A nil *Options uses the defaults.
+type Options struct {
+	// ChunkSize is the most data written to one variable, or in one
+	// write with Append. It defaults to DefaultChunkSize.
+	ChunkSize int
+
+	// Append writes a single variable in several appending writes,
+	// rather than splitting it across several variables. Data which
+	// Read would take for a manifest is split across variables anyway.
+	Append bool
+
+	// Mode is the permissions of the variables in efivarfs. It defaults to 0644.
+	Mode os.FileMode
+}
+
+func (o *Options) chunkSize() int {
+	if o == nil || o.ChunkSize <= 0 {
+		return DefaultChunkSize
+	}
+	return o.ChunkSize
+}
+
+func (o *Options) mode() os.FileMode {
+	if o == nil || o.Mode == 0 {
+		return 0644
+	}
+	return o.Mode
+}
+
+func set(vn efivar.VariableName, attrs efivar.Attributes, data []byte, mode os.FileMode) error {
+	v := &efivar.Variable{VariableName: vn, Attributes: attrs, Data: data}
+	if err := v.Set(mode); err != nil {
+		return fmt.Errorf("varchunk: writing %v: %v", vn.Name, err)
+	}
+	return nil
+}
+
+// Write stores data in vn, split as opts says. attrs must not ask for an
+// authenticated write. Chunks left over from an earlier, longer Write are
+// deleted.
+func Write(vn efivar.VariableName, attrs efivar.Attributes, data []byte, opts *Options) error {
+	if attrs&(efivar.AuthenticatedWriteAccess|efivar.TimeBasedAuthenticatedWriteAccess) != 0 {
+		return fmt.Errorf("varchunk: cannot split an authenticated write to %v", vn.Name)
+	}
+	attrs &^= efivar.AppendWrite
+	size, mode := opts.chunkSize(), opts.mode()
+
+	old, err := readManifest(vn)
+	if err != nil {
+		return err
+	}
+	var m *manifest
+	switch {
+	case len(data) <= size && parseManifest(data) == nil:
+		if err := set(vn, attrs, data, mode); err != nil {
+			return err
+		}
+	case opts != nil && opts.Append && parseManifest(data) == nil:
+		first := size
+		if first > len(data) {
+			first = len(data)
+		}
+		if err := set(vn, attrs, data[:first], mode); err != nil {
+			return err
+		}
+		for off := size; off < len(data); off += size {
+			end := off + size
+			if end > len(data) {
+				end = len(data)
+			}
+			if err := set(vn, attrs|efivar.AppendWrite, data[off:end], mode); err != nil {
+				return err
+			}
+		}
+	default:
+		m = &manifest{length: uint32(len(data)), sum: sha256.Sum256(data)}
+		for off := 0; off < len(data); off += size {
+			end := off + size
+			if end > len(data) {
+				end = len(data)
+			}
+			if err := set(ChunkName(vn, int(m.count)), attrs, data[off:end], mode); err != nil {
+				return err
+			}
+			m.count++
+		}
+		if err := set(vn, attrs, m.bytes(), mode); err != nil {
+			return err
+		}
+	}
+
+	if old != nil {
+		keep := 0
+		if m != nil {
+			keep = int(m.count)
+		}
+		return deleteChunks(vn, keep, int(old.count))
+	}
+	return nil
+}
+
+// readManifest returns the manifest in vn, or nil if vn does not exist or
+// holds something else.
+func readManifest(vn efivar.VariableName) (*manifest, error) {
+	v, err := vn.Get()
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return parseManifest(v.Data), nil
+}
+
+// deleteChunks deletes chunks from to to of vn.
+func deleteChunks(vn efivar.VariableName, from, to int) error {
+	for n := from; n < to; n++ {
+		cn := ChunkName(vn, n)
+		if err := cn.Delete(); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("varchunk: deleting %v: %v", cn.Name, err)
+		}
+	}
+	return nil
+}
+
+// Read returns the data stored in vn by Write, or the content of vn if it
+// is an ordinary variable.
+func Read(vn efivar.VariableName) ([]byte, error) {
+	v, err := vn.Get()
+	if err != nil {
+		return nil, err
+	}
+	m := parseManifest(v.Data)
+	if m == nil {
+		return v.Data, nil
+	}
+	var buf bytes.Buffer
+	for n := 0; n < int(m.count); n++ {
+		c, err := ChunkName(vn, n).Get()
+		if os.IsNotExist(err) {
+			return nil, ErrCorrupted
+		} else if err != nil {
+			return nil, err
+		}
+		buf.Write(c.Data)
+		if buf.Len() > int(m.length) {
+			return nil, ErrCorrupted
+		}
+	}
+	if buf.Len() != int(m.length) || sha256.Sum256(buf.Bytes()) != m.sum {
+		return nil, ErrCorrupted
+	}
+	return buf.Bytes(), nil
+}
+
+// Delete deletes vn and any chunks it refers to.
+func Delete(vn efivar.VariableName) error {
+	m, err := readManifest(vn)
+	if err != nil {
+		return err
+	}
+	if err := vn.Delete(); err != nil {
+		return err
+	}
+	if m != nil {
+		return deleteChunks(vn, 0, int(m.count))
+	}
+	return nil
+}
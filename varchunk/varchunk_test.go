@@ -0,0 +1,147 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package varchunk
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/varstore"
+)
+
+var testName = efivar.VariableName{GUID: uuid.MustParse("74552304-ce9f-4e52-89a0-f6c6fa47deac"), Name: "Blob"}
+
+const testAttrs = efivar.NonVolatile | efivar.BootserviceAccess | efivar.RuntimeAccess
+
+func useTempStore(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "varchunk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	efivar.UseStore(&varstore.Dir{Path: dir})
+	return func() {
+		efivar.UseStore(nil)
+		os.RemoveAll(dir)
+	}
+}
+
+func blob(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i * 7)
+	}
+	return b
+}
+
+func names(t *testing.T) []string {
+	vns, err := efivar.Variables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []string
+	for _, vn := range vns {
+		out = append(out, vn.Name)
+	}
+	return out
+}
+
+func TestWriteRead(t *testing.T) {
+	defer useTempStore(t)()
+	for _, tc := range []struct {
+		name  string
+		size  int
+		opts  *Options
+		names string
+	}{
+		{"small", 100, nil, "[Blob]"},
+		{"exact", 16, &Options{ChunkSize: 16}, "[Blob]"},
+		{"numbered", 40, &Options{ChunkSize: 16}, "[Blob Blob.0000 Blob.0001 Blob.0002]"},
+		{"shrunk", 20, &Options{ChunkSize: 16}, "[Blob Blob.0000 Blob.0001]"},
+		{"append", 40, &Options{ChunkSize: 16, Append: true}, "[Blob]"},
+		{"default size", 3*DefaultChunkSize + 1, nil, "[Blob Blob.0000 Blob.0001 Blob.0002 Blob.0003]"},
+	} {
+		data := blob(tc.size)
+		if err := Write(testName, testAttrs, data, tc.opts); err != nil {
+			t.Errorf("%v: Write: %v", tc.name, err)
+			continue
+		}
+		got, err := Read(testName)
+		if err != nil || !bytes.Equal(got, data) {
+			t.Errorf("%v: Read = %x, %v; want %x", tc.name, got, err, data)
+		}
+		if got := names(t); fmt.Sprint(got) != tc.names {
+			t.Errorf("%v: variables = %v; want %v", tc.name, got, tc.names)
+		}
+	}
+
+	if err := Delete(testName); err != nil {
+		t.Errorf("Delete: %v", err)
+	}
+	if got := names(t); len(got) != 0 {
+		t.Errorf("variables after Delete = %v; want none", got)
+	}
+}
+
+func TestReadCorrupted(t *testing.T) {
+	defer useTempStore(t)()
+	if err := Write(testName, testAttrs, blob(40), &Options{ChunkSize: 16}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	v := &efivar.Variable{VariableName: ChunkName(testName, 1), Attributes: testAttrs, Data: blob(15)}
+	if err := v.Set(0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read(testName); err != ErrCorrupted {
+		t.Errorf("Read with a short chunk = %v; want %v", err, ErrCorrupted)
+	}
+	if err := ChunkName(testName, 2).Delete(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read(testName); err != ErrCorrupted {
+		t.Errorf("Read with a missing chunk = %v; want %v", err, ErrCorrupted)
+	}
+}
+
+func TestWriteAuthenticated(t *testing.T) {
+	defer useTempStore(t)()
+	if err := Write(testName, testAttrs|efivar.TimeBasedAuthenticatedWriteAccess, blob(10), nil); err == nil {
+		t.Errorf("Write of an authenticated variable succeeded")
+	}
+}
+
+// TestWriteManifestShaped writes data which Read would take for a manifest
+// if it were stored as it is.
+func TestWriteManifestShaped(t *testing.T) {
+	defer useTempStore(t)()
+	data := append([]byte("GOEFICHK"), blob(manifestSize-8)...)
+	for _, opts := range []*Options{
+		nil,
+		{ChunkSize: 64, Append: true},
+		{ChunkSize: 16, Append: true},
+	} {
+		if err := Write(testName, testAttrs, data, opts); err != nil {
+			t.Errorf("Write(%+v): %v", opts, err)
+			continue
+		}
+		if got, err := Read(testName); err != nil || !bytes.Equal(got, data) {
+			t.Errorf("Read after Write(%+v) = %x, %v; want %x", opts, got, err, data)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vardecode
+
+import (
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lukegb/goefivar/efiboot"
+	"github.com/lukegb/goefivar/efisig"
+	"github.com/lukegb/goefivar/efivar"
+)
+
+var byteOrder = binary.LittleEndian
+
+func global(name string) efivar.VariableName {
+	return efivar.VariableName{GUID: efivar.GlobalUUID, Name: name}
+}
+
+var loadOptionName = regexp.MustCompile(`^(Boot|Driver|SysPrep|PlatformRecovery)[0-9A-F]{4}$`)
+
+func init() {
+	for _, name := range []string{"BootCurrent", "BootNext"} {
+		Register(global(name), decodeBootNumber)
+	}
+	for _, name := range []string{"BootOrder", "DriverOrder", "SysPrepOrder"} {
+		Register(global(name), decodeBootOrder)
+	}
+	for _, name := range []string{"SecureBoot", "SetupMode", "AuditMode", "DeployedMode"} {
+		Register(global(name), decodeBool)
+	}
+	for _, name := range []string{"PlatformLang", "Lang", "PlatformLangCodes", "LangCodes"} {
+		Register(global(name), decodeASCII)
+	}
+	Register(global("Timeout"), decodeTimeout)
+	Register(global("OsIndications"), decodeOsIndications)
+	Register(global("OsIndicationsSupported"), decodeOsIndications)
+	RegisterMatch(efivar.GlobalUUID, loadOptionName.MatchString, decodeLoadOption)
+
+	for _, vn := range []efivar.VariableName{efisig.PKName, efisig.KEKName, efisig.DBName, efisig.DBXName} {
+		Register(vn, decodeDatabase)
+	}
+	Register(efisig.MOKListRTName, decodeDatabase)
+	Register(efisig.MOKListXRTName, decodeDatabase)
+	Register(efisig.MOKSBStateRTName, decodeBool)
+	Register(efisig.SBATLevelRTName, decodeSBATLevel)
+
+	Register(AMITSESetupName, decodeAMITSESetup)
+}
+
+func one(name, value string) []Field { return []Field{{name, value}} }
+
+func decodeBootNumber(b []byte) ([]Field, error) {
+	if len(b) != 2 {
+		return nil, ErrVariableCorrupted
+	}
+	return one("Option", fmt.Sprintf("Boot%04X", byteOrder.Uint16(b))), nil
+}
+
+func decodeBootOrder(b []byte) ([]Field, error) {
+	if len(b)%2 != 0 {
+		return nil, ErrVariableCorrupted
+	}
+	opts := make([]string, len(b)/2)
+	for n := range opts {
+		opts[n] = fmt.Sprintf("%04X", byteOrder.Uint16(b[2*n:]))
+	}
+	return one("Order", strings.Join(opts, ",")), nil
+}
+
+func decodeBool(b []byte) ([]Field, error) {
+	if len(b) != 1 {
+		return nil, ErrVariableCorrupted
+	}
+	return one("Enabled", strconv.FormatBool(b[0] == 1)), nil
+}
+
+func decodeASCII(b []byte) ([]Field, error) {
+	return one("Value", strings.TrimRight(string(b), "\x00")), nil
+}
+
+func decodeTimeout(b []byte) ([]Field, error) {
+	if len(b) != 2 {
+		return nil, ErrVariableCorrupted
+	}
+	return one("Seconds", strconv.Itoa(int(byteOrder.Uint16(b)))), nil
+}
+
+var osIndicationBits = []string{
+	"BOOT_TO_FW_UI",
+	"TIMESTAMP_REVOCATION",
+	"FILE_CAPSULE_DELIVERY_SUPPORTED",
+	"FMP_CAPSULE_SUPPORTED",
+	"CAPSULE_RESULT_VAR_SUPPORTED",
+	"START_OS_RECOVERY",
+	"START_PLATFORM_RECOVERY",
+	"JSON_CONFIG_DATA_REFRESH",
+}
+
+func decodeOsIndications(b []byte) ([]Field, error) {
+	if len(b) != 8 {
+		return nil, ErrVariableCorrupted
+	}
+	v := byteOrder.Uint64(b)
+	var set []string
+	for n, name := range osIndicationBits {
+		if v&(1<<uint(n)) != 0 {
+			set = append(set, name)
+			v &^= 1 << uint(n)
+		}
+	}
+	if v != 0 {
+		set = append(set, fmt.Sprintf("%#x", v))
+	}
+	return one("Flags", strings.Join(set, "|")), nil
+}
+
+func decodeLoadOption(b []byte) ([]Field, error) {
+	lo, err := efiboot.FromBytes(b)
+	if err != nil {
+		return nil, err
+	}
+	return []Field{
+		{"Description", lo.Description},
+		{"Attributes", fmt.Sprintf("%#x", uint32(lo.Attributes))},
+		{"FilePath", lo.FilePath},
+		{"OptionalData", lo.OptionalData.String()},
+	}, nil
+}
+
+func decodeDatabase(b []byte) ([]Field, error) {
+	db, err := efisig.ParseDatabase(b)
+	if err != nil {
+		return nil, err
+	}
+	fields := []Field{{"Entries", strconv.Itoa(db.Len())}}
+	certs, err := db.Certificates()
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range certs {
+		fields = append(fields, Field{"Certificate", c.Subject.String()})
+	}
+	return fields, nil
+}
+
+func decodeSBATLevel(b []byte) ([]Field, error) {
+	l, err := efisig.ParseSBATLevel(b)
+	if err != nil {
+		return nil, err
+	}
+	fields := []Field{{"Version", strconv.Itoa(l.Version)}, {"Datestamp", l.Datestamp}}
+	for _, g := range l.Generations {
+		fields = append(fields, Field{g.Component, strconv.Itoa(g.Generation)})
+	}
+	return fields, nil
+}
+
+// AMITSESetupName holds the setup passwords of AMI Aptio firmware.
+var AMITSESetupName = efivar.VariableName{GUID: uuid.MustParse("c811fa38-42c8-4579-a9bb-60e94eddfb34"), Name: "AMITSESetup"}
+
+// amiPasswordLength is SETUP_PASSWORD_LENGTH: the number of CHAR16s in
+// each of the (encoded) passwords at the start of AMITSESETUP.
+const amiPasswordLength = 20
+
+func passwordSet(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func decodeAMITSESetup(b []byte) ([]Field, error) {
+	const pw = 2 * amiPasswordLength
+	if len(b) < 2*pw+1 {
+		return nil, ErrVariableCorrupted
+	}
+	return []Field{
+		{"UserPasswordSet", strconv.FormatBool(passwordSet(b[:pw]))},
+		{"AdminPasswordSet", strconv.FormatBool(passwordSet(b[pw : 2*pw]))},
+		{"SilentBoot", strconv.FormatBool(b[2*pw] != 0)},
+	}, nil
+}
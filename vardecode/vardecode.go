@@ -0,0 +1,94 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package vardecode renders the content of well-known EFI variables as
+// named fields, for inspection tools which would otherwise print hex.
+//
+// Decoders are registered by vendor GUID and variable name. The package
+// registers decoders for the standard global variables, the Secure Boot
+// databases, shim's variables and some OEM variables; callers may register
+// their own.
+package vardecode
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/lukegb/goefivar/efivar"
+)
+
+// ErrVariableCorrupted is returned by decoders for content they don't understand.
+var ErrVariableCorrupted = efivar.NewError(efivar.Corrupt, "vardecode: variable content is not valid")
+
+// Field is one named value of a decoded variable.
+type Field struct {
+	Name  string
+	Value string
+}
+
+// DecodeFunc decodes the content of a variable.
+type DecodeFunc func(data []byte) ([]Field, error)
+
+type matcher struct {
+	guid  uuid.UUID
+	match func(name string) bool
+	f     DecodeFunc
+}
+
+var (
+	mu       sync.RWMutex
+	byName   = make(map[efivar.VariableName]DecodeFunc)
+	matchers []matcher
+)
+
+// Register sets the decoder for the variable vn, replacing any existing one.
+func Register(vn efivar.VariableName, f DecodeFunc) {
+	mu.Lock()
+	defer mu.Unlock()
+	byName[vn] = f
+}
+
+// RegisterMatch sets the decoder for the variables of vendor guid whose names
+// satisfy match, such as Boot####. Decoders registered by name take
+// precedence; otherwise matchers are tried in the order they were registered.
+func RegisterMatch(guid uuid.UUID, match func(name string) bool, f DecodeFunc) {
+	mu.Lock()
+	defer mu.Unlock()
+	matchers = append(matchers, matcher{guid, match, f})
+}
+
+// Lookup returns the decoder for vn.
+func Lookup(vn efivar.VariableName) (DecodeFunc, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if f, ok := byName[vn]; ok {
+		return f, true
+	}
+	for _, m := range matchers {
+		if m.guid == vn.GUID && m.match(vn.Name) {
+			return m.f, true
+		}
+	}
+	return nil, false
+}
+
+// Decode decodes v with the registered decoder. ok is false if there is none.
+func Decode(v *efivar.Variable) (fields []Field, ok bool, err error) {
+	f, ok := Lookup(v.VariableName)
+	if !ok {
+		return nil, false, nil
+	}
+	fields, err = f(v.Data)
+	return fields, true, err
+}
@@ -0,0 +1,70 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vardecode
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/lukegb/goefivar/efivar"
+)
+
+func TestDecode(t *testing.T) {
+	amiSetup := make([]byte, 81)
+	amiSetup[40] = 0x5a
+	amiSetup[80] = 1
+
+	for _, tc := range []struct {
+		vn   efivar.VariableName
+		data []byte
+		want []Field
+	}{
+		{global("BootCurrent"), []byte{0x0a, 0x00}, []Field{{"Option", "Boot000A"}}},
+		{global("BootOrder"), []byte{0x01, 0x00, 0x00, 0x00, 0x10, 0x20}, []Field{{"Order", "0001,0000,2010"}}},
+		{global("SecureBoot"), []byte{1}, []Field{{"Enabled", "true"}}},
+		{global("PlatformLang"), []byte("en-US\x00"), []Field{{"Value", "en-US"}}},
+		{global("OsIndicationsSupported"), []byte{0x05, 0x01, 0, 0, 0, 0, 0, 0}, []Field{{"Flags", "BOOT_TO_FW_UI|FILE_CAPSULE_DELIVERY_SUPPORTED|0x100"}}},
+		{AMITSESetupName, amiSetup, []Field{{"UserPasswordSet", "false"}, {"AdminPasswordSet", "true"}, {"SilentBoot", "true"}}},
+	} {
+		got, ok, err := Decode(&efivar.Variable{VariableName: tc.vn, Data: tc.data})
+		if !ok || err != nil {
+			t.Errorf("Decode(%v) = _, %v, %v", tc.vn.Name, ok, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Decode(%v) = %v; want %v", tc.vn.Name, got, tc.want)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	vendor := uuid.MustParse("5b92f7de-3a43-4f5b-a4b4-ab3c7f0b4b8a")
+	vn := efivar.VariableName{GUID: vendor, Name: "DiagnosticsMode"}
+	if _, ok := Lookup(vn); ok {
+		t.Fatalf("Lookup(%v) succeeded before registration", vn.Name)
+	}
+	Register(vn, decodeBool)
+	if _, ok := Lookup(vn); !ok {
+		t.Errorf("Lookup(%v) failed after Register", vn.Name)
+	}
+
+	if _, ok := Lookup(global("Boot0001")); !ok {
+		t.Error("Lookup(Boot0001) failed")
+	}
+	if _, ok := Lookup(global("Boot000g")); ok {
+		t.Error("Lookup(Boot000g) succeeded")
+	}
+}
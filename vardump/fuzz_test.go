@@ -0,0 +1,71 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package vardump
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func FuzzReadTar(f *testing.F) {
+	var buf bytes.Buffer
+	if err := WriteTar(&buf, testVariables); err != nil {
+		f.Fatal(err)
+	}
+	f.Add(buf.Bytes())
+	f.Fuzz(func(t *testing.T, b []byte) {
+		vs, err := ReadTar(bytes.NewReader(b))
+		if err != nil {
+			return
+		}
+		var buf bytes.Buffer
+		if err := WriteTar(&buf, vs); err != nil {
+			t.Fatalf("WriteTar: %v", err)
+		}
+		got, err := ReadTar(&buf)
+		if err != nil {
+			t.Fatalf("ReadTar(WriteTar(ReadTar(%x))): %v", b, err)
+		}
+		if !reflect.DeepEqual(got, vs) {
+			t.Errorf("ReadTar(WriteTar(ReadTar(%x))) = %v; want %v", b, got, vs)
+		}
+	})
+}
+
+func FuzzReadJSON(f *testing.F) {
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, testVariables); err != nil {
+		f.Fatal(err)
+	}
+	f.Add(buf.Bytes())
+	f.Add([]byte(`{"variables":[{"guid":"8be4df61-93ca-11d2-aa0d-00e098032b8c","name":"BootCurrent","attributes":6,"data":"DAA="}]}`))
+	f.Fuzz(func(t *testing.T, b []byte) {
+		vs, err := ReadJSON(bytes.NewReader(b))
+		if err != nil {
+			return
+		}
+		var buf bytes.Buffer
+		if err := WriteJSON(&buf, vs); err != nil {
+			t.Fatalf("WriteJSON: %v", err)
+		}
+		if _, err := ReadJSON(&buf); err != nil {
+			t.Fatalf("ReadJSON(WriteJSON(ReadJSON(%x))): %v", b, err)
+		}
+	})
+}
@@ -0,0 +1,358 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package vardump saves EFI variables to files and loads them back. A dump is
+// a directory laid out like efivarfs, a tar archive of such a directory, or a
+// JSON document; all three preserve the variables' attributes.
+package vardump
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lukegb/goefivar/efivar"
+)
+
+// Format is the layout of a dump.
+type Format int
+
+const (
+	// Dir is a directory holding one file per variable, named NAME-GUID and
+	// containing the attributes as a little-endian uint32 followed by the
+	// data, just as efivarfs presents them.
+	Dir Format = iota
+	// Tar is a tar archive of a Dir dump, gzipped if its name ends in .gz
+	// or .tgz.
+	Tar
+	// JSON is a JSON document; see jsonDump.
+	JSON
+)
+
+var formatNames = map[Format]string{
+	Dir:  "dir",
+	Tar:  "tar",
+	JSON: "json",
+}
+
+func (f Format) String() string {
+	if s, ok := formatNames[f]; ok {
+		return s
+	}
+	return fmt.Sprintf("Format(%d)", int(f))
+}
+
+// ParseFormat returns the Format named s, as returned by Format.String.
+func ParseFormat(s string) (Format, error) {
+	for f, name := range formatNames {
+		if s == name {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("vardump: unknown format %q", s)
+}
+
+// FormatOf guesses the format of the dump at path from its name.
+func FormatOf(path string) Format {
+	switch {
+	case strings.HasSuffix(path, ".json"):
+		return JSON
+	case strings.HasSuffix(path, ".tar"), strings.HasSuffix(path, ".tar.gz"), strings.HasSuffix(path, ".tgz"):
+		return Tar
+	}
+	return Dir
+}
+
+func isGzip(path string) bool {
+	return strings.HasSuffix(path, ".gz") || strings.HasSuffix(path, ".tgz")
+}
+
+// Capture reads the variables for which match returns true, or all variables
+// if match is nil. Variables which disappear while they are being read are
+// skipped.
+func Capture(match func(efivar.VariableName) bool) ([]*efivar.Variable, error) {
+	vns, err := efivar.Variables()
+	if err != nil {
+		return nil, fmt.Errorf("vardump: listing variables: %v", err)
+	}
+	var vs []*efivar.Variable
+	for _, vn := range vns {
+		if match != nil && !match(vn) {
+			continue
+		}
+		v, err := vn.Get()
+		switch {
+		case os.IsNotExist(err):
+			continue
+		case err != nil:
+			return nil, fmt.Errorf("vardump: reading %v: %v", vn, err)
+		}
+		vs = append(vs, v)
+	}
+	sortVariables(vs)
+	return vs, nil
+}
+
+func sortVariables(vs []*efivar.Variable) {
+	sort.Slice(vs, func(i, j int) bool {
+		if vs[i].GUID != vs[j].GUID {
+			return vs[i].GUID.String() < vs[j].GUID.String()
+		}
+		return vs[i].Name < vs[j].Name
+	})
+}
+
+// FileName returns the name of vn's file in a Dir dump, NAME-GUID, as
+// vn.String does.
+func FileName(vn efivar.VariableName) string {
+	return vn.String()
+}
+
+// ParseFileName is the inverse of FileName. It is efivar.ParseVariableName.
+func ParseFileName(s string) (efivar.VariableName, error) {
+	return efivar.ParseVariableName(s)
+}
+
+// MarshalFile returns v's content in the efivarfs layout.
+func MarshalFile(v *efivar.Variable) []byte {
+	b := make([]byte, 4+len(v.Data))
+	binary.LittleEndian.PutUint32(b, uint32(v.Attributes))
+	copy(b[4:], v.Data)
+	return b
+}
+
+// UnmarshalFile parses the file called name in a Dir dump.
+func UnmarshalFile(name string, b []byte) (*efivar.Variable, error) {
+	vn, err := ParseFileName(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < 4 {
+		return nil, fmt.Errorf("vardump: %v is too short", name)
+	}
+	return &efivar.Variable{
+		VariableName: vn,
+		Attributes:   efivar.Attributes(binary.LittleEndian.Uint32(b)),
+		Data:         append([]byte(nil), b[4:]...),
+	}, nil
+}
+
+// WriteDir writes vs to the directory dir, creating it if necessary.
+func WriteDir(dir string, vs []*efivar.Variable) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	for _, v := range vs {
+		if err := ioutil.WriteFile(filepath.Join(dir, FileName(v.VariableName)), MarshalFile(v), 0600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadDir reads the Dir dump in dir.
+func ReadDir(dir string) ([]*efivar.Variable, error) {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var vs []*efivar.Variable
+	for _, fi := range fis {
+		if !fi.Mode().IsRegular() {
+			continue
+		}
+		b, err := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return nil, err
+		}
+		v, err := UnmarshalFile(fi.Name(), b)
+		if err != nil {
+			return nil, err
+		}
+		vs = append(vs, v)
+	}
+	sortVariables(vs)
+	return vs, nil
+}
+
+// WriteTar writes vs to w as a tar archive.
+func WriteTar(w io.Writer, vs []*efivar.Variable) error {
+	tw := tar.NewWriter(w)
+	now := time.Now()
+	for _, v := range vs {
+		b := MarshalFile(v)
+		hdr := &tar.Header{
+			Name:    FileName(v.VariableName),
+			Mode:    0600,
+			Size:    int64(len(b)),
+			ModTime: now,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(b); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// ReadTar reads a tar archive written by WriteTar.
+func ReadTar(r io.Reader) ([]*efivar.Variable, error) {
+	tr := tar.NewReader(r)
+	var vs []*efivar.Variable
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		b, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		v, err := UnmarshalFile(filepath.Base(hdr.Name), b)
+		if err != nil {
+			return nil, err
+		}
+		vs = append(vs, v)
+	}
+	sortVariables(vs)
+	return vs, nil
+}
+
+// jsonDump is the schema of a JSON dump.
+type jsonDump struct {
+	Variables []jsonVariable `json:"variables"`
+}
+
+type jsonVariable struct {
+	GUID       uuid.UUID `json:"guid"`
+	Name       string    `json:"name"`
+	Attributes uint32    `json:"attributes"`
+	// Data is base64-encoded.
+	Data []byte `json:"data"`
+}
+
+// WriteJSON writes vs to w as a JSON document.
+func WriteJSON(w io.Writer, vs []*efivar.Variable) error {
+	d := jsonDump{Variables: make([]jsonVariable, len(vs))}
+	for n, v := range vs {
+		d.Variables[n] = jsonVariable{v.GUID, v.Name, uint32(v.Attributes), v.Data}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d)
+}
+
+// ReadJSON reads a JSON document written by WriteJSON.
+func ReadJSON(r io.Reader) ([]*efivar.Variable, error) {
+	var d jsonDump
+	if err := json.NewDecoder(r).Decode(&d); err != nil {
+		return nil, fmt.Errorf("vardump: %v", err)
+	}
+	vs := make([]*efivar.Variable, len(d.Variables))
+	for n, jv := range d.Variables {
+		vs[n] = &efivar.Variable{
+			VariableName: efivar.VariableName{GUID: jv.GUID, Name: jv.Name},
+			Attributes:   efivar.Attributes(jv.Attributes),
+			Data:         jv.Data,
+		}
+	}
+	sortVariables(vs)
+	return vs, nil
+}
+
+// Save writes vs to path in format f.
+func Save(path string, f Format, vs []*efivar.Variable) error {
+	if f == Dir {
+		return WriteDir(path, vs)
+	}
+
+	var buf bytes.Buffer
+	switch f {
+	case Tar:
+		var w io.Writer = &buf
+		var gz *gzip.Writer
+		if isGzip(path) {
+			gz = gzip.NewWriter(&buf)
+			w = gz
+		}
+		if err := WriteTar(w, vs); err != nil {
+			return err
+		}
+		if gz != nil {
+			if err := gz.Close(); err != nil {
+				return err
+			}
+		}
+	case JSON:
+		if err := WriteJSON(&buf, vs); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("vardump: unknown format %v", f)
+	}
+	return ioutil.WriteFile(path, buf.Bytes(), 0600)
+}
+
+// Load reads the dump at path, guessing its format from its name.
+func Load(path string) ([]*efivar.Variable, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		return ReadDir(path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch FormatOf(path) {
+	case JSON:
+		return ReadJSON(f)
+	case Tar:
+		var r io.Reader = f
+		if isGzip(path) {
+			gz, err := gzip.NewReader(f)
+			if err != nil {
+				return nil, err
+			}
+			defer gz.Close()
+			r = gz
+		}
+		return ReadTar(r)
+	}
+	return nil, fmt.Errorf("vardump: %v is neither a directory nor a JSON or tar dump", path)
+}
@@ -0,0 +1,101 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vardump
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/lukegb/goefivar/efivar"
+)
+
+var testVariables = []*efivar.Variable{
+	{
+		VariableName: efivar.VariableName{GUID: uuid.MustParse("605dab50-e046-4300-abb6-3dd810dd8b23"), Name: "MokListRT"},
+		Attributes:   efivar.BootserviceAccess | efivar.RuntimeAccess,
+		Data:         []byte{0xa1, 0x59, 0xc0, 0xa5},
+	},
+	{
+		VariableName: efivar.VariableName{GUID: uuid.MustParse("8be4df61-93ca-11d2-aa0d-00e098032b8c"), Name: "BootOrder"},
+		Attributes:   efivar.NonVolatile | efivar.BootserviceAccess | efivar.RuntimeAccess,
+		Data:         []byte{1, 0, 0, 0},
+	},
+}
+
+func TestParseFileName(t *testing.T) {
+	for _, v := range testVariables {
+		got, err := ParseFileName(FileName(v.VariableName))
+		if err != nil || got != v.VariableName {
+			t.Errorf("ParseFileName(FileName(%v)) = %v, %v", v.VariableName, got, err)
+		}
+	}
+	for _, s := range []string{"", "BootOrder", "BootOrder-8be4df61-93ca-11d2-aa0d-00e098032b8", "BootOrder_8be4df61-93ca-11d2-aa0d-00e098032b8c"} {
+		if _, err := ParseFileName(s); err == nil {
+			t.Errorf("ParseFileName(%q) succeeded", s)
+		}
+	}
+}
+
+func TestRoundtrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vardump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"dump", "dump.tar", "dump.tar.gz", "dump.json"} {
+		path := filepath.Join(dir, name)
+		if err := Save(path, FormatOf(path), testVariables); err != nil {
+			t.Errorf("Save(%v): %v", name, err)
+			continue
+		}
+		got, err := Load(path)
+		if err != nil {
+			t.Errorf("Load(%v): %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, testVariables) {
+			t.Errorf("Load(%v) = %v; want %v", name, got, testVariables)
+		}
+	}
+}
+
+func TestReadDirEfivarfsLayout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vardump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// As found in /sys/firmware/efi/efivars.
+	if err := ioutil.WriteFile(filepath.Join(dir, "BootOrder-8be4df61-93ca-11d2-aa0d-00e098032b8c"), []byte{7, 0, 0, 0, 1, 0, 0, 0}, 0600); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	if want := testVariables[1:]; !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadDir = %v; want %v", got, want)
+	}
+	if b := MarshalFile(got[0]); !bytes.Equal(b, []byte{7, 0, 0, 0, 1, 0, 0, 0}) {
+		t.Errorf("MarshalFile = %x", b)
+	}
+}
@@ -0,0 +1,350 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package varqueue delays and merges writes to EFI variables, so that a
+// program which sets the same variables over and over, such as a reconcile
+// loop, writes NVRAM rarely. Flash wears with each write, and some firmware
+// misbehaves when written to quickly.
+//
+// A Queue holds each write for Options.Delay, during which later writes to
+// the same variable replace or extend it, and makes at most one write every
+// Options.MinInterval. Reads through the Queue see the writes it holds.
+package varqueue
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/lukegb/goefivar/efivar"
+)
+
+// Defaults for Options.
+const (
+	DefaultDelay       = time.Second
+	DefaultMinInterval = time.Second
+)
+
+// Options configures a Queue. Zero values select the defaults.
+type Options struct {
+	// Delay is how long a write is held, from when it is first queued,
+	// for later writes to the same variable to merge into.
+	Delay time.Duration
+	// MinInterval is the shortest time between two writes.
+	MinInterval time.Duration
+	// Mode is the permissions of variables created in efivarfs. It
+	// defaults to 0644.
+	Mode os.FileMode
+	// OnError, if set, is called with the errors of writes made in the
+	// background. They are also returned by the next Flush.
+	OnError func(vn efivar.VariableName, err error)
+}
+
+// op is a write held by a Queue: a deletion if v is nil, and otherwise a
+// Set of v, which appends if it has AppendWrite.
+type op struct {
+	v      *efivar.Variable
+	queued time.Time
+}
+
+// Queue holds writes to variables. It implements efivar.Store, so it can be
+// passed to code which takes one; it must not be installed with
+// efivar.UseStore unless it writes to another Store, as it would then write
+// to itself. A Queue is safe for concurrent use.
+type Queue struct {
+	target efivar.Store
+	delay  time.Duration
+	min    time.Duration
+	mode   os.FileMode
+	onErr  func(efivar.VariableName, error)
+
+	mu      sync.Mutex
+	pending map[efivar.VariableName]*op
+	order   []efivar.VariableName // of pending, by when first queued
+	last    time.Time             // of the last write
+	err     error                 // the first background error since Flush
+	closed  bool
+
+	wake chan struct{}
+	done chan struct{}
+}
+
+// New returns a Queue writing to target, or to the running system's
+// variables if target is nil, and starts writing in the background.
+func New(target efivar.Store, opts *Options) *Queue {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	if o.Delay == 0 {
+		o.Delay = DefaultDelay
+	}
+	if o.MinInterval == 0 {
+		o.MinInterval = DefaultMinInterval
+	}
+	if o.Mode == 0 {
+		o.Mode = 0644
+	}
+	q := &Queue{
+		target:  target,
+		delay:   o.Delay,
+		min:     o.MinInterval,
+		mode:    o.Mode,
+		onErr:   o.OnError,
+		pending: make(map[efivar.VariableName]*op),
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+func copyVariable(v *efivar.Variable) *efivar.Variable {
+	c := *v
+	c.Data = append([]byte(nil), v.Data...)
+	return &c
+}
+
+// authenticated reports whether v is an authenticated write, whose signature
+// covers exactly its data, so that it cannot be merged with another.
+func authenticated(v *efivar.Variable) bool {
+	return v.Attributes&(efivar.AuthenticatedWriteAccess|efivar.TimeBasedAuthenticatedWriteAccess) != 0
+}
+
+// Set queues v to be written. Authenticated writes are not delayed: Set
+// makes them at once, after any write already queued for the variable, and
+// returns their error.
+func (q *Queue) Set(v *efivar.Variable) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return os.ErrClosed
+	}
+	if authenticated(v) {
+		if p, ok := q.pending[v.VariableName]; ok {
+			if err := q.write(v.VariableName, p); err != nil {
+				return err
+			}
+		}
+		return q.write(v.VariableName, &op{v: v})
+	}
+	q.enqueue(v.VariableName, copyVariable(v))
+	return nil
+}
+
+// Delete queues the deletion of vn.
+func (q *Queue) Delete(vn efivar.VariableName) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return os.ErrClosed
+	}
+	q.enqueue(vn, nil)
+	return nil
+}
+
+// enqueue merges v, or a deletion if v is nil, into the writes held for vn.
+func (q *Queue) enqueue(vn efivar.VariableName, v *efivar.Variable) {
+	p, ok := q.pending[vn]
+	if !ok {
+		q.pending[vn] = &op{v: v, queued: time.Now()}
+		q.order = append(q.order, vn)
+		q.poke()
+		return
+	}
+	switch {
+	case v == nil || !v.Attributes.Has(efivar.AppendWrite):
+		p.v = v
+	case p.v == nil:
+		// Appending to a deleted variable creates it.
+		v.Attributes = v.Attributes.Without(efivar.AppendWrite)
+		p.v = v
+	default:
+		p.v.Data = append(p.v.Data, v.Data...)
+	}
+}
+
+// poke wakes the background writer to reconsider when to write next.
+func (q *Queue) poke() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Get returns vn as it will be once the queued writes are made.
+func (q *Queue) Get(vn efivar.VariableName) (*efivar.Variable, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	p, ok := q.pending[vn]
+	switch {
+	case !ok:
+		return q.get(vn)
+	case p.v == nil:
+		return nil, efivar.NotExist("get", vn)
+	case !p.v.Attributes.Has(efivar.AppendWrite):
+		return copyVariable(p.v), nil
+	}
+	v, err := q.get(vn)
+	if os.IsNotExist(err) {
+		v = &efivar.Variable{VariableName: vn, Attributes: p.v.Attributes.Without(efivar.AppendWrite)}
+	} else if err != nil {
+		return nil, err
+	}
+	v.Data = append(v.Data, p.v.Data...)
+	return v, nil
+}
+
+// Variables lists the variables as they will be once the queued writes are
+// made.
+func (q *Queue) Variables() ([]efivar.VariableName, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	vns, err := q.variables()
+	if err != nil {
+		return nil, err
+	}
+	var out []efivar.VariableName
+	seen := make(map[efivar.VariableName]bool)
+	for _, vn := range vns {
+		seen[vn] = true
+		if p, ok := q.pending[vn]; !ok || p.v != nil {
+			out = append(out, vn)
+		}
+	}
+	for _, vn := range q.order {
+		if !seen[vn] && q.pending[vn].v != nil {
+			out = append(out, vn)
+		}
+	}
+	return out, nil
+}
+
+func (q *Queue) get(vn efivar.VariableName) (*efivar.Variable, error) {
+	if q.target != nil {
+		return q.target.Get(vn)
+	}
+	return vn.Get()
+}
+
+func (q *Queue) variables() ([]efivar.VariableName, error) {
+	if q.target != nil {
+		return q.target.Variables()
+	}
+	return efivar.Variables()
+}
+
+// write makes the write p to vn, and removes it from the queue.
+func (q *Queue) write(vn efivar.VariableName, p *op) error {
+	if q.pending[vn] == p {
+		delete(q.pending, vn)
+		for n, o := range q.order {
+			if o == vn {
+				q.order = append(q.order[:n], q.order[n+1:]...)
+				break
+			}
+		}
+	}
+	q.last = time.Now()
+	var err error
+	switch {
+	case p.v == nil && q.target != nil:
+		err = q.target.Delete(vn)
+	case p.v == nil:
+		err = vn.Delete()
+	case q.target != nil:
+		err = q.target.Set(p.v)
+	default:
+		err = p.v.Set(q.mode)
+	}
+	if p.v == nil && os.IsNotExist(err) {
+		err = nil
+	}
+	return err
+}
+
+// run makes the queued writes as they fall due, until Close.
+func (q *Queue) run() {
+	t := time.NewTimer(time.Hour)
+	for {
+		q.mu.Lock()
+		wait := time.Hour
+		if len(q.order) > 0 {
+			vn := q.order[0]
+			p := q.pending[vn]
+			due := p.queued.Add(q.delay)
+			if next := q.last.Add(q.min); next.After(due) {
+				due = next
+			}
+			if wait = time.Until(due); wait <= 0 {
+				if err := q.write(vn, p); err != nil {
+					if q.err == nil {
+						q.err = err
+					}
+					if q.onErr != nil {
+						q.onErr(vn, err)
+					}
+				}
+				q.mu.Unlock()
+				continue
+			}
+		}
+		q.mu.Unlock()
+
+		if !t.Stop() {
+			select {
+			case <-t.C:
+			default:
+			}
+		}
+		t.Reset(wait)
+		select {
+		case <-t.C:
+		case <-q.wake:
+		case <-q.done:
+			t.Stop()
+			return
+		}
+	}
+}
+
+// Flush makes every queued write now, without waiting for Delay or
+// MinInterval. It returns the first error among them and the writes made
+// in the background since the last Flush.
+func (q *Queue) Flush() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	err := q.err
+	q.err = nil
+	for len(q.order) > 0 {
+		vn := q.order[0]
+		if werr := q.write(vn, q.pending[vn]); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	return err
+}
+
+// Close flushes q and stops it. Later writes return os.ErrClosed.
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return os.ErrClosed
+	}
+	q.closed = true
+	close(q.done)
+	q.mu.Unlock()
+	return q.Flush()
+}
@@ -0,0 +1,208 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package varqueue
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lukegb/goefivar/efivar"
+)
+
+// countingStore is a Store in memory which counts writes.
+type countingStore struct {
+	mu     sync.Mutex
+	vars   map[efivar.VariableName]*efivar.Variable
+	writes int
+}
+
+func newCountingStore() *countingStore {
+	return &countingStore{vars: make(map[efivar.VariableName]*efivar.Variable)}
+}
+
+func (s *countingStore) Get(vn efivar.VariableName) (*efivar.Variable, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.vars[vn]
+	if !ok {
+		return nil, efivar.NotExist("get", vn)
+	}
+	return copyVariable(v), nil
+}
+
+func (s *countingStore) Set(v *efivar.Variable) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writes++
+	if v.Attributes&efivar.AppendWrite != 0 {
+		if old, ok := s.vars[v.VariableName]; ok {
+			old.Data = append(old.Data, v.Data...)
+			return nil
+		}
+	}
+	c := copyVariable(v)
+	c.Attributes &^= efivar.AppendWrite
+	s.vars[v.VariableName] = c
+	return nil
+}
+
+func (s *countingStore) Delete(vn efivar.VariableName) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writes++
+	if _, ok := s.vars[vn]; !ok {
+		return efivar.NotExist("delete", vn)
+	}
+	delete(s.vars, vn)
+	return nil
+}
+
+func (s *countingStore) Variables() ([]efivar.VariableName, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []efivar.VariableName
+	for vn := range s.vars {
+		out = append(out, vn)
+	}
+	return out, nil
+}
+
+func (s *countingStore) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.writes
+}
+
+var testGUID = uuid.MustParse("74552304-ce9f-4e52-89a0-f6c6fa47deac")
+
+func testVar(name, data string, attrs efivar.Attributes) *efivar.Variable {
+	return &efivar.Variable{
+		VariableName: efivar.VariableName{GUID: testGUID, Name: name},
+		Attributes:   efivar.NonVolatile | efivar.BootserviceAccess | attrs,
+		Data:         []byte(data),
+	}
+}
+
+func TestCoalesce(t *testing.T) {
+	s := newCountingStore()
+	q := New(s, &Options{Delay: 50 * time.Millisecond, MinInterval: time.Millisecond})
+	defer q.Close()
+	for n := 0; n < 10; n++ {
+		if err := q.Set(testVar("A", fmt.Sprint(n), 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, err := q.Get(testVar("A", "", 0).VariableName); err != nil || string(got.Data) != "9" {
+		t.Errorf("Get before the write = %+v, %v; want 9", got, err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if n := s.count(); n != 1 {
+		t.Errorf("made %d writes; want 1", n)
+	}
+	if got, err := s.Get(testVar("A", "", 0).VariableName); err != nil || string(got.Data) != "9" {
+		t.Errorf("written variable = %+v, %v; want 9", got, err)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		writes []*efivar.Variable // nil deletes A
+		want   string             // "" for no variable
+	}{
+		{"append", []*efivar.Variable{testVar("A", "base", 0), testVar("A", "+x", efivar.AppendWrite), testVar("A", "+y", efivar.AppendWrite)}, "base+x+y"},
+		{"append to existing", []*efivar.Variable{testVar("A", "+x", efivar.AppendWrite), testVar("A", "+y", efivar.AppendWrite)}, "old+x+y"},
+		{"set after append", []*efivar.Variable{testVar("A", "+x", efivar.AppendWrite), testVar("A", "new", 0)}, "new"},
+		{"delete", []*efivar.Variable{testVar("A", "new", 0), nil}, ""},
+		{"append after delete", []*efivar.Variable{nil, testVar("A", "x", efivar.AppendWrite)}, "x"},
+	} {
+		s := newCountingStore()
+		s.vars[testVar("A", "", 0).VariableName] = testVar("A", "old", 0)
+		q := New(s, &Options{Delay: time.Hour})
+		for _, v := range tc.writes {
+			if v == nil {
+				q.Delete(testVar("A", "", 0).VariableName)
+			} else {
+				q.Set(v)
+			}
+		}
+		check := func(what string, got *efivar.Variable, err error) {
+			switch {
+			case tc.want == "" && !os.IsNotExist(err):
+				t.Errorf("%v: %v = %+v, %v; want no variable", tc.name, what, got, err)
+			case tc.want != "" && (err != nil || string(got.Data) != tc.want || got.Attributes&efivar.AppendWrite != 0):
+				t.Errorf("%v: %v = %+v, %v; want %q", tc.name, what, got, err, tc.want)
+			}
+		}
+		got, err := q.Get(testVar("A", "", 0).VariableName)
+		check("Get from the queue", got, err)
+		if err := q.Close(); err != nil {
+			t.Errorf("%v: Close: %v", tc.name, err)
+		}
+		got, err = s.Get(testVar("A", "", 0).VariableName)
+		check("written variable", got, err)
+		if n := s.count(); n != 1 {
+			t.Errorf("%v: made %d writes; want 1", tc.name, n)
+		}
+	}
+}
+
+func TestMinInterval(t *testing.T) {
+	s := newCountingStore()
+	q := New(s, &Options{Delay: time.Millisecond, MinInterval: time.Hour})
+	for _, name := range []string{"A", "B", "C"} {
+		q.Set(testVar(name, "x", 0))
+	}
+	time.Sleep(100 * time.Millisecond)
+	if n := s.count(); n != 1 {
+		t.Errorf("made %d writes within MinInterval; want 1", n)
+	}
+	vns, err := q.Variables()
+	sort.Slice(vns, func(i, j int) bool { return vns[i].Name < vns[j].Name })
+	if err != nil || len(vns) != 3 || vns[0].Name != "A" || vns[2].Name != "C" {
+		t.Errorf("Variables = %v, %v; want A, B and C", vns, err)
+	}
+	if err := q.Flush(); err != nil {
+		t.Errorf("Flush: %v", err)
+	}
+	if n := s.count(); n != 3 {
+		t.Errorf("made %d writes after Flush; want 3", n)
+	}
+	q.Close()
+	if err := q.Set(testVar("A", "y", 0)); err != os.ErrClosed {
+		t.Errorf("Set after Close = %v; want %v", err, os.ErrClosed)
+	}
+}
+
+func TestAuthenticated(t *testing.T) {
+	s := newCountingStore()
+	q := New(s, &Options{Delay: time.Hour})
+	defer q.Close()
+	q.Set(testVar("A", "queued", 0))
+	auth := testVar("A", "signed", efivar.TimeBasedAuthenticatedWriteAccess)
+	if err := q.Set(auth); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.Get(auth.VariableName)
+	if n := s.count(); n != 2 || err != nil || !reflect.DeepEqual(got, auth) {
+		t.Errorf("after an authenticated write, %d writes and %+v, %v; want 2 writes and %+v", n, got, err, auth)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package varstore
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/vardump"
+)
+
+// Dir is a store kept in a directory dump, as written by vardump.WriteDir:
+// one file per variable, laid out as efivarfs presents them.
+type Dir struct {
+	Path string
+}
+
+func (d *Dir) path(vn efivar.VariableName) string {
+	return filepath.Join(d.Path, vardump.FileName(vn))
+}
+
+func (d *Dir) Get(vn efivar.VariableName) (*efivar.Variable, error) {
+	b, err := ioutil.ReadFile(d.path(vn))
+	if os.IsNotExist(err) {
+		return nil, efivar.NotExist("get", vn)
+	} else if err != nil {
+		return nil, err
+	}
+	return vardump.UnmarshalFile(vardump.FileName(vn), b)
+}
+
+func (d *Dir) Set(v *efivar.Variable) error {
+	old, err := d.Get(v.VariableName)
+	if os.IsNotExist(err) {
+		old = nil
+	} else if err != nil {
+		return err
+	}
+	nv, err := update(old, v)
+	if err != nil {
+		return err
+	}
+	if nv == nil {
+		return d.Delete(v.VariableName)
+	}
+	return ioutil.WriteFile(d.path(v.VariableName), vardump.MarshalFile(nv), 0600)
+}
+
+func (d *Dir) Delete(vn efivar.VariableName) error {
+	err := os.Remove(d.path(vn))
+	if os.IsNotExist(err) {
+		return efivar.NotExist("delete", vn)
+	}
+	return err
+}
+
+func (d *Dir) Variables() ([]efivar.VariableName, error) {
+	fis, err := ioutil.ReadDir(d.Path)
+	if err != nil {
+		return nil, err
+	}
+	var out []efivar.VariableName
+	for _, fi := range fis {
+		if !fi.Mode().IsRegular() {
+			continue
+		}
+		vn, err := vardump.ParseFileName(fi.Name())
+		if err != nil {
+			continue
+		}
+		out = append(out, vn)
+	}
+	return out, nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package varstore
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lukegb/goefivar/efivar"
+)
+
+func FuzzParseImage(f *testing.F) {
+	f.Add(testImage(256))
+	img, err := ParseImage(testImage(512))
+	if err != nil {
+		f.Fatal(err)
+	}
+	if err := img.Set(&efivar.Variable{
+		VariableName: efivar.VariableName{GUID: efivar.GlobalUUID, Name: "BootOrder"},
+		Data:         []byte{1, 0},
+		Attributes:   efivar.NonVolatile | efivar.BootserviceAccess | efivar.RuntimeAccess,
+	}); err != nil {
+		f.Fatal(err)
+	}
+	b, err := img.Bytes()
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(b)
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		img, err := ParseImage(b)
+		if err != nil {
+			return
+		}
+		vns, err := img.Variables()
+		if err != nil {
+			t.Fatalf("Variables: %v", err)
+		}
+		var vs []*efivar.Variable
+		for _, vn := range vns {
+			v, err := img.Get(vn)
+			if err != nil {
+				t.Fatalf("Get(%v) of a listed variable: %v", vn, err)
+			}
+			vs = append(vs, v)
+		}
+		out, err := img.Bytes()
+		if err != nil {
+			return
+		}
+		got, err := ParseImage(out)
+		if err != nil {
+			t.Fatalf("ParseImage(Bytes()): %v", err)
+		}
+		gotVNs, _ := got.Variables()
+		if !reflect.DeepEqual(gotVNs, vns) {
+			t.Errorf("Variables() after Bytes() = %v; want %v", gotVNs, vns)
+		}
+		for _, want := range vs {
+			if v, err := got.Get(want.VariableName); err != nil || !reflect.DeepEqual(v, want) {
+				t.Errorf("Get(%v) after Bytes() = %+v, %v; want %+v", want.Name, v, err, want)
+			}
+		}
+	})
+}
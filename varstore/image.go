@@ -0,0 +1,348 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package varstore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"unicode/utf16"
+
+	"github.com/google/uuid"
+	"github.com/lukegb/goefivar/efivar"
+)
+
+var (
+	ErrNotImage  = errors.New("varstore: not a firmware volume holding a variable store")
+	ErrCorrupted = efivar.NewError(efivar.Corrupt, "varstore: variable store is corrupted")
+	ErrStoreFull = efivar.NewError(efivar.NVRAMFull, "varstore: not enough space in the variable store")
+
+	// VariableStoreUUID and AuthenticatedVariableStoreUUID identify the two
+	// layouts of EDK II variable store.
+	VariableStoreUUID              = uuid.MustParse("ddcf3616-3275-4164-98b6-fe85707ffe7d")
+	AuthenticatedVariableStoreUUID = uuid.MustParse("aaf32c78-947b-439a-a180-2e144ec37792")
+)
+
+var byteOrder = binary.LittleEndian
+
+const (
+	fvSignature     = "_FVH"
+	fvHeaderMinSize = 56
+
+	storeHeaderSize = 28
+	storeFormatted  = 0x5a
+	storeHealthy    = 0xfe
+
+	variableStartID = 0x55aa
+	headerSize      = 32
+	authHeaderSize  = 60
+
+	// varAdded is the state of a live variable. One in the middle of being
+	// replaced has the in-deleted-transition bit cleared as well.
+	varAdded                = 0x3f
+	varAddedDeletingPending = 0x3e
+)
+
+// imageVariable is a variable in an Image, with the authenticated header
+// fields it had in the image.
+type imageVariable struct {
+	*efivar.Variable
+	monotonicCount uint64
+	timestamp      [16]byte
+	pubKeyIndex    uint32
+}
+
+// Image is the variable store of an EDK II firmware volume, such as
+// OVMF_VARS.fd. Changes are written back to the file it was opened from.
+// An Image is safe for concurrent use.
+type Image struct {
+	mu sync.Mutex
+
+	path string
+	data []byte
+
+	authenticated bool
+	// varsStart and varsEnd delimit the space for variables in data.
+	varsStart, varsEnd int
+
+	vars []*imageVariable
+}
+
+func align4(n int) int { return (n + 3) &^ 3 }
+
+// ParseImage parses a firmware volume holding a variable store.
+func ParseImage(b []byte) (*Image, error) {
+	if len(b) < fvHeaderMinSize || string(b[40:44]) != fvSignature {
+		return nil, ErrNotImage
+	}
+	storeStart := int(byteOrder.Uint16(b[48:50]))
+	if storeStart+storeHeaderSize > len(b) {
+		return nil, ErrNotImage
+	}
+	store := b[storeStart:]
+	img := &Image{data: append([]byte(nil), b...)}
+	switch efivar.GUIDFromBytes(store[0:16]) {
+	case VariableStoreUUID:
+	case AuthenticatedVariableStoreUUID:
+		img.authenticated = true
+	default:
+		return nil, ErrNotImage
+	}
+	size := int(byteOrder.Uint32(store[16:20]))
+	if store[20] != storeFormatted || size < storeHeaderSize || storeStart+size > len(b) {
+		return nil, ErrCorrupted
+	}
+	img.varsStart = align4(storeStart + storeHeaderSize)
+	img.varsEnd = storeStart + size
+
+	hdrSize := headerSize
+	if img.authenticated {
+		hdrSize = authHeaderSize
+	}
+	pending := make(map[efivar.VariableName]*imageVariable)
+	for off := img.varsStart; off+hdrSize <= img.varsEnd; {
+		h := b[off : off+hdrSize]
+		if byteOrder.Uint16(h[0:2]) != variableStartID {
+			break
+		}
+		state := h[2]
+		iv := &imageVariable{Variable: &efivar.Variable{Attributes: efivar.Attributes(byteOrder.Uint32(h[4:8]))}}
+		sizes := h[8:]
+		if img.authenticated {
+			iv.monotonicCount = byteOrder.Uint64(h[8:16])
+			copy(iv.timestamp[:], h[16:32])
+			iv.pubKeyIndex = byteOrder.Uint32(h[32:36])
+			sizes = h[36:]
+		}
+		nameSize := int(byteOrder.Uint32(sizes[0:4]))
+		dataSize := int(byteOrder.Uint32(sizes[4:8]))
+		iv.GUID = efivar.GUIDFromBytes(sizes[8:24])
+
+		nameStart := off + hdrSize
+		dataStart := nameStart + nameSize
+		if nameSize%2 != 0 || dataSize < 0 || dataStart+dataSize > img.varsEnd {
+			return nil, ErrCorrupted
+		}
+		iv.Name = decodeName(b[nameStart:dataStart])
+		iv.Data = append([]byte(nil), b[dataStart:dataStart+dataSize]...)
+		off = align4(dataStart + dataSize)
+
+		switch state {
+		case varAdded:
+			img.replace(iv)
+			delete(pending, iv.VariableName)
+		case varAddedDeletingPending:
+			// Superseded by a later copy, unless the update was interrupted.
+			if img.index(iv.VariableName) < 0 {
+				pending[iv.VariableName] = iv
+			}
+		}
+	}
+	for _, iv := range pending {
+		img.replace(iv)
+	}
+	return img, nil
+}
+
+// OpenImage opens the firmware image at path.
+func OpenImage(path string) (*Image, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	img, err := ParseImage(b)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+	img.path = path
+	return img, nil
+}
+
+func decodeName(b []byte) string {
+	u := make([]uint16, 0, len(b)/2)
+	for n := 0; n+1 < len(b); n += 2 {
+		c := byteOrder.Uint16(b[n:])
+		if c == 0 {
+			break
+		}
+		u = append(u, c)
+	}
+	return string(utf16.Decode(u))
+}
+
+func encodeName(s string) []byte {
+	u := append(utf16.Encode([]rune(s)), 0)
+	b := make([]byte, 2*len(u))
+	for n, c := range u {
+		byteOrder.PutUint16(b[2*n:], c)
+	}
+	return b
+}
+
+func (img *Image) index(vn efivar.VariableName) int {
+	for n, iv := range img.vars {
+		if iv.VariableName == vn {
+			return n
+		}
+	}
+	return -1
+}
+
+func (img *Image) replace(iv *imageVariable) {
+	if n := img.index(iv.VariableName); n >= 0 {
+		img.vars[n] = iv
+		return
+	}
+	img.vars = append(img.vars, iv)
+}
+
+// Bytes returns the image with the current variables. The store is
+// rewritten compactly, as the firmware does when it reclaims space.
+func (img *Image) Bytes() ([]byte, error) {
+	img.mu.Lock()
+	defer img.mu.Unlock()
+	return img.bytes()
+}
+
+func (img *Image) bytes() ([]byte, error) {
+	out := append([]byte(nil), img.data...)
+	area := out[img.varsStart:img.varsEnd]
+	for n := range area {
+		area[n] = 0xff
+	}
+
+	var buf bytes.Buffer
+	for _, iv := range img.vars {
+		name := encodeName(iv.Name)
+		binary.Write(&buf, byteOrder, uint16(variableStartID))
+		buf.Write([]byte{varAdded, 0})
+		binary.Write(&buf, byteOrder, uint32(iv.Attributes))
+		if img.authenticated {
+			binary.Write(&buf, byteOrder, iv.monotonicCount)
+			buf.Write(iv.timestamp[:])
+			binary.Write(&buf, byteOrder, iv.pubKeyIndex)
+		}
+		binary.Write(&buf, byteOrder, uint32(len(name)))
+		binary.Write(&buf, byteOrder, uint32(len(iv.Data)))
+		buf.Write(efivar.GUIDBytes(iv.GUID))
+		buf.Write(name)
+		buf.Write(iv.Data)
+		for buf.Len()%4 != 0 {
+			buf.WriteByte(0xff)
+		}
+	}
+	if buf.Len() > len(area) {
+		return nil, ErrStoreFull
+	}
+	copy(area, buf.Bytes())
+	return out, nil
+}
+
+// save writes the image back to its file, if it has one.
+func (img *Image) save() error {
+	b, err := img.bytes()
+	if err != nil {
+		return err
+	}
+	if img.path == "" {
+		img.data = b
+		return nil
+	}
+	if err := ioutil.WriteFile(img.path, b, 0644); err != nil {
+		return err
+	}
+	img.data = b
+	return nil
+}
+
+func (img *Image) Get(vn efivar.VariableName) (*efivar.Variable, error) {
+	img.mu.Lock()
+	defer img.mu.Unlock()
+	n := img.index(vn)
+	if n < 0 {
+		return nil, efivar.NotExist("get", vn)
+	}
+	v := *img.vars[n].Variable
+	v.Data = append([]byte(nil), v.Data...)
+	return &v, nil
+}
+
+func (img *Image) Set(v *efivar.Variable) error {
+	img.mu.Lock()
+	defer img.mu.Unlock()
+	n := img.index(v.VariableName)
+	var old *efivar.Variable
+	if n >= 0 {
+		old = img.vars[n].Variable
+	}
+	nv, err := update(old, v)
+	if err != nil {
+		return err
+	}
+	if nv == nil {
+		if n < 0 {
+			return efivar.NotExist("set", v.VariableName)
+		}
+		return img.delete(v.VariableName)
+	}
+
+	prev := img.vars
+	img.vars = append([]*imageVariable(nil), img.vars...)
+	if n >= 0 {
+		iv := *img.vars[n]
+		iv.Variable = nv
+		img.vars[n] = &iv
+	} else {
+		img.vars = append(img.vars, &imageVariable{Variable: nv})
+	}
+	if err := img.save(); err != nil {
+		img.vars = prev
+		return err
+	}
+	return nil
+}
+
+func (img *Image) Delete(vn efivar.VariableName) error {
+	img.mu.Lock()
+	defer img.mu.Unlock()
+	return img.delete(vn)
+}
+
+func (img *Image) delete(vn efivar.VariableName) error {
+	n := img.index(vn)
+	if n < 0 {
+		return efivar.NotExist("delete", vn)
+	}
+	prev := img.vars
+	img.vars = append(append([]*imageVariable(nil), img.vars[:n]...), img.vars[n+1:]...)
+	if err := img.save(); err != nil {
+		img.vars = prev
+		return err
+	}
+	return nil
+}
+
+func (img *Image) Variables() ([]efivar.VariableName, error) {
+	img.mu.Lock()
+	defer img.mu.Unlock()
+	out := make([]efivar.VariableName, len(img.vars))
+	for n, iv := range img.vars {
+		out[n] = iv.VariableName
+	}
+	return out, nil
+}
@@ -0,0 +1,198 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package varstore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/lukegb/goefivar/efivar"
+)
+
+// testImage returns an empty authenticated variable store of size bytes,
+// laid out as in OVMF_VARS.fd.
+func testImage(size int) []byte {
+	const fvHeaderLength = 0x48
+	b := make([]byte, fvHeaderLength+size)
+	binary.LittleEndian.PutUint64(b[32:40], uint64(len(b)))
+	copy(b[40:44], fvSignature)
+	binary.LittleEndian.PutUint16(b[48:50], fvHeaderLength)
+	store := b[fvHeaderLength:]
+	copy(store, efivar.GUIDBytes(AuthenticatedVariableStoreUUID))
+	binary.LittleEndian.PutUint32(store[16:20], uint32(size))
+	store[20] = storeFormatted
+	store[21] = storeHealthy
+	for n := storeHeaderSize; n < size; n++ {
+		store[n] = 0xff
+	}
+	return b
+}
+
+func TestImageRoundtrip(t *testing.T) {
+	img, err := ParseImage(testImage(512))
+	if err != nil {
+		t.Fatalf("ParseImage: %v", err)
+	}
+	boot := &efivar.Variable{
+		VariableName: efivar.VariableName{GUID: efivar.GlobalUUID, Name: "Boot0001"},
+		Data:         []byte("load option"),
+		Attributes:   efivar.NonVolatile | efivar.BootserviceAccess | efivar.RuntimeAccess,
+	}
+	order := &efivar.Variable{
+		VariableName: efivar.VariableName{GUID: efivar.GlobalUUID, Name: "BootOrder"},
+		Data:         []byte{1, 0},
+		Attributes:   efivar.NonVolatile | efivar.BootserviceAccess | efivar.RuntimeAccess,
+	}
+	for _, v := range []*efivar.Variable{boot, order} {
+		if err := img.Set(v); err != nil {
+			t.Fatalf("Set(%v): %v", v.Name, err)
+		}
+	}
+	if err := img.Set(&efivar.Variable{VariableName: order.VariableName, Data: []byte{2, 0}, Attributes: order.Attributes | efivar.AppendWrite}); err != nil {
+		t.Fatalf("Set(append): %v", err)
+	}
+	order.Data = []byte{1, 0, 2, 0}
+
+	b, err := img.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes: %v", err)
+	}
+	got, err := ParseImage(b)
+	if err != nil {
+		t.Fatalf("ParseImage(Bytes()): %v", err)
+	}
+	vns, _ := got.Variables()
+	if want := []efivar.VariableName{boot.VariableName, order.VariableName}; !reflect.DeepEqual(vns, want) {
+		t.Errorf("Variables() = %v; want %v", vns, want)
+	}
+	for _, want := range []*efivar.Variable{boot, order} {
+		v, err := got.Get(want.VariableName)
+		if err != nil || !reflect.DeepEqual(v, want) {
+			t.Errorf("Get(%v) = %+v, %v; want %+v", want.Name, v, err, want)
+		}
+	}
+
+	if err := got.Delete(boot.VariableName); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := got.Get(boot.VariableName); !os.IsNotExist(err) {
+		t.Errorf("Get after Delete = %v; want a not-exist error", err)
+	}
+	if err := got.Set(&efivar.Variable{VariableName: boot.VariableName, Data: bytes.Repeat([]byte{0}, 1024)}); err != ErrStoreFull {
+		t.Errorf("Set(too large) = %v; want ErrStoreFull", err)
+	}
+
+	if _, err := ParseImage(make([]byte, 128)); err != ErrNotImage {
+		t.Errorf("ParseImage(zeroes) = %v; want ErrNotImage", err)
+	}
+}
+
+func TestDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "varstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	v := &efivar.Variable{
+		VariableName: efivar.VariableName{GUID: efivar.GlobalUUID, Name: "Timeout"},
+		Data:         []byte{5, 0},
+		Attributes:   efivar.NonVolatile | efivar.BootserviceAccess | efivar.RuntimeAccess,
+	}
+	if err := s.Set(v); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Timeout-8be4df61-93ca-11d2-aa0d-00e098032b8c")); err != nil {
+		t.Errorf("Set did not write the variable's file: %v", err)
+	}
+	if got, err := s.Get(v.VariableName); err != nil || !reflect.DeepEqual(got, v) {
+		t.Errorf("Get = %+v, %v; want %+v", got, err, v)
+	}
+	if err := s.Set(&efivar.Variable{VariableName: v.VariableName}); err != nil {
+		t.Fatalf("Set(empty): %v", err)
+	}
+	if vns, err := s.Variables(); err != nil || len(vns) != 0 {
+		t.Errorf("Variables after deleting = %v, %v; want none", vns, err)
+	}
+}
+
+func TestUpdateAuthenticated(t *testing.T) {
+	payload := []byte("signature list")
+	data := make([]byte, efiTimeSize+8)
+	binary.LittleEndian.PutUint32(data[efiTimeSize:], 8)
+	data = append(data, payload...)
+
+	v := &efivar.Variable{
+		VariableName: efivar.VariableName{GUID: efivar.GlobalUUID, Name: "KEK"},
+		Data:         data,
+		Attributes:   efivar.NonVolatile | efivar.TimeBasedAuthenticatedWriteAccess,
+	}
+	got, err := update(nil, v)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if !bytes.Equal(got.Data, payload) {
+		t.Errorf("update stored %q; want %q", got.Data, payload)
+	}
+	v.Data = data[:efiTimeSize+4]
+	if _, err := update(nil, v); err != ErrAuthentication {
+		t.Errorf("update(truncated) = %v; want ErrAuthentication", err)
+	}
+}
+
+// TestImageConcurrent is most useful with -race.
+func TestImageConcurrent(t *testing.T) {
+	img, err := ParseImage(testImage(8192))
+	if err != nil {
+		t.Fatalf("ParseImage: %v", err)
+	}
+	var wg sync.WaitGroup
+	for n := 0; n < 8; n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			v := &efivar.Variable{
+				VariableName: efivar.VariableName{GUID: efivar.GlobalUUID, Name: fmt.Sprintf("Boot%04X", n)},
+				Data:         []byte("load option"),
+				Attributes:   efivar.NonVolatile | efivar.BootserviceAccess | efivar.RuntimeAccess,
+			}
+			for i := 0; i < 20; i++ {
+				if err := img.Set(v); err != nil {
+					t.Errorf("Set(%v): %v", v.Name, err)
+					return
+				}
+				if _, err := img.Get(v.VariableName); err != nil {
+					t.Errorf("Get(%v): %v", v.Name, err)
+				}
+				img.Variables()
+				if err := img.Delete(v.VariableName); err != nil {
+					t.Errorf("Delete(%v): %v", v.Name, err)
+				}
+			}
+		}(n)
+	}
+	wg.Wait()
+}
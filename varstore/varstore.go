@@ -0,0 +1,78 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package varstore provides efivar.Stores backed by files rather than the
+// running system: a directory dump written by vardump, or the variable store
+// of a firmware image such as OVMF_VARS.fd. With efivar.UseStore, the rest of
+// the library can then prepare a virtual machine's boot configuration offline.
+package varstore
+
+import (
+	"encoding/binary"
+	"os"
+
+	"github.com/lukegb/goefivar/efivar"
+)
+
+// ErrAuthentication is returned for a time-based authenticated write whose
+// authentication descriptor cannot be parsed. The signature itself is not
+// checked: a file does not enforce Secure Boot.
+var ErrAuthentication = efivar.NewError(efivar.AuthRequired, "varstore: malformed authentication descriptor")
+
+// efiTimeSize is the size of the EFI_TIME at the start of an EFI_VARIABLE_AUTHENTICATION_2.
+const efiTimeSize = 16
+
+// Open opens the store at path: a directory dump if it is a directory, and
+// a firmware image otherwise.
+func Open(path string) (efivar.Store, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		return &Dir{Path: path}, nil
+	}
+	return OpenImage(path)
+}
+
+// update returns what a store holds after v is written over old, which is
+// nil if the variable does not exist. As with SetVariable, AppendWrite
+// appends to the existing data and writing no data deletes the variable, in
+// which case update returns nil.
+func update(old, v *efivar.Variable) (*efivar.Variable, error) {
+	data := v.Data
+	attrs := v.Attributes.Without(efivar.AppendWrite)
+	if v.Attributes.Has(efivar.TimeBasedAuthenticatedWriteAccess) {
+		// EFI_VARIABLE_AUTHENTICATION_2 is an EFI_TIME followed by a WIN_CERTIFICATE.
+		if len(data) < efiTimeSize+8 {
+			return nil, ErrAuthentication
+		}
+		certSize := int(binary.LittleEndian.Uint32(data[efiTimeSize:]))
+		if certSize < 8 || efiTimeSize+certSize > len(data) {
+			return nil, ErrAuthentication
+		}
+		data = data[efiTimeSize+certSize:]
+	}
+
+	if v.Attributes.Has(efivar.AppendWrite) {
+		if old == nil {
+			return &efivar.Variable{VariableName: v.VariableName, Data: append([]byte(nil), data...), Attributes: attrs}, nil
+		}
+		return &efivar.Variable{VariableName: v.VariableName, Data: append(append([]byte(nil), old.Data...), data...), Attributes: old.Attributes}, nil
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	return &efivar.Variable{VariableName: v.VariableName, Data: append([]byte(nil), data...), Attributes: attrs}, nil
+}
@@ -0,0 +1,159 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package varwatch reports changes to EFI variables as they happen.
+//
+// Neither efivarfs nor the firmware notify anyone of changes, so the Watcher
+// polls: it compares successive snapshots of the variables it is interested
+// in. An efivar.Glob's Match method selects variables by pattern.
+package varwatch
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/vardump"
+)
+
+// Op is the kind of change made to a variable.
+type Op int
+
+const (
+	Create Op = iota
+	Modify
+	Delete
+)
+
+func (o Op) String() string {
+	switch o {
+	case Create:
+		return "create"
+	case Modify:
+		return "modify"
+	case Delete:
+		return "delete"
+	}
+	return fmt.Sprintf("Op(%d)", int(o))
+}
+
+// Event describes a change to one variable.
+type Event struct {
+	Op   Op
+	Name efivar.VariableName
+
+	// Old is the variable before the change, and is nil for Create.
+	Old *efivar.Variable
+	// New is the variable after the change, and is nil for Delete.
+	New *efivar.Variable
+}
+
+// Snapshot is the content of a set of variables at one point in time.
+type Snapshot map[efivar.VariableName]*efivar.Variable
+
+// Take reads the variables for which match returns true, or all variables if
+// match is nil.
+func Take(match func(efivar.VariableName) bool) (Snapshot, error) {
+	vs, err := vardump.Capture(match)
+	if err != nil {
+		return nil, err
+	}
+	s := make(Snapshot, len(vs))
+	for _, v := range vs {
+		s[v.VariableName] = v
+	}
+	return s, nil
+}
+
+// Diff returns the changes which turn old into new, ordered by GUID and name.
+// A change of attributes alone counts as a modification.
+func Diff(old, new Snapshot) []Event {
+	var evs []Event
+	for vn, nv := range new {
+		ov, ok := old[vn]
+		switch {
+		case !ok:
+			evs = append(evs, Event{Op: Create, Name: vn, New: nv})
+		case ov.Attributes != nv.Attributes || !bytes.Equal(ov.Data, nv.Data):
+			evs = append(evs, Event{Op: Modify, Name: vn, Old: ov, New: nv})
+		}
+	}
+	for vn, ov := range old {
+		if _, ok := new[vn]; !ok {
+			evs = append(evs, Event{Op: Delete, Name: vn, Old: ov})
+		}
+	}
+	sort.Slice(evs, func(i, j int) bool {
+		if evs[i].Name.GUID != evs[j].Name.GUID {
+			return evs[i].Name.GUID.String() < evs[j].Name.GUID.String()
+		}
+		return evs[i].Name.Name < evs[j].Name.Name
+	})
+	return evs
+}
+
+// Watcher polls for changes to variables.
+type Watcher struct {
+	match func(efivar.VariableName) bool
+	last  Snapshot
+}
+
+// NewWatcher returns a Watcher for the variables for which match returns
+// true, or for all variables if match is nil. Changes are reported relative
+// to the variables' content when NewWatcher is called.
+func NewWatcher(match func(efivar.VariableName) bool) (*Watcher, error) {
+	s, err := Take(match)
+	if err != nil {
+		return nil, err
+	}
+	return &Watcher{match: match, last: s}, nil
+}
+
+// Poll returns the changes since the previous call to Poll.
+func (w *Watcher) Poll() ([]Event, error) {
+	s, err := Take(w.match)
+	if err != nil {
+		return nil, err
+	}
+	evs := Diff(w.last, s)
+	w.last = s
+	return evs, nil
+}
+
+// Run polls every interval and sends the changes to events until stop is
+// closed or polling fails.
+func (w *Watcher) Run(interval time.Duration, events chan<- Event, stop <-chan struct{}) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-t.C:
+		}
+		evs, err := w.Poll()
+		if err != nil {
+			return err
+		}
+		for _, ev := range evs {
+			select {
+			case events <- ev:
+			case <-stop:
+				return nil
+			}
+		}
+	}
+}
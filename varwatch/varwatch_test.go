@@ -0,0 +1,91 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package varwatch
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/lukegb/goefivar/efivar"
+	"github.com/lukegb/goefivar/internal/testfixture"
+)
+
+func TestDiff(t *testing.T) {
+	vn := func(name string) efivar.VariableName {
+		return efivar.VariableName{GUID: uuid.MustParse("8be4df61-93ca-11d2-aa0d-00e098032b8c"), Name: name}
+	}
+	v := func(name string, attrs efivar.Attributes, data string) *efivar.Variable {
+		return &efivar.Variable{VariableName: vn(name), Attributes: attrs, Data: []byte(data)}
+	}
+	old := Snapshot{
+		vn("A"): v("A", 7, "same"),
+		vn("B"): v("B", 7, "before"),
+		vn("C"): v("C", 7, "attrs"),
+		vn("D"): v("D", 7, "gone"),
+	}
+	new := Snapshot{
+		vn("A"): v("A", 7, "same"),
+		vn("B"): v("B", 7, "after"),
+		vn("C"): v("C", 6, "attrs"),
+		vn("E"): v("E", 7, "new"),
+	}
+
+	evs := Diff(old, new)
+	want := []struct {
+		op   Op
+		name string
+	}{{Modify, "B"}, {Modify, "C"}, {Delete, "D"}, {Create, "E"}}
+	if len(evs) != len(want) {
+		t.Fatalf("Diff returned %d events; want %d: %+v", len(evs), len(want), evs)
+	}
+	for n, ev := range evs {
+		if ev.Op != want[n].op || ev.Name.Name != want[n].name {
+			t.Errorf("event %d = %v %v; want %v %v", n, ev.Op, ev.Name.Name, want[n].op, want[n].name)
+		}
+	}
+	if evs[0].Old != old[vn("B")] || evs[0].New != new[vn("B")] {
+		t.Errorf("Modify event does not carry the old and new variables")
+	}
+	if evs[2].New != nil || evs[3].Old != nil {
+		t.Errorf("Delete or Create event carries a variable it shouldn't")
+	}
+
+	if evs := Diff(old, old); len(evs) != 0 {
+		t.Errorf("Diff(old, old) = %+v; want no events", evs)
+	}
+}
+
+func TestWatcherGlob(t *testing.T) {
+	defer testfixture.Use(t, "laptop")()
+	w, err := NewWatcher(efivar.MustGlob("Boot????").Match)
+	if err != nil {
+		t.Fatalf("NewWatcher: %v", err)
+	}
+	for _, v := range []*efivar.Variable{
+		{VariableName: efivar.VariableName{GUID: efivar.GlobalUUID, Name: "Timeout"}, Attributes: 7, Data: []byte{9, 0}},
+		{VariableName: efivar.VariableName{GUID: efivar.GlobalUUID, Name: "Boot0001"}, Attributes: 7, Data: []byte("x")},
+	} {
+		if err := v.Set(0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	evs, err := w.Poll()
+	if err != nil {
+		t.Fatalf("Poll: %v", err)
+	}
+	if len(evs) != 1 || evs[0].Op != Create || evs[0].Name.Name != "Boot0001" {
+		t.Errorf("Poll = %+v; want just the creation of Boot0001", evs)
+	}
+}